@@ -2,13 +2,22 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
+	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	_ "subsaggregator/docs"
+	"subsaggregator/internal/auth"
 	"subsaggregator/internal/db"
+	"subsaggregator/internal/notifier"
+	"subsaggregator/internal/repository"
 	"subsaggregator/internal/router"
+	"subsaggregator/internal/scheduler"
+	"subsaggregator/internal/webhook"
 	"syscall"
 	"time"
 
@@ -22,15 +31,27 @@ import (
 // @host		localhost:8080
 // @BasePath	/
 // @schemes		http
+//
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
+// @description				JWT в формате "Bearer <токен>" или API-ключ в формате "ApiKey <ключ>"
 func main() {
 	godotenv.Load(".env")
 
 	db.Init()
 
-	r := router.NewRouter()
-
 	loggerInit()
 
+	verifier, err := authInit()
+
+	if err != nil {
+		slog.Error(fmt.Errorf("аутентификация не настроена: %w", err).Error())
+		os.Exit(1)
+	}
+
+	r := router.NewRouter(verifier)
+
 	server := &http.Server{
 		Addr:    ":8080",
 		Handler: r,
@@ -40,7 +61,116 @@ func main() {
 		server.ListenAndServe()
 	}()
 
+	ctx, stopScheduler := context.WithCancel(context.Background())
+
+	go schedulerInit().Run(ctx)
+
+	go webhookDispatcherInit().Run(ctx)
+
 	gracefulShutdown(server)
+
+	stopScheduler()
+}
+
+// schedulerInit настраивает планировщик напоминаний. Письма отправляются, только если задан SMTP_ADDR
+func schedulerInit() *scheduler.Scheduler {
+	notifiers := []notifier.Notifier{
+		&notifier.WebhookNotifier{Client: notifier.NewWebhookClient(10 * time.Second)},
+	}
+
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		notifiers = append(notifiers, &notifier.SMTPNotifier{
+			Addr:     addr,
+			From:     os.Getenv("SMTP_FROM"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		})
+	}
+
+	interval, err := time.ParseDuration(os.Getenv("REMINDER_INTERVAL"))
+
+	if err != nil {
+		interval = 0
+	}
+
+	return &scheduler.Scheduler{
+		Interval:         interval,
+		SubscriptionRepo: &repository.SubscriptionRepo{},
+		ReminderRepo:     &repository.ReminderRepo{},
+		Notifiers:        notifiers,
+	}
+}
+
+// authInit настраивает проверку JWT: HS256 по JWT_HS256_SECRET, RS256 по ключу из JWT_RS256_PUBLIC_KEY_FILE
+// и набору ключей из JWT_JWKS_FILE. Без ключей сервер запускается, только если AUTH_DISABLED=true
+func authInit() (*auth.Verifier, error) {
+	if disabled, _ := strconv.ParseBool(os.Getenv("AUTH_DISABLED")); disabled {
+		slog.Warn("Аутентификация отключена, все запросы выполняются с правами администратора")
+
+		return nil, nil
+	}
+
+	verifier := &auth.Verifier{
+		HMACSecret: []byte(os.Getenv("JWT_HS256_SECRET")),
+		RSAKeys:    make(map[string]*rsa.PublicKey),
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		Audience:   os.Getenv("JWT_AUDIENCE"),
+		AdminRole:  os.Getenv("JWT_ADMIN_ROLE"),
+		Leeway:     time.Minute,
+	}
+
+	if path := os.Getenv("JWT_JWKS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys, err := auth.ParseJWKS(data)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		maps.Copy(verifier.RSAKeys, keys)
+	}
+
+	if path := os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := auth.ParseRSAPublicKeyPEM(data)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		verifier.RSAKeys[os.Getenv("JWT_RS256_KEY_ID")] = key
+	}
+
+	if len(verifier.HMACSecret) == 0 && len(verifier.RSAKeys) == 0 {
+		return nil, fmt.Errorf("no JWT keys configured, set JWT_HS256_SECRET, JWT_RS256_PUBLIC_KEY_FILE or JWT_JWKS_FILE")
+	}
+
+	return verifier, nil
+}
+
+// webhookDispatcherInit настраивает доставку событий жизненного цикла подписок по вебхукам
+func webhookDispatcherInit() *scheduler.WebhookDispatcher {
+	interval, err := time.ParseDuration(os.Getenv("WEBHOOK_INTERVAL"))
+
+	if err != nil {
+		interval = 0
+	}
+
+	return &scheduler.WebhookDispatcher{
+		Interval: interval,
+		Repo:     &repository.WebhookRepo{},
+		Sender:   &webhook.Sender{Client: notifier.NewWebhookClient(10 * time.Second)},
+	}
 }
 
 func loggerInit() {
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultAdminRole роль администратора в claim roles, если AdminRole не задана
+const DefaultAdminRole = "admin"
+
+// Verifier проверяет JWT, подписанные по HS256 общим ключом или по RS256 открытыми ключами RSA
+type Verifier struct {
+	// Общий ключ для HS256. Если не задан, токены HS256 отклоняются
+	HMACSecret []byte
+
+	// Открытые ключи для RS256 по kid. Ключ с пустым kid подходит для токенов без kid
+	RSAKeys map[string]*rsa.PublicKey
+
+	// Ожидаемые iss и aud. Если не заданы, не проверяются
+	Issuer   string
+	Audience string
+
+	// Роль в claim roles, дающая права администратора
+	AdminRole string
+
+	// Допустимое расхождение часов при проверке exp и nbf
+	Leeway time.Duration
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string     `json:"sub"`
+	Issuer    string     `json:"iss"`
+	Audience  stringList `json:"aud"`
+	ExpiresAt *int64     `json:"exp"`
+	NotBefore *int64     `json:"nbf"`
+	Roles     stringList `json:"roles"`
+	TenantId  string     `json:"tenant_id"`
+}
+
+// stringList значение claim, которое может быть строкой или массивом строк
+type stringList []string
+
+func (list *stringList) UnmarshalJSON(data []byte) error {
+	var value string
+
+	if err := json.Unmarshal(data, &value); err == nil {
+		*list = stringList{value}
+		return nil
+	}
+
+	var values []string
+
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	*list = values
+
+	return nil
+}
+
+// Verify проверяет подпись и срок действия токена и возвращает вызывающего.
+// Subject токена становится ИД пользователя, роль AdminRole в claim roles даёт права администратора,
+// claim tenant_id привязывает вызывающего к организации
+func (verifier *Verifier) Verify(token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header jwtHeader
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	if err := verifier.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("token has no expiration time")
+	}
+
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(verifier.Leeway)) {
+		return nil, fmt.Errorf("token is expired")
+	}
+
+	if claims.NotBefore != nil && now.Before(time.Unix(*claims.NotBefore, 0).Add(-verifier.Leeway)) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+
+	if verifier.Issuer != "" && claims.Issuer != verifier.Issuer {
+		return nil, fmt.Errorf("unexpected token issuer: %s", claims.Issuer)
+	}
+
+	if verifier.Audience != "" && !slices.Contains(claims.Audience, verifier.Audience) {
+		return nil, fmt.Errorf("token is not intended for this audience")
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	adminRole := verifier.AdminRole
+
+	if adminRole == "" {
+		adminRole = DefaultAdminRole
+	}
+
+	return &Principal{
+		UserId:   claims.Subject,
+		Admin:    slices.Contains(claims.Roles, adminRole),
+		TenantId: claims.TenantId,
+	}, nil
+}
+
+// verifySignature проверяет подпись токена алгоритмом из заголовка. Алгоритмы, кроме HS256 и RS256, не принимаются
+func (verifier *Verifier) verifySignature(header jwtHeader, signed string, signature []byte) error {
+	switch header.Alg {
+	case "HS256":
+		if len(verifier.HMACSecret) == 0 {
+			return fmt.Errorf("HS256 tokens are not accepted")
+		}
+
+		mac := hmac.New(sha256.New, verifier.HMACSecret)
+		mac.Write([]byte(signed))
+
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return fmt.Errorf("invalid token signature")
+		}
+
+		return nil
+	case "RS256":
+		key, ok := verifier.RSAKeys[header.Kid]
+
+		if !ok {
+			return fmt.Errorf("unknown token key: %s", header.Kid)
+		}
+
+		digest := sha256.Sum256([]byte(signed))
+
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unsupported token algorithm: %s", header.Alg)
+	}
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
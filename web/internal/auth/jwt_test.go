@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signToken собирает JWT с заголовком header и claims, подписанный sign
+func signToken(t *testing.T, header map[string]any, claims map[string]any, sign func(signed []byte) []byte) string {
+	headerJSON, _ := json.Marshal(header)
+	claimsJSON, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256(secret []byte) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+
+		return mac.Sum(nil)
+	}
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+
+		if err != nil {
+			t.Fatalf("rsa.SignPKCS1v15() error = %v", err)
+		}
+
+		return signature
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	secret := []byte("тестовый общий ключ HS256")
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+
+	jwks, _ := json.Marshal(map[string]any{
+		"keys": []map[string]string{
+			{"kty": "EC", "kid": "ec", "crv": "P-256"},
+			{
+				"kty": "RSA",
+				"kid": "key-1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+		},
+	})
+
+	rsaKeys, err := ParseJWKS(jwks)
+
+	if err != nil || len(rsaKeys) != 1 {
+		t.Fatalf("ParseJWKS() = %v, %v", rsaKeys, err)
+	}
+
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	pemKey, err := ParseRSAPublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	if err != nil || !pemKey.Equal(&rsaKey.PublicKey) {
+		t.Fatalf("ParseRSAPublicKeyPEM() = %v, %v", pemKey, err)
+	}
+
+	rsaKeys[""] = pemKey
+
+	verifier := &Verifier{
+		HMACSecret: secret,
+		RSAKeys:    rsaKeys,
+		Issuer:     "https://auth.example.com",
+		Audience:   "subsaggregator",
+		Leeway:     time.Minute,
+	}
+
+	claims := func(overrides map[string]any) map[string]any {
+		result := map[string]any{
+			"sub": "Тестовый UUID",
+			"iss": "https://auth.example.com",
+			"aud": []string{"subsaggregator", "other"},
+			"exp": now.Add(time.Hour).Unix(),
+		}
+
+		for key, value := range overrides {
+			if value == nil {
+				delete(result, key)
+			} else {
+				result[key] = value
+			}
+		}
+
+		return result
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name      string
+		token     string
+		wantErr   bool
+		wantAdmin bool
+	}{
+		{"HS256", signToken(t, map[string]any{"alg": "HS256"}, claims(nil), hs256(secret)), false, false},
+		{"RS256 из JWKS", signToken(t, map[string]any{"alg": "RS256", "kid": "key-1"}, claims(nil), rs256(t, rsaKey)), false, false},
+		{"RS256 без kid", signToken(t, map[string]any{"alg": "RS256"}, claims(nil), rs256(t, rsaKey)), false, false},
+		{"администратор", signToken(t, map[string]any{"alg": "HS256"}, claims(map[string]any{"roles": "admin"}), hs256(secret)), false, true},
+		{"истёкший с учётом расхождения часов", signToken(t, map[string]any{"alg": "HS256"}, claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}), hs256(secret)), false, false},
+		{"истёкший", signToken(t, map[string]any{"alg": "HS256"}, claims(map[string]any{"exp": now.Add(-time.Hour).Unix()}), hs256(secret)), true, false},
+		{"без срока действия", signToken(t, map[string]any{"alg": "HS256"}, claims(map[string]any{"exp": nil}), hs256(secret)), true, false},
+		{"ещё не действует", signToken(t, map[string]any{"alg": "HS256"}, claims(map[string]any{"nbf": now.Add(time.Hour).Unix()}), hs256(secret)), true, false},
+		{"другой издатель", signToken(t, map[string]any{"alg": "HS256"}, claims(map[string]any{"iss": "https://evil.example.com"}), hs256(secret)), true, false},
+		{"другая аудитория", signToken(t, map[string]any{"alg": "HS256"}, claims(map[string]any{"aud": "other"}), hs256(secret)), true, false},
+		{"без subject", signToken(t, map[string]any{"alg": "HS256"}, claims(map[string]any{"sub": nil}), hs256(secret)), true, false},
+		{"неверный ключ HS256", signToken(t, map[string]any{"alg": "HS256"}, claims(nil), hs256([]byte("другой ключ"))), true, false},
+		{"неверный ключ RS256", signToken(t, map[string]any{"alg": "RS256", "kid": "key-1"}, claims(nil), rs256(t, otherKey)), true, false},
+		{"неизвестный kid", signToken(t, map[string]any{"alg": "RS256", "kid": "key-2"}, claims(nil), rs256(t, rsaKey)), true, false},
+		{"alg none", signToken(t, map[string]any{"alg": "none"}, claims(nil), func([]byte) []byte { return nil }), true, false},
+		{"не JWT", "abc.def", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(tt.token, now)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err == nil && (principal.UserId != "Тестовый UUID" || principal.Admin != tt.wantAdmin) {
+				t.Errorf("Verify() = %+v", principal)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	secret := []byte("тестовый общий ключ HS256")
+
+	handler := Middleware(&Verifier{HMACSecret: secret}, nil)(RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(FromContext(r.Context()).UserId))
+	})))
+
+	token := func(roles []string) string {
+		return signToken(t, map[string]any{"alg": "HS256"}, map[string]any{
+			"sub":   "Тестовый UUID",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"roles": roles,
+		}, hs256(secret))
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"без токена", "", http.StatusUnauthorized},
+		{"неверный токен", "Bearer abc.def.ghi", http.StatusUnauthorized},
+		{"не администратор", "Bearer " + token(nil), http.StatusForbidden},
+		{"администратор", "Bearer " + token([]string{"admin"}), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/webhook", nil)
+
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("статус = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMiddlewareApiKey(t *testing.T) {
+	apiKeys := func(key string, now time.Time) (*Principal, error) {
+		if key != "sa_reports" {
+			return nil, fmt.Errorf("invalid api key")
+		}
+
+		return &Principal{Admin: true, Scopes: []string{ScopeReportsRead}}, nil
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	middleware := Middleware(&Verifier{HMACSecret: []byte("тестовый общий ключ HS256")}, apiKeys)
+
+	tests := []struct {
+		name          string
+		handler       http.Handler
+		authorization string
+		want          int
+	}{
+		{"отчёты", RequireScope(ScopeReportsRead)(ok), "ApiKey sa_reports", http.StatusOK},
+		{"запись без разрешения", RequireScope(ScopeSubscriptionsWrite)(ok), "ApiKey sa_reports", http.StatusForbidden},
+		{"администрирование без разрешения", RequireAdmin(ok), "ApiKey sa_reports", http.StatusForbidden},
+		{"неверный ключ", RequireScope(ScopeReportsRead)(ok), "ApiKey sa_other", http.StatusUnauthorized},
+		{"неизвестная схема", RequireScope(ScopeReportsRead)(ok), "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/subscription/sum-price", nil)
+			r.Header.Set("Authorization", tt.authorization)
+
+			w := httptest.NewRecorder()
+			middleware(tt.handler).ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("статус = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFeedTokenMiddleware(t *testing.T) {
+	feeds := func(token string) (*Principal, error) {
+		if token != "cal_feed" {
+			return nil, fmt.Errorf("invalid calendar feed token")
+		}
+
+		return &Principal{UserId: "Тестовый UUID", Scopes: []string{}}, nil
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(FromContext(r.Context()).UserId))
+	})
+	middleware := FeedTokenMiddleware(&Verifier{HMACSecret: []byte("тестовый общий ключ HS256")}, feeds)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		target  string
+		want    int
+	}{
+		{"лента", ok, "/user/1/calendar.ics?token=cal_feed", http.StatusOK},
+		{"без токена", ok, "/user/1/calendar.ics", http.StatusUnauthorized},
+		{"неверный токен", ok, "/user/1/calendar.ics?token=cal_other", http.StatusUnauthorized},
+		{"маршрут с разрешением", RequireScope(ScopeSubscriptionsRead)(ok), "/user/1/calendar.ics?token=cal_feed", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			middleware(tt.handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if w.Code != tt.want {
+				t.Errorf("статус = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestResolveTenant(t *testing.T) {
+	handler := ResolveTenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(TenantFromContext(r.Context())))
+	}))
+
+	tests := []struct {
+		name      string
+		principal Principal
+		requested string
+		want      int
+		tenantId  string
+	}{
+		{"пользователь без организации", Principal{UserId: "Тестовый UUID"}, "", http.StatusOK, "default"},
+		{"пользователь организации", Principal{UserId: "Тестовый UUID", TenantId: "marketing"}, "", http.StatusOK, "marketing"},
+		{"чужая организация", Principal{UserId: "Тестовый UUID", TenantId: "marketing"}, "sales", http.StatusForbidden, ""},
+		{"пользователь выбирает организацию", Principal{UserId: "Тестовый UUID"}, "sales", http.StatusForbidden, ""},
+		{"администратор организации", Principal{Admin: true, TenantId: "marketing"}, "sales", http.StatusForbidden, ""},
+		{"администратор без организации", Principal{Admin: true}, "sales", http.StatusOK, "sales"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/subscription", nil)
+			r = r.WithContext(NewContext(r.Context(), tt.principal))
+
+			if tt.requested != "" {
+				r.Header.Set(TenantHeader, tt.requested)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("статус = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+
+			if tt.want == http.StatusOK && w.Body.String() != tt.tenantId {
+				t.Errorf("организация = %s, want %s", w.Body.String(), tt.tenantId)
+			}
+		})
+	}
+}
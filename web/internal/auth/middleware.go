@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ApiKeyResolver возвращает вызывающего по API-ключу или ошибку, если ключ недействителен
+type ApiKeyResolver func(key string, now time.Time) (*Principal, error)
+
+// Middleware проверяет заголовок Authorization и кладёт вызывающего в контекст запроса.
+// Пользователи передают JWT в виде "Bearer <токен>", сервисы и фоновые задачи — API-ключ в виде "ApiKey <ключ>".
+// Если verifier не задан, аутентификация отключена и все запросы выполняются с правами администратора
+func Middleware(verifier *Verifier, apiKeys ApiKeyResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if verifier == nil {
+				next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), Principal{Admin: true})))
+				return
+			}
+
+			scheme, credentials, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			credentials = strings.TrimSpace(credentials)
+
+			if credentials == "" {
+				unauthorized(w, "bearer token or api key is required")
+				return
+			}
+
+			var principal *Principal
+			var err error
+
+			switch {
+			case strings.EqualFold(scheme, "Bearer"):
+				principal, err = verifier.Verify(credentials, time.Now())
+			case strings.EqualFold(scheme, "ApiKey") && apiKeys != nil:
+				principal, err = apiKeys(credentials, time.Now())
+			default:
+				err = fmt.Errorf("unsupported authorization scheme: %s", scheme)
+			}
+
+			if err != nil {
+				unauthorized(w, err.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), *principal)))
+		})
+	}
+}
+
+// FeedTokenResolver возвращает вызывающего по токену ленты или ошибку, если токен недействителен
+type FeedTokenResolver func(token string) (*Principal, error)
+
+// FeedTokenMiddleware проверяет токен ленты из параметра token и кладёт вызывающего в контекст запроса.
+// Используется для лент, которые запрашивают приложения без заголовка Authorization, например календари.
+// Если verifier не задан, аутентификация отключена и все запросы выполняются с правами администратора
+func FeedTokenMiddleware(verifier *Verifier, feeds FeedTokenResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if verifier == nil {
+				next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), Principal{Admin: true})))
+				return
+			}
+
+			token := r.URL.Query().Get("token")
+
+			if token == "" {
+				http.Error(w, "Unauthorized: feed token is required", http.StatusUnauthorized)
+				return
+			}
+
+			principal, err := feeds(token)
+
+			if err != nil {
+				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), *principal)))
+		})
+	}
+}
+
+// RequireScope пропускает только запросы с разрешением scope
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !FromContext(r.Context()).HasScope(scope) {
+				http.Error(w, "Forbidden: scope "+scope+" is required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAdmin пропускает только запросы администратора, а для API-ключей — ключей с разрешением admin
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal := FromContext(r.Context()); !principal.Admin || !principal.HasScope(ScopeAdmin) {
+			http.Error(w, "Forbidden: admin role is required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Add("WWW-Authenticate", `Bearer realm="subsaggregator"`)
+	w.Header().Add("WWW-Authenticate", `ApiKey realm="subsaggregator"`)
+	http.Error(w, "Unauthorized: "+message, http.StatusUnauthorized)
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Разрешения API-ключей
+const (
+	ScopeSubscriptionsRead  = "subscriptions:read"
+	ScopeSubscriptionsWrite = "subscriptions:write"
+	ScopeReportsRead        = "reports:read"
+	ScopeAdmin              = "admin"
+)
+
+// Scopes все разрешения API-ключей
+var Scopes = []string{ScopeSubscriptionsRead, ScopeSubscriptionsWrite, ScopeReportsRead, ScopeAdmin}
+
+// Principal представляет вызывающего, прошедшего аутентификацию
+type Principal struct {
+	// ИД пользователя из subject токена или пользователя, от имени которого действует API-ключ
+	UserId string
+
+	// Администратор может работать с подписками всех пользователей
+	Admin bool
+
+	// Разрешения API-ключа. Для пользователей с JWT не задаются, и им доступны все маршруты
+	Scopes []string
+
+	// ИД API-ключа, если запрос выполнен с ним
+	ApiKeyId int
+
+	// ИД организации, к которой привязан вызывающий. Администратор без организации управляет всеми организациями
+	TenantId string
+}
+
+type principalKey struct{}
+
+// NewContext возвращает контекст запроса с вызывающим
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext возвращает вызывающего из контекста запроса. Если вызывающего нет, возвращается пустой Principal без прав
+func FromContext(ctx context.Context) Principal {
+	principal, _ := ctx.Value(principalKey{}).(Principal)
+
+	return principal
+}
+
+// Owns сообщает, может ли вызывающий работать с данными пользователя
+func (principal Principal) Owns(userId string) bool {
+	return principal.Admin || (principal.UserId != "" && principal.UserId == userId)
+}
+
+// PlatformAdmin сообщает, может ли вызывающий управлять организациями и видеть данные всех организаций
+func (principal Principal) PlatformAdmin() bool {
+	return principal.Admin && principal.TenantId == "" && principal.HasScope(ScopeAdmin)
+}
+
+// HasScope сообщает, есть ли у вызывающего разрешение
+func (principal Principal) HasScope(scope string) bool {
+	return principal.Scopes == nil || slices.Contains(principal.Scopes, scope)
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"subsaggregator/internal/model"
+)
+
+// TenantHeader заголовок, в котором администратор без организации выбирает организацию запроса
+const TenantHeader = "X-Tenant-Id"
+
+type tenantKey struct{}
+
+// Tenant возвращает организацию, с данными которой работает запрос. Вызывающий, привязанный к организации,
+// работает только с ней; администратор без организации выбирает её в requested, остальные вызывающие
+// работают с организацией по умолчанию
+func (principal Principal) Tenant(requested string) (string, error) {
+	switch {
+	case principal.TenantId != "" && requested != "" && requested != principal.TenantId:
+		return "", fmt.Errorf("access to organisation %s is denied", requested)
+	case principal.TenantId != "":
+		return principal.TenantId, nil
+	case requested == "":
+		return model.DefaultTenantId, nil
+	case principal.Admin || requested == model.DefaultTenantId:
+		return requested, nil
+	}
+
+	return "", fmt.Errorf("access to organisation %s is denied", requested)
+}
+
+// ResolveTenant определяет организацию запроса по вызывающему и заголовку X-Tenant-Id и кладёт её в контекст.
+// Запрос к чужой организации отклоняется с кодом 403
+func ResolveTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantId, err := FromContext(r.Context()).Tenant(r.Header.Get(TenantHeader))
+
+		if err != nil {
+			http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenantId)))
+	})
+}
+
+// TenantFromContext возвращает организацию запроса. Если она не определена, возвращается организация по умолчанию
+func TenantFromContext(ctx context.Context) string {
+	if tenantId, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenantId
+	}
+
+	return model.DefaultTenantId
+}
+
+// RequirePlatformAdmin пропускает только запросы администратора, не привязанного к организации
+func RequirePlatformAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !FromContext(r.Context()).PlatformAdmin() {
+			http.Error(w, "Forbidden: platform admin role is required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,3 @@
+DROP FUNCTION IF EXISTS exchange_rate(TEXT, DATE);
+DROP TABLE IF EXISTS exchange_rates;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS currency;
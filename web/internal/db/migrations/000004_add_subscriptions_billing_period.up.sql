@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS billing_period TEXT NOT NULL DEFAULT 'month' -- расчётный период подписки
+    CHECK (billing_period IN ('week', 'month', 'quarter', 'year'));
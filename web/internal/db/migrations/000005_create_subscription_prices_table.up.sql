@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS subscription_prices (
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE, -- ИД записи о подписке
+    effective_from DATE NOT NULL,                                                     -- месяц, с которого действует стоимость
+    price INTEGER NOT NULL,                                                           -- стоимость подписки за расчётный период
+    PRIMARY KEY (subscription_id, effective_from)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS subscription_audit;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,13 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ DEFAULT NULL; -- дата удаления записи о подписке
+
+CREATE TABLE IF NOT EXISTS subscription_audit (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL,                -- ИД записи о подписке
+    action TEXT NOT NULL,                            -- действие: create, update, delete, restore
+    actor TEXT NOT NULL DEFAULT '',                  -- кто выполнил действие
+    before JSONB DEFAULT NULL,                       -- запись о подписке до изменения
+    after JSONB DEFAULT NULL,                        -- запись о подписке после изменения
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()    -- дата изменения
+);
+
+CREATE INDEX IF NOT EXISTS subscription_audit_subscription_id_index ON subscription_audit (subscription_id, created_at);
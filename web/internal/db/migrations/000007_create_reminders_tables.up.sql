@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS reminder_settings (
+    user_id TEXT PRIMARY KEY,                        -- ИД пользователя
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,          -- пользователь согласился получать напоминания
+    email TEXT NOT NULL DEFAULT '',                  -- адрес для напоминаний по почте
+    webhook_url TEXT NOT NULL DEFAULT '',            -- адрес для напоминаний через вебхук
+    days_before INTEGER NOT NULL DEFAULT 3,          -- за сколько дней напоминать
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()    -- дата изменения настроек
+);
+
+CREATE TABLE IF NOT EXISTS sent_reminders (
+    subscription_id INTEGER NOT NULL,                -- ИД записи о подписке
+    kind TEXT NOT NULL,                              -- вид напоминания: renewal, end
+    due_date DATE NOT NULL,                          -- дата продления или окончания подписки
+    channel TEXT NOT NULL,                           -- канал отправки: email, webhook
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),      -- дата отправки
+    PRIMARY KEY (subscription_id, kind, due_date, channel)
+);
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,                               -- адрес, на который отправляются события
+    secret TEXT NOT NULL,                            -- ключ подписи HMAC-SHA256
+    event_types TEXT[] NOT NULL,                     -- типы событий, на которые подписан вебхук
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),   -- дата регистрации
+    deleted_at TIMESTAMPTZ DEFAULT NULL              -- дата удаления
+);
+
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),   -- ИД события
+    type TEXT NOT NULL,                              -- тип события: subscription.created, subscription.updated, subscription.deleted
+    data JSONB NOT NULL,                             -- запись о подписке
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),   -- дата события
+    dispatched_at TIMESTAMPTZ DEFAULT NULL           -- дата создания доставок по вебхукам
+);
+
+CREATE INDEX IF NOT EXISTS webhook_outbox_pending_index ON webhook_outbox (created_at) WHERE dispatched_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints (id),
+    event_id UUID NOT NULL REFERENCES webhook_outbox (id),
+    status TEXT NOT NULL DEFAULT 'pending',          -- статус доставки: pending, delivered, failed
+    attempts INTEGER NOT NULL DEFAULT 0,             -- количество попыток
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- дата следующей попытки
+    last_status_code INTEGER NOT NULL DEFAULT 0,     -- код ответа последней попытки
+    last_error TEXT NOT NULL DEFAULT '',             -- ошибка последней попытки
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),   -- дата создания доставки
+    delivered_at TIMESTAMPTZ DEFAULT NULL,           -- дата успешной доставки
+    UNIQUE (endpoint_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_index ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_id_index ON webhook_deliveries (endpoint_id, created_at);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS budgets (
+    id SERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,                           -- ИД пользователя
+    service_name TEXT NOT NULL DEFAULT '',           -- название сервиса, пустое для бюджета на все подписки
+    amount INTEGER NOT NULL CHECK (amount > 0),      -- лимит расходов в месяц
+    currency TEXT NOT NULL DEFAULT 'RUB',            -- валюта лимита по ISO 4217
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),   -- дата создания
+    UNIQUE (user_id, service_name)
+);
+
+CREATE TABLE IF NOT EXISTS budget_alerts (
+    budget_id INTEGER NOT NULL REFERENCES budgets (id) ON DELETE CASCADE,
+    month DATE NOT NULL,                             -- месяц, в котором расходы достигли порога
+    threshold INTEGER NOT NULL,                      -- порог в процентах от лимита: 80, 100
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),   -- дата предупреждения
+    PRIMARY KEY (budget_id, month, threshold)
+);
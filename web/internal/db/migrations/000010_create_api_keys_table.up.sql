@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,                              -- назначение ключа
+    prefix TEXT NOT NULL,                            -- начало ключа для опознания в списке
+    key_hash TEXT NOT NULL UNIQUE,                   -- SHA-256 ключа, сам ключ не хранится
+    user_id TEXT NOT NULL DEFAULT '',                -- пользователь, от имени которого действует ключ; пустой — все пользователи
+    scopes TEXT[] NOT NULL,                          -- разрешения: subscriptions:read, subscriptions:write, reports:read, admin
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),   -- дата создания
+    expires_at TIMESTAMPTZ DEFAULT NULL,             -- дата окончания действия
+    last_used_at TIMESTAMPTZ DEFAULT NULL,           -- дата последнего использования
+    revoked_at TIMESTAMPTZ DEFAULT NULL              -- дата отзыва
+);
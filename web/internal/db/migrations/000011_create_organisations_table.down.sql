@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS subscriptions_tenant_id_index;
+
+ALTER TABLE reminder_settings DROP CONSTRAINT IF EXISTS reminder_settings_pkey;
+ALTER TABLE reminder_settings DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE reminder_settings ADD PRIMARY KEY (user_id);
+
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_tenant_id_user_id_service_name_key;
+ALTER TABLE budgets DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE budgets ADD CONSTRAINT budgets_user_id_service_name_key UNIQUE (user_id, service_name);
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhook_outbox DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhook_endpoints DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS organisations;
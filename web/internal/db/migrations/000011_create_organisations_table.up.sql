@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS organisations (
+    id TEXT PRIMARY KEY,                             -- ИД организации, используется в tenant_id
+    name TEXT NOT NULL,                              -- название организации
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()    -- дата создания
+);
+
+-- Существующие данные переходят в организацию по умолчанию
+INSERT INTO organisations (id, name) VALUES ('default', 'Организация по умолчанию') ON CONFLICT DO NOTHING;
+
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES organisations (id); -- ИД организации
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES organisations (id);
+ALTER TABLE reminder_settings ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES organisations (id);
+ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES organisations (id);
+ALTER TABLE webhook_outbox ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES organisations (id);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES organisations (id);
+
+-- Пользователи разных организаций с одинаковым user_id — разные пользователи
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_user_id_service_name_key;
+ALTER TABLE budgets ADD CONSTRAINT budgets_tenant_id_user_id_service_name_key UNIQUE (tenant_id, user_id, service_name);
+
+ALTER TABLE reminder_settings DROP CONSTRAINT IF EXISTS reminder_settings_pkey;
+ALTER TABLE reminder_settings ADD PRIMARY KEY (tenant_id, user_id);
+
+CREATE INDEX IF NOT EXISTS subscriptions_tenant_id_index ON subscriptions (tenant_id, user_id);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS services (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,                       -- каноническое название сервиса
+    category TEXT NOT NULL DEFAULT 'other',          -- категория: video, music, cloud, software, games, news, education, other
+    default_price INTEGER NOT NULL DEFAULT 0 CHECK (default_price >= 0), -- стоимость по умолчанию в RUB за месяц, 0 — не задана
+    vendor_url TEXT NOT NULL DEFAULT '',             -- сайт поставщика
+    logo_url TEXT NOT NULL DEFAULT '',               -- адрес логотипа
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()    -- дата создания
+);
+
+-- Названия, по которым подписка связывается с сервисом каталога, включая каноническое название.
+-- Название ищется в нормализованном виде: в нижнем регистре, без пробелов по краям и повторных пробелов
+CREATE TABLE IF NOT EXISTS service_aliases (
+    normalized TEXT PRIMARY KEY,                     -- нормализованное название
+    alias TEXT NOT NULL,                             -- название в том виде, в каком оно добавлено в каталог
+    service_id INTEGER NOT NULL REFERENCES services (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS service_aliases_service_id_index ON service_aliases (service_id);
+
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS service_id INTEGER DEFAULT NULL REFERENCES services (id) ON DELETE SET NULL; -- ИД сервиса каталога
+
+CREATE INDEX IF NOT EXISTS subscriptions_service_id_index ON subscriptions (service_id);
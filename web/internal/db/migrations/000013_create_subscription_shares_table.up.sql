@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS subscription_shares (
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,                           -- ИД участника подписки, не владельца
+    percent NUMERIC(5, 2) DEFAULT NULL CHECK (percent > 0 AND percent <= 100), -- доля в процентах от стоимости
+    amount INTEGER DEFAULT NULL CHECK (amount > 0),  -- фиксированная доля за расчётный период в валюте подписки
+    PRIMARY KEY (subscription_id, user_id),
+    CHECK ((percent IS NULL) <> (amount IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS subscription_shares_user_id_index ON subscription_shares (user_id);
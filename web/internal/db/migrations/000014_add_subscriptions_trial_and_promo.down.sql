@@ -0,0 +1,6 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_promo_check;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_trial_end_date_check;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS promo_months;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS promo_price;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS trial_end_date;
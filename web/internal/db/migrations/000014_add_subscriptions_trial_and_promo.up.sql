@@ -0,0 +1,6 @@
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS trial_end_date DATE; -- последний месяц бесплатного пробного периода
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS promo_price INTEGER CHECK (promo_price >= 0); -- стоимость за расчётный период по акции
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS promo_months INTEGER CHECK (promo_months > 0); -- длительность акции в месяцах после пробного периода
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_trial_end_date_check CHECK (trial_end_date >= date_trunc('month', start_date));
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_promo_check CHECK ((promo_price IS NULL) = (promo_months IS NULL));
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS subscription_pauses (
+    subscription_id INTEGER NOT NULL REFERENCES subscriptions (id) ON DELETE CASCADE, -- ИД записи о подписке
+    start_date DATE NOT NULL,                                                         -- первый месяц паузы
+    end_date DATE DEFAULT NULL,                                                       -- последний месяц паузы, NULL — до возобновления
+    PRIMARY KEY (subscription_id, start_date),
+    CHECK (end_date IS NULL OR end_date >= start_date)
+);
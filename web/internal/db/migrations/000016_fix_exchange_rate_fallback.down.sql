@@ -0,0 +1,22 @@
+-- Возвращает курс валюты к рублю, действующий в указанном месяце
+CREATE OR REPLACE FUNCTION exchange_rate(rate_currency TEXT, rate_month DATE) RETURNS NUMERIC AS $$
+DECLARE
+    result NUMERIC;
+BEGIN
+    IF rate_currency = 'RUB' THEN
+        RETURN 1;
+    END IF;
+
+    SELECT rate INTO result
+    FROM exchange_rates
+    WHERE currency = rate_currency
+    ORDER BY month > rate_month, abs(month - rate_month)
+    LIMIT 1;
+
+    IF result IS NULL THEN
+        RAISE EXCEPTION 'exchange rate for % not found', rate_currency;
+    END IF;
+
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql STABLE;
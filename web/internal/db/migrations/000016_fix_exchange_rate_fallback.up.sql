@@ -0,0 +1,23 @@
+-- Возвращает курс валюты к рублю, действующий в указанном месяце: последний курс, начавший действовать не позже месяца.
+-- Более поздние курсы не подставляются, при отсутствии курса функция завершается ошибкой
+CREATE OR REPLACE FUNCTION exchange_rate(rate_currency TEXT, rate_month DATE) RETURNS NUMERIC AS $$
+DECLARE
+    result NUMERIC;
+BEGIN
+    IF rate_currency = 'RUB' THEN
+        RETURN 1;
+    END IF;
+
+    SELECT rate INTO result
+    FROM exchange_rates
+    WHERE currency = rate_currency AND month <= rate_month
+    ORDER BY month DESC
+    LIMIT 1;
+
+    IF result IS NULL THEN
+        RAISE EXCEPTION 'exchange rate for % on % not found', rate_currency, rate_month;
+    END IF;
+
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql STABLE;
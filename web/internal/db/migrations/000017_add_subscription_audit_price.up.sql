@@ -0,0 +1 @@
+ALTER TABLE subscription_audit ADD COLUMN IF NOT EXISTS price JSONB DEFAULT NULL; -- изменение стоимости для действия change_price
@@ -0,0 +1 @@
+ALTER TABLE subscription_pauses DROP CONSTRAINT IF EXISTS subscription_pauses_no_overlap;
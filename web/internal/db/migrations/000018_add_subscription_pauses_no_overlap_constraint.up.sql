@@ -0,0 +1,6 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE subscription_pauses ADD CONSTRAINT subscription_pauses_no_overlap EXCLUDE USING GIST (
+    subscription_id WITH =,
+    daterange(start_date, end_date, '[]') WITH &&
+);
@@ -0,0 +1 @@
+ALTER TABLE subscription_audit ADD COLUMN IF NOT EXISTS pause JSONB DEFAULT NULL; -- пауза для действий pause и resume
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    tenant_id TEXT NOT NULL DEFAULT 'default' REFERENCES organisations (id), -- ИД организации
+    user_id TEXT NOT NULL,                                                   -- пользователь, чья лента календаря доступна по токену
+    token_hash TEXT NOT NULL UNIQUE,                                         -- SHA-256 токена, сам токен не хранится
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),                           -- дата выпуска токена
+    PRIMARY KEY (tenant_id, user_id)
+);
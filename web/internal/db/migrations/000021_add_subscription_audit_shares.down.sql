@@ -0,0 +1 @@
+ALTER TABLE subscription_audit DROP COLUMN IF EXISTS shares;
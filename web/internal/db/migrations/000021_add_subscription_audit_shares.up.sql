@@ -0,0 +1 @@
+ALTER TABLE subscription_audit ADD COLUMN IF NOT EXISTS shares JSONB DEFAULT NULL; -- доли участников после действия change_shares
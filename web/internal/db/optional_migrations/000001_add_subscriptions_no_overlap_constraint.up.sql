@@ -0,0 +1,8 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_no_overlap EXCLUDE USING GIST (
+    tenant_id WITH =,
+    user_id WITH =,
+    service_name WITH =,
+    daterange(start_date, end_date, '[]') WITH &&
+) WHERE (deleted_at IS NULL);
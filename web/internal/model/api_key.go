@@ -0,0 +1,62 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"time"
+)
+
+// ApiKey представляет ключ доступа к API для сервисов и фоновых задач
+//
+//	@modelId	api-key
+//
+// swagger:model ApiKey
+type ApiKey struct {
+	// ИД ключа
+	// required: true
+	// min: 1
+	Id int `json:"id"`
+
+	// Назначение ключа
+	// required: true
+	// example: "Ночная выгрузка отчётов"
+	Name string `json:"name"`
+
+	// Начало ключа для опознания в списке
+	// required: true
+	// example: "sa_1f3a9c0b"
+	Prefix string `json:"prefix"`
+
+	// Ключ целиком. Возвращается только при создании, в базе хранится только его хеш
+	// required: false
+	Key string `json:"key,omitempty"`
+
+	// ИД пользователя, от имени которого действует ключ. Если не указан, ключ действует для всех пользователей
+	// required: false
+	UserId string `json:"user_id,omitempty"`
+
+	// ИД организации, данными которой ограничен ключ
+	// required: true
+	// example: "default"
+	TenantId string `json:"tenant_id"`
+
+	// Разрешения ключа
+	// required: true
+	// example: ["subscriptions:read", "reports:read"]
+	Scopes []string `json:"scopes"`
+
+	// Дата создания
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+
+	// Дата окончания действия
+	// required: false
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Дата последнего использования
+	// required: false
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	// Дата отзыва
+	// required: false
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/utils"
+	"time"
+)
+
+// BudgetThresholds пороги расходов в процентах от лимита бюджета, при достижении которых отправляется предупреждение
+var BudgetThresholds = []int{80, 100}
+
+// WebhookEventBudgetThreshold событие достижения порога расходов бюджета
+const WebhookEventBudgetThreshold = "budget.threshold_reached"
+
+// Budget представляет лимит расходов пользователя на подписки в месяц
+//
+//	@modelId	budget
+//
+// swagger:model Budget
+type Budget struct {
+	// ИД бюджета
+	// required: true
+	// min: 1
+	Id int `json:"id"`
+
+	// ИД пользователя
+	// required: true
+	UserId string `json:"user_id"`
+
+	// ИД организации пользователя
+	// required: true
+	// example: "default"
+	TenantId string `json:"tenant_id"`
+
+	// Название сервиса. Если не указано, бюджет ограничивает расходы на все подписки пользователя
+	// required: false
+	// example: "Yandex Plus"
+	ServiceName string `json:"service_name,omitempty"`
+
+	// Лимит расходов в месяц
+	// required: true
+	// min: 1
+	Amount int `json:"amount"`
+
+	// Код валюты лимита по ISO 4217
+	// required: true
+	// example: "RUB"
+	Currency string `json:"currency"`
+
+	// Дата создания
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BudgetMonth представляет расходы по бюджету за месяц
+//
+//	@modelId	budget-month
+//
+// swagger:model BudgetMonth
+type BudgetMonth struct {
+	// Месяц
+	// required: true
+	Month *utils.Date `json:"month" swaggertype:"string" example:"07-2025"`
+
+	// Расходы за месяц в валюте бюджета
+	// required: true
+	Spent int `json:"spent"`
+
+	// Расходы в процентах от лимита
+	// required: true
+	// example: 85
+	Percent int `json:"percent"`
+
+	// Наибольший достигнутый порог в процентах: 80 или 100, 0 если порог не достигнут
+	// required: true
+	// example: 80
+	Threshold int `json:"threshold"`
+}
+
+// BudgetStatus представляет сравнение расходов по месяцам с лимитом бюджета
+//
+//	@modelId	budget-status
+//
+// swagger:model BudgetStatus
+type BudgetStatus struct {
+	// Бюджет
+	// required: true
+	Budget Budget `json:"budget"`
+
+	// Расходы по месяцам
+	// required: true
+	Months []BudgetMonth `json:"months"`
+}
+
+// BudgetAlert представляет предупреждение о достижении порога расходов бюджета,
+// отправляемое по вебхукам как событие budget.threshold_reached
+//
+//	@modelId	budget-alert
+//
+// swagger:model BudgetAlert
+type BudgetAlert struct {
+	// Бюджет
+	// required: true
+	Budget Budget `json:"budget"`
+
+	// Месяц
+	// required: true
+	Month *utils.Date `json:"month" swaggertype:"string" example:"07-2025"`
+
+	// Достигнутый порог в процентах
+	// required: true
+	// example: 100
+	Threshold int `json:"threshold"`
+
+	// Расходы за месяц в валюте бюджета
+	// required: true
+	Spent int `json:"spent"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"time"
+)
+
+// CalendarFeed представляет токен ленты календаря пользователя. Приложения календаря не передают заголовок Authorization,
+// поэтому лента запрашивается с токеном в параметре token
+//
+//	@modelId	calendar-feed
+//
+// swagger:model CalendarFeed
+type CalendarFeed struct {
+	// ИД пользователя, чья лента доступна по токену
+	// required: true
+	UserId string `json:"user_id"`
+
+	// ИД организации пользователя
+	// required: true
+	// example: "default"
+	TenantId string `json:"tenant_id"`
+
+	// Токен ленты. Возвращается только при выпуске, в базе хранится только его хеш
+	// required: false
+	Token string `json:"token,omitempty"`
+
+	// Дата выпуска токена
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"time"
+)
+
+// Категории сервисов каталога
+const (
+	ServiceCategoryVideo     = "video"
+	ServiceCategoryMusic     = "music"
+	ServiceCategoryCloud     = "cloud"
+	ServiceCategorySoftware  = "software"
+	ServiceCategoryGames     = "games"
+	ServiceCategoryNews      = "news"
+	ServiceCategoryEducation = "education"
+
+	// ServiceCategoryOther категория сервисов без категории и подписок, не связанных с каталогом
+	ServiceCategoryOther = "other"
+)
+
+// ServiceCategories все категории сервисов каталога
+var ServiceCategories = []string{
+	ServiceCategoryVideo,
+	ServiceCategoryMusic,
+	ServiceCategoryCloud,
+	ServiceCategorySoftware,
+	ServiceCategoryGames,
+	ServiceCategoryNews,
+	ServiceCategoryEducation,
+	ServiceCategoryOther,
+}
+
+// CatalogService представляет сервис каталога, с которым связываются записи о подписках
+//
+//	@modelId	catalog-service
+//
+// swagger:model CatalogService
+type CatalogService struct {
+	// ИД сервиса
+	// required: true
+	// min: 1
+	Id int `json:"id"`
+
+	// Каноническое название сервиса, которое получают связанные записи о подписках
+	// required: true
+	// example: "Yandex Plus"
+	Name string `json:"name"`
+
+	// Другие названия сервиса, по которым с ним связываются записи о подписках. Регистр и лишние пробелы не учитываются
+	// required: true
+	// example: ["Яндекс Плюс"]
+	Aliases []string `json:"aliases"`
+
+	// Категория сервиса: video, music, cloud, software, games, news, education или other
+	// required: true
+	// example: "video"
+	Category string `json:"category"`
+
+	// Стоимость подписки за месяц в RUB, которая подставляется в записи о подписке без стоимости
+	// required: false
+	// example: 400
+	DefaultPrice int `json:"default_price,omitempty"`
+
+	// Сайт поставщика
+	// required: false
+	// example: "https://plus.yandex.ru"
+	VendorUrl string `json:"vendor_url,omitempty"`
+
+	// Адрес логотипа
+	// required: false
+	// example: "https://yastatic.net/s3/plus/logo.svg"
+	LogoUrl string `json:"logo_url,omitempty"`
+
+	// Дата создания
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+}
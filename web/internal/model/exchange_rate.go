@@ -0,0 +1,27 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/utils"
+)
+
+// ExchangeRate представляет курс валюты к рублю, действующий с указанного месяца
+//
+//	@modelId	exchange-rate
+//
+// swagger:model ExchangeRate
+type ExchangeRate struct {
+	// Код валюты по ISO 4217
+	// required: true
+	// example: "USD"
+	Currency string `json:"currency"`
+
+	// Месяц, с которого действует курс
+	// required: true
+	Month *utils.Date `json:"month" swaggertype:"string" example:"07-2025"`
+
+	// Стоимость единицы валюты в рублях
+	// required: true
+	// example: 92.5
+	Rate float64 `json:"rate"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/utils"
+)
+
+// Forecast представляет прогноз расходов на подписки на ближайшие месяцы
+//
+//	@modelId	forecast
+//
+// swagger:model Forecast
+type Forecast struct {
+	// Первый месяц прогноза
+	// required: true
+	From *utils.Date `json:"from" swaggertype:"string" example:"07-2025"`
+
+	// Последний месяц прогноза
+	// required: true
+	To *utils.Date `json:"to" swaggertype:"string" example:"06-2026"`
+
+	// Код валюты прогноза по ISO 4217
+	// required: true
+	// example: "RUB"
+	Currency string `json:"currency"`
+
+	// Суммарные расходы за весь период прогноза
+	// required: true
+	Total int `json:"total"`
+
+	// Расходы за весь период прогноза в разрезе сервисов
+	// required: true
+	Services []ServicePrice `json:"services"`
+
+	// Расходы по месяцам, включая месяцы без расходов
+	// required: true
+	Months []MonthlyPrice `json:"months"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/utils"
+)
+
+// MonthlyPrice представляет суммарную стоимость подписок за месяц
+//
+//	@modelId	monthly-price
+//
+// swagger:model MonthlyPrice
+type MonthlyPrice struct {
+	// Месяц
+	// required: true
+	Month *utils.Date `json:"month" swaggertype:"string" example:"07-2025"`
+
+	// Суммарная стоимость подписок за месяц
+	// required: true
+	Total int `json:"total"`
+
+	// Стоимость подписок за месяц в разрезе сервисов
+	// required: true
+	Services []ServicePrice `json:"services"`
+}
+
+// ServicePrice представляет суммарную стоимость подписок сервиса
+//
+//	@modelId	service-price
+//
+// swagger:model ServicePrice
+type ServicePrice struct {
+	// Название сервиса, предоставляющего подписку
+	// required: true
+	// example: "Yandex Plus"
+	ServiceName string `json:"service_name"`
+
+	// Суммарная стоимость подписок сервиса
+	// required: true
+	Total int `json:"total"`
+}
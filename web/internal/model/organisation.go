@@ -0,0 +1,31 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"time"
+)
+
+// DefaultTenantId организация, к которой относятся данные, созданные до появления организаций,
+// и данные вызывающих, не привязанных к организации
+const DefaultTenantId = "default"
+
+// Organisation представляет организацию, которой принадлежат пользователи и их подписки
+//
+//	@modelId	organisation
+//
+// swagger:model Organisation
+type Organisation struct {
+	// ИД организации, используется как tenant_id
+	// required: true
+	// example: "marketing"
+	Id string `json:"id"`
+
+	// Название организации
+	// required: true
+	// example: "Отдел маркетинга"
+	Name string `json:"name"`
+
+	// Дата создания
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+}
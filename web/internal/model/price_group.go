@@ -0,0 +1,54 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/utils"
+)
+
+// Поля, по которым группируется суммарная стоимость подписок
+const (
+	GroupByUserId      = "user_id"
+	GroupByServiceName = "service_name"
+	GroupByMonth       = "month"
+	GroupByTenantId    = "tenant_id"
+	GroupByCategory    = "category"
+)
+
+// PriceGroup представляет суммарную стоимость подписок в группе
+//
+//	@modelId	price-group
+//
+// swagger:model PriceGroup
+type PriceGroup struct {
+	// ИД организации, если группировка выполнена по tenant_id
+	// required: false
+	// example: "default"
+	TenantId string `json:"tenant_id,omitempty"`
+
+	// ИД пользователя, если группировка выполнена по user_id
+	// required: false
+	UserId string `json:"user_id,omitempty"`
+
+	// Название сервиса, если группировка выполнена по service_name
+	// required: false
+	// example: "Yandex Plus"
+	ServiceName string `json:"service_name,omitempty"`
+
+	// Категория сервиса каталога, если группировка выполнена по category.
+	// Подписки, не связанные с каталогом, относятся к категории other
+	// required: false
+	// example: "video"
+	Category string `json:"category,omitempty"`
+
+	// Месяц, если группировка выполнена по month
+	// required: false
+	Month *utils.Date `json:"month,omitempty" swaggertype:"string" example:"07-2025"`
+
+	// Суммарная стоимость подписок в группе
+	// required: true
+	Total int `json:"total"`
+
+	// Количество подписок в группе
+	// required: true
+	Count int `json:"count"`
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"time"
+)
+
+// Виды напоминаний о подписках
+const (
+	ReminderKindRenewal = "renewal"
+	ReminderKindEnd     = "end"
+)
+
+// Каналы отправки напоминаний
+const (
+	ReminderChannelEmail   = "email"
+	ReminderChannelWebhook = "webhook"
+)
+
+// ReminderSettings представляет настройки напоминаний пользователя о продлении и окончании подписок
+//
+//	@modelId	reminder-settings
+//
+// swagger:model ReminderSettings
+type ReminderSettings struct {
+	// ИД пользователя
+	// required: true
+	UserId string `json:"user_id"`
+
+	// ИД организации пользователя
+	// required: true
+	// example: "default"
+	TenantId string `json:"tenant_id"`
+
+	// Пользователь согласился получать напоминания
+	// required: true
+	Enabled bool `json:"enabled"`
+
+	// Адрес для напоминаний по почте
+	// required: false
+	// example: "user@example.com"
+	Email string `json:"email,omitempty"`
+
+	// Адрес для напоминаний через вебхук
+	// required: false
+	// example: "https://example.com/hooks/reminders"
+	WebhookUrl string `json:"webhook_url,omitempty"`
+
+	// За сколько дней до продления или окончания подписки напоминать
+	// required: true
+	// example: 3
+	DaysBefore int `json:"days_before"`
+}
+
+// Reminder представляет напоминание о продлении или окончании подписки
+//
+//	@modelId	reminder
+//
+// swagger:model Reminder
+type Reminder struct {
+	// Вид напоминания: renewal или end
+	// required: true
+	// example: "renewal"
+	Kind string `json:"kind"`
+
+	// ИД записи о подписке
+	// required: true
+	SubscriptionId int `json:"subscription_id"`
+
+	// ИД пользователя
+	// required: true
+	UserId string `json:"user_id"`
+
+	// Название сервиса, предоставляющего подписку
+	// required: true
+	ServiceName string `json:"service_name"`
+
+	// Стоимость продления
+	// required: false
+	Price int `json:"price,omitempty"`
+
+	// Валюта стоимости
+	// required: false
+	Currency string `json:"currency,omitempty"`
+
+	// Дата продления или окончания подписки
+	// required: true
+	DueDate time.Time `json:"due_date" format:"date"`
+}
@@ -3,6 +3,34 @@ package model
 import (
 	_ "subsaggregator/docs"
 	"subsaggregator/internal/utils"
+	"time"
+)
+
+// DefaultCurrency валюта, в которой хранятся и суммируются стоимости подписок по умолчанию
+const DefaultCurrency = "RUB"
+
+// Расчётные периоды подписки
+const (
+	BillingPeriodWeek    = "week"
+	BillingPeriodMonth   = "month"
+	BillingPeriodQuarter = "quarter"
+	BillingPeriodYear    = "year"
+)
+
+// BillingPeriodMonths длительность расчётных периодов в месяцах
+var BillingPeriodMonths = map[string]int{
+	BillingPeriodMonth:   1,
+	BillingPeriodQuarter: 3,
+	BillingPeriodYear:    12,
+}
+
+// Режимы учёта стоимости подписок с расчётным периодом длиннее месяца
+const (
+	// BillingModeCharge учитывает стоимость в месяце продления подписки
+	BillingModeCharge = "charge"
+
+	// BillingModeAmortize распределяет стоимость равными долями по месяцам
+	BillingModeAmortize = "amortize"
 )
 
 // Subscription представляет запись о подписке
@@ -21,16 +49,37 @@ type Subscription struct {
 	// example: "Yandex Plus"
 	ServiceName string `json:"service_name"`
 
-	// Стоимость месячной подписки в рублях
+	// ИД сервиса каталога, с которым связана запись о подписке по названию сервиса
+	// required: false
+	// min: 1
+	ServiceId *int `json:"service_id,omitempty"`
+
+	// Стоимость подписки за расчётный период в валюте подписки на момент начала подписки.
+	// Последующие изменения стоимости хранятся в истории SubscriptionPrice
 	// required: true
 	// min: 1
 	Price int `json:"price"`
 
+	// Код валюты стоимости подписки по ISO 4217
+	// required: true
+	// example: "RUB"
+	Currency string `json:"currency"`
+
+	// Расчётный период подписки: week, month, quarter или year
+	// required: true
+	// example: "month"
+	BillingPeriod string `json:"billing_period"`
+
 	// ИД пользователя
 	// required: true
 	// min: 1
 	UserId string `json:"user_id"`
 
+	// ИД организации, которой принадлежит запись о подписке
+	// required: true
+	// example: "default"
+	TenantId string `json:"tenant_id"`
+
 	// Дата начала подписки
 	// required: true
 	StartDate *utils.Date `json:"start_date"`
@@ -38,4 +87,27 @@ type Subscription struct {
 	// Дата окончания подписки
 	// required: false
 	EndDate *utils.Date `json:"end_date,omitempty"`
+
+	// Последний месяц бесплатного пробного периода. Расчётные периоды начинаются со следующего месяца
+	// required: false
+	TrialEndDate *utils.Date `json:"trial_end_date,omitempty"`
+
+	// Стоимость за расчётный период по акции в валюте подписки, действующая PromoMonths месяцев после пробного периода
+	// required: false
+	// min: 0
+	PromoPrice int `json:"promo_price,omitempty"`
+
+	// Длительность акции в месяцах. Если не указана, акции нет
+	// required: false
+	// min: 1
+	PromoMonths int `json:"promo_months,omitempty"`
+
+	// Дата удаления записи о подписке
+	// required: false
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Пересечения с другими записями о подписке того же пользователя на тот же сервис.
+	// Возвращаются только в ответе на создание или изменение записи в режиме overlap_mode = warn и не сохраняются
+	// required: false
+	Overlaps []SubscriptionOverlap `json:"overlaps,omitempty"`
 }
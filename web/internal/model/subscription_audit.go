@@ -0,0 +1,69 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"time"
+)
+
+// Действия над записью о подписке, попадающие в журнал изменений
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+
+	AuditActionChangePrice  = "change_price"
+	AuditActionChangeShares = "change_shares"
+	AuditActionPause        = "pause"
+	AuditActionResume       = "resume"
+)
+
+// SubscriptionAudit представляет запись журнала изменений подписки
+//
+//	@modelId	sub-audit
+//
+// swagger:model SubscriptionAudit
+type SubscriptionAudit struct {
+	// ИД записи журнала
+	// required: true
+	// min: 1
+	Id int `json:"id"`
+
+	// ИД записи о подписке
+	// required: true
+	// min: 1
+	SubscriptionId int `json:"subscription_id"`
+
+	// Действие: create, update, delete, restore, change_price, change_shares, pause или resume
+	// required: true
+	// example: "update"
+	Action string `json:"action"`
+
+	// Кто выполнил действие
+	// required: true
+	Actor string `json:"actor"`
+
+	// Запись о подписке до изменения
+	// required: false
+	Before *Subscription `json:"before,omitempty"`
+
+	// Запись о подписке после изменения
+	// required: false
+	After *Subscription `json:"after,omitempty"`
+
+	// Изменение стоимости для действия change_price
+	// required: false
+	Price *SubscriptionPrice `json:"price,omitempty"`
+
+	// Пауза для действий pause и resume
+	// required: false
+	Pause *SubscriptionPause `json:"pause,omitempty"`
+
+	// Доли участников после изменения для действия change_shares
+	// required: false
+	Shares []SubscriptionShare `json:"shares,omitempty"`
+
+	// Дата изменения
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+)
+
+// Режимы выполнения пакета операций над записями о подписках
+const (
+	// BatchModeAllOrNothing при ошибке любой операции отменяет весь пакет
+	BatchModeAllOrNothing = "all_or_nothing"
+
+	// BatchModeBestEffort применяет успешные операции и пропускает операции с ошибкой
+	BatchModeBestEffort = "best_effort"
+)
+
+// Результаты выполнения операции пакета
+const (
+	BatchStatusOk         = "ok"
+	BatchStatusFailed     = "failed"
+	BatchStatusRolledBack = "rolled_back"
+)
+
+// BatchResult представляет результат выполнения операции пакета над записями о подписках
+//
+//	@modelId	batch-result
+//
+// swagger:model BatchResult
+type BatchResult struct {
+	// Порядковый номер операции в пакете, начиная с 0
+	// required: true
+	Index int `json:"index"`
+
+	// Действие: create, update или delete
+	// required: true
+	// example: "create"
+	Action string `json:"action"`
+
+	// Результат: ok, failed или rolled_back, если операция отменена из-за ошибки другой операции пакета
+	// required: true
+	// example: "ok"
+	Status string `json:"status"`
+
+	// Ошибка выполнения операции
+	// required: false
+	Error string `json:"error,omitempty"`
+
+	// Запись о подписке после выполнения операции
+	// required: false
+	Subscription *Subscription `json:"subscription,omitempty"`
+}
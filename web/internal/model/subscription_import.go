@@ -0,0 +1,56 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+)
+
+// ImportReport представляет результат импорта записей о подписках из CSV
+//
+//	@modelId	import-report
+//
+// swagger:model ImportReport
+type ImportReport struct {
+	// Импорт выполнен без сохранения записей
+	// required: true
+	DryRun bool `json:"dry_run"`
+
+	// Количество строк с данными без заголовка
+	// required: true
+	TotalRows int `json:"total_rows"`
+
+	// Количество строк без ошибок
+	// required: true
+	ValidRows int `json:"valid_rows"`
+
+	// Количество созданных записей о подписках
+	// required: true
+	Imported int `json:"imported"`
+
+	// ИД созданных записей о подписках
+	// required: true
+	CreatedIds []int `json:"created_ids"`
+
+	// Ошибки в строках файла
+	// required: true
+	Errors []ImportRowError `json:"errors"`
+}
+
+// ImportRowError представляет ошибку в строке импортируемого файла
+//
+//	@modelId	import-row-error
+//
+// swagger:model ImportRowError
+type ImportRowError struct {
+	// Номер строки в файле, начиная с 1
+	// required: true
+	Row int `json:"row"`
+
+	// Столбец с ошибкой, если ошибка относится к столбцу
+	// required: false
+	// example: "start_date"
+	Column string `json:"column,omitempty"`
+
+	// Описание ошибки
+	// required: true
+	Error string `json:"error"`
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/utils"
+)
+
+// Режимы проверки пересечений при создании и изменении записи о подписке
+const (
+	// OverlapModeAllow сохраняет запись без проверки пересечений
+	OverlapModeAllow = "allow"
+
+	// OverlapModeWarn сохраняет запись и возвращает найденные пересечения
+	OverlapModeWarn = "warn"
+
+	// OverlapModeReject отклоняет запись, период которой пересекается с другими записями
+	OverlapModeReject = "reject"
+)
+
+// SubscriptionOverlap представляет пересечение периодов двух записей о подписке одного пользователя на один сервис.
+// В суммарной стоимости за месяцы пересечения учитывается только одна из записей
+//
+//	@modelId	sub-overlap
+//
+// swagger:model SubscriptionOverlap
+type SubscriptionOverlap struct {
+	// ИД пользователя
+	// required: true
+	UserId string `json:"user_id"`
+
+	// Название сервиса
+	// required: true
+	// example: "Yandex Plus"
+	ServiceName string `json:"service_name"`
+
+	// ИД записи о подписке
+	// required: true
+	// min: 1
+	SubscriptionId int `json:"subscription_id"`
+
+	// ИД пересекающейся записи о подписке
+	// required: true
+	// min: 1
+	OtherSubscriptionId int `json:"other_subscription_id"`
+
+	// Первый месяц пересечения
+	// required: true
+	StartDate *utils.Date `json:"start_date" swaggertype:"string" example:"03-2025"`
+
+	// Последний месяц пересечения. Отсутствует, если обе записи без даты окончания
+	// required: false
+	EndDate *utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"05-2025"`
+}
+
+// SubscriptionMerge представляет объединение пересекающихся записей о подписках в одну запись
+//
+//	@modelId	sub-merge
+//
+// swagger:model SubscriptionMerge
+type SubscriptionMerge struct {
+	// Запись о подписке после объединения
+	// required: true
+	Subscription *Subscription `json:"subscription"`
+
+	// ИД удалённых записей о подписке, периоды которых вошли в объединённую запись
+	// required: true
+	MergedIds []int `json:"merged_ids"`
+
+	// Различия стоимости, долей и пауз удаляемых записей с объединённой записью, из-за которых группа не объединяется
+	// required: false
+	Conflicts []string `json:"conflicts,omitempty"`
+}
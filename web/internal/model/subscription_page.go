@@ -0,0 +1,36 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+)
+
+// Поля, по которым сортируется список записей о подписках
+const (
+	SortById          = "id"
+	SortByStartDate   = "start_date"
+	SortByPrice       = "price"
+	SortByServiceName = "service_name"
+)
+
+// SubscriptionPage представляет страницу списка записей о подписках
+//
+//	@modelId	sub-page
+//
+// swagger:model SubscriptionPage
+type SubscriptionPage struct {
+	// Записи о подписках на странице
+	// required: true
+	Items []Subscription `json:"items"`
+
+	// Количество записей о подписках, подходящих под фильтр
+	// required: true
+	Total int `json:"total"`
+
+	// Курсор следующей страницы
+	// required: false
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Курсор предыдущей страницы
+	// required: false
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
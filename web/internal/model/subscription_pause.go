@@ -0,0 +1,26 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/utils"
+)
+
+// SubscriptionPause представляет паузу подписки. Месяцы паузы не оплачиваются
+//
+//	@modelId	sub-pause
+//
+// swagger:model SubscriptionPause
+type SubscriptionPause struct {
+	// ИД записи о подписке
+	// required: true
+	// min: 1
+	SubscriptionId int `json:"subscription_id"`
+
+	// Первый месяц паузы
+	// required: true
+	StartDate *utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
+
+	// Последний месяц паузы. Если не указан, подписка приостановлена до возобновления
+	// required: false
+	EndDate *utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"09-2025"`
+}
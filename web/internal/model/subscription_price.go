@@ -0,0 +1,27 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/utils"
+)
+
+// SubscriptionPrice представляет изменение стоимости подписки
+//
+//	@modelId	sub-price
+//
+// swagger:model SubscriptionPrice
+type SubscriptionPrice struct {
+	// ИД записи о подписке
+	// required: true
+	// min: 1
+	SubscriptionId int `json:"subscription_id"`
+
+	// Месяц, с которого действует стоимость
+	// required: true
+	EffectiveFrom *utils.Date `json:"effective_from" swaggertype:"string" example:"07-2025"`
+
+	// Стоимость подписки за расчётный период в валюте подписки
+	// required: true
+	// min: 1
+	Price int `json:"price"`
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/utils"
+)
+
+// SubscriptionShare представляет долю участника в стоимости подписки. Владелец подписки платит за неё
+// и несёт стоимость, оставшуюся после долей участников
+//
+//	@modelId	sub-share
+//
+// swagger:model SubscriptionShare
+type SubscriptionShare struct {
+	// ИД записи о подписке
+	// required: true
+	// min: 1
+	SubscriptionId int `json:"subscription_id"`
+
+	// ИД участника подписки
+	// required: true
+	UserId string `json:"user_id"`
+
+	// Доля в процентах от стоимости подписки. Указывается либо процент, либо фиксированная сумма
+	// required: false
+	// example: 25
+	Percent float64 `json:"percent,omitempty"`
+
+	// Фиксированная доля за расчётный период в валюте подписки
+	// required: false
+	// example: 100
+	Amount int `json:"amount,omitempty"`
+}
+
+// Settlement представляет долг одного пользователя другому за период по долям в подписках
+//
+//	@modelId	settlement
+//
+// swagger:model Settlement
+type Settlement struct {
+	// ИД пользователя, который должен
+	// required: true
+	FromUserId string `json:"from_user_id"`
+
+	// ИД пользователя, которому должны
+	// required: true
+	ToUserId string `json:"to_user_id"`
+
+	// Сумма долга за вычетом встречного долга
+	// required: true
+	Amount int `json:"amount"`
+}
+
+// SettlementReport представляет взаиморасчёты пользователей по долям в подписках за период
+//
+//	@modelId	settlement-report
+//
+// swagger:model SettlementReport
+type SettlementReport struct {
+	// Первый месяц периода
+	// required: true
+	StartDate *utils.Date `json:"start_date" swaggertype:"string" example:"01-2025"`
+
+	// Последний месяц периода
+	// required: true
+	EndDate *utils.Date `json:"end_date" swaggertype:"string" example:"12-2025"`
+
+	// Код валюты сумм по ISO 4217
+	// required: true
+	// example: "RUB"
+	Currency string `json:"currency"`
+
+	// Долги пользователей
+	// required: true
+	Settlements []Settlement `json:"settlements"`
+}
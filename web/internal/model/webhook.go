@@ -0,0 +1,145 @@
+package model
+
+import (
+	"encoding/json"
+	_ "subsaggregator/docs"
+	"time"
+)
+
+// Типы событий жизненного цикла подписки, отправляемых по вебхукам
+const (
+	WebhookEventSubscriptionCreated  = "subscription.created"
+	WebhookEventSubscriptionUpdated  = "subscription.updated"
+	WebhookEventSubscriptionDeleted  = "subscription.deleted"
+	WebhookEventSubscriptionRestored = "subscription.restored"
+	WebhookEventSubscriptionPaused   = "subscription.paused"
+	WebhookEventSubscriptionResumed  = "subscription.resumed"
+)
+
+// WebhookEventTypes типы событий по действиям над записью о подписке
+var WebhookEventTypes = map[string]string{
+	AuditActionCreate:       WebhookEventSubscriptionCreated,
+	AuditActionUpdate:       WebhookEventSubscriptionUpdated,
+	AuditActionDelete:       WebhookEventSubscriptionDeleted,
+	AuditActionRestore:      WebhookEventSubscriptionRestored,
+	AuditActionChangeShares: WebhookEventSubscriptionUpdated,
+	AuditActionPause:        WebhookEventSubscriptionPaused,
+	AuditActionResume:       WebhookEventSubscriptionResumed,
+}
+
+// Статусы доставки события по вебхуку
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEndpoint представляет вебхук, на который отправляются события жизненного цикла подписок
+//
+//	@modelId	webhook-endpoint
+//
+// swagger:model WebhookEndpoint
+type WebhookEndpoint struct {
+	// ИД вебхука
+	// required: true
+	// min: 1
+	Id int `json:"id"`
+
+	// Адрес, на который отправляются события
+	// required: true
+	// example: "https://example.com/hooks/subscriptions"
+	Url string `json:"url"`
+
+	// Ключ подписи HMAC-SHA256. Возвращается только при регистрации вебхука
+	// required: false
+	Secret string `json:"secret,omitempty"`
+
+	// Типы событий, на которые подписан вебхук
+	// required: true
+	// example: ["subscription.created", "subscription.deleted"]
+	EventTypes []string `json:"event_types"`
+
+	// Дата регистрации
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookEvent представляет событие жизненного цикла подписки.
+// Событие сохраняется в одной транзакции с изменением подписки и затем доставляется по вебхукам
+//
+//	@modelId	webhook-event
+//
+// swagger:model WebhookEvent
+type WebhookEvent struct {
+	// ИД события
+	// required: true
+	// example: "0b6f1c9e-2f5a-4a47-9d3e-6c1f0a8b7d21"
+	Id string `json:"id"`
+
+	// Тип события
+	// required: true
+	// example: "subscription.created"
+	Type string `json:"type"`
+
+	// Дата события
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+
+	// Запись о подписке после изменения, для удаления — до изменения
+	// required: true
+	Data json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// WebhookDelivery представляет доставку события по вебхуку
+//
+//	@modelId	webhook-delivery
+//
+// swagger:model WebhookDelivery
+type WebhookDelivery struct {
+	// ИД доставки
+	// required: true
+	// min: 1
+	Id int `json:"id"`
+
+	// ИД вебхука
+	// required: true
+	EndpointId int `json:"endpoint_id"`
+
+	// ИД события
+	// required: true
+	EventId string `json:"event_id"`
+
+	// Тип события
+	// required: true
+	// example: "subscription.created"
+	EventType string `json:"event_type"`
+
+	// Статус доставки: pending, delivered или failed
+	// required: true
+	// example: "delivered"
+	Status string `json:"status"`
+
+	// Количество попыток доставки
+	// required: true
+	Attempts int `json:"attempts"`
+
+	// Дата следующей попытки доставки
+	// required: false
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+
+	// Код ответа последней попытки
+	// required: false
+	LastStatusCode int `json:"last_status_code,omitempty"`
+
+	// Ошибка последней попытки
+	// required: false
+	LastError string `json:"last_error,omitempty"`
+
+	// Дата создания доставки
+	// required: true
+	CreatedAt time.Time `json:"created_at"`
+
+	// Дата успешной доставки
+	// required: false
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+}
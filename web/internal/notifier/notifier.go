@@ -0,0 +1,42 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"subsaggregator/internal/model"
+)
+
+// Notifier отправляет напоминания о подписках по одному каналу
+type Notifier interface {
+	// Channel возвращает канал отправки, один из model.ReminderChannel*
+	Channel() string
+
+	// Accepts сообщает, указан ли в настройках пользователя адрес для этого канала
+	Accepts(settings model.ReminderSettings) bool
+
+	// Notify отправляет напоминание по адресу из настроек пользователя
+	Notify(ctx context.Context, settings model.ReminderSettings, reminder model.Reminder) error
+}
+
+// reminderSubject возвращает тему напоминания
+func reminderSubject(reminder model.Reminder) string {
+	if reminder.Kind == model.ReminderKindEnd {
+		return fmt.Sprintf("Подписка %s заканчивается %s", reminder.ServiceName, reminder.DueDate.Format("02.01.2006"))
+	}
+
+	return fmt.Sprintf("Подписка %s продлевается %s", reminder.ServiceName, reminder.DueDate.Format("02.01.2006"))
+}
+
+// reminderText возвращает текст напоминания
+func reminderText(reminder model.Reminder) string {
+	if reminder.Kind == model.ReminderKindEnd {
+		return fmt.Sprintf("Подписка %s заканчивается %s.", reminder.ServiceName, reminder.DueDate.Format("02.01.2006"))
+	}
+
+	return fmt.Sprintf("Подписка %s продлевается %s. Стоимость продления: %d %s.",
+		reminder.ServiceName,
+		reminder.DueDate.Format("02.01.2006"),
+		reminder.Price,
+		reminder.Currency,
+	)
+}
@@ -0,0 +1,189 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"subsaggregator/internal/model"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer принимает одно письмо по SMTP и возвращает получателя и данные письма
+func fakeSMTPServer(t *testing.T) (string, <-chan [2]string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan [2]string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		reply("220 localhost ESMTP")
+
+		var rcpt, data strings.Builder
+
+		for {
+			line, err := reader.ReadString('\n')
+
+			if err != nil {
+				return
+			}
+
+			command := strings.ToUpper(strings.TrimSpace(line))
+
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				rcpt.WriteString(strings.TrimSpace(line[len("RCPT TO:"):]))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+
+				for {
+					line, err := reader.ReadString('\n')
+
+					if err != nil {
+						return
+					}
+
+					if line == ".\r\n" {
+						break
+					}
+
+					data.WriteString(line)
+				}
+
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 Bye")
+				received <- [2]string{rcpt.String(), data.String()}
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func testReminder() model.Reminder {
+	return model.Reminder{
+		Kind:           model.ReminderKindRenewal,
+		SubscriptionId: 7,
+		UserId:         "Тестовый UUID",
+		ServiceName:    "Тестовый сервис",
+		Price:          300,
+		Currency:       "RUB",
+		DueDate:        time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+
+	n := &SMTPNotifier{Addr: addr, From: "subsaggregator@localhost"}
+	settings := model.ReminderSettings{UserId: "Тестовый UUID", Enabled: true, Email: "user@example.com"}
+
+	if !n.Accepts(settings) || n.Accepts(model.ReminderSettings{}) {
+		t.Errorf("SMTPNotifier.Accepts() неверно определяет наличие адреса")
+	}
+
+	if err := n.Notify(context.Background(), settings, testReminder()); err != nil {
+		t.Errorf("SMTPNotifier.Notify() error = %v", err)
+		return
+	}
+
+	var message [2]string
+
+	select {
+	case message = <-received:
+	case <-time.After(5 * time.Second):
+		t.Errorf("SMTPNotifier.Notify() письмо не получено")
+		return
+	}
+
+	if message[0] != "<user@example.com>" {
+		t.Errorf("SMTPNotifier.Notify() получатель = %s", message[0])
+	}
+
+	headers, body, _ := strings.Cut(message[1], "\r\n\r\n")
+
+	if !strings.Contains(headers, "Subject: =?utf-8?q?") || !strings.Contains(headers, "To: user@example.com") {
+		t.Errorf("SMTPNotifier.Notify() заголовки = %s", headers)
+	}
+
+	text, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+
+	if err != nil || !strings.Contains(string(text), "Стоимость продления: 300 RUB") {
+		t.Errorf("SMTPNotifier.Notify() текст = %s, %v", text, err)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var payload map[string]any
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	defer server.Close()
+
+	n := &WebhookNotifier{Client: server.Client()}
+	settings := model.ReminderSettings{UserId: "Тестовый UUID", Enabled: true, WebhookUrl: server.URL + "/hook"}
+
+	if err := n.Notify(context.Background(), settings, testReminder()); err != nil {
+		t.Errorf("WebhookNotifier.Notify() error = %v", err)
+		return
+	}
+
+	if payload["kind"] != model.ReminderKindRenewal || payload["subscription_id"] != float64(7) || payload["subject"] == "" {
+		t.Errorf("WebhookNotifier.Notify() payload = %+v", payload)
+	}
+
+	settings.WebhookUrl = server.URL + "/fail"
+
+	if err := n.Notify(context.Background(), settings, testReminder()); err == nil {
+		t.Errorf("WebhookNotifier.Notify() не вернула ошибку при ответе 500")
+	}
+
+	// Клиент вебхуков не подключается к адресам внутренней сети, даже если адрес прошёл проверку при сохранении
+	n = &WebhookNotifier{Client: NewWebhookClient(time.Second)}
+	settings.WebhookUrl = server.URL + "/hook"
+	payload = nil
+
+	if err := n.Notify(context.Background(), settings, testReminder()); err == nil || payload != nil {
+		t.Errorf("WebhookNotifier.Notify() на адрес loopback error = %v, payload = %+v", err, payload)
+	}
+}
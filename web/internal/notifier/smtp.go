@@ -0,0 +1,99 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"subsaggregator/internal/model"
+	"time"
+)
+
+// SMTPNotifier отправляет напоминания письмом через SMTP-сервер
+type SMTPNotifier struct {
+	// Адрес SMTP-сервера в виде host:port
+	Addr string
+
+	// Адрес отправителя
+	From string
+
+	// Учётные данные SMTP-сервера. Если Username пустой, авторизация не выполняется
+	Username string
+	Password string
+}
+
+func (n *SMTPNotifier) Channel() string {
+	return model.ReminderChannelEmail
+}
+
+func (n *SMTPNotifier) Accepts(settings model.ReminderSettings) bool {
+	return settings.Email != ""
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, settings model.ReminderSettings, reminder model.Reminder) error {
+	var auth smtp.Auth
+
+	if n.Username != "" {
+		host, _, err := net.SplitHostPort(n.Addr)
+
+		if err != nil {
+			return fmt.Errorf("invalid smtp address: %w", err)
+		}
+
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	message, err := n.message(settings.Email, reminder)
+
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- smtp.SendMail(n.Addr, auth, n.From, []string{settings.Email}, message)
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to send reminder email: %w", err)
+	}
+
+	return nil
+}
+
+// message собирает письмо с напоминанием в кодировке UTF-8
+func (n *SMTPNotifier) message(to string, reminder model.Reminder) ([]byte, error) {
+	var message bytes.Buffer
+
+	fmt.Fprintf(&message, "From: %s\r\n", n.From)
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", reminderSubject(reminder)))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&message)
+
+	if _, err := body.Write([]byte(reminderText(reminder))); err != nil {
+		return nil, err
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	message.WriteString("\r\n")
+
+	return message.Bytes(), nil
+}
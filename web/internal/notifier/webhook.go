@@ -0,0 +1,143 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"subsaggregator/internal/model"
+	"syscall"
+	"time"
+)
+
+// WebhookNotifier отправляет напоминания POST-запросом с JSON на адрес вебхука пользователя
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+// webhookPayload тело запроса вебхука с напоминанием
+type webhookPayload struct {
+	model.Reminder
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+}
+
+// NewWebhookClient создаёт HTTP-клиент вебхуков, который не подключается к адресам внутренней сети.
+// Адрес проверяется после разрешения имени при каждом подключении, в том числе при перенаправлениях,
+// поэтому смена DNS-записи после сохранения адреса вебхука не открывает доступ к внутренней сети
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("webhook address %s is not allowed", host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Через прокси проверялся бы адрес прокси, а не вебхука
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// CheckWebhookHost проверяет, что узел вебхука не относится к внутренней сети: localhost, адреса loopback,
+// частных сетей, link-local и неуказанные адреса запрещены. Имя, которое не удалось разрешить, допускается:
+// адрес всё равно проверяется при отправке клиентом из NewWebhookClient
+func CheckWebhookHost(ctx context.Context, host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("webhook host %s is not allowed", host)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicIP(ip) {
+			return fmt.Errorf("webhook host %s is not allowed", host)
+		}
+
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+
+	if err != nil {
+		return nil
+	}
+
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return fmt.Errorf("webhook host %s resolves to not allowed address %s", host, addr.IP)
+		}
+	}
+
+	return nil
+}
+
+// publicIP проверяет, что адрес не относится к внутренней сети
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
+
+func (n *WebhookNotifier) Channel() string {
+	return model.ReminderChannelWebhook
+}
+
+func (n *WebhookNotifier) Accepts(settings model.ReminderSettings) bool {
+	return settings.WebhookUrl != ""
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, settings model.ReminderSettings, reminder model.Reminder) error {
+	body, err := json.Marshal(webhookPayload{
+		Reminder: reminder,
+		Subject:  reminderSubject(reminder),
+		Text:     reminderText(reminder),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to encode reminder: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, settings.WebhookUrl, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return fmt.Errorf("failed to send reminder webhook: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("reminder webhook responded with status %d", response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ApiKeyRepository interface {
+	Create(entity *model.ApiKey, keyHash string) error
+	FindById(id int) (*model.ApiKey, error)
+	List() ([]model.ApiKey, error)
+	Revoke(entity *model.ApiKey) error
+	Authenticate(keyHash string, now time.Time) (*model.ApiKey, error)
+}
+
+// ApiKeyRepo хранилище API-ключей, ограниченное организацией TenantId. Пустой TenantId не ограничивает организацию
+type ApiKeyRepo struct {
+	TenantId string
+}
+
+// apiKeyColumns перечисляет столбцы api_keys в порядке полей model.ApiKey, кроме Key
+const apiKeyColumns = "id, name, prefix, user_id, tenant_id, scopes, created_at, expires_at, last_used_at, revoked_at"
+
+func (repo *ApiKeyRepo) Create(entity *model.ApiKey, keyHash string) error {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, user_id, scopes, expires_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at;
+	`
+
+	entity.TenantId = tenantOrDefault(repo.TenantId)
+
+	err := db.Postgres.QueryRow(
+		query,
+		entity.Name,
+		entity.Prefix,
+		keyHash,
+		entity.UserId,
+		pq.Array(entity.Scopes),
+		entity.ExpiresAt,
+		entity.TenantId,
+	).Scan(&entity.Id, &entity.CreatedAt)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("API-ключ не создан: %w", err).Error())
+
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Создание API-ключа. ИД: %d. Назначение: %s. Разрешения: %v", entity.Id, entity.Name, entity.Scopes))
+
+	return nil
+}
+
+func (repo *ApiKeyRepo) FindById(id int) (*model.ApiKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE id = $1 AND ($2 = '' OR tenant_id = $2);
+	`
+
+	var key model.ApiKey
+
+	err := scanApiKey(db.Postgres.QueryRow(query, id, repo.TenantId), &key)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("API-ключ невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (repo *ApiKeyRepo) List() ([]model.ApiKey, error) {
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE $1 = '' OR tenant_id = $1
+		ORDER BY id;
+	`
+
+	rows, err := db.Postgres.Query(query, repo.TenantId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("API-ключи не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("api keys not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	keys := []model.ApiKey{}
+
+	for rows.Next() {
+		var key model.ApiKey
+
+		err = scanApiKey(rows, &key)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("API-ключ невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("API-ключи невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (repo *ApiKeyRepo) Revoke(entity *model.ApiKey) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = now()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING revoked_at;
+	`
+
+	err := db.Postgres.QueryRow(query, entity.Id).Scan(&entity.RevokedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("api key is already revoked")
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("API-ключ не отозван: %w", err).Error())
+
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Отзыв API-ключа. ИД: %d", entity.Id))
+
+	return nil
+}
+
+// Authenticate находит действующий ключ по хешу и отмечает время его использования
+func (repo *ApiKeyRepo) Authenticate(keyHash string, now time.Time) (*model.ApiKey, error) {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = $2
+		WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+		RETURNING ` + apiKeyColumns + `;
+	`
+
+	var key model.ApiKey
+
+	err := scanApiKey(db.Postgres.QueryRow(query, keyHash, now), &key)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("API-ключ невозможно проверить: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return &key, nil
+}
+
+func scanApiKey(row rowScanner, key *model.ApiKey) error {
+	return row.Scan(
+		&key.Id,
+		&key.Name,
+		&key.Prefix,
+		&key.UserId,
+		&key.TenantId,
+		pq.Array(&key.Scopes),
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+}
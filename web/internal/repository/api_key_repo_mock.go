@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"fmt"
+	"subsaggregator/internal/model"
+	"time"
+)
+
+type ApiKeyRepoMock struct {
+	Keys     map[int]*model.ApiKey
+	Hashes   map[int]string
+	TenantId string
+}
+
+func (repo ApiKeyRepoMock) Create(entity *model.ApiKey, keyHash string) error {
+	entity.Id = len(repo.Keys) + 1
+	entity.TenantId = tenantOrDefault(repo.TenantId)
+	entity.CreatedAt = time.Now()
+
+	key := *entity
+	key.Key = ""
+
+	repo.Keys[entity.Id] = &key
+	repo.Hashes[entity.Id] = keyHash
+
+	return nil
+}
+
+func (repo ApiKeyRepoMock) FindById(id int) (*model.ApiKey, error) {
+	if key, ok := repo.Keys[id]; ok && (repo.TenantId == "" || key.TenantId == repo.TenantId) {
+		result := *key
+
+		return &result, nil
+	}
+
+	return nil, nil
+}
+
+func (repo ApiKeyRepoMock) List() ([]model.ApiKey, error) {
+	keys := []model.ApiKey{}
+
+	for id := 1; id <= len(repo.Keys); id++ {
+		if repo.TenantId == "" || repo.Keys[id].TenantId == repo.TenantId {
+			keys = append(keys, *repo.Keys[id])
+		}
+	}
+
+	return keys, nil
+}
+
+func (repo ApiKeyRepoMock) Revoke(entity *model.ApiKey) error {
+	key := repo.Keys[entity.Id]
+
+	if key.RevokedAt != nil {
+		return fmt.Errorf("api key is already revoked")
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	entity.RevokedAt = &now
+
+	return nil
+}
+
+func (repo ApiKeyRepoMock) Authenticate(keyHash string, now time.Time) (*model.ApiKey, error) {
+	for id, hash := range repo.Hashes {
+		key := repo.Keys[id]
+
+		if hash != keyHash || key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+			continue
+		}
+
+		key.LastUsedAt = &now
+		result := *key
+
+		return &result, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+type BudgetRepository interface {
+	FindById(id int) (*model.Budget, error)
+	ListByUser(userId string) ([]model.Budget, error)
+	Create(entity *model.Budget) error
+	Update(entity *model.Budget) error
+	Delete(entity *model.Budget) error
+	RecordAlert(alert *model.BudgetAlert) (bool, error)
+	ClearAlerts(budgetId int, month utils.Date, percent int) error
+}
+
+// BudgetRepo хранилище бюджетов, ограниченное организацией TenantId. Пустой TenantId не ограничивает организацию
+type BudgetRepo struct {
+	TenantId string
+}
+
+// budgetColumns перечисляет столбцы budgets в порядке полей model.Budget
+const budgetColumns = "id, user_id, tenant_id, service_name, amount, currency, created_at"
+
+func (repo *BudgetRepo) FindById(id int) (*model.Budget, error) {
+	rows, err := db.Postgres.Query(`SELECT `+budgetColumns+` FROM budgets WHERE id = $1 AND ($2 = '' OR tenant_id = $2);`, id, repo.TenantId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("бюджет не найден: %w", err).Error())
+
+		return nil, fmt.Errorf("budget not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var budget model.Budget
+
+	err = scanBudget(rows, &budget)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("бюджет невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return &budget, nil
+}
+
+func (repo *BudgetRepo) ListByUser(userId string) ([]model.Budget, error) {
+	rows, err := db.Postgres.Query(
+		`SELECT `+budgetColumns+` FROM budgets WHERE user_id = $1 AND ($2 = '' OR tenant_id = $2) ORDER BY service_name, id;`,
+		userId,
+		repo.TenantId,
+	)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("бюджеты не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("budgets not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	budgets := []model.Budget{}
+
+	for rows.Next() {
+		var budget model.Budget
+
+		err = scanBudget(rows, &budget)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("бюджет невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		budgets = append(budgets, budget)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("бюджеты невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение бюджетов пользователя. ИД пользователя: %s", userId))
+
+	return budgets, nil
+}
+
+func (repo *BudgetRepo) Create(entity *model.Budget) error {
+	query := `
+		INSERT INTO budgets (user_id, service_name, amount, currency, tenant_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at;
+	`
+
+	entity.TenantId = tenantOrDefault(repo.TenantId)
+
+	err := db.Postgres.QueryRow(
+		query,
+		entity.UserId,
+		entity.ServiceName,
+		entity.Amount,
+		entity.Currency,
+		entity.TenantId,
+	).Scan(&entity.Id, &entity.CreatedAt)
+
+	if err != nil {
+		return budgetWriteError(err, "бюджет не создан", "failed to create budget")
+	}
+
+	slog.Info(fmt.Sprintf("Создание бюджета. ИД пользователя: %s. Сервис: %s. Лимит: %d %s",
+		entity.UserId,
+		entity.ServiceName,
+		entity.Amount,
+		entity.Currency,
+	))
+
+	return nil
+}
+
+// Update изменяет лимит бюджета. Предупреждения о достигнутых порогах сбрасываются,
+// чтобы расходы сравнивались с новым лимитом
+func (repo *BudgetRepo) Update(entity *model.Budget) error {
+	return withTransaction(func(q queryer) error {
+		_, err := q.Exec(
+			`UPDATE budgets SET service_name = $2, amount = $3, currency = $4 WHERE id = $1 AND ($5 = '' OR tenant_id = $5);`,
+			entity.Id,
+			entity.ServiceName,
+			entity.Amount,
+			entity.Currency,
+			repo.TenantId,
+		)
+
+		if err != nil {
+			return budgetWriteError(err, "бюджет не изменён", "failed to update budget")
+		}
+
+		_, err = q.Exec(`DELETE FROM budget_alerts WHERE budget_id = $1;`, entity.Id)
+
+		if err != nil {
+			return budgetWriteError(err, "предупреждения бюджета не сброшены", "failed to update budget")
+		}
+
+		slog.Info(fmt.Sprintf("Изменение бюджета. ИД: %d. Сервис: %s. Лимит: %d %s",
+			entity.Id,
+			entity.ServiceName,
+			entity.Amount,
+			entity.Currency,
+		))
+
+		return nil
+	})
+}
+
+func (repo *BudgetRepo) Delete(entity *model.Budget) error {
+	_, err := db.Postgres.Exec(`DELETE FROM budgets WHERE id = $1 AND ($2 = '' OR tenant_id = $2);`, entity.Id, repo.TenantId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("бюджет не удалён: %w", err).Error())
+
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Удаление бюджета. ИД: %d. ИД пользователя: %s", entity.Id, entity.UserId))
+
+	return nil
+}
+
+// RecordAlert отмечает, что расходы бюджета за месяц достигли порога, и в той же транзакции
+// сохраняет событие для вебхуков. Возвращает false, если предупреждение уже было отправлено
+func (repo *BudgetRepo) RecordAlert(alert *model.BudgetAlert) (bool, error) {
+	recorded := false
+
+	err := withTransaction(func(q queryer) error {
+		result, err := q.Exec(
+			`INSERT INTO budget_alerts (budget_id, month, threshold) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;`,
+			alert.Budget.Id,
+			alert.Month,
+			alert.Threshold,
+		)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("предупреждение бюджета не сохранено: %w", err).Error())
+
+			return fmt.Errorf("failed to record budget alert: %w", err)
+		}
+
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+
+		recorded = true
+
+		return insertWebhookEvent(q, alert.Budget.TenantId, model.WebhookEventBudgetThreshold, alert)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	if recorded {
+		slog.Info(fmt.Sprintf("Предупреждение бюджета. ИД: %d. Месяц: %s. Порог: %d%%. Расходы: %d из %d %s",
+			alert.Budget.Id,
+			alert.Month,
+			alert.Threshold,
+			alert.Spent,
+			alert.Budget.Amount,
+			alert.Budget.Currency,
+		))
+	}
+
+	return recorded, nil
+}
+
+// ClearAlerts снимает отметки о порогах выше percent, чтобы при повторном превышении предупреждение отправилось снова
+func (repo *BudgetRepo) ClearAlerts(budgetId int, month utils.Date, percent int) error {
+	_, err := db.Postgres.Exec(`DELETE FROM budget_alerts WHERE budget_id = $1 AND month = $2 AND threshold > $3;`, budgetId, month, percent)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("предупреждения бюджета не сброшены: %w", err).Error())
+
+		return fmt.Errorf("failed to clear budget alerts: %w", err)
+	}
+
+	return nil
+}
+
+// scanBudget читает строку со столбцами budgetColumns
+func scanBudget(row rowScanner, budget *model.Budget) error {
+	return row.Scan(&budget.Id, &budget.UserId, &budget.TenantId, &budget.ServiceName, &budget.Amount, &budget.Currency, &budget.CreatedAt)
+}
+
+// budgetWriteError логирует ошибку записи бюджета и сообщает о повторном бюджете на тот же сервис
+func budgetWriteError(err error, message string, description string) error {
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("budget for this service already exists")
+	}
+
+	slog.Error(fmt.Errorf("%s: %w", message, err).Error())
+
+	return fmt.Errorf("%s: %w", description, err)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/utils"
+	"time"
+)
+
+type BudgetRepoMock struct {
+	Budgets  map[int]*model.Budget
+	Alerts   map[string]bool
+	Outbox   map[string]*model.WebhookEvent
+	Count    int
+	TenantId string
+}
+
+func (repo BudgetRepoMock) FindById(id int) (*model.Budget, error) {
+	if budget, ok := repo.Budgets[id]; ok && (repo.TenantId == "" || budget.TenantId == repo.TenantId) {
+		result := *budget
+
+		return &result, nil
+	}
+
+	return nil, nil
+}
+
+func (repo BudgetRepoMock) ListByUser(userId string) ([]model.Budget, error) {
+	budgets := []model.Budget{}
+
+	for _, budget := range repo.Budgets {
+		if budget.UserId == userId && (repo.TenantId == "" || budget.TenantId == repo.TenantId) {
+			budgets = append(budgets, *budget)
+		}
+	}
+
+	sort.Slice(budgets, func(i, j int) bool {
+		return budgets[i].ServiceName < budgets[j].ServiceName
+	})
+
+	return budgets, nil
+}
+
+func (repo BudgetRepoMock) Create(entity *model.Budget) error {
+	if err := repo.checkUnique(entity); err != nil {
+		return err
+	}
+
+	repo.Count++
+
+	for id := range repo.Budgets {
+		repo.Count = max(repo.Count, id+1)
+	}
+
+	entity.Id = repo.Count
+	entity.TenantId = tenantOrDefault(repo.TenantId)
+	entity.CreatedAt = time.Now()
+
+	budget := *entity
+
+	repo.Budgets[entity.Id] = &budget
+
+	return nil
+}
+
+func (repo BudgetRepoMock) Update(entity *model.Budget) error {
+	if err := repo.checkUnique(entity); err != nil {
+		return err
+	}
+
+	budget := *entity
+
+	repo.Budgets[entity.Id] = &budget
+
+	for key := range repo.Alerts {
+		if budgetAlertBudgetId(key) == entity.Id {
+			delete(repo.Alerts, key)
+		}
+	}
+
+	return nil
+}
+
+func (repo BudgetRepoMock) Delete(entity *model.Budget) error {
+	delete(repo.Budgets, entity.Id)
+
+	return nil
+}
+
+func (repo BudgetRepoMock) RecordAlert(alert *model.BudgetAlert) (bool, error) {
+	key := budgetAlertKey(alert.Budget.Id, *alert.Month, alert.Threshold)
+
+	if repo.Alerts[key] {
+		return false, nil
+	}
+
+	repo.Alerts[key] = true
+
+	if repo.Outbox != nil {
+		data, _ := json.Marshal(alert)
+		id := fmt.Sprintf("event-%08d", len(repo.Outbox)+1)
+
+		repo.Outbox[id] = &model.WebhookEvent{
+			Id:        id,
+			Type:      model.WebhookEventBudgetThreshold,
+			CreatedAt: time.Now(),
+			Data:      data,
+		}
+	}
+
+	return true, nil
+}
+
+func (repo BudgetRepoMock) ClearAlerts(budgetId int, month utils.Date, percent int) error {
+	for _, threshold := range model.BudgetThresholds {
+		if threshold > percent {
+			delete(repo.Alerts, budgetAlertKey(budgetId, month, threshold))
+		}
+	}
+
+	return nil
+}
+
+// checkUnique проверяет, что у пользователя нет другого бюджета на тот же сервис
+func (repo BudgetRepoMock) checkUnique(entity *model.Budget) error {
+	for _, budget := range repo.Budgets {
+		if budget.Id != entity.Id && budget.TenantId == tenantOrDefault(repo.TenantId) && budget.UserId == entity.UserId && budget.ServiceName == entity.ServiceName {
+			return fmt.Errorf("budget for this service already exists")
+		}
+	}
+
+	return nil
+}
+
+func budgetAlertKey(budgetId int, month utils.Date, threshold int) string {
+	return fmt.Sprintf("%d:%s:%d", budgetId, month.Time.Format("2006-01"), threshold)
+}
+
+func budgetAlertBudgetId(key string) int {
+	var budgetId int
+
+	fmt.Sscanf(key, "%d:", &budgetId)
+
+	return budgetId
+}
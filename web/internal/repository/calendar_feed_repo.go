@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+)
+
+type CalendarFeedRepository interface {
+	Save(entity *model.CalendarFeed, tokenHash string) error
+	Delete(userId string) (bool, error)
+	Authenticate(tokenHash string) (*model.CalendarFeed, error)
+}
+
+// CalendarFeedRepo хранилище токенов лент календаря, ограниченное организацией TenantId.
+// Пустой TenantId не ограничивает организацию и используется для проверки токена
+type CalendarFeedRepo struct {
+	TenantId string
+}
+
+// Save сохраняет токен ленты пользователя. Прежний токен пользователя заменяется и перестаёт действовать
+func (repo *CalendarFeedRepo) Save(entity *model.CalendarFeed, tokenHash string) error {
+	query := `
+		INSERT INTO calendar_feeds (tenant_id, user_id, token_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = now()
+		RETURNING created_at;
+	`
+
+	entity.TenantId = tenantOrDefault(repo.TenantId)
+
+	err := db.Postgres.QueryRow(query, entity.TenantId, entity.UserId, tokenHash).Scan(&entity.CreatedAt)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("токен ленты календаря не сохранён: %w", err).Error())
+
+		return fmt.Errorf("failed to save calendar feed token: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Выпуск токена ленты календаря. Пользователь: %s", entity.UserId))
+
+	return nil
+}
+
+// Delete отзывает токен ленты пользователя. Возвращает false, если токена не было
+func (repo *CalendarFeedRepo) Delete(userId string) (bool, error) {
+	query := `
+		DELETE FROM calendar_feeds
+		WHERE tenant_id = $1 AND user_id = $2;
+	`
+
+	result, err := db.Postgres.Exec(query, tenantOrDefault(repo.TenantId), userId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("токен ленты календаря не отозван: %w", err).Error())
+
+		return false, fmt.Errorf("failed to revoke calendar feed token: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke calendar feed token: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Отзыв токена ленты календаря. Пользователь: %s", userId))
+
+	return count > 0, nil
+}
+
+// Authenticate находит ленту по хешу токена
+func (repo *CalendarFeedRepo) Authenticate(tokenHash string) (*model.CalendarFeed, error) {
+	query := `
+		SELECT user_id, tenant_id, created_at
+		FROM calendar_feeds
+		WHERE token_hash = $1 AND ($2 = '' OR tenant_id = $2);
+	`
+
+	var feed model.CalendarFeed
+
+	err := db.Postgres.QueryRow(query, tokenHash, repo.TenantId).Scan(&feed.UserId, &feed.TenantId, &feed.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("токен ленты календаря невозможно проверить: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return &feed, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"subsaggregator/internal/model"
+	"time"
+)
+
+type CalendarFeedRepoMock struct {
+	Feeds    map[string]*model.CalendarFeed
+	Hashes   map[string]string
+	TenantId string
+}
+
+func (repo CalendarFeedRepoMock) Save(entity *model.CalendarFeed, tokenHash string) error {
+	entity.TenantId = tenantOrDefault(repo.TenantId)
+	entity.CreatedAt = time.Now()
+
+	feed := *entity
+	feed.Token = ""
+
+	repo.Feeds[feed.TenantId+"/"+feed.UserId] = &feed
+	repo.Hashes[feed.TenantId+"/"+feed.UserId] = tokenHash
+
+	return nil
+}
+
+func (repo CalendarFeedRepoMock) Delete(userId string) (bool, error) {
+	key := tenantOrDefault(repo.TenantId) + "/" + userId
+
+	if _, ok := repo.Feeds[key]; !ok {
+		return false, nil
+	}
+
+	delete(repo.Feeds, key)
+	delete(repo.Hashes, key)
+
+	return true, nil
+}
+
+func (repo CalendarFeedRepoMock) Authenticate(tokenHash string) (*model.CalendarFeed, error) {
+	for key, hash := range repo.Hashes {
+		feed := repo.Feeds[key]
+
+		if hash == tokenHash && (repo.TenantId == "" || feed.TenantId == repo.TenantId) {
+			result := *feed
+
+			return &result, nil
+		}
+	}
+
+	return nil, nil
+}
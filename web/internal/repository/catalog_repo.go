@@ -0,0 +1,433 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+
+	"github.com/lib/pq"
+)
+
+type CatalogRepository interface {
+	FindById(id int) (*model.CatalogService, error)
+	List(category string) ([]model.CatalogService, error)
+	Create(entity *model.CatalogService, actor string) error
+	Update(entity *model.CatalogService, actor string) error
+	Delete(entity *model.CatalogService, actor string) error
+}
+
+// CatalogRepo хранилище каталога сервисов. Каталог общий для всех организаций
+type CatalogRepo struct{}
+
+// catalogServiceColumns перечисляет столбцы services в порядке полей model.CatalogService.
+// Псевдонимы собираются из service_aliases без канонического названия
+const catalogServiceColumns = `services.id, services.name,
+	ARRAY(
+		SELECT service_aliases.alias
+		FROM service_aliases
+		WHERE service_aliases.service_id = services.id AND service_aliases.alias <> services.name
+		ORDER BY service_aliases.alias
+	),
+	services.category, services.default_price, services.vendor_url, services.logo_url, services.created_at`
+
+// ServiceAliasKey нормализует название сервиса для поиска в каталоге: приводит к нижнему регистру
+// и убирает пробелы по краям и повторные пробелы
+func ServiceAliasKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// serviceAliasKeyColumn нормализует название сервиса в SQL так же, как ServiceAliasKey
+const serviceAliasKeyColumn = `lower(btrim(regexp_replace(subscriptions.service_name, '\s+', ' ', 'g')))`
+
+func (repo *CatalogRepo) FindById(id int) (*model.CatalogService, error) {
+	query := `
+		SELECT ` + catalogServiceColumns + `
+		FROM services
+		WHERE id = $1;
+	`
+
+	var service model.CatalogService
+
+	err := scanCatalogService(db.Postgres.QueryRow(query, id), &service)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("сервис каталога невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return &service, nil
+}
+
+func (repo *CatalogRepo) List(category string) ([]model.CatalogService, error) {
+	query := `
+		SELECT ` + catalogServiceColumns + `
+		FROM services
+		WHERE $1 = '' OR category = $1
+		ORDER BY name;
+	`
+
+	rows, err := db.Postgres.Query(query, category)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("сервисы каталога не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("catalog services not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	services := []model.CatalogService{}
+
+	for rows.Next() {
+		var service model.CatalogService
+
+		err = scanCatalogService(rows, &service)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("сервис каталога невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		services = append(services, service)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("сервисы каталога невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return services, nil
+}
+
+// Create добавляет сервис в каталог и связывает с ним записи о подписках, название которых совпадает
+// с названием или псевдонимом сервиса. Связанные записи получают каноническое название, изменение каждой записи
+// записывается в журнал изменений от имени actor
+func (repo *CatalogRepo) Create(entity *model.CatalogService, actor string) error {
+	query := `
+		INSERT INTO services (name, category, default_price, vendor_url, logo_url)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at;
+	`
+
+	var linked []*model.Subscription
+
+	err := withTransaction(func(q queryer) error {
+		err := q.QueryRow(query, entity.Name, entity.Category, entity.DefaultPrice, entity.VendorUrl, entity.LogoUrl).
+			Scan(&entity.Id, &entity.CreatedAt)
+
+		var pqErr *pq.Error
+
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("service already exists")
+		}
+
+		if err != nil {
+			slog.Error(fmt.Errorf("сервис каталога не создан: %w", err).Error())
+
+			return fmt.Errorf("failed to create catalog service: %w", err)
+		}
+
+		if err = insertServiceAliases(q, entity); err != nil {
+			return err
+		}
+
+		linked, err = linkSubscriptions(q, entity, actor)
+
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range linked {
+		deleteSubscriptionCache(sub)
+	}
+
+	slog.Info(fmt.Sprintf("Создание сервиса каталога. ИД: %d. Название: %s. Категория: %s. Связано записей о подписках: %d",
+		entity.Id,
+		entity.Name,
+		entity.Category,
+		len(linked),
+	))
+
+	return nil
+}
+
+// Update изменяет сервис каталога, заменяет его псевдонимы и переименовывает связанные записи о подписках.
+// Записи, название которых совпадает с новым псевдонимом, связываются с сервисом. Изменение каждой записи
+// записывается в журнал изменений от имени actor
+func (repo *CatalogRepo) Update(entity *model.CatalogService, actor string) error {
+	query := `
+		UPDATE services
+		SET name = $2, category = $3, default_price = $4, vendor_url = $5, logo_url = $6
+		WHERE id = $1
+		RETURNING created_at;
+	`
+
+	var linked []*model.Subscription
+
+	err := withTransaction(func(q queryer) error {
+		err := q.QueryRow(query, entity.Id, entity.Name, entity.Category, entity.DefaultPrice, entity.VendorUrl, entity.LogoUrl).
+			Scan(&entity.CreatedAt)
+
+		var pqErr *pq.Error
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("service not found")
+		}
+
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("service already exists")
+		}
+
+		if err != nil {
+			slog.Error(fmt.Errorf("сервис каталога не изменён: %w", err).Error())
+
+			return fmt.Errorf("failed to update catalog service: %w", err)
+		}
+
+		_, err = q.Exec(`DELETE FROM service_aliases WHERE service_id = $1;`, entity.Id)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("псевдонимы сервиса каталога не удалены: %w", err).Error())
+
+			return fmt.Errorf("failed to update catalog service aliases: %w", err)
+		}
+
+		if err = insertServiceAliases(q, entity); err != nil {
+			return err
+		}
+
+		linked, err = linkSubscriptions(q, entity, actor)
+
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range linked {
+		deleteSubscriptionCache(sub)
+	}
+
+	slog.Info(fmt.Sprintf("Изменение сервиса каталога. ИД: %d. Название: %s. Категория: %s. Изменено записей о подписках: %d",
+		entity.Id,
+		entity.Name,
+		entity.Category,
+		len(linked),
+	))
+
+	return nil
+}
+
+// Delete удаляет сервис из каталога. Связанные записи о подписках сохраняют название и перестают быть связаны с каталогом,
+// изменение каждой неудалённой записи записывается в журнал изменений от имени actor
+func (repo *CatalogRepo) Delete(entity *model.CatalogService, actor string) error {
+	var unlinked []*model.Subscription
+
+	err := withTransaction(func(q queryer) error {
+		var err error
+
+		unlinked, err = relinkSubscriptions(q, `subscriptions.service_id = $1`, []any{entity.Id}, actor, func(sub *model.Subscription) {
+			sub.ServiceId = nil
+		})
+
+		if err != nil {
+			slog.Error(fmt.Errorf("записи о подписках не отвязаны от сервиса каталога: %w", err).Error())
+
+			return fmt.Errorf("failed to unlink subscriptions: %w", err)
+		}
+
+		// Удалённые записи отвязываются без журнала изменений: они не видны и не отправляются в вебхуки
+		_, err = q.Exec(`UPDATE subscriptions SET service_id = NULL WHERE service_id = $1 AND deleted_at IS NOT NULL;`, entity.Id)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("удалённые записи о подписках не отвязаны от сервиса каталога: %w", err).Error())
+
+			return fmt.Errorf("failed to unlink subscriptions: %w", err)
+		}
+
+		result, err := q.Exec(`DELETE FROM services WHERE id = $1;`, entity.Id)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("сервис каталога не удалён: %w", err).Error())
+
+			return fmt.Errorf("failed to delete catalog service: %w", err)
+		}
+
+		if deleted, _ := result.RowsAffected(); deleted == 0 {
+			return fmt.Errorf("service not found")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range unlinked {
+		deleteSubscriptionCache(sub)
+	}
+
+	slog.Info(fmt.Sprintf("Удаление сервиса каталога. ИД: %d. Название: %s", entity.Id, entity.Name))
+
+	return nil
+}
+
+// ResolveService находит сервис каталога по названию или псевдониму без учёта регистра и лишних пробелов.
+// Если сервис не найден, возвращается nil без ошибки
+func (repo *SubscriptionRepo) ResolveService(name string) (*model.CatalogService, error) {
+	query := `
+		SELECT ` + catalogServiceColumns + `
+		FROM services
+			JOIN service_aliases ON service_aliases.service_id = services.id
+		WHERE service_aliases.normalized = $1;
+	`
+
+	var service model.CatalogService
+
+	err := scanCatalogService(db.Postgres.QueryRow(query, ServiceAliasKey(name)), &service)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("сервис каталога невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return &service, nil
+}
+
+// insertServiceAliases сохраняет каноническое название и псевдонимы сервиса каталога для поиска по названию
+func insertServiceAliases(q queryer, entity *model.CatalogService) error {
+	query := `
+		INSERT INTO service_aliases (normalized, alias, service_id)
+		VALUES ($1, $2, $3);
+	`
+
+	for _, alias := range append([]string{entity.Name}, entity.Aliases...) {
+		_, err := q.Exec(query, ServiceAliasKey(alias), alias, entity.Id)
+
+		var pqErr *pq.Error
+
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("alias is used by another service: %s", alias)
+		}
+
+		if err != nil {
+			slog.Error(fmt.Errorf("псевдоним сервиса каталога не сохранён: %w", err).Error())
+
+			return fmt.Errorf("failed to save catalog service alias: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// linkSubscriptions связывает с сервисом каталога неудалённые записи о подписках, название которых совпадает с его названием
+// или псевдонимом, и переименовывает связанные записи в каноническое название. Возвращает изменённые записи
+func linkSubscriptions(q queryer, entity *model.CatalogService, actor string) ([]*model.Subscription, error) {
+	condition := `(subscriptions.service_id = $1 OR (subscriptions.service_id IS NULL AND ` + serviceAliasKeyColumn + ` IN (
+				SELECT normalized FROM service_aliases WHERE service_id = $1
+			)))
+			AND (subscriptions.service_id IS DISTINCT FROM $1 OR subscriptions.service_name <> $2)`
+
+	subs, err := relinkSubscriptions(q, condition, []any{entity.Id, entity.Name}, actor, func(sub *model.Subscription) {
+		serviceId := entity.Id
+
+		sub.ServiceId = &serviceId
+		sub.ServiceName = entity.Name
+	})
+
+	if err != nil {
+		slog.Error(fmt.Errorf("записи о подписках не связаны с сервисом каталога: %w", err).Error())
+
+		return nil, fmt.Errorf("failed to link subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// relinkSubscriptions изменяет неудалённые записи о подписках всех организаций, подходящие под условие condition, функцией change.
+// Каждое изменение записывается в журнал изменений от имени actor, и для него сохраняется событие для вебхуков в той же транзакции.
+// Возвращает изменённые записи
+func relinkSubscriptions(q queryer, condition string, args []any, actor string, change func(sub *model.Subscription)) ([]*model.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE ` + condition + `
+			AND subscriptions.deleted_at IS NULL
+		ORDER BY subscriptions.id
+		FOR UPDATE;
+	`
+
+	rows, err := q.Query(query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	subs := []*model.Subscription{}
+
+	for rows.Next() {
+		var sub model.Subscription
+
+		if err = scanSubscription(rows, &sub); err != nil {
+			return nil, err
+		}
+
+		subs = append(subs, &sub)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows.Close()
+
+	for _, sub := range subs {
+		before := *sub
+
+		change(sub)
+
+		err = applyBatchOperation(q, "", BatchOperation{Action: model.AuditActionUpdate, Subscription: sub, Before: &before, Actor: actor})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return subs, nil
+}
+
+func scanCatalogService(row rowScanner, service *model.CatalogService) error {
+	return row.Scan(
+		&service.Id,
+		&service.Name,
+		pq.Array(&service.Aliases),
+		&service.Category,
+		&service.DefaultPrice,
+		&service.VendorUrl,
+		&service.LogoUrl,
+		&service.CreatedAt,
+	)
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"subsaggregator/internal/model"
+	"time"
+)
+
+type CatalogRepoMock struct {
+	Services map[int]*model.CatalogService
+
+	// Записи о подписках, которые связываются с сервисами каталога
+	Subscriptions map[int]*model.Subscription
+
+	// Журнал изменений и события для вебхуков по изменённым записям о подписках
+	Audit  map[int][]model.SubscriptionAudit
+	Outbox map[string]*model.WebhookEvent
+}
+
+func (repo CatalogRepoMock) FindById(id int) (*model.CatalogService, error) {
+	if service, ok := repo.Services[id]; ok {
+		result := *service
+
+		return &result, nil
+	}
+
+	return nil, nil
+}
+
+func (repo CatalogRepoMock) List(category string) ([]model.CatalogService, error) {
+	services := []model.CatalogService{}
+
+	for _, service := range repo.Services {
+		if category == "" || service.Category == category {
+			services = append(services, *service)
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	return services, nil
+}
+
+func (repo CatalogRepoMock) Create(entity *model.CatalogService, actor string) error {
+	if err := repo.checkAliases(entity); err != nil {
+		return err
+	}
+
+	entity.Id = 1
+
+	for id := range repo.Services {
+		entity.Id = max(entity.Id, id+1)
+	}
+
+	entity.CreatedAt = time.Now()
+
+	service := *entity
+	repo.Services[entity.Id] = &service
+
+	repo.linkSubscriptions(entity, actor)
+
+	return nil
+}
+
+func (repo CatalogRepoMock) Update(entity *model.CatalogService, actor string) error {
+	existing, ok := repo.Services[entity.Id]
+
+	if !ok {
+		return fmt.Errorf("service not found")
+	}
+
+	if err := repo.checkAliases(entity); err != nil {
+		return err
+	}
+
+	entity.CreatedAt = existing.CreatedAt
+
+	service := *entity
+	repo.Services[entity.Id] = &service
+
+	repo.linkSubscriptions(entity, actor)
+
+	return nil
+}
+
+func (repo CatalogRepoMock) Delete(entity *model.CatalogService, actor string) error {
+	if _, ok := repo.Services[entity.Id]; !ok {
+		return fmt.Errorf("service not found")
+	}
+
+	for _, sub := range repo.Subscriptions {
+		if sub.ServiceId != nil && *sub.ServiceId == entity.Id {
+			before := *sub
+
+			sub.ServiceId = nil
+
+			if sub.DeletedAt == nil {
+				repo.recordChange(&before, sub, actor)
+			}
+		}
+	}
+
+	delete(repo.Services, entity.Id)
+
+	return nil
+}
+
+// checkAliases проверяет уникальность названия и псевдонимов сервиса так же, как ограничения services и service_aliases
+func (repo CatalogRepoMock) checkAliases(entity *model.CatalogService) error {
+	for _, service := range repo.Services {
+		if service.Id == entity.Id {
+			continue
+		}
+
+		if service.Name == entity.Name {
+			return fmt.Errorf("service already exists")
+		}
+
+		keys := []string{ServiceAliasKey(service.Name)}
+
+		for _, alias := range service.Aliases {
+			keys = append(keys, ServiceAliasKey(alias))
+		}
+
+		for _, alias := range append([]string{entity.Name}, entity.Aliases...) {
+			if slices.Contains(keys, ServiceAliasKey(alias)) {
+				return fmt.Errorf("alias is used by another service: %s", alias)
+			}
+		}
+	}
+
+	return nil
+}
+
+// linkSubscriptions связывает записи о подписках с сервисом так же, как CatalogRepo
+func (repo CatalogRepoMock) linkSubscriptions(entity *model.CatalogService, actor string) {
+	keys := []string{ServiceAliasKey(entity.Name)}
+
+	for _, alias := range entity.Aliases {
+		keys = append(keys, ServiceAliasKey(alias))
+	}
+
+	for _, sub := range repo.Subscriptions {
+		if sub.DeletedAt != nil {
+			continue
+		}
+
+		linked := sub.ServiceId != nil && *sub.ServiceId == entity.Id
+
+		if linked && sub.ServiceName == entity.Name {
+			continue
+		}
+
+		if linked || (sub.ServiceId == nil && slices.Contains(keys, ServiceAliasKey(sub.ServiceName))) {
+			before := *sub
+			serviceId := entity.Id
+
+			sub.ServiceId = &serviceId
+			sub.ServiceName = entity.Name
+
+			repo.recordChange(&before, sub, actor)
+		}
+	}
+}
+
+// recordChange записывает изменение записи о подписке в журнал изменений и событие для вебхуков, если мок создан с Audit и Outbox
+func (repo CatalogRepoMock) recordChange(before *model.Subscription, sub *model.Subscription, actor string) {
+	subscriptionRepo := SubscriptionRepoMock{Audit: repo.Audit, Outbox: repo.Outbox}
+
+	subscriptionRepo.recordWebhookEvent(model.AuditActionUpdate, sub)
+	subscriptionRepo.recordAudit(&model.SubscriptionAudit{
+		SubscriptionId: sub.Id,
+		Action:         model.AuditActionUpdate,
+		Actor:          actor,
+		Before:         before,
+		After:          snapshot(sub),
+	})
+}
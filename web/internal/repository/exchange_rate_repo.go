@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+)
+
+type ExchangeRateRepository interface {
+	List(currency string) ([]model.ExchangeRate, error)
+	Save(entities []model.ExchangeRate) error
+}
+
+type ExchangeRateRepo struct{}
+
+func (repo *ExchangeRateRepo) List(currency string) ([]model.ExchangeRate, error) {
+	query := `
+		SELECT currency, month, rate
+		FROM exchange_rates
+		WHERE ($1::TEXT IS NULL OR currency = $1)
+		ORDER BY currency, month;
+	`
+
+	rows, err := db.Postgres.Query(query, getFilter(currency))
+
+	if err != nil {
+		slog.Error(fmt.Errorf("курсы валют не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("exchange rates not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	rates := []model.ExchangeRate{}
+
+	for rows.Next() {
+		var rate model.ExchangeRate
+
+		err = rows.Scan(&rate.Currency, &rate.Month, &rate.Rate)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("курс валюты невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		rates = append(rates, rate)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("курсы валют невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение курсов валют. Валюта: %s", currency))
+
+	return rates, nil
+}
+
+func (repo *ExchangeRateRepo) Save(entities []model.ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates (currency, month, rate)
+		VALUES ($1, date_trunc('month', $2::DATE), $3)
+		ON CONFLICT (currency, month) DO UPDATE SET rate = EXCLUDED.rate;
+	`
+
+	tx, err := db.Postgres.Begin()
+
+	if err != nil {
+		slog.Error(fmt.Errorf("транзакция для сохранения курсов валют не начата: %w", err).Error())
+
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	for _, entity := range entities {
+		_, err = tx.Exec(query, entity.Currency, entity.Month, entity.Rate)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("курс валюты не сохранён: %w", err).Error())
+
+			return fmt.Errorf("failed to save exchange rate: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		slog.Error(fmt.Errorf("курсы валют не сохранены: %w", err).Error())
+
+		return fmt.Errorf("failed to save exchange rates: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Сохранение курсов валют. Количество: %d", len(entities)))
+
+	return nil
+}
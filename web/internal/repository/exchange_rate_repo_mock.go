@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"subsaggregator/internal/model"
+)
+
+type ExchangeRateRepoMock struct {
+	ExchangeRates map[string]*model.ExchangeRate
+}
+
+func (repo ExchangeRateRepoMock) List(currency string) ([]model.ExchangeRate, error) {
+	rates := []model.ExchangeRate{}
+
+	for _, rate := range repo.ExchangeRates {
+		if currency != "" && rate.Currency != currency {
+			continue
+		}
+
+		rates = append(rates, *rate)
+	}
+
+	return rates, nil
+}
+
+func (repo ExchangeRateRepoMock) Save(entities []model.ExchangeRate) error {
+	for _, entity := range entities {
+		rate := entity
+
+		repo.ExchangeRates[rate.Currency+":"+rate.Month.String()] = &rate
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package repository
+
+// ListOptions параметры сортировки и постраничного получения списка записей о подписках
+type ListOptions struct {
+	// Поле сортировки, одно из model.SortBy*
+	SortBy string
+
+	// Сортировка по убыванию
+	Descending bool
+
+	// Позиция, после которой начинается страница. Если не задана, используется Offset
+	Cursor *Cursor
+
+	Offset int
+	Limit  int
+}
+
+// Cursor позиция записи в отсортированном списке записей о подписках
+type Cursor struct {
+	// Значение поля сортировки у записи
+	Value string `json:"v"`
+
+	// Поле и направление сортировки, для которых получен курсор
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+
+	// ИД записи
+	Id int `json:"id"`
+
+	// Страница запрашивается перед записью, а не после неё
+	Backward bool `json:"b,omitempty"`
+}
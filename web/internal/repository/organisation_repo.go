@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+
+	"github.com/lib/pq"
+)
+
+type OrganisationRepository interface {
+	FindById(id string) (*model.Organisation, error)
+	List() ([]model.Organisation, error)
+	Create(entity *model.Organisation) error
+}
+
+type OrganisationRepo struct{}
+
+func (repo *OrganisationRepo) FindById(id string) (*model.Organisation, error) {
+	rows, err := db.Postgres.Query(`SELECT id, name, created_at FROM organisations WHERE id = $1;`, id)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("организация не найдена: %w", err).Error())
+
+		return nil, fmt.Errorf("organisation not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var organisation model.Organisation
+
+	err = rows.Scan(&organisation.Id, &organisation.Name, &organisation.CreatedAt)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("организацию невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return &organisation, nil
+}
+
+func (repo *OrganisationRepo) List() ([]model.Organisation, error) {
+	rows, err := db.Postgres.Query(`SELECT id, name, created_at FROM organisations ORDER BY id;`)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("организации не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("organisations not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	organisations := []model.Organisation{}
+
+	for rows.Next() {
+		var organisation model.Organisation
+
+		err = rows.Scan(&organisation.Id, &organisation.Name, &organisation.CreatedAt)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("организацию невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		organisations = append(organisations, organisation)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("организации невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return organisations, nil
+}
+
+func (repo *OrganisationRepo) Create(entity *model.Organisation) error {
+	query := `
+		INSERT INTO organisations (id, name)
+		VALUES ($1, $2)
+		RETURNING created_at;
+	`
+
+	err := db.Postgres.QueryRow(query, entity.Id, entity.Name).Scan(&entity.CreatedAt)
+
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return fmt.Errorf("organisation already exists")
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("организация не создана: %w", err).Error())
+
+		return fmt.Errorf("failed to create organisation: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Создание организации. ИД: %s. Название: %s", entity.Id, entity.Name))
+
+	return nil
+}
+
+// tenantOrDefault возвращает организацию, которой принадлежат новые записи хранилища с ограничением tenantId.
+// Хранилище без ограничения создаёт записи в организации по умолчанию
+func tenantOrDefault(tenantId string) string {
+	if tenantId == "" {
+		return model.DefaultTenantId
+	}
+
+	return tenantId
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"subsaggregator/internal/model"
+	"time"
+)
+
+type OrganisationRepoMock struct {
+	Organisations map[string]*model.Organisation
+}
+
+func (repo OrganisationRepoMock) FindById(id string) (*model.Organisation, error) {
+	if organisation, ok := repo.Organisations[id]; ok {
+		result := *organisation
+
+		return &result, nil
+	}
+
+	return nil, nil
+}
+
+func (repo OrganisationRepoMock) List() ([]model.Organisation, error) {
+	organisations := []model.Organisation{}
+
+	for _, organisation := range repo.Organisations {
+		organisations = append(organisations, *organisation)
+	}
+
+	sort.Slice(organisations, func(i, j int) bool { return organisations[i].Id < organisations[j].Id })
+
+	return organisations, nil
+}
+
+func (repo OrganisationRepoMock) Create(entity *model.Organisation) error {
+	if _, ok := repo.Organisations[entity.Id]; ok {
+		return fmt.Errorf("organisation already exists")
+	}
+
+	entity.CreatedAt = time.Now()
+
+	organisation := *entity
+
+	repo.Organisations[entity.Id] = &organisation
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+)
+
+type ReminderRepository interface {
+	FindSettings(userId string) (*model.ReminderSettings, error)
+	SaveSettings(entity *model.ReminderSettings) error
+	ListEnabledSettings() ([]model.ReminderSettings, error)
+	MarkSent(reminder model.Reminder, channel string) (bool, error)
+	UnmarkSent(reminder model.Reminder, channel string) error
+}
+
+// ReminderRepo хранилище настроек и отметок об отправке напоминаний. Настройки ограничены организацией TenantId,
+// а хранилище без организации возвращает настройки всех организаций и используется рассылкой напоминаний
+type ReminderRepo struct {
+	TenantId string
+}
+
+// reminderSettingsColumns перечисляет столбцы reminder_settings в порядке полей model.ReminderSettings
+const reminderSettingsColumns = "user_id, tenant_id, enabled, email, webhook_url, days_before"
+
+// FindSettings возвращает настройки напоминаний пользователя или nil, если пользователь их не сохранял
+func (repo *ReminderRepo) FindSettings(userId string) (*model.ReminderSettings, error) {
+	query := `
+		SELECT ` + reminderSettingsColumns + `
+		FROM reminder_settings
+		WHERE user_id = $1 AND tenant_id = $2;
+	`
+
+	var settings model.ReminderSettings
+
+	err := scanReminderSettings(db.Postgres.QueryRow(query, userId, tenantOrDefault(repo.TenantId)), &settings)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("настройки напоминаний невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение настроек напоминаний. ИД пользователя: %s", userId))
+
+	return &settings, nil
+}
+
+func (repo *ReminderRepo) SaveSettings(entity *model.ReminderSettings) error {
+	query := `
+		INSERT INTO reminder_settings (user_id, tenant_id, enabled, email, webhook_url, days_before)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id, user_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			email = EXCLUDED.email,
+			webhook_url = EXCLUDED.webhook_url,
+			days_before = EXCLUDED.days_before,
+			updated_at = now();
+	`
+
+	entity.TenantId = tenantOrDefault(repo.TenantId)
+
+	_, err := db.Postgres.Exec(query, entity.UserId, entity.TenantId, entity.Enabled, entity.Email, entity.WebhookUrl, entity.DaysBefore)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("настройки напоминаний не сохранены: %w", err).Error())
+
+		return fmt.Errorf("failed to save reminder settings: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Сохранение настроек напоминаний. ИД пользователя: %s. Включены: %t. За дней: %d",
+		entity.UserId,
+		entity.Enabled,
+		entity.DaysBefore,
+	))
+
+	return nil
+}
+
+func (repo *ReminderRepo) ListEnabledSettings() ([]model.ReminderSettings, error) {
+	query := `
+		SELECT ` + reminderSettingsColumns + `
+		FROM reminder_settings
+		WHERE enabled AND ($1 = '' OR tenant_id = $1)
+		ORDER BY tenant_id, user_id;
+	`
+
+	rows, err := db.Postgres.Query(query, repo.TenantId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("настройки напоминаний не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("reminder settings not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	settingsList := []model.ReminderSettings{}
+
+	for rows.Next() {
+		var settings model.ReminderSettings
+
+		err = scanReminderSettings(rows, &settings)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("настройки напоминаний невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		settingsList = append(settingsList, settings)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("настройки напоминаний невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return settingsList, nil
+}
+
+// scanReminderSettings читает строку со столбцами reminderSettingsColumns
+func scanReminderSettings(row rowScanner, settings *model.ReminderSettings) error {
+	return row.Scan(&settings.UserId, &settings.TenantId, &settings.Enabled, &settings.Email, &settings.WebhookUrl, &settings.DaysBefore)
+}
+
+// MarkSent отмечает напоминание отправленным по каналу и возвращает false, если оно уже было отмечено.
+// Отметка ставится до отправки, чтобы несколько экземпляров сервиса не отправили одно напоминание дважды
+func (repo *ReminderRepo) MarkSent(reminder model.Reminder, channel string) (bool, error) {
+	query := `
+		INSERT INTO sent_reminders (subscription_id, kind, due_date, channel)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING;
+	`
+
+	result, err := db.Postgres.Exec(query, reminder.SubscriptionId, reminder.Kind, reminder.DueDate, channel)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("напоминание не отмечено отправленным: %w", err).Error())
+
+		return false, fmt.Errorf("failed to mark reminder as sent: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, fmt.Errorf("failed to mark reminder as sent: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// UnmarkSent снимает отметку об отправке, если напоминание отправить не удалось
+func (repo *ReminderRepo) UnmarkSent(reminder model.Reminder, channel string) error {
+	query := `
+		DELETE FROM sent_reminders
+		WHERE subscription_id = $1 AND kind = $2 AND due_date = $3 AND channel = $4;
+	`
+
+	_, err := db.Postgres.Exec(query, reminder.SubscriptionId, reminder.Kind, reminder.DueDate, channel)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("отметка об отправке напоминания не снята: %w", err).Error())
+
+		return fmt.Errorf("failed to unmark reminder as sent: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"subsaggregator/internal/model"
+)
+
+type ReminderRepoMock struct {
+	Settings map[string]*model.ReminderSettings
+	Sent     map[string]bool
+	TenantId string
+}
+
+func (repo ReminderRepoMock) FindSettings(userId string) (*model.ReminderSettings, error) {
+	if settings, ok := repo.Settings[userId]; ok && settings.TenantId == tenantOrDefault(repo.TenantId) {
+		result := *settings
+
+		return &result, nil
+	}
+
+	return nil, nil
+}
+
+func (repo ReminderRepoMock) SaveSettings(entity *model.ReminderSettings) error {
+	entity.TenantId = tenantOrDefault(repo.TenantId)
+	settings := *entity
+
+	repo.Settings[entity.UserId] = &settings
+
+	return nil
+}
+
+func (repo ReminderRepoMock) ListEnabledSettings() ([]model.ReminderSettings, error) {
+	settingsList := []model.ReminderSettings{}
+
+	for _, settings := range repo.Settings {
+		if settings.Enabled {
+			settingsList = append(settingsList, *settings)
+		}
+	}
+
+	sort.Slice(settingsList, func(i, j int) bool {
+		return settingsList[i].UserId < settingsList[j].UserId
+	})
+
+	return settingsList, nil
+}
+
+func (repo ReminderRepoMock) MarkSent(reminder model.Reminder, channel string) (bool, error) {
+	key := sentReminderKey(reminder, channel)
+
+	if repo.Sent[key] {
+		return false, nil
+	}
+
+	repo.Sent[key] = true
+
+	return true, nil
+}
+
+func (repo ReminderRepoMock) UnmarkSent(reminder model.Reminder, channel string) error {
+	delete(repo.Sent, sentReminderKey(reminder, channel))
+
+	return nil
+}
+
+func sentReminderKey(reminder model.Reminder, channel string) string {
+	return fmt.Sprintf("%d:%s:%s:%s", reminder.SubscriptionId, reminder.Kind, reminder.DueDate.Format("2006-01-02"), channel)
+}
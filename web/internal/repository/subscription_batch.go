@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+)
+
+// BatchOperation операция пакета над записью о подписке
+type BatchOperation struct {
+	// Порядковый номер операции в запросе
+	Index int
+
+	// Действие, одно из model.AuditActionCreate, model.AuditActionUpdate, model.AuditActionDelete, model.AuditActionRestore
+	Action string
+
+	// Запись о подписке для создания, изменения или удаления
+	Subscription *model.Subscription
+
+	// Запись о подписке до изменения для журнала изменений
+	Before *model.Subscription
+
+	// Изменение стоимости, записываемое в историю стоимости вместе с изменением записи
+	Price *model.SubscriptionPrice
+
+	// Автор изменения для журнала изменений
+	Actor string
+}
+
+// Batch выполняет операции над записями о подписках в одной транзакции.
+// В режиме model.BatchModeAllOrNothing ошибка любой операции откатывает транзакцию,
+// в режиме model.BatchModeBestEffort каждая операция выполняется в своей точке сохранения и при ошибке откатывается только она
+func (repo *SubscriptionRepo) Batch(operations []BatchOperation, mode string) ([]model.BatchResult, error) {
+	tx, err := db.Postgres.Begin()
+
+	if err != nil {
+		slog.Error(fmt.Errorf("транзакция пакета операций над подписками не начата: %w", err).Error())
+
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	results := make([]model.BatchResult, len(operations))
+	failed := false
+
+	for i, operation := range operations {
+		results[i] = model.BatchResult{Index: operation.Index, Action: operation.Action}
+
+		if operation.Action == model.AuditActionCreate {
+			repo.assignTenant(operation.Subscription)
+		}
+	}
+
+	for i, operation := range operations {
+		if mode == model.BatchModeBestEffort {
+			_, err = tx.Exec("SAVEPOINT batch_operation;")
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to create savepoint: %w", err)
+			}
+		}
+
+		err = applyBatchOperation(tx, repo.TenantId, operation)
+
+		if err != nil {
+			results[i].Status = model.BatchStatusFailed
+			results[i].Error = err.Error()
+			failed = true
+
+			if mode != model.BatchModeBestEffort {
+				break
+			}
+
+			_, err = tx.Exec("ROLLBACK TO SAVEPOINT batch_operation;")
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to rollback to savepoint: %w", err)
+			}
+
+			continue
+		}
+
+		results[i].Status = model.BatchStatusOk
+		results[i].Subscription = operation.Subscription
+	}
+
+	if failed && mode != model.BatchModeBestEffort {
+		for i := range results {
+			if results[i].Status != model.BatchStatusFailed {
+				results[i].Status = model.BatchStatusRolledBack
+				results[i].Subscription = nil
+			}
+		}
+
+		slog.Info(fmt.Sprintf("Пакет операций над подписками отменён. Операций: %d", len(operations)))
+
+		return results, nil
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		slog.Error(fmt.Errorf("транзакция пакета операций над подписками не завершена: %w", err).Error())
+
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for i, operation := range operations {
+		if results[i].Status != model.BatchStatusOk {
+			continue
+		}
+
+		if operation.Action == model.AuditActionDelete {
+			deleteSubscriptionCache(operation.Subscription)
+		} else {
+			setSubscriptionCache(operation.Subscription)
+		}
+	}
+
+	slog.Info(fmt.Sprintf("Выполнение пакета операций над подписками. Операций: %d", len(operations)))
+
+	return results, nil
+}
+
+// applyBatchOperation выполняет операцию пакета над записью организации tenantId, записывает её в журнал изменений
+// и сохраняет событие для вебхуков в рамках транзакции
+func applyBatchOperation(q queryer, tenantId string, operation BatchOperation) error {
+	var err error
+	var after *model.Subscription
+
+	switch operation.Action {
+	case model.AuditActionCreate:
+		err = insertSubscription(q, operation.Subscription)
+	case model.AuditActionUpdate:
+		err = updateSubscription(q, tenantId, operation.Subscription)
+
+		if err == nil && operation.Price != nil {
+			err = upsertPrice(q, operation.Price)
+		}
+	case model.AuditActionDelete:
+		err = deleteSubscription(q, tenantId, operation.Subscription)
+	case model.AuditActionRestore:
+		err = restoreSubscription(q, tenantId, operation.Subscription)
+	default:
+		err = fmt.Errorf("unknown batch action: %s", operation.Action)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if operation.Action != model.AuditActionDelete {
+		snapshot := *operation.Subscription
+		after = &snapshot
+	}
+
+	err = insertWebhookEvent(q, operation.Subscription.TenantId, model.WebhookEventTypes[operation.Action], operation.Subscription)
+
+	if err != nil {
+		return err
+	}
+
+	return insertAudit(q, &model.SubscriptionAudit{
+		SubscriptionId: operation.Subscription.Id,
+		Action:         operation.Action,
+		Actor:          operation.Actor,
+		Before:         operation.Before,
+		After:          after,
+	})
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+// SubscriptionFilter фильтр записей о подписках для списка и суммарной стоимости.
+// Пустые поля не ограничивают выборку
+type SubscriptionFilter struct {
+	// ИД организаций. Хранилище, ограниченное организацией, заменяет их своей организацией
+	TenantIds []string
+
+	// ИД пользователей
+	UserIds []string
+
+	// Точные названия сервисов
+	ServiceNames []string
+
+	// Начало названия сервиса без учёта регистра
+	ServiceNameSearch string
+
+	// Категории сервисов каталога. Подписки, не связанные с каталогом, относятся к категории model.ServiceCategoryOther
+	Categories []string
+
+	// Минимальная и максимальная базовая стоимость подписки за расчётный период (subscriptions.price).
+	// История изменения стоимости, пробный период и промо-цена не учитываются
+	MinPrice int
+	MaxPrice int
+
+	// Период: подписки, начавшиеся не позже MaxStartDate и закончившиеся не раньше MinEndDate.
+	// Если заданы обе даты, подписка должна действовать и не быть приостановленной хотя бы в одном месяце периода.
+	// Если задана только MinEndDate, подписки без даты окончания не попадают в фильтр
+	MaxStartDate utils.Date
+	MinEndDate   utils.Date
+
+	// Месяц, в котором подписка действует и не приостановлена
+	ActiveOn utils.Date
+
+	// Только подписки без даты окончания
+	OpenEndedOnly bool
+
+	// Месяц, до которого в суммах по месяцам учитываются подписки без даты окончания.
+	// Если не задан, такие подписки в суммах не учитываются
+	ProjectUntil utils.Date
+}
+
+// condition собирает условие WHERE для таблицы subscriptions и добавляет его параметры в args
+func (filter SubscriptionFilter) condition(args *[]any) string {
+	conditions := []string{"subscriptions.deleted_at IS NULL"}
+
+	if len(filter.TenantIds) > 0 {
+		conditions = append(conditions, "subscriptions.tenant_id = ANY("+bind(args, pq.Array(filter.TenantIds))+"::TEXT[])")
+	}
+
+	if len(filter.UserIds) > 0 {
+		conditions = append(conditions, "subscriptions.user_id = ANY("+bind(args, pq.Array(filter.UserIds))+"::TEXT[])")
+	}
+
+	if len(filter.ServiceNames) > 0 {
+		conditions = append(conditions, "subscriptions.service_name = ANY("+bind(args, pq.Array(filter.ServiceNames))+"::TEXT[])")
+	}
+
+	if filter.ServiceNameSearch != "" {
+		conditions = append(conditions, "subscriptions.service_name ILIKE "+bind(args, likePrefix(filter.ServiceNameSearch)))
+	}
+
+	if len(filter.Categories) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"COALESCE((SELECT services.category FROM services WHERE services.id = subscriptions.service_id), '%s') = ANY(%s::TEXT[])",
+			model.ServiceCategoryOther,
+			bind(args, pq.Array(filter.Categories)),
+		))
+	}
+
+	if filter.MinPrice > 0 {
+		conditions = append(conditions, "subscriptions.price >= "+bind(args, filter.MinPrice))
+	}
+
+	if filter.MaxPrice > 0 {
+		conditions = append(conditions, "subscriptions.price <= "+bind(args, filter.MaxPrice))
+	}
+
+	switch {
+	case filter.MaxStartDate.Valid && filter.MinEndDate.Valid:
+		periodStart, periodEnd := bind(args, filter.MaxStartDate), bind(args, filter.MinEndDate)
+
+		conditions = append(conditions, fmt.Sprintf(
+			"daterange(subscriptions.start_date, subscriptions.end_date, '[]') && daterange(%s::DATE, %s::DATE, '[]')",
+			periodStart,
+			periodEnd,
+		), fmt.Sprintf(`EXISTS (
+			SELECT 1
+			FROM generate_series(
+				GREATEST(date_trunc('month', subscriptions.start_date), date_trunc('month', %[1]s::DATE)),
+				LEAST(date_trunc('month', COALESCE(subscriptions.end_date, %[2]s::DATE)), date_trunc('month', %[2]s::DATE)),
+				interval '1 month'
+			) AS months(month)
+			WHERE %[3]s
+		)`, periodStart, periodEnd, notPausedCondition("months.month::DATE")))
+	case filter.MaxStartDate.Valid:
+		conditions = append(conditions, "subscriptions.start_date <= "+bind(args, filter.MaxStartDate))
+	case filter.MinEndDate.Valid:
+		conditions = append(conditions, "subscriptions.end_date >= "+bind(args, filter.MinEndDate))
+	}
+
+	if filter.ActiveOn.Valid {
+		activeOn := "date_trunc('month', " + bind(args, filter.ActiveOn) + "::DATE)::DATE"
+
+		conditions = append(conditions,
+			"daterange(subscriptions.start_date, subscriptions.end_date, '[]') @> "+activeOn,
+			notPausedCondition(activeOn),
+		)
+	}
+
+	if filter.OpenEndedOnly {
+		conditions = append(conditions, "subscriptions.end_date IS NULL")
+	}
+
+	return strings.Join(conditions, "\n\t\t\tAND ")
+}
+
+func (filter SubscriptionFilter) String() string {
+	serviceNames := slices.Clone(filter.ServiceNames)
+
+	if filter.ServiceNameSearch != "" {
+		serviceNames = append(serviceNames, filter.ServiceNameSearch+"*")
+	}
+
+	return fmt.Sprintf(
+		"c %s до %s. ИД пользователей: %s. Названия сервисов: %s",
+		filter.MinEndDate.Time.Format("01-2006"),
+		filter.MaxStartDate.Time.Format("01-2006"),
+		strings.Join(filter.UserIds, ", "),
+		strings.Join(serviceNames, ", "),
+	)
+}
+
+// bind добавляет параметр запроса и возвращает его плейсхолдер
+func bind(args *[]any, value any) string {
+	*args = append(*args, value)
+
+	return fmt.Sprintf("$%d", len(*args))
+}
+
+// likePrefix экранирует спецсимволы LIKE и превращает строку в шаблон поиска по началу
+func likePrefix(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+	return replacer.Replace(value) + "%"
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"subsaggregator/internal/model"
+	"time"
+)
+
+// BillingStart возвращает начало первого оплачиваемого расчётного периода подписки:
+// первый день месяца после пробного периода или дату начала подписки
+func BillingStart(sub model.Subscription) time.Time {
+	if sub.TrialEndDate != nil && sub.TrialEndDate.Valid {
+		return truncateMonth(sub.TrialEndDate.Time).AddDate(0, 1, 0)
+	}
+
+	return sub.StartDate.Time
+}
+
+// IntroductoryPrice возвращает стоимость подписки за расчётный период в месяце month, если месяц приходится
+// на пробный период (0) или на акцию (sub.PromoPrice). Вне пробного периода и акции возвращает false,
+// и действует стоимость по истории изменения стоимости
+func IntroductoryPrice(sub model.Subscription, month time.Time) (int, bool) {
+	month = truncateMonth(month)
+	billingStart := truncateMonth(BillingStart(sub))
+
+	if month.Before(billingStart) && sub.TrialEndDate != nil && sub.TrialEndDate.Valid {
+		return 0, true
+	}
+
+	if sub.PromoMonths > 0 && !month.Before(billingStart) && month.Before(billingStart.AddDate(0, sub.PromoMonths, 0)) {
+		return sub.PromoPrice, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+// subscriptionsNoOverlapConstraint ограничение, запрещающее пересечение периодов записей о подписках одного пользователя
+// на один сервис. Добавляется необязательной миграцией из internal/db/optional_migrations
+const subscriptionsNoOverlapConstraint = "subscriptions_no_overlap"
+
+// errSubscriptionOverlapConflict ошибка записи о подписке, нарушающей ограничение subscriptionsNoOverlapConstraint
+var errSubscriptionOverlapConflict = errors.New("subscription conflict: period overlaps with another subscription of the same user and service")
+
+// subscriptionWriteError логирует ошибку записи о подписке и сообщает о конфликте с ограничением subscriptionsNoOverlapConstraint
+func subscriptionWriteError(err error, message string, description string) error {
+	var pqErr *pq.Error
+
+	if errors.As(err, &pqErr) && pqErr.Code == "23P01" && pqErr.Constraint == subscriptionsNoOverlapConstraint {
+		return errSubscriptionOverlapConflict
+	}
+
+	slog.Error(fmt.Errorf("%s: %w", message, err).Error())
+
+	return fmt.Errorf("%s: %w", description, err)
+}
+
+// ListOverlaps находит пары записей о подписках одного пользователя на один сервис с пересекающимися периодами.
+// Период фильтра ограничивает не записи, а месяцы пересечения. Пересечение ищется по индексу subscriptions_hybrid_index
+func (repo *SubscriptionRepo) ListOverlaps(filter SubscriptionFilter) ([]model.SubscriptionOverlap, error) {
+	args := []any{}
+
+	period := filter
+	filter.MaxStartDate, filter.MinEndDate = utils.Date{}, utils.Date{}
+
+	condition := repo.scoped(filter).condition(&args)
+
+	if period.MaxStartDate.Valid || period.MinEndDate.Valid {
+		condition += fmt.Sprintf(
+			"\n\t\t\tAND daterange(GREATEST(subscriptions.start_date, other.start_date), LEAST(subscriptions.end_date, other.end_date), '[]') && daterange(%s::DATE, %s::DATE, '[]')",
+			bind(&args, period.MaxStartDate),
+			bind(&args, period.MinEndDate),
+		)
+	}
+
+	query := `
+		SELECT
+			subscriptions.user_id,
+			subscriptions.service_name,
+			subscriptions.id,
+			other.id,
+			GREATEST(subscriptions.start_date, other.start_date),
+			LEAST(subscriptions.end_date, other.end_date)
+		FROM subscriptions
+			JOIN subscriptions AS other ON other.tenant_id = subscriptions.tenant_id
+				AND other.user_id = subscriptions.user_id
+				AND other.service_name = subscriptions.service_name
+				AND daterange(other.start_date, other.end_date, '[]') && daterange(subscriptions.start_date, subscriptions.end_date, '[]')
+				AND other.id > subscriptions.id
+				AND other.deleted_at IS NULL
+		WHERE ` + condition + `
+		ORDER BY subscriptions.user_id, subscriptions.service_name, subscriptions.id, other.id;
+	`
+
+	rows, err := db.Postgres.Query(query, args...)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("пересечения записей о подписках не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("subscription overlaps not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	overlaps := []model.SubscriptionOverlap{}
+
+	for rows.Next() {
+		var overlap model.SubscriptionOverlap
+
+		err = rows.Scan(
+			&overlap.UserId,
+			&overlap.ServiceName,
+			&overlap.SubscriptionId,
+			&overlap.OtherSubscriptionId,
+			&overlap.StartDate,
+			&overlap.EndDate,
+		)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("пересечение записей о подписках невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		overlaps = append(overlaps, overlap)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("пересечения записей о подписках невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение пересечений записей о подписках %s", period))
+
+	return overlaps, nil
+}
+
+// FindOverlapping находит записи о подписках того же пользователя на тот же сервис, период которых пересекается
+// с периодом записи entity. Сама запись entity в результат не входит
+func (repo *SubscriptionRepo) FindOverlapping(entity *model.Subscription) ([]model.Subscription, error) {
+	candidate := *entity
+	repo.assignTenant(&candidate)
+
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE subscriptions.tenant_id = $1
+			AND subscriptions.user_id = $2
+			AND subscriptions.service_name = $3
+			AND daterange(subscriptions.start_date, subscriptions.end_date, '[]') && daterange($4::DATE, $5::DATE, '[]')
+			AND subscriptions.id <> $6
+			AND subscriptions.deleted_at IS NULL
+		ORDER BY subscriptions.start_date, subscriptions.id;
+	`
+
+	rows, err := db.Postgres.Query(
+		query,
+		candidate.TenantId,
+		candidate.UserId,
+		candidate.ServiceName,
+		candidate.StartDate,
+		candidate.EndDate,
+		candidate.Id,
+	)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("пересекающиеся записи о подписках не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("subscription overlaps not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	subs := []model.Subscription{}
+
+	for rows.Next() {
+		var sub model.Subscription
+
+		err = scanSubscription(rows, &sub)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("запись о подписке невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("записи о подписке невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return subs, nil
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// subscriptionPausesNoOverlapConstraint ограничение, запрещающее пересечение пауз одной подписки
+const subscriptionPausesNoOverlapConstraint = "subscription_pauses_no_overlap"
+
+// errSubscriptionPauseConflict ошибка паузы, нарушающей ограничение subscriptionPausesNoOverlapConstraint
+var errSubscriptionPauseConflict = errors.New("subscription conflict: pause overlaps with another pause of the subscription")
+
+// Paused проверяет, что месяц month приходится на одну из пауз подписки
+func Paused(pauses []model.SubscriptionPause, month time.Time) bool {
+	month = truncateMonth(month)
+
+	for _, pause := range pauses {
+		if !month.Before(pause.StartDate.Time) && (pause.EndDate == nil || !pause.EndDate.Valid || !month.After(pause.EndDate.Time)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notPausedCondition возвращает условие, что подписка subscriptions не приостановлена в месяце month
+func notPausedCondition(month string) string {
+	return `NOT EXISTS (
+				SELECT 1
+				FROM subscription_pauses
+				WHERE subscription_pauses.subscription_id = subscriptions.id
+					AND daterange(subscription_pauses.start_date, subscription_pauses.end_date, '[]') @> ` + month + `
+			)`
+}
+
+func (repo *SubscriptionRepo) ListPauses(subscriptionId int) ([]model.SubscriptionPause, error) {
+	query := `
+		SELECT subscription_id, start_date, end_date
+		FROM subscription_pauses
+		WHERE subscription_id = $1
+		ORDER BY start_date;
+	`
+
+	rows, err := db.Postgres.Query(query, subscriptionId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("паузы подписки не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("subscription pauses not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	pauses := []model.SubscriptionPause{}
+
+	for rows.Next() {
+		var pause model.SubscriptionPause
+
+		err = rows.Scan(&pause.SubscriptionId, &pause.StartDate, &pause.EndDate)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("паузу подписки невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		pauses = append(pauses, pause)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("паузы подписки невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение пауз подписки. ИД: %d", subscriptionId))
+
+	return pauses, nil
+}
+
+// SavePause сохраняет паузу подписки и записывает действие action (pause или resume) в журнал изменений
+// и событие для вебхуков в той же транзакции. Пауза с тем же первым месяцем заменяется,
+// пауза, пересекающаяся с другой паузой подписки, отклоняется ограничением subscriptionPausesNoOverlapConstraint
+func (repo *SubscriptionRepo) SavePause(entity *model.SubscriptionPause, action string, actor string) error {
+	query := `
+		INSERT INTO subscription_pauses (subscription_id, start_date, end_date)
+		VALUES ($1, date_trunc('month', $2::DATE), date_trunc('month', $3::DATE))
+		ON CONFLICT (subscription_id, start_date) DO UPDATE SET end_date = EXCLUDED.end_date;
+	`
+
+	err := withTransaction(func(q queryer) error {
+		tenantId, err := repo.lockSubscription(q, entity.SubscriptionId)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = q.Exec(query, entity.SubscriptionId, entity.StartDate, entity.EndDate)
+
+		if err != nil {
+			var pqErr *pq.Error
+
+			if errors.As(err, &pqErr) && pqErr.Code == "23P01" && pqErr.Constraint == subscriptionPausesNoOverlapConstraint {
+				return errSubscriptionPauseConflict
+			}
+
+			slog.Error(fmt.Errorf("пауза подписки не сохранена: %w", err).Error())
+
+			return fmt.Errorf("failed to save subscription pause: %w", err)
+		}
+
+		return recordPauseChange(q, tenantId, entity, action, actor)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	slog.Info(fmt.Sprintf("Приостановка подписки. ИД: %d. С: %s. По: %s",
+		entity.SubscriptionId,
+		entity.StartDate,
+		entity.EndDate,
+	))
+
+	return nil
+}
+
+// DeletePause удаляет паузу подписки, начинающуюся в месяце entity.StartDate, и записывает возобновление подписки
+// в журнал изменений и событие для вебхуков в той же транзакции
+func (repo *SubscriptionRepo) DeletePause(entity *model.SubscriptionPause, actor string) error {
+	query := `
+		DELETE FROM subscription_pauses
+		WHERE subscription_id = $1 AND start_date = date_trunc('month', $2::DATE);
+	`
+
+	err := withTransaction(func(q queryer) error {
+		tenantId, err := repo.lockSubscription(q, entity.SubscriptionId)
+
+		if err != nil {
+			return err
+		}
+
+		result, err := q.Exec(query, entity.SubscriptionId, entity.StartDate)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("пауза подписки не удалена: %w", err).Error())
+
+			return fmt.Errorf("failed to delete subscription pause: %w", err)
+		}
+
+		if deleted, _ := result.RowsAffected(); deleted == 0 {
+			return fmt.Errorf("subscription pause not found")
+		}
+
+		return recordPauseChange(q, tenantId, entity, model.AuditActionResume, actor)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	slog.Info(fmt.Sprintf("Удаление паузы подписки. ИД: %d. С: %s", entity.SubscriptionId, entity.StartDate))
+
+	return nil
+}
+
+// lockSubscription блокирует запись о подписке до конца транзакции, чтобы паузы подписки изменялись по очереди,
+// и возвращает организацию записи
+func (repo *SubscriptionRepo) lockSubscription(q queryer, subscriptionId int) (string, error) {
+	query := `
+		SELECT tenant_id
+		FROM subscriptions
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = '' OR tenant_id = $2)
+		FOR UPDATE;
+	`
+
+	var tenantId string
+
+	err := q.QueryRow(query, subscriptionId, repo.TenantId).Scan(&tenantId)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("subscription not found: %d", subscriptionId)
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("запись о подписке не заблокирована: %w", err).Error())
+
+		return "", fmt.Errorf("failed to lock subscription: %w", err)
+	}
+
+	return tenantId, nil
+}
+
+// recordPauseChange записывает действие action над паузой подписки в журнал изменений и событие для вебхуков
+func recordPauseChange(q queryer, tenantId string, entity *model.SubscriptionPause, action string, actor string) error {
+	err := insertWebhookEvent(q, tenantId, model.WebhookEventTypes[action], entity)
+
+	if err != nil {
+		return err
+	}
+
+	return insertAudit(q, &model.SubscriptionAudit{
+		SubscriptionId: entity.SubscriptionId,
+		Action:         action,
+		Actor:          actor,
+		Pause:          entity,
+	})
+}
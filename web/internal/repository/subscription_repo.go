@@ -7,27 +7,57 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"subsaggregator/internal/db"
 	"subsaggregator/internal/model"
 	"subsaggregator/internal/utils"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 )
 
 type SubscriptionRepository interface {
 	FindById(id int) (*model.Subscription, error)
-	List(userId string, serviceName string, maxStartDate utils.Date, minEndDate utils.Date, offset int, limit int) ([]model.Subscription, error)
-	SumPrices(userId string, serviceName string, maxStartDate utils.Date, minEndDate utils.Date) (*int, error)
-	Create(entity *model.Subscription) error
-	Update(entity *model.Subscription) error
-	Delete(entity *model.Subscription) error
+	FindDeletedById(id int) (*model.Subscription, error)
+	List(filter SubscriptionFilter, options ListOptions) ([]model.Subscription, error)
+	ListEach(filter SubscriptionFilter, options ListOptions, fn func(sub *model.Subscription) error) error
+	Total(filter SubscriptionFilter) (int, error)
+	SumPrices(filter SubscriptionFilter, currency string, billingMode string) (*int, error)
+	SumPricesGrouped(filter SubscriptionFilter, currency string, billingMode string, groupBy []string) ([]model.PriceGroup, error)
+	SumPricesByMonth(filter SubscriptionFilter, currency string, billingMode string) ([]model.MonthlyPrice, error)
+	SumPricesByMonthEach(filter SubscriptionFilter, currency string, billingMode string, fn func(month utils.Date, servicePrice model.ServicePrice) error) error
+	ListPrices(subscriptionId int) ([]model.SubscriptionPrice, error)
+	ChangePrice(entity *model.SubscriptionPrice, actor string) error
+	SumSharedPrices(filter SubscriptionFilter, currency string, billingMode string) ([]model.Settlement, error)
+	ListShares(subscriptionId int) ([]model.SubscriptionShare, error)
+	SaveShares(entity *model.Subscription, shares []model.SubscriptionShare, actor string) error
+	ListPauses(subscriptionId int) ([]model.SubscriptionPause, error)
+	SavePause(entity *model.SubscriptionPause, action string, actor string) error
+	DeletePause(entity *model.SubscriptionPause, actor string) error
+	ListOverlaps(filter SubscriptionFilter) ([]model.SubscriptionOverlap, error)
+	FindOverlapping(entity *model.Subscription) ([]model.Subscription, error)
+	Create(entity *model.Subscription, actor string) error
+	Update(entity *model.Subscription, before *model.Subscription, price *model.SubscriptionPrice, actor string) error
+	Delete(entity *model.Subscription, actor string) error
+	Restore(entity *model.Subscription, actor string) error
+	ListAudit(subscriptionId int) ([]model.SubscriptionAudit, error)
+	Batch(operations []BatchOperation, mode string) ([]model.BatchResult, error)
+	ResolveService(name string) (*model.CatalogService, error)
 }
 
-type SubscriptionRepo struct{}
+// SubscriptionRepo хранилище записей о подписках. Запросы ограничены организацией TenantId,
+// а хранилище без организации работает с записями всех организаций и используется фоновыми задачами
+type SubscriptionRepo struct {
+	TenantId string
+}
+
+// subscriptionColumns перечисляет столбцы subscriptions в порядке полей model.Subscription
+const subscriptionColumns = "subscriptions.id, subscriptions.service_name, subscriptions.service_id, subscriptions.price, subscriptions.currency, subscriptions.billing_period, subscriptions.user_id, subscriptions.tenant_id, subscriptions.start_date, subscriptions.end_date, subscriptions.deleted_at, subscriptions.trial_end_date, COALESCE(subscriptions.promo_price, 0), COALESCE(subscriptions.promo_months, 0)"
 
 func (repo *SubscriptionRepo) FindById(id int) (*model.Subscription, error) {
-	subCache, err := getSubscriptionCache(id)
+	subCache, err := getSubscriptionCache(repo.TenantId, id)
 	if subCache != nil {
 		slog.Info(fmt.Sprintf("Получение кешированной записи о подписке. ИД: %d", id))
 
@@ -35,12 +65,12 @@ func (repo *SubscriptionRepo) FindById(id int) (*model.Subscription, error) {
 	}
 
 	query := `
-		SELECT *
+		SELECT ` + subscriptionColumns + `
 		FROM subscriptions
-		WHERE id = $1;
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = '' OR tenant_id = $2);
 	`
 
-	row, err := db.Postgres.Query(query, id)
+	row, err := db.Postgres.Query(query, id, repo.TenantId)
 	defer row.Close()
 
 	if !row.Next() {
@@ -51,7 +81,7 @@ func (repo *SubscriptionRepo) FindById(id int) (*model.Subscription, error) {
 
 	var sub model.Subscription
 
-	err = row.Scan(&sub.Id, &sub.ServiceName, &sub.Price, &sub.UserId, &sub.StartDate, &sub.EndDate)
+	err = scanSubscription(row, &sub)
 
 	if err != nil {
 		slog.Error(fmt.Errorf("запись о подписке невозможно прочитать: %w", err).Error())
@@ -66,115 +96,400 @@ func (repo *SubscriptionRepo) FindById(id int) (*model.Subscription, error) {
 	return &sub, nil
 }
 
-func (repo *SubscriptionRepo) List(
-	userId string,
-	serviceName string,
-	maxStartDate utils.Date,
-	minEndDate utils.Date,
-	offset int,
-	limit int,
-) ([]model.Subscription, error) {
+func (repo *SubscriptionRepo) FindDeletedById(id int) (*model.Subscription, error) {
 	query := `
-    	SELECT subscriptions.*
+		SELECT ` + subscriptionColumns + `
 		FROM subscriptions
-    	WHERE ($1::TEXT IS NULL OR subscriptions.user_id = $1) 
-    	  AND ($2::TEXT IS NULL OR subscriptions.service_name = $2)
-    	  AND (CASE 
-       		WHEN $3 <> '0001-01-01'::DATE AND $4 <> '0001-01-01'::DATE
-       		THEN daterange(start_date, end_date, '[]') && daterange($3, $4, '[]')
-       		ELSE (CASE 
-       				WHEN $3 <> '0001-01-01'::DATE THEN subscriptions.start_date <= $3
-       				ELSE TRUE
-     			END)
-    	  		AND (CASE 
-       				WHEN $4 <> '0001-01-01'::DATE THEN subscriptions.end_date <= $4
-       				ELSE TRUE
-     			END)
-     		END)
-    	OFFSET $5
-    	LIMIT $6;
+		WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = '' OR tenant_id = $2);
 	`
 
-	rows, err := db.Postgres.Query(query, getFilter(userId), getFilter(serviceName), maxStartDate, minEndDate, offset, limit)
-	defer rows.Close()
+	var sub model.Subscription
+
+	err := scanSubscription(db.Postgres.QueryRow(query, id, repo.TenantId), &sub)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		slog.Error(fmt.Errorf("удалённая запись о подписке не найдена: %w", err).Error())
+
+		return nil, fmt.Errorf("deleted subscription not found: %w", err)
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("запись о подписке невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение удалённой записи о подписке. ИД: %d", id))
+
+	return &sub, nil
+}
+
+func (repo *SubscriptionRepo) List(filter SubscriptionFilter, options ListOptions) ([]model.Subscription, error) {
+	subs := []model.Subscription{}
+
+	err := repo.ListEach(filter, options, func(sub *model.Subscription) error {
+		subs = append(subs, *sub)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Cursor != nil && options.Cursor.Backward {
+		slices.Reverse(subs)
+	}
+
+	slog.Info(fmt.Sprintf("Получение записей о подписках %s", filter))
+
+	return subs, nil
+}
+
+// ListEach передаёт записи о подписках в fn по мере чтения из базы данных, не собирая их в память.
+// Ошибка fn прекращает чтение и возвращается вызывающему.
+// При Limit = 0 выдаются все записи; при курсоре назад записи выдаются в обратном порядке
+func (repo *SubscriptionRepo) ListEach(filter SubscriptionFilter, options ListOptions, fn func(sub *model.Subscription) error) error {
+	sortColumn, ok := sortColumns[options.SortBy]
+
+	if !ok {
+		return fmt.Errorf("unknown sort field: %s", options.SortBy)
+	}
+
+	descending := options.Descending
+
+	if options.Cursor != nil && options.Cursor.Backward {
+		descending = !descending
+	}
+
+	direction, comparison := "ASC", ">"
+
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	args := []any{}
+	condition := repo.scoped(filter).condition(&args)
+
+	if options.Cursor != nil {
+		condition += fmt.Sprintf(
+			"\n\t\t\tAND (%s, subscriptions.id) %s (%s::%s, %s)",
+			sortColumn.column,
+			comparison,
+			bind(&args, options.Cursor.Value),
+			sortColumn.cast,
+			bind(&args, options.Cursor.Id),
+		)
+	}
+
+	limit := "ALL"
+
+	if options.Limit > 0 {
+		limit = bind(&args, options.Limit)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT `+subscriptionColumns+`
+		FROM subscriptions
+		WHERE %s
+		ORDER BY %s %s, subscriptions.id %s
+		OFFSET %s
+		LIMIT %s;
+	`, condition, sortColumn.column, direction, direction, bind(&args, options.Offset), limit)
+
+	rows, err := db.Postgres.Query(query, args...)
 
 	if err != nil {
 		slog.Error(fmt.Errorf("записи о подписках не найдены: %w", err).Error())
 
-		return nil, fmt.Errorf("subscriptions not found: %w", err)
+		return fmt.Errorf("subscriptions not found: %w", err)
 	}
 
-	var subs []model.Subscription
+	defer rows.Close()
 
 	for rows.Next() {
 		var sub model.Subscription
 
-		err = rows.Scan(&sub.Id, &sub.ServiceName, &sub.Price, &sub.UserId, &sub.StartDate, &sub.EndDate)
+		err = scanSubscription(rows, &sub)
 
 		if err != nil {
 			slog.Error(fmt.Errorf("запись о подписке невозможно прочитать: %w", err).Error())
 
-			return nil, fmt.Errorf("failing to read data from database: %w", err)
+			return fmt.Errorf("failing to read data from database: %w", err)
 		}
 
-		subs = append(subs, sub)
+		if err = fn(&sub); err != nil {
+			return err
+		}
 	}
 
 	if err := rows.Err(); err != nil {
 		slog.Error(fmt.Errorf("записи о подписке невозможно прочитать: %w", err).Error())
 
-		return nil, fmt.Errorf("failing to read data from database: %w", err)
+		return fmt.Errorf("failing to read data from database: %w", err)
 	}
 
-	slog.Info(fmt.Sprintf(
-		"Получение записей о подписках c %s до %s. ИД пользователя: %s. Название сервиса: %s",
-		minEndDate.Time.Format("01-2006"),
-		maxStartDate.Time.Format("01-2006"),
-		userId,
-		serviceName,
-	))
-
-	return subs, nil
+	return nil
 }
 
-func (repo *SubscriptionRepo) SumPrices(userId string, serviceName string, maxStartDate utils.Date, minEndDate utils.Date) (*int, error) {
+func (repo *SubscriptionRepo) Total(filter SubscriptionFilter) (int, error) {
+	args := []any{}
+
 	query := `
-    	WITH active_subscriptions AS (
+		SELECT COUNT(*)
+		FROM subscriptions
+		WHERE ` + repo.scoped(filter).condition(&args) + `;
+	`
+
+	var count int
+
+	err := db.Postgres.QueryRow(query, args...).Scan(&count)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("количество записей о подписках не получено: %w", err).Error())
+
+		return 0, fmt.Errorf("failed to count subscriptions: %w", err)
+	}
+
+	return count, nil
+}
+
+// sortColumns сопоставляет поля сортировки со столбцами subscriptions и их типами
+var sortColumns = map[string]struct {
+	column string
+	cast   string
+}{
+	model.SortById:          {column: "subscriptions.id", cast: "INTEGER"},
+	model.SortByStartDate:   {column: "subscriptions.start_date", cast: "DATE"},
+	model.SortByPrice:       {column: "subscriptions.price", cast: "INTEGER"},
+	model.SortByServiceName: {column: "subscriptions.service_name", cast: "TEXT"},
+}
+
+// groupByColumns сопоставляет поля группировки со столбцами attributed_subscriptions
+var groupByColumns = map[string]string{
+	model.GroupByTenantId:    "tenant_id",
+	model.GroupByUserId:      "user_id",
+	model.GroupByServiceName: "service_name",
+	model.GroupByMonth:       "month",
+	model.GroupByCategory:    "category",
+}
+
+// activeSubscriptionsQuery разворачивает отфильтрованные подписки по месяцам,
+// берёт стоимость, действующую в каждом месяце по истории изменения стоимости,
+// и пересчитывает стоимость в выбранную валюту по курсу, действующему в каждом месяце.
+// Месяцы пробного периода бесплатны, в месяцы акции действует стоимость по акции; расчётные периоды отсчитываются
+// от первого месяца после пробного периода (BillingStart).
+// Стоимость подписок с расчётным периодом длиннее месяца учитывается в месяце продления (model.BillingModeCharge)
+// или распределяется по месяцам периода (model.BillingModeAmortize).
+// Подписки без даты окончания разворачиваются до filter.ProjectUntil, месяцы пауз подписки не учитываются.
+// Категория берётся из сервиса каталога, подписки без сервиса каталога относятся к model.ServiceCategoryOther.
+// Стоимость каждого месяца распределяется между участниками подписки по долям, остаток относится на владельца;
+// attributed_subscriptions содержит стоимость, отнесённую на пользователя user_id по подписке владельца owner_id.
+// Фильтр по пользователям отбирает подписки, в которых пользователи владеют или участвуют, и их доли
+func activeSubscriptionsQuery(filter SubscriptionFilter, currency string, billingMode string, args *[]any) string {
+	userIds := filter.UserIds
+	filter.UserIds = nil
+
+	condition := filter.condition(args)
+	attributedCondition := "TRUE"
+
+	if len(userIds) > 0 {
+		usersParam := bind(args, pq.Array(userIds)) + "::TEXT[]"
+
+		condition += fmt.Sprintf(`
+			AND (subscriptions.user_id = ANY(%[1]s) OR EXISTS (
+				SELECT 1
+				FROM subscription_shares
+				WHERE subscription_shares.subscription_id = subscriptions.id AND subscription_shares.user_id = ANY(%[1]s)
+			))`, usersParam)
+
+		attributedCondition = "user_id = ANY(" + usersParam + ")"
+	}
+
+	currencyParam := bind(args, currency)
+	billingModeParam := bind(args, billingMode)
+	projectUntilParam := bind(args, filter.ProjectUntil)
+
+	return fmt.Sprintf(`
+    	WITH expanded_subscriptions AS (
     		SELECT
+        		id,
+        		tenant_id,
         		user_id,
         		service_name,
+        		COALESCE((SELECT services.category FROM services WHERE services.id = subscriptions.service_id), '%[5]s') AS category,
         		price,
-        		generate_series(date_trunc('month', start_date), date_trunc('month', end_date), interval '1 month') AS month
-    		FROM subscriptions
-    		WHERE ($1::TEXT IS NULL OR user_id = $1)
-      			AND ($2::TEXT IS NULL OR service_name = $2)
-      			AND (CASE 
-            			WHEN $3 <> '0001-01-01'::DATE AND $4 <> '0001-01-01'::DATE
-            			THEN daterange(start_date, end_date, '[]') && daterange($3, $4, '[]')
-            		ELSE (CASE 
-                   			WHEN $3 <> '0001-01-01'::DATE THEN start_date <= $3
-                   			ELSE TRUE
-                 		END)
-                 		AND (CASE 
-                    		WHEN $4 <> '0001-01-01'::DATE THEN end_date >= $4
-                       		ELSE TRUE
-                     	END)	
-          			END)
+        		currency,
+        		billing_period,
+        		COALESCE((date_trunc('month', trial_end_date) + interval '1 month')::DATE, start_date) AS billing_start,
+        		trial_end_date,
+        		promo_price,
+        		promo_months,
+        		months.month::DATE AS month
+    		FROM subscriptions,
+    			generate_series(date_trunc('month', start_date), date_trunc('month', COALESCE(end_date, %[4]s::DATE)), interval '1 month') AS months(month)
+    		WHERE %[1]s
+    			AND %[7]s
+		),
+		priced_subscriptions AS (
+    		SELECT
+        		expanded_subscriptions.id,
+        		expanded_subscriptions.tenant_id,
+        		expanded_subscriptions.user_id,
+        		expanded_subscriptions.service_name,
+        		expanded_subscriptions.category,
+        		CASE
+        			WHEN expanded_subscriptions.trial_end_date IS NOT NULL
+        				AND expanded_subscriptions.month < expanded_subscriptions.billing_start THEN 0
+        			WHEN expanded_subscriptions.promo_months IS NOT NULL
+        				AND expanded_subscriptions.month < date_trunc('month', expanded_subscriptions.billing_start)
+        					+ expanded_subscriptions.promo_months * interval '1 month' THEN expanded_subscriptions.promo_price
+        			ELSE COALESCE(prices.price, expanded_subscriptions.price)
+        		END AS price,
+        		expanded_subscriptions.currency,
+        		expanded_subscriptions.billing_period,
+        		expanded_subscriptions.billing_start,
+        		expanded_subscriptions.month
+    		FROM expanded_subscriptions
+    			LEFT JOIN LATERAL (
+    				SELECT subscription_prices.price
+    				FROM subscription_prices
+    				WHERE subscription_prices.subscription_id = expanded_subscriptions.id
+    					AND subscription_prices.effective_from <= expanded_subscriptions.month
+    				ORDER BY subscription_prices.effective_from DESC
+    				LIMIT 1
+    			) AS prices ON TRUE
+		),
+		billed_subscriptions AS (
+    		SELECT
+        		id,
+        		tenant_id,
+        		user_id,
+        		service_name,
+        		category,
+        		currency,
+        		price AS list_price,
+        		CASE billing_period
+        			WHEN 'week' THEN (CASE
+        				WHEN %[3]s = 'amortize' THEN price * 52 / 12.0
+        				ELSE price * (
+        					CEIL(GREATEST((month + interval '1 month')::DATE - billing_start, 0) / 7.0)
+        					- CEIL(GREATEST(month - billing_start, 0) / 7.0)
+        				)
+        			END)
+        			WHEN 'quarter' THEN (CASE
+        				WHEN %[3]s = 'amortize' THEN price / 3.0
+        				WHEN months_since_start %% 3 = 0 THEN price
+        				ELSE 0
+        			END)
+        			WHEN 'year' THEN (CASE
+        				WHEN %[3]s = 'amortize' THEN price / 12.0
+        				WHEN months_since_start %% 12 = 0 THEN price
+        				ELSE 0
+        			END)
+        			ELSE price
+        		END AS price,
+        		month
+    		FROM priced_subscriptions,
+    			LATERAL (
+    				SELECT ((date_part('year', month) - date_part('year', billing_start)) * 12
+    					+ date_part('month', month) - date_part('month', billing_start))::INTEGER AS months_since_start
+    			) AS periods
+		),
+		active_subscriptions AS (
+    		SELECT
+        		id,
+        		tenant_id,
+        		user_id,
+        		service_name,
+        		category,
+        		list_price,
+        		CASE
+        			WHEN currency = %[2]s THEN price
+        			ELSE price * exchange_rate(currency, month) / exchange_rate(%[2]s, month)
+        		END AS price,
+        		month
+    		FROM billed_subscriptions
 		),
 		unique_subscriptions AS (
-    		SELECT DISTINCT ON (user_id, service_name, month)
+    		SELECT DISTINCT ON (tenant_id, user_id, service_name, month)
+        		id,
+        		tenant_id,
         		user_id,
         		service_name,
+        		category,
+        		list_price,
         		price,
         		month
     		FROM active_subscriptions
-    		ORDER BY user_id, service_name, month ASC
+    		ORDER BY tenant_id, user_id, service_name, month ASC
+		),
+		shared_subscriptions AS (
+    		SELECT
+        		unique_subscriptions.id,
+        		subscription_shares.user_id,
+        		unique_subscriptions.month,
+        		CASE
+        			WHEN subscription_shares.percent IS NOT NULL THEN subscription_shares.percent / 100.0
+        			ELSE LEAST(subscription_shares.amount::NUMERIC / NULLIF(unique_subscriptions.list_price, 0), 1)
+        		END AS share
+    		FROM unique_subscriptions
+    			JOIN subscription_shares ON subscription_shares.subscription_id = unique_subscriptions.id
+		),
+		normalized_shares AS (
+    		SELECT
+        		id,
+        		user_id,
+        		month,
+        		share / GREATEST(SUM(share) OVER (PARTITION BY id, month), 1) AS share
+    		FROM shared_subscriptions
+		),
+		attributed_subscriptions AS (
+    		SELECT id, tenant_id, user_id, owner_id, service_name, category, price, month
+    		FROM (
+    			SELECT
+        			unique_subscriptions.id,
+        			unique_subscriptions.tenant_id,
+        			normalized_shares.user_id,
+        			unique_subscriptions.user_id AS owner_id,
+        			unique_subscriptions.service_name,
+        			unique_subscriptions.category,
+        			unique_subscriptions.price * normalized_shares.share AS price,
+        			unique_subscriptions.month
+    			FROM unique_subscriptions
+    				JOIN normalized_shares ON normalized_shares.id = unique_subscriptions.id
+    					AND normalized_shares.month = unique_subscriptions.month
+    			UNION ALL
+    			SELECT
+        			unique_subscriptions.id,
+        			unique_subscriptions.tenant_id,
+        			unique_subscriptions.user_id,
+        			unique_subscriptions.user_id AS owner_id,
+        			unique_subscriptions.service_name,
+        			unique_subscriptions.category,
+        			unique_subscriptions.price * (1 - COALESCE(shares.share, 0)) AS price,
+        			unique_subscriptions.month
+    			FROM unique_subscriptions
+    				LEFT JOIN (
+    					SELECT id, month, SUM(share) AS share
+    					FROM normalized_shares
+    					GROUP BY id, month
+    				) AS shares ON shares.id = unique_subscriptions.id AND shares.month = unique_subscriptions.month
+    		) AS attributed
+    		WHERE %[6]s
 		)
-		SELECT SUM(price) AS total_price
-		FROM unique_subscriptions;
+`, condition, currencyParam, billingModeParam, projectUntilParam, model.ServiceCategoryOther, attributedCondition, notPausedCondition("months.month::DATE"))
+}
+
+func (repo *SubscriptionRepo) SumPrices(filter SubscriptionFilter, currency string, billingMode string) (*int, error) {
+	args := []any{}
+
+	query := activeSubscriptionsQuery(repo.scoped(filter), currency, billingMode, &args) + `
+		SELECT COALESCE(ROUND(SUM(price)), 0)::INTEGER AS total_price
+		FROM attributed_subscriptions;
 	`
 
-	row := db.Postgres.QueryRow(query, getFilter(userId), getFilter(serviceName), maxStartDate, minEndDate)
+	row := db.Postgres.QueryRow(query, args...)
 
 	var sumPrice int
 
@@ -186,43 +501,479 @@ func (repo *SubscriptionRepo) SumPrices(userId string, serviceName string, maxSt
 		return nil, fmt.Errorf("failed to sum subscriptions prices: %w", err)
 	}
 
-	slog.Info(fmt.Sprintf(
-		"Получение суммарной стоимости подписок c %s до %s. ИД пользователя: %s. Название сервиса: %s",
-		minEndDate.Time.Format("01-2006"),
-		maxStartDate.Time.Format("01-2006"),
-		userId,
-		serviceName,
-	))
+	slog.Info(fmt.Sprintf("Получение суммарной стоимости подписок %s", filter))
 
 	return &sumPrice, nil
 }
 
-func (repo *SubscriptionRepo) Create(entity *model.Subscription) error {
+func (repo *SubscriptionRepo) SumPricesGrouped(filter SubscriptionFilter, currency string, billingMode string, groupBy []string) ([]model.PriceGroup, error) {
+	columns := []string{}
+
+	for _, field := range groupBy {
+		column, ok := groupByColumns[field]
+
+		if !ok {
+			return nil, fmt.Errorf("unknown group_by field: %s", field)
+		}
+
+		columns = append(columns, column)
+	}
+
+	groupColumns := strings.Join(columns, ", ")
+
+	args := []any{}
+
+	query := activeSubscriptionsQuery(repo.scoped(filter), currency, billingMode, &args) + fmt.Sprintf(`
+		SELECT %s, ROUND(SUM(price))::INTEGER AS total_price, COUNT(DISTINCT id) AS subscriptions_count
+		FROM attributed_subscriptions
+		GROUP BY %s
+		ORDER BY %s;
+	`, groupColumns, groupColumns, groupColumns)
+
+	rows, err := db.Postgres.Query(query, args...)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("сгруппированная стоимость подписок не получена: %w", err).Error())
+
+		return nil, fmt.Errorf("failed to sum subscriptions prices by groups: %w", err)
+	}
+
+	defer rows.Close()
+
+	groups := []model.PriceGroup{}
+
+	for rows.Next() {
+		var group model.PriceGroup
+
+		dest := []any{}
+
+		for _, field := range groupBy {
+			switch field {
+			case model.GroupByTenantId:
+				dest = append(dest, &group.TenantId)
+			case model.GroupByUserId:
+				dest = append(dest, &group.UserId)
+			case model.GroupByServiceName:
+				dest = append(dest, &group.ServiceName)
+			case model.GroupByCategory:
+				dest = append(dest, &group.Category)
+			case model.GroupByMonth:
+				group.Month = &utils.Date{}
+				dest = append(dest, group.Month)
+			}
+		}
+
+		dest = append(dest, &group.Total, &group.Count)
+
+		err = rows.Scan(dest...)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("сгруппированную стоимость подписок невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("сгруппированную стоимость подписок невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение сгруппированной по %s стоимости подписок %s", strings.Join(groupBy, ", "), filter))
+
+	return groups, nil
+}
+
+func (repo *SubscriptionRepo) SumPricesByMonth(filter SubscriptionFilter, currency string, billingMode string) ([]model.MonthlyPrice, error) {
+	monthlyPrices := []model.MonthlyPrice{}
+
+	err := repo.SumPricesByMonthEach(filter, currency, billingMode, func(month utils.Date, servicePrice model.ServicePrice) error {
+		last := len(monthlyPrices) - 1
+
+		if last < 0 || !monthlyPrices[last].Month.Time.Equal(month.Time) {
+			monthlyPrices = append(monthlyPrices, model.MonthlyPrice{
+				Month:    &month,
+				Services: []model.ServicePrice{},
+			})
+			last++
+		}
+
+		monthlyPrices[last].Total += servicePrice.Total
+		monthlyPrices[last].Services = append(monthlyPrices[last].Services, servicePrice)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info(fmt.Sprintf("Получение помесячной стоимости подписок %s", filter))
+
+	return monthlyPrices, nil
+}
+
+// SumPricesByMonthEach передаёт в fn стоимость подписок каждого сервиса за каждый месяц по мере чтения из базы данных,
+// упорядочивая по месяцу и названию сервиса. Ошибка fn прекращает чтение и возвращается вызывающему
+func (repo *SubscriptionRepo) SumPricesByMonthEach(filter SubscriptionFilter, currency string, billingMode string, fn func(month utils.Date, servicePrice model.ServicePrice) error) error {
+	args := []any{}
+
+	query := activeSubscriptionsQuery(repo.scoped(filter), currency, billingMode, &args)
+
+	query += `
+		SELECT month, service_name, ROUND(SUM(price))::INTEGER AS total_price
+		FROM attributed_subscriptions
+		WHERE ` + periodMonthsCondition(filter, &args) + `
+		GROUP BY month, service_name
+		ORDER BY month, service_name;
+	`
+
+	rows, err := db.Postgres.Query(query, args...)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("помесячная стоимость подписок не получена: %w", err).Error())
+
+		return fmt.Errorf("failed to sum subscriptions prices by month: %w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var month utils.Date
+		var servicePrice model.ServicePrice
+
+		err = rows.Scan(&month, &servicePrice.ServiceName, &servicePrice.Total)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("помесячную стоимость подписок невозможно прочитать: %w", err).Error())
+
+			return fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		if err = fn(month, servicePrice); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("помесячную стоимость подписок невозможно прочитать: %w", err).Error())
+
+		return fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return nil
+}
+
+// SumSharedPrices возвращает стоимость, отнесённую на участников подписок по их долям, за месяцы периода фильтра
+// по парам участник — владелец подписки. Встречные долги не взаимозачитываются
+func (repo *SubscriptionRepo) SumSharedPrices(filter SubscriptionFilter, currency string, billingMode string) ([]model.Settlement, error) {
+	args := []any{}
+
+	query := activeSubscriptionsQuery(repo.scoped(filter), currency, billingMode, &args)
+
+	query += `
+		SELECT user_id, owner_id, ROUND(SUM(price))::INTEGER AS total_price
+		FROM attributed_subscriptions
+		WHERE user_id <> owner_id AND ` + periodMonthsCondition(filter, &args) + `
+		GROUP BY user_id, owner_id
+		ORDER BY user_id, owner_id;
+	`
+
+	rows, err := db.Postgres.Query(query, args...)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("стоимость долей в подписках не получена: %w", err).Error())
+
+		return nil, fmt.Errorf("failed to sum subscription shares: %w", err)
+	}
+
+	defer rows.Close()
+
+	settlements := []model.Settlement{}
+
+	for rows.Next() {
+		var settlement model.Settlement
+
+		err = rows.Scan(&settlement.FromUserId, &settlement.ToUserId, &settlement.Amount)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("стоимость долей в подписках невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		settlements = append(settlements, settlement)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("стоимость долей в подписках невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение стоимости долей в подписках %s", filter))
+
+	return settlements, nil
+}
+
+// periodMonthsCondition ограничивает месяцы attributed_subscriptions периодом фильтра от MaxStartDate до MinEndDate
+func periodMonthsCondition(filter SubscriptionFilter, args *[]any) string {
+	monthConditions := []string{"TRUE"}
+
+	if filter.MaxStartDate.Valid {
+		monthConditions = append(monthConditions, "month >= date_trunc('month', "+bind(args, filter.MaxStartDate)+"::DATE)")
+	}
+
+	if filter.MinEndDate.Valid {
+		monthConditions = append(monthConditions, "month <= date_trunc('month', "+bind(args, filter.MinEndDate)+"::DATE)")
+	}
+
+	return strings.Join(monthConditions, " AND ")
+}
+
+func (repo *SubscriptionRepo) ListShares(subscriptionId int) ([]model.SubscriptionShare, error) {
+	query := `
+		SELECT subscription_id, user_id, COALESCE(percent, 0), COALESCE(amount, 0)
+		FROM subscription_shares
+		WHERE subscription_id = $1
+		ORDER BY user_id;
+	`
+
+	rows, err := db.Postgres.Query(query, subscriptionId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("доли в подписке не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("subscription shares not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	shares := []model.SubscriptionShare{}
+
+	for rows.Next() {
+		var share model.SubscriptionShare
+
+		err = rows.Scan(&share.SubscriptionId, &share.UserId, &share.Percent, &share.Amount)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("долю в подписке невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		shares = append(shares, share)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("доли в подписке невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение долей в подписке. ИД: %d", subscriptionId))
+
+	return shares, nil
+}
+
+// SaveShares заменяет доли участников подписки, записывает их в журнал изменений и событие subscription.updated
+// для вебхуков в той же транзакции
+func (repo *SubscriptionRepo) SaveShares(entity *model.Subscription, shares []model.SubscriptionShare, actor string) error {
+	query := `
+		INSERT INTO subscription_shares (subscription_id, user_id, percent, amount)
+		VALUES ($1, $2, NULLIF($3::NUMERIC, 0), NULLIF($4::INTEGER, 0));
+	`
+
+	subscriptionId := entity.Id
+
+	err := withTransaction(func(q queryer) error {
+		tenantId, err := repo.lockSubscription(q, subscriptionId)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = q.Exec(`DELETE FROM subscription_shares WHERE subscription_id = $1;`, subscriptionId)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("доли в подписке не удалены: %w", err).Error())
+
+			return fmt.Errorf("failed to save subscription shares: %w", err)
+		}
+
+		for _, share := range shares {
+			_, err = q.Exec(query, subscriptionId, share.UserId, share.Percent, share.Amount)
+
+			if err != nil {
+				slog.Error(fmt.Errorf("доля в подписке не сохранена: %w", err).Error())
+
+				return fmt.Errorf("failed to save subscription shares: %w", err)
+			}
+		}
+
+		err = insertWebhookEvent(q, tenantId, model.WebhookEventTypes[model.AuditActionChangeShares], entity)
+
+		if err != nil {
+			return err
+		}
+
+		return insertAudit(q, &model.SubscriptionAudit{
+			SubscriptionId: subscriptionId,
+			Action:         model.AuditActionChangeShares,
+			Actor:          actor,
+			Shares:         shares,
+		})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	slog.Info(fmt.Sprintf("Изменение долей в подписке. ИД: %d. Участников: %d", subscriptionId, len(shares)))
+
+	return nil
+}
+
+func (repo *SubscriptionRepo) ListPrices(subscriptionId int) ([]model.SubscriptionPrice, error) {
+	query := `
+		SELECT subscription_id, effective_from, price
+		FROM subscription_prices
+		WHERE subscription_id = $1
+		ORDER BY effective_from;
+	`
+
+	rows, err := db.Postgres.Query(query, subscriptionId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("история стоимости подписки не найдена: %w", err).Error())
+
+		return nil, fmt.Errorf("subscription prices not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	prices := []model.SubscriptionPrice{}
+
+	for rows.Next() {
+		var price model.SubscriptionPrice
+
+		err = rows.Scan(&price.SubscriptionId, &price.EffectiveFrom, &price.Price)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("стоимость подписки невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		prices = append(prices, price)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("историю стоимости подписки невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение истории стоимости подписки. ИД: %d", subscriptionId))
+
+	return prices, nil
+}
+
+// ChangePrice изменяет стоимость подписки с месяца entity.EffectiveFrom и записывает изменение в журнал изменений
+// в той же транзакции
+func (repo *SubscriptionRepo) ChangePrice(entity *model.SubscriptionPrice, actor string) error {
+	return withTransaction(func(q queryer) error {
+		if err := upsertPrice(q, entity); err != nil {
+			return err
+		}
+
+		return insertAudit(q, &model.SubscriptionAudit{
+			SubscriptionId: entity.SubscriptionId,
+			Action:         model.AuditActionChangePrice,
+			Actor:          actor,
+			Price:          entity,
+		})
+	})
+}
+
+// upsertPrice записывает стоимость подписки, действующую с месяца entity.EffectiveFrom, в базе данных или транзакции
+func upsertPrice(q queryer, entity *model.SubscriptionPrice) error {
 	query := `
-		INSERT INTO subscriptions (service_name, price, user_id, start_date, end_date) 
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO subscription_prices (subscription_id, effective_from, price)
+		VALUES ($1, date_trunc('month', $2::DATE), $3)
+		ON CONFLICT (subscription_id, effective_from) DO UPDATE SET price = EXCLUDED.price;
 	`
 
-	_, err := db.Postgres.Query(
+	_, err := q.Exec(query, entity.SubscriptionId, entity.EffectiveFrom, entity.Price)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("стоимость подписки не изменена: %w", err).Error())
+
+		return fmt.Errorf("failed to change subscription price: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Изменение стоимости подписки. ИД: %d. Стоимость: %d. Действует с: %s",
+		entity.SubscriptionId,
+		entity.Price,
+		entity.EffectiveFrom,
+	))
+
+	return nil
+}
+
+// Create создаёт запись о подписке, записывает её в журнал изменений и сохраняет событие для вебхуков в одной транзакции
+func (repo *SubscriptionRepo) Create(entity *model.Subscription, actor string) error {
+	repo.assignTenant(entity)
+
+	err := withTransaction(func(q queryer) error {
+		return applyBatchOperation(q, repo.TenantId, BatchOperation{Action: model.AuditActionCreate, Subscription: entity, Actor: actor})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	setSubscriptionCache(entity)
+
+	return nil
+}
+
+// insertSubscription создаёт запись о подписке в базе данных или транзакции без обновления кэша
+func insertSubscription(q queryer, entity *model.Subscription) error {
+	query := `
+		INSERT INTO subscriptions (service_name, price, currency, billing_period, user_id, start_date, end_date, tenant_id, service_id, trial_end_date, promo_price, promo_months) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CASE WHEN $12::INTEGER > 0 THEN $11::INTEGER END, NULLIF($12::INTEGER, 0))
+		RETURNING id
+	`
+
+	err := q.QueryRow(
 		query,
 		entity.ServiceName,
 		entity.Price,
+		entity.Currency,
+		entity.BillingPeriod,
 		entity.UserId,
 		entity.StartDate,
 		entity.EndDate,
-	)
+		entity.TenantId,
+		entity.ServiceId,
+		entity.TrialEndDate,
+		entity.PromoPrice,
+		entity.PromoMonths,
+	).Scan(&entity.Id)
 
 	if err != nil {
-		slog.Error(fmt.Errorf("запись о подписке не создана: %w", err).Error())
-
-		return fmt.Errorf("failed to create subscription: %w", err)
+		return subscriptionWriteError(err, "запись о подписке не создана", "failed to create subscription")
 	}
 
-	setSubscriptionCache(entity)
-
-	slog.Info(fmt.Sprintf("Создание записи о подписке. Название сервиса: %s. Стоимость: %d. ИД пользователя: %s. Дата начала: %s. Дата окончания: %s",
+	slog.Info(fmt.Sprintf("Создание записи о подписке. Название сервиса: %s. Стоимость: %d %s за %s. ИД пользователя: %s. Дата начала: %s. Дата окончания: %s",
 		entity.ServiceName,
 		entity.Price,
+		entity.Currency,
+		entity.BillingPeriod,
 		entity.UserId,
 		entity.StartDate,
 		entity.EndDate,
@@ -231,34 +982,69 @@ func (repo *SubscriptionRepo) Create(entity *model.Subscription) error {
 	return nil
 }
 
-func (repo *SubscriptionRepo) Update(entity *model.Subscription) error {
+// Update изменяет запись о подписке, записывает изменение в журнал изменений и сохраняет событие для вебхуков в одной транзакции.
+// Изменение стоимости price, если оно есть, записывается в историю стоимости в той же транзакции
+func (repo *SubscriptionRepo) Update(entity *model.Subscription, before *model.Subscription, price *model.SubscriptionPrice, actor string) error {
+	err := withTransaction(func(q queryer) error {
+		return applyBatchOperation(q, repo.TenantId, BatchOperation{
+			Action:       model.AuditActionUpdate,
+			Subscription: entity,
+			Before:       before,
+			Price:        price,
+			Actor:        actor,
+		})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	setSubscriptionCache(entity)
+
+	return nil
+}
+
+// updateSubscription изменяет неудалённую запись о подписке организации tenantId в базе данных или транзакции без обновления кэша.
+// Пустой tenantId не ограничивает организацию
+func updateSubscription(q queryer, tenantId string, entity *model.Subscription) error {
 	query := `
 		UPDATE subscriptions 
-		SET service_name = $2, price = $3, user_id = $4, start_date = $5, end_date = $6
-		WHERE id = $1
+		SET service_name = $2, price = $3, currency = $4, billing_period = $5, user_id = $6, start_date = $7, end_date = $8, service_id = $10,
+			trial_end_date = $11, promo_price = CASE WHEN $13::INTEGER > 0 THEN $12::INTEGER END, promo_months = NULLIF($13::INTEGER, 0)
+		WHERE id = $1 AND deleted_at IS NULL AND ($9 = '' OR tenant_id = $9)
+		RETURNING tenant_id;
 	`
 
-	_, err := db.Postgres.Query(
+	err := q.QueryRow(
 		query,
 		entity.Id,
 		entity.ServiceName,
 		entity.Price,
+		entity.Currency,
+		entity.BillingPeriod,
 		entity.UserId,
 		entity.StartDate,
 		entity.EndDate,
-	)
+		tenantId,
+		entity.ServiceId,
+		entity.TrialEndDate,
+		entity.PromoPrice,
+		entity.PromoMonths,
+	).Scan(&entity.TenantId)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("subscription not found")
+	}
 
 	if err != nil {
-		slog.Error(fmt.Errorf("запись о подписке не изменена: %w", err).Error())
-
-		return fmt.Errorf("failed to update subscription: %w", err)
+		return subscriptionWriteError(err, "запись о подписке не изменена", "failed to update subscription")
 	}
 
-	setSubscriptionCache(entity)
-
-	slog.Info(fmt.Sprintf("Изменение записи о подписке. Название сервиса: %s. Стоимость: %d. ИД пользователя: %s. Дата начала: %s. Дата окончания: %s",
+	slog.Info(fmt.Sprintf("Изменение записи о подписке. Название сервиса: %s. Стоимость: %d %s за %s. ИД пользователя: %s. Дата начала: %s. Дата окончания: %s",
 		entity.ServiceName,
 		entity.Price,
+		entity.Currency,
+		entity.BillingPeriod,
 		entity.UserId,
 		entity.StartDate,
 		entity.EndDate,
@@ -267,14 +1053,39 @@ func (repo *SubscriptionRepo) Update(entity *model.Subscription) error {
 	return nil
 }
 
-func (repo *SubscriptionRepo) Delete(entity *model.Subscription) error {
+// Delete помечает запись о подписке удалённой, записывает удаление в журнал изменений и сохраняет событие для вебхуков
+// в одной транзакции
+func (repo *SubscriptionRepo) Delete(entity *model.Subscription, actor string) error {
+	before := *entity
+
+	err := withTransaction(func(q queryer) error {
+		return applyBatchOperation(q, repo.TenantId, BatchOperation{Action: model.AuditActionDelete, Subscription: entity, Before: &before, Actor: actor})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	deleteSubscriptionCache(entity)
+
+	return nil
+}
+
+// deleteSubscription помечает запись о подписке организации tenantId удалённой в базе данных или транзакции без обновления кэша.
+// Пустой tenantId не ограничивает организацию
+func deleteSubscription(q queryer, tenantId string, entity *model.Subscription) error {
 	query := `
-		DELETE 
-		FROM subscriptions 
-		WHERE id = $1;
+		UPDATE subscriptions 
+		SET deleted_at = now()
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = '' OR tenant_id = $2)
+		RETURNING deleted_at, tenant_id;
 	`
 
-	_, err := db.Postgres.Query(query, entity.Id)
+	err := q.QueryRow(query, entity.Id, tenantId).Scan(&entity.DeletedAt, &entity.TenantId)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("subscription not found")
+	}
 
 	if err != nil {
 		slog.Error(fmt.Errorf("запись о подписке не удалена: %w", err).Error())
@@ -282,11 +1093,11 @@ func (repo *SubscriptionRepo) Delete(entity *model.Subscription) error {
 		return fmt.Errorf("failed to delete subscription: %w", err)
 	}
 
-	deleteSubscriptionCache(entity)
-
-	slog.Info(fmt.Sprintf("Удаление записи о подписке. Название сервиса: %s. Стоимость: %d. ИД пользователя: %s. Дата начада: %s. Дата окончания: %s",
+	slog.Info(fmt.Sprintf("Удаление записи о подписке. Название сервиса: %s. Стоимость: %d %s за %s. ИД пользователя: %s. Дата начада: %s. Дата окончания: %s",
 		entity.ServiceName,
 		entity.Price,
+		entity.Currency,
+		entity.BillingPeriod,
 		entity.UserId,
 		entity.StartDate,
 		entity.EndDate,
@@ -295,6 +1106,252 @@ func (repo *SubscriptionRepo) Delete(entity *model.Subscription) error {
 	return nil
 }
 
+// Restore восстанавливает удалённую запись о подписке, записывает восстановление в журнал изменений и сохраняет событие
+// для вебхуков в одной транзакции
+func (repo *SubscriptionRepo) Restore(entity *model.Subscription, actor string) error {
+	before := *entity
+
+	err := withTransaction(func(q queryer) error {
+		return applyBatchOperation(q, repo.TenantId, BatchOperation{Action: model.AuditActionRestore, Subscription: entity, Before: &before, Actor: actor})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	setSubscriptionCache(entity)
+
+	slog.Info(fmt.Sprintf("Восстановление записи о подписке. ИД: %d. Название сервиса: %s. ИД пользователя: %s",
+		entity.Id,
+		entity.ServiceName,
+		entity.UserId,
+	))
+
+	return nil
+}
+
+// restoreSubscription снимает отметку об удалении с записи о подписке организации tenantId в базе данных или транзакции
+// без обновления кэша. Пустой tenantId не ограничивает организацию
+func restoreSubscription(q queryer, tenantId string, entity *model.Subscription) error {
+	query := `
+		UPDATE subscriptions 
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL AND ($2 = '' OR tenant_id = $2)
+		RETURNING tenant_id;
+	`
+
+	err := q.QueryRow(query, entity.Id, tenantId).Scan(&entity.TenantId)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("deleted subscription not found")
+	}
+
+	if err != nil {
+		return subscriptionWriteError(err, "запись о подписке не восстановлена", "failed to restore subscription")
+	}
+
+	entity.DeletedAt = nil
+
+	return nil
+}
+
+func (repo *SubscriptionRepo) ListAudit(subscriptionId int) ([]model.SubscriptionAudit, error) {
+	query := `
+		SELECT subscription_audit.id, subscription_id, action, actor, before, after, subscription_audit.price, pause, shares, created_at
+		FROM subscription_audit
+			JOIN subscriptions ON subscriptions.id = subscription_audit.subscription_id
+		WHERE subscription_id = $1 AND ($2 = '' OR subscriptions.tenant_id = $2)
+		ORDER BY created_at, subscription_audit.id;
+	`
+
+	rows, err := db.Postgres.Query(query, subscriptionId, repo.TenantId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("журнал изменений подписки не найден: %w", err).Error())
+
+		return nil, fmt.Errorf("subscription history not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	entries := []model.SubscriptionAudit{}
+
+	for rows.Next() {
+		var entry model.SubscriptionAudit
+		var before, after, price, pause, shares []byte
+
+		err = rows.Scan(&entry.Id, &entry.SubscriptionId, &entry.Action, &entry.Actor, &before, &after, &price, &pause, &shares, &entry.CreatedAt)
+
+		if err == nil && before != nil {
+			err = json.Unmarshal(before, &entry.Before)
+		}
+
+		if err == nil && after != nil {
+			err = json.Unmarshal(after, &entry.After)
+		}
+
+		if err == nil && price != nil {
+			err = json.Unmarshal(price, &entry.Price)
+		}
+
+		if err == nil && pause != nil {
+			err = json.Unmarshal(pause, &entry.Pause)
+		}
+
+		if err == nil && shares != nil {
+			err = json.Unmarshal(shares, &entry.Shares)
+		}
+
+		if err != nil {
+			slog.Error(fmt.Errorf("запись журнала изменений подписки невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("журнал изменений подписки невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение журнала изменений подписки. ИД: %d", subscriptionId))
+
+	return entries, nil
+}
+
+// insertAudit создаёт запись журнала изменений подписки в базе данных или транзакции
+func insertAudit(q queryer, entry *model.SubscriptionAudit) error {
+	query := `
+		INSERT INTO subscription_audit (subscription_id, action, actor, before, after, price, pause, shares)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at;
+	`
+
+	before, err := auditSnapshot(entry.Before)
+
+	if err != nil {
+		return fmt.Errorf("failed to record subscription history: %w", err)
+	}
+
+	after, err := auditSnapshot(entry.After)
+
+	if err != nil {
+		return fmt.Errorf("failed to record subscription history: %w", err)
+	}
+
+	price, err := auditSnapshot(entry.Price)
+
+	if err != nil {
+		return fmt.Errorf("failed to record subscription history: %w", err)
+	}
+
+	pause, err := auditSnapshot(entry.Pause)
+
+	if err != nil {
+		return fmt.Errorf("failed to record subscription history: %w", err)
+	}
+
+	var shares any
+
+	if entry.Shares != nil {
+		shares, err = auditSnapshot(&entry.Shares)
+
+		if err != nil {
+			return fmt.Errorf("failed to record subscription history: %w", err)
+		}
+	}
+
+	err = q.QueryRow(query, entry.SubscriptionId, entry.Action, entry.Actor, before, after, price, pause, shares).Scan(&entry.Id, &entry.CreatedAt)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("запись журнала изменений подписки не создана: %w", err).Error())
+
+		return fmt.Errorf("failed to record subscription history: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Запись в журнал изменений подписки. ИД: %d. Действие: %s. Автор: %s",
+		entry.SubscriptionId,
+		entry.Action,
+		entry.Actor,
+	))
+
+	return nil
+}
+
+// auditSnapshot сериализует запись о подписке или изменение стоимости для журнала изменений
+func auditSnapshot[T any](value *T) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	snapshot, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return string(snapshot), nil
+}
+
+// queryer общие методы *sql.DB и *sql.Tx, позволяющие выполнять запросы как напрямую, так и в транзакции
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// withTransaction выполняет fn в транзакции и фиксирует её, если fn не вернула ошибку
+func withTransaction(fn func(q queryer) error) error {
+	tx, err := db.Postgres.Begin()
+
+	if err != nil {
+		slog.Error(fmt.Errorf("транзакция не начата: %w", err).Error())
+
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		slog.Error(fmt.Errorf("транзакция не завершена: %w", err).Error())
+
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSubscription читает строку со столбцами subscriptionColumns
+func scanSubscription(row rowScanner, sub *model.Subscription) error {
+	return row.Scan(
+		&sub.Id,
+		&sub.ServiceName,
+		&sub.ServiceId,
+		&sub.Price,
+		&sub.Currency,
+		&sub.BillingPeriod,
+		&sub.UserId,
+		&sub.TenantId,
+		&sub.StartDate,
+		&sub.EndDate,
+		&sub.DeletedAt,
+		&sub.TrialEndDate,
+		&sub.PromoPrice,
+		&sub.PromoMonths,
+	)
+}
+
 func getFilter(value string) sql.NullString {
 	var filter sql.NullString
 	if value == "" {
@@ -312,16 +1369,22 @@ func setSubscriptionCache(sub *model.Subscription) {
 
 	db.Redis.Set(
 		context.Background(),
-		fmt.Sprintf("sub:%d", sub.Id),
+		subscriptionCacheKey(sub.TenantId, sub.Id),
 		jsonBytes,
 		3*time.Minute,
 	)
 }
 
-func getSubscriptionCache(subId int) (*model.Subscription, error) {
+// getSubscriptionCache возвращает кешированную запись о подписке организации tenantId.
+// Хранилище без организации кешем не пользуется, поскольку не знает ключа
+func getSubscriptionCache(tenantId string, subId int) (*model.Subscription, error) {
+	if tenantId == "" {
+		return nil, redis.Nil
+	}
+
 	result, err := db.Redis.Get(
 		context.Background(),
-		fmt.Sprintf("sub:%d", subId),
+		subscriptionCacheKey(tenantId, subId),
 	).Result()
 
 	if errors.Is(err, redis.Nil) {
@@ -342,6 +1405,29 @@ func getSubscriptionCache(subId int) (*model.Subscription, error) {
 func deleteSubscriptionCache(sub *model.Subscription) {
 	db.Redis.Del(
 		context.Background(),
-		fmt.Sprintf("sub:%d", sub.Id),
+		subscriptionCacheKey(sub.TenantId, sub.Id),
 	)
 }
+
+// subscriptionCacheKey возвращает ключ кеша записи о подписке. Ключ включает организацию,
+// чтобы запись не была получена из кеша в запросе другой организации
+func subscriptionCacheKey(tenantId string, subId int) string {
+	return fmt.Sprintf("sub:%s:%d", tenantId, subId)
+}
+
+// scoped ограничивает фильтр организацией хранилища
+func (repo *SubscriptionRepo) scoped(filter SubscriptionFilter) SubscriptionFilter {
+	if repo.TenantId != "" {
+		filter.TenantIds = []string{repo.TenantId}
+	}
+
+	return filter
+}
+
+// assignTenant относит новую запись о подписке к организации хранилища.
+// Хранилище без организации сохраняет организацию записи, а если она не указана, относит запись к организации по умолчанию
+func (repo *SubscriptionRepo) assignTenant(entity *model.Subscription) {
+	if repo.TenantId != "" || entity.TenantId == "" {
+		entity.TenantId = tenantOrDefault(repo.TenantId)
+	}
+}
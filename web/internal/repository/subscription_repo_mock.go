@@ -1,117 +1,1166 @@
 package repository
 
 import (
+	"cmp"
+	"database/sql"
+	"encoding/json"
 	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"subsaggregator/internal/model"
 	"subsaggregator/internal/utils"
+	"time"
 )
 
 type SubscriptionRepoMock struct {
 	Subscriptions map[int]*model.Subscription
+	Prices        map[int][]model.SubscriptionPrice
+	Audit         map[int][]model.SubscriptionAudit
+	Outbox        map[string]*model.WebhookEvent
 	Count         int
+	TenantId      string
+
+	// Каталог сервисов для ResolveService и категорий подписок
+	Services map[int]*model.CatalogService
+
+	// Доли участников подписок по ИД записи о подписке
+	Shares map[int][]model.SubscriptionShare
+
+	// Паузы подписок по ИД записи о подписке
+	Pauses map[int][]model.SubscriptionPause
+
+	// Курсы валют к рублю для пересчёта сумм в выбранную валюту
+	ExchangeRates []model.ExchangeRate
+
+	// Эмулирует ограничение subscriptions_no_overlap при создании, изменении и восстановлении записей
+	NoOverlap bool
 }
 
 func (repo SubscriptionRepoMock) FindById(id int) (*model.Subscription, error) {
-	if repo.Subscriptions[id] != nil {
+	if repo.ownsSubscription(id) && repo.Subscriptions[id].DeletedAt == nil {
 		return repo.Subscriptions[id], nil
 	}
 
 	return nil, nil
 }
 
-func (repo SubscriptionRepoMock) List(
-	userId string,
-	serviceName string,
-	maxStartDate utils.Date,
-	minEndDate utils.Date,
-	offset int,
-	limit int,
-) ([]model.Subscription, error) {
-	subs := []model.Subscription{}
-	count := 0
+func (repo SubscriptionRepoMock) FindDeletedById(id int) (*model.Subscription, error) {
+	if repo.ownsSubscription(id) && repo.Subscriptions[id].DeletedAt != nil {
+		return repo.Subscriptions[id], nil
+	}
 
-	for _, sub := range repo.Subscriptions {
-		if maxStartDate.NullTime.Time.After(sub.EndDate.Time) || minEndDate.NullTime.Time.Before(sub.StartDate.Time) {
-			continue
+	return nil, nil
+}
+
+// ownsSubscription проверяет, что запись о подписке существует и относится к организации мока
+func (repo SubscriptionRepoMock) ownsSubscription(id int) bool {
+	sub := repo.Subscriptions[id]
+
+	return sub != nil && (repo.TenantId == "" || sub.TenantId == repo.TenantId)
+}
+
+// scoped ограничивает фильтр организацией мока так же, как SubscriptionRepo.scoped
+func (repo SubscriptionRepoMock) scoped(filter SubscriptionFilter) SubscriptionFilter {
+	if repo.TenantId != "" {
+		filter.TenantIds = []string{repo.TenantId}
+	}
+
+	return filter
+}
+
+func (repo SubscriptionRepoMock) List(filter SubscriptionFilter, options ListOptions) ([]model.Subscription, error) {
+	sortValue, ok := mockSortValues[options.SortBy]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field: %s", options.SortBy)
+	}
+
+	descending := options.Descending
+
+	if options.Cursor != nil && options.Cursor.Backward {
+		descending = !descending
+	}
+
+	// compare сравнивает записи по полю сортировки и ИД в порядке выдачи
+	compare := func(sub *model.Subscription, value string, id int) int {
+		result := mockCompareSortValues(options.SortBy, sortValue(sub), value)
+
+		if result == 0 {
+			result = cmp.Compare(sub.Id, id)
+		}
+
+		if descending {
+			return -result
 		}
 
-		if userId != "" && sub.UserId != userId {
+		return result
+	}
+
+	filtered := []*model.Subscription{}
+	filter = repo.scoped(filter)
+
+	for _, sub := range repo.Subscriptions {
+		if !repo.filterMatches(sub, filter) {
 			continue
 		}
 
-		if serviceName != "" && sub.ServiceName != serviceName {
+		if options.Cursor != nil && compare(sub, options.Cursor.Value, options.Cursor.Id) <= 0 {
 			continue
 		}
 
-		if count < offset {
-			count++
+		filtered = append(filtered, sub)
+	}
+
+	slices.SortFunc(filtered, func(a, b *model.Subscription) int {
+		return compare(a, sortValue(b), b.Id)
+	})
+
+	subs := []model.Subscription{}
+
+	for i, sub := range filtered {
+		if i < options.Offset {
 			continue
 		}
 
 		subs = append(subs, *sub)
 
-		if len(subs) == limit {
-			return subs, nil
+		if len(subs) == options.Limit {
+			break
 		}
 	}
 
+	if options.Cursor != nil && options.Cursor.Backward {
+		slices.Reverse(subs)
+	}
+
 	return subs, nil
 }
 
-func (repo SubscriptionRepoMock) SumPrices(userId string, serviceName string, maxStartDate utils.Date, minEndDate utils.Date) (*int, error) {
-	var sumPrice int
+func (repo SubscriptionRepoMock) ListEach(filter SubscriptionFilter, options ListOptions, fn func(sub *model.Subscription) error) error {
+	if options.Limit == 0 {
+		options.Limit = len(repo.Subscriptions)
+	}
 
-	uniquePrices := make(map[string]bool)
+	subs, err := repo.List(filter, options)
+
+	if err != nil {
+		return err
+	}
+
+	if options.Cursor != nil && options.Cursor.Backward {
+		slices.Reverse(subs)
+	}
+
+	for i := range subs {
+		if err := fn(&subs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo SubscriptionRepoMock) Total(filter SubscriptionFilter) (int, error) {
+	count := 0
+	filter = repo.scoped(filter)
 
 	for _, sub := range repo.Subscriptions {
-		if maxStartDate.NullTime.Time.After(sub.EndDate.Time) ||
-			minEndDate.NullTime.Time.Before(sub.StartDate.Time) {
-			continue
+		if repo.filterMatches(sub, filter) {
+			count++
 		}
+	}
 
-		if userId != "" && sub.UserId != userId {
-			continue
+	return count, nil
+}
+
+func (repo SubscriptionRepoMock) SumPrices(
+	filter SubscriptionFilter,
+	currency string,
+	billingMode string,
+) (*int, error) {
+	var sumPrice float64
+
+	subMonths, err := repo.expandMonths(filter, currency, billingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subMonth := range subMonths {
+		sumPrice += subMonth.price
+	}
+
+	result := int(math.Round(sumPrice))
+
+	return &result, nil
+}
+
+func (repo SubscriptionRepoMock) SumPricesGrouped(
+	filter SubscriptionFilter,
+	currency string,
+	billingMode string,
+	groupBy []string,
+) ([]model.PriceGroup, error) {
+	groups := []model.PriceGroup{}
+	totals := []float64{}
+
+	groupIndexes := make(map[string]int)
+	groupSubs := make(map[string]map[int]bool)
+
+	subMonths, err := repo.expandMonths(filter, currency, billingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subMonth := range subMonths {
+		sub := subMonth.sub
+
+		group := model.PriceGroup{}
+		groupKey := ""
+
+		for _, field := range groupBy {
+			switch field {
+			case model.GroupByTenantId:
+				group.TenantId = sub.TenantId
+				groupKey += ":" + sub.TenantId
+			case model.GroupByUserId:
+				group.UserId = subMonth.userId
+				groupKey += ":" + subMonth.userId
+			case model.GroupByServiceName:
+				group.ServiceName = sub.ServiceName
+				groupKey += ":" + sub.ServiceName
+			case model.GroupByCategory:
+				group.Category = repo.category(sub)
+				groupKey += ":" + group.Category
+			case model.GroupByMonth:
+				group.Month = &utils.Date{NullTime: sql.NullTime{Time: subMonth.month, Valid: true}}
+				groupKey += ":" + subMonth.month.Format("01-2006")
+			default:
+				return nil, fmt.Errorf("unknown group_by field: %s", field)
+			}
+		}
+
+		index, exists := groupIndexes[groupKey]
+
+		if !exists {
+			index = len(groups)
+			groupIndexes[groupKey] = index
+			groupSubs[groupKey] = make(map[int]bool)
+			groups = append(groups, group)
+			totals = append(totals, 0)
 		}
 
-		if serviceName != "" && sub.ServiceName != serviceName {
+		totals[index] += subMonth.price
+
+		if !groupSubs[groupKey][sub.Id] {
+			groupSubs[groupKey][sub.Id] = true
+			groups[index].Count++
+		}
+	}
+
+	for i := range groups {
+		groups[i].Total = int(math.Round(totals[i]))
+	}
+
+	return groups, nil
+}
+
+func (repo SubscriptionRepoMock) SumPricesByMonth(
+	filter SubscriptionFilter,
+	currency string,
+	billingMode string,
+) ([]model.MonthlyPrice, error) {
+	monthlyPrices := []model.MonthlyPrice{}
+
+	servicePrices := make(map[time.Time]map[string]float64)
+
+	subMonths, err := repo.expandMonths(filter, currency, billingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subMonth := range subMonths {
+		if (filter.MaxStartDate.Valid && subMonth.month.Before(truncateMonth(filter.MaxStartDate.Time))) ||
+			(filter.MinEndDate.Valid && subMonth.month.After(filter.MinEndDate.Time)) {
 			continue
 		}
 
-		for currentMonth := sub.StartDate.NullTime.Time; !currentMonth.After(sub.EndDate.NullTime.Time); currentMonth = currentMonth.AddDate(0, 1, 0) {
-			key := fmt.Sprintf("%s:%s:%s", sub.UserId, sub.ServiceName, currentMonth.Format("01-2006"))
+		if servicePrices[subMonth.month] == nil {
+			servicePrices[subMonth.month] = make(map[string]float64)
+		}
+
+		servicePrices[subMonth.month][subMonth.sub.ServiceName] += subMonth.price
+	}
+
+	months := []time.Time{}
+
+	for month := range servicePrices {
+		months = append(months, month)
+	}
+
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+
+	for _, month := range months {
+		monthlyPrice := model.MonthlyPrice{
+			Month:    &utils.Date{NullTime: sql.NullTime{Time: month, Valid: true}},
+			Services: []model.ServicePrice{},
+		}
+
+		names := []string{}
+
+		for name := range servicePrices[month] {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			total := int(math.Round(servicePrices[month][name]))
+
+			monthlyPrice.Total += total
+			monthlyPrice.Services = append(monthlyPrice.Services, model.ServicePrice{
+				ServiceName: name,
+				Total:       total,
+			})
+		}
+
+		monthlyPrices = append(monthlyPrices, monthlyPrice)
+	}
+
+	return monthlyPrices, nil
+}
+
+func (repo SubscriptionRepoMock) SumPricesByMonthEach(
+	filter SubscriptionFilter,
+	currency string,
+	billingMode string,
+	fn func(month utils.Date, servicePrice model.ServicePrice) error,
+) error {
+	monthlyPrices, err := repo.SumPricesByMonth(filter, currency, billingMode)
 
-			if _, exists := uniquePrices[key]; !exists {
-				sumPrice += sub.Price
-				uniquePrices[key] = true
+	if err != nil {
+		return err
+	}
+
+	for _, monthlyPrice := range monthlyPrices {
+		for _, servicePrice := range monthlyPrice.Services {
+			if err := fn(*monthlyPrice.Month, servicePrice); err != nil {
+				return err
 			}
 		}
 	}
 
-	return &sumPrice, nil
+	return nil
+}
+
+func (repo SubscriptionRepoMock) SumSharedPrices(
+	filter SubscriptionFilter,
+	currency string,
+	billingMode string,
+) ([]model.Settlement, error) {
+	totals := make(map[[2]string]float64)
+
+	subMonths, err := repo.expandMonths(filter, currency, billingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subMonth := range subMonths {
+		if subMonth.userId == subMonth.sub.UserId ||
+			(filter.MaxStartDate.Valid && subMonth.month.Before(truncateMonth(filter.MaxStartDate.Time))) ||
+			(filter.MinEndDate.Valid && subMonth.month.After(filter.MinEndDate.Time)) {
+			continue
+		}
+
+		totals[[2]string{subMonth.userId, subMonth.sub.UserId}] += subMonth.price
+	}
+
+	settlements := []model.Settlement{}
+
+	for pair, total := range totals {
+		settlements = append(settlements, model.Settlement{FromUserId: pair[0], ToUserId: pair[1], Amount: int(math.Round(total))})
+	}
+
+	slices.SortFunc(settlements, func(a, b model.Settlement) int {
+		return cmp.Or(cmp.Compare(a.FromUserId, b.FromUserId), cmp.Compare(a.ToUserId, b.ToUserId))
+	})
+
+	return settlements, nil
+}
+
+func (repo SubscriptionRepoMock) ListShares(subscriptionId int) ([]model.SubscriptionShare, error) {
+	shares := append([]model.SubscriptionShare{}, repo.Shares[subscriptionId]...)
+
+	slices.SortFunc(shares, func(a, b model.SubscriptionShare) int { return cmp.Compare(a.UserId, b.UserId) })
+
+	return shares, nil
+}
+
+func (repo SubscriptionRepoMock) SaveShares(entity *model.Subscription, shares []model.SubscriptionShare, actor string) error {
+	repo.Shares[entity.Id] = append([]model.SubscriptionShare{}, shares...)
+
+	repo.recordWebhookEvent(model.AuditActionChangeShares, entity)
+	repo.recordAudit(&model.SubscriptionAudit{
+		SubscriptionId: entity.Id,
+		Action:         model.AuditActionChangeShares,
+		Actor:          actor,
+		Shares:         append([]model.SubscriptionShare{}, shares...),
+	})
+
+	return nil
+}
+
+func (repo SubscriptionRepoMock) ListPauses(subscriptionId int) ([]model.SubscriptionPause, error) {
+	pauses := append([]model.SubscriptionPause{}, repo.Pauses[subscriptionId]...)
+
+	slices.SortFunc(pauses, func(a, b model.SubscriptionPause) int { return a.StartDate.Time.Compare(b.StartDate.Time) })
+
+	return pauses, nil
+}
+
+func (repo SubscriptionRepoMock) SavePause(entity *model.SubscriptionPause, action string, actor string) error {
+	pauses := repo.Pauses[entity.SubscriptionId]
+	index := slices.IndexFunc(pauses, func(pause model.SubscriptionPause) bool { return pause.StartDate.Time.Equal(entity.StartDate.Time) })
+
+	if index >= 0 {
+		pauses[index].EndDate = entity.EndDate
+	} else {
+		repo.Pauses[entity.SubscriptionId] = append(pauses, *entity)
+	}
+
+	repo.recordPauseChange(entity, action, actor)
+
+	return nil
+}
+
+func (repo SubscriptionRepoMock) DeletePause(entity *model.SubscriptionPause, actor string) error {
+	pauses := repo.Pauses[entity.SubscriptionId]
+	index := slices.IndexFunc(pauses, func(pause model.SubscriptionPause) bool { return pause.StartDate.Time.Equal(entity.StartDate.Time) })
+
+	if index < 0 {
+		return fmt.Errorf("subscription pause not found")
+	}
+
+	repo.Pauses[entity.SubscriptionId] = slices.Delete(pauses, index, index+1)
+
+	repo.recordPauseChange(entity, model.AuditActionResume, actor)
+
+	return nil
+}
+
+// recordPauseChange записывает действие над паузой подписки в журнал изменений и событие для вебхуков
+func (repo SubscriptionRepoMock) recordPauseChange(entity *model.SubscriptionPause, action string, actor string) {
+	pause := *entity
+
+	repo.recordWebhookEvent(action, &pause)
+	repo.recordAudit(&model.SubscriptionAudit{
+		SubscriptionId: pause.SubscriptionId,
+		Action:         action,
+		Actor:          actor,
+		Pause:          &pause,
+	})
+}
+
+func (repo SubscriptionRepoMock) ListPrices(subscriptionId int) ([]model.SubscriptionPrice, error) {
+	prices := append([]model.SubscriptionPrice{}, repo.Prices[subscriptionId]...)
+
+	sort.Slice(prices, func(i, j int) bool { return prices[i].EffectiveFrom.Time.Before(prices[j].EffectiveFrom.Time) })
+
+	return prices, nil
+}
+
+func (repo SubscriptionRepoMock) ChangePrice(entity *model.SubscriptionPrice, actor string) error {
+	repo.upsertPrice(entity)
+
+	repo.recordAudit(&model.SubscriptionAudit{
+		SubscriptionId: entity.SubscriptionId,
+		Action:         model.AuditActionChangePrice,
+		Actor:          actor,
+		Price:          entity,
+	})
+
+	return nil
 }
 
-func (repo SubscriptionRepoMock) Create(entity *model.Subscription) error {
+// upsertPrice записывает стоимость подписки, действующую с месяца entity.EffectiveFrom
+func (repo SubscriptionRepoMock) upsertPrice(entity *model.SubscriptionPrice) {
+	prices := repo.Prices[entity.SubscriptionId]
+
+	for i, price := range prices {
+		if price.EffectiveFrom.Time.Equal(entity.EffectiveFrom.Time) {
+			prices[i].Price = entity.Price
+
+			return
+		}
+	}
+
+	repo.Prices[entity.SubscriptionId] = append(prices, *entity)
+}
+
+func (repo SubscriptionRepoMock) Create(entity *model.Subscription, actor string) error {
+	if err := repo.checkNoOverlap(entity); err != nil {
+		return err
+	}
+
 	repo.Count++
 
+	for id := range repo.Subscriptions {
+		repo.Count = max(repo.Count, id+1)
+	}
+
 	entity.Id = repo.Count
 
+	if repo.TenantId != "" || entity.TenantId == "" {
+		entity.TenantId = tenantOrDefault(repo.TenantId)
+	}
+
 	repo.Subscriptions[entity.Id] = entity
 
+	repo.recordWebhookEvent(model.AuditActionCreate, entity)
+	repo.recordAudit(&model.SubscriptionAudit{SubscriptionId: entity.Id, Action: model.AuditActionCreate, Actor: actor, After: snapshot(entity)})
+
 	return nil
 }
 
-func (repo SubscriptionRepoMock) Update(entity *model.Subscription) error {
+func (repo SubscriptionRepoMock) Update(entity *model.Subscription, before *model.Subscription, price *model.SubscriptionPrice, actor string) error {
+	if err := repo.checkNoOverlap(entity); err != nil {
+		return err
+	}
+
+	if price != nil {
+		repo.upsertPrice(price)
+	}
+
 	repo.Subscriptions[entity.Id].ServiceName = entity.ServiceName
+	repo.Subscriptions[entity.Id].ServiceId = entity.ServiceId
 	repo.Subscriptions[entity.Id].Price = entity.Price
+	repo.Subscriptions[entity.Id].Currency = entity.Currency
+	repo.Subscriptions[entity.Id].BillingPeriod = entity.BillingPeriod
 	repo.Subscriptions[entity.Id].UserId = entity.UserId
 	repo.Subscriptions[entity.Id].StartDate = entity.StartDate
 	repo.Subscriptions[entity.Id].EndDate = entity.EndDate
+	repo.Subscriptions[entity.Id].TrialEndDate = entity.TrialEndDate
+	repo.Subscriptions[entity.Id].PromoPrice = entity.PromoPrice
+	repo.Subscriptions[entity.Id].PromoMonths = entity.PromoMonths
+
+	repo.recordWebhookEvent(model.AuditActionUpdate, entity)
+	repo.recordAudit(&model.SubscriptionAudit{SubscriptionId: entity.Id, Action: model.AuditActionUpdate, Actor: actor, Before: before, After: snapshot(entity)})
+
+	return nil
+}
+
+func (repo SubscriptionRepoMock) Delete(entity *model.Subscription, actor string) error {
+	before := snapshot(repo.Subscriptions[entity.Id])
+	deletedAt := time.Now()
+
+	repo.Subscriptions[entity.Id].DeletedAt = &deletedAt
+	entity.DeletedAt = &deletedAt
+
+	repo.recordWebhookEvent(model.AuditActionDelete, repo.Subscriptions[entity.Id])
+	repo.recordAudit(&model.SubscriptionAudit{SubscriptionId: entity.Id, Action: model.AuditActionDelete, Actor: actor, Before: before})
 
 	return nil
 }
 
-func (repo SubscriptionRepoMock) Delete(entity *model.Subscription) error {
-	delete(repo.Subscriptions, entity.Id)
+func (repo SubscriptionRepoMock) Restore(entity *model.Subscription, actor string) error {
+	if err := repo.checkNoOverlap(repo.Subscriptions[entity.Id]); err != nil {
+		return err
+	}
+
+	before := snapshot(repo.Subscriptions[entity.Id])
+
+	repo.Subscriptions[entity.Id].DeletedAt = nil
+	entity.DeletedAt = nil
+
+	repo.recordWebhookEvent(model.AuditActionRestore, repo.Subscriptions[entity.Id])
+	repo.recordAudit(&model.SubscriptionAudit{SubscriptionId: entity.Id, Action: model.AuditActionRestore, Actor: actor, Before: before, After: snapshot(entity)})
+
+	return nil
+}
+
+// snapshot возвращает копию записи о подписке для журнала изменений
+func snapshot(sub *model.Subscription) *model.Subscription {
+	result := *sub
+
+	return &result
+}
+
+func (repo SubscriptionRepoMock) ResolveService(name string) (*model.CatalogService, error) {
+	key := ServiceAliasKey(name)
+
+	for _, service := range repo.Services {
+		for _, alias := range append([]string{service.Name}, service.Aliases...) {
+			if ServiceAliasKey(alias) == key {
+				result := *service
+
+				return &result, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// category возвращает категорию сервиса каталога, с которым связана запись о подписке
+func (repo SubscriptionRepoMock) category(sub *model.Subscription) string {
+	if sub.ServiceId != nil && repo.Services[*sub.ServiceId] != nil {
+		return repo.Services[*sub.ServiceId].Category
+	}
+
+	return model.ServiceCategoryOther
+}
+
+// recordWebhookEvent сохраняет событие для вебхуков с данными data, если мок создан с Outbox
+func (repo SubscriptionRepoMock) recordWebhookEvent(action string, data any) {
+	if repo.Outbox == nil {
+		return
+	}
+
+	payload, _ := json.Marshal(data)
+	id := fmt.Sprintf("event-%08d", len(repo.Outbox)+1)
+
+	repo.Outbox[id] = &model.WebhookEvent{
+		Id:        id,
+		Type:      model.WebhookEventTypes[action],
+		CreatedAt: time.Now(),
+		Data:      payload,
+	}
+}
+
+func (repo SubscriptionRepoMock) ListAudit(subscriptionId int) ([]model.SubscriptionAudit, error) {
+	return append([]model.SubscriptionAudit{}, repo.Audit[subscriptionId]...), nil
+}
+
+// recordAudit записывает изменение подписки в журнал изменений, если мок создан с Audit
+func (repo SubscriptionRepoMock) recordAudit(entry *model.SubscriptionAudit) {
+	if repo.Audit == nil {
+		return
+	}
+
+	entry.CreatedAt = time.Now()
+	entry.Id = len(repo.Audit[entry.SubscriptionId]) + 1
+
+	repo.Audit[entry.SubscriptionId] = append(repo.Audit[entry.SubscriptionId], *entry)
+}
+
+func (repo SubscriptionRepoMock) Batch(operations []BatchOperation, mode string) ([]model.BatchResult, error) {
+	results := make([]model.BatchResult, len(operations))
+
+	for i, operation := range operations {
+		results[i] = model.BatchResult{Index: operation.Index, Action: operation.Action}
+	}
+
+	// Мок не умеет откатывать изменения, поэтому в режиме model.BatchModeAllOrNothing операции проверяются заранее
+	if mode != model.BatchModeBestEffort {
+		for i, operation := range operations {
+			if err := repo.checkBatchOperation(operation); err != nil {
+				for j := range results {
+					results[j].Status = model.BatchStatusRolledBack
+				}
+
+				results[i].Status = model.BatchStatusFailed
+				results[i].Error = err.Error()
+
+				return results, nil
+			}
+		}
+	}
+
+	for i, operation := range operations {
+		if err := repo.checkBatchOperation(operation); err != nil {
+			results[i].Status = model.BatchStatusFailed
+			results[i].Error = err.Error()
+
+			continue
+		}
+
+		switch operation.Action {
+		case model.AuditActionCreate:
+			repo.Create(operation.Subscription, operation.Actor)
+		case model.AuditActionUpdate:
+			repo.Update(operation.Subscription, operation.Before, operation.Price, operation.Actor)
+		case model.AuditActionDelete:
+			repo.Delete(operation.Subscription, operation.Actor)
+		case model.AuditActionRestore:
+			repo.Restore(operation.Subscription, operation.Actor)
+		}
+
+		results[i].Status = model.BatchStatusOk
+		results[i].Subscription = operation.Subscription
+	}
+
+	return results, nil
+}
+
+// checkBatchOperation проверяет, что операцию пакета можно выполнить
+func (repo SubscriptionRepoMock) checkBatchOperation(operation BatchOperation) error {
+	switch operation.Action {
+	case model.AuditActionCreate:
+		return nil
+	case model.AuditActionUpdate, model.AuditActionDelete:
+		sub, ok := repo.Subscriptions[operation.Subscription.Id]
+
+		if !ok || sub.DeletedAt != nil {
+			return fmt.Errorf("subscription not found")
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown batch action: %s", operation.Action)
+	}
+}
+
+func (repo SubscriptionRepoMock) sortedIds() []int {
+	ids := []int{}
+
+	for id := range repo.Subscriptions {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+func truncateMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// subscriptionMonth месяц действия подписки и стоимость подписки в этом месяце, отнесённая на пользователя userId
+type subscriptionMonth struct {
+	sub    *model.Subscription
+	userId string
+	month  time.Time
+	price  float64
+}
+
+// expandMonths разворачивает отфильтрованные подписки по месяцам без повторов пользователя и сервиса в одном месяце
+// и распределяет стоимость каждого месяца между владельцем и участниками так же, как activeSubscriptionsQuery.
+// Стоимость пересчитывается в валюту currency по курсу, действующему в месяце
+func (repo SubscriptionRepoMock) expandMonths(filter SubscriptionFilter, currency string, billingMode string) ([]subscriptionMonth, error) {
+	subMonths := []subscriptionMonth{}
+
+	uniquePrices := make(map[string]bool)
+	filter = repo.scoped(filter)
+
+	userIds := filter.UserIds
+	filter.UserIds = nil
+
+	// attributed проверяет, что стоимость отнесена на одного из пользователей фильтра
+	attributed := func(userId string) bool {
+		return len(userIds) == 0 || slices.Contains(userIds, userId)
+	}
+
+	for _, id := range repo.sortedIds() {
+		sub := repo.Subscriptions[id]
+
+		if !repo.filterMatches(sub, filter) {
+			continue
+		}
+
+		participates := attributed(sub.UserId)
+
+		for _, share := range repo.Shares[sub.Id] {
+			participates = participates || attributed(share.UserId)
+		}
+
+		if !participates {
+			continue
+		}
+
+		endDate := filter.ProjectUntil
+
+		if sub.EndDate != nil && sub.EndDate.Valid {
+			endDate = *sub.EndDate
+		}
+
+		if !endDate.Valid {
+			continue
+		}
+
+		for currentMonth := sub.StartDate.NullTime.Time; !currentMonth.After(endDate.Time); currentMonth = currentMonth.AddDate(0, 1, 0) {
+			if Paused(repo.Pauses[sub.Id], currentMonth) {
+				continue
+			}
+
+			key := fmt.Sprintf("%s:%s:%s:%s", sub.TenantId, sub.UserId, sub.ServiceName, currentMonth.Format("01-2006"))
+
+			if _, exists := uniquePrices[key]; exists {
+				continue
+			}
+
+			uniquePrices[key] = true
+
+			month := truncateMonth(currentMonth)
+			listPrice := repo.priceAt(sub, month)
+			price := billedPrice(sub, listPrice, month, billingMode)
+
+			if sub.Currency != currency {
+				rate, err := repo.exchangeRate(sub.Currency, month)
+
+				if err != nil {
+					return nil, err
+				}
+
+				targetRate, err := repo.exchangeRate(currency, month)
+
+				if err != nil {
+					return nil, err
+				}
+
+				price = price * rate / targetRate
+			}
+
+			fractions := repo.shareFractions(sub, listPrice)
+			users := []string{sub.UserId}
+
+			for _, share := range repo.Shares[sub.Id] {
+				users = append(users, share.UserId)
+			}
+
+			for _, userId := range users {
+				if attributed(userId) {
+					subMonths = append(subMonths, subscriptionMonth{sub: sub, userId: userId, month: month, price: price * fractions[userId]})
+				}
+			}
+		}
+	}
+
+	return subMonths, nil
+}
+
+// exchangeRate возвращает курс валюты к рублю, действующий в месяце month, так же, как функция exchange_rate:
+// берётся последний курс, начавший действовать не позже месяца
+func (repo SubscriptionRepoMock) exchangeRate(currency string, month time.Time) (float64, error) {
+	if currency == model.DefaultCurrency {
+		return 1, nil
+	}
+
+	var found *model.ExchangeRate
+
+	for i, rate := range repo.ExchangeRates {
+		if rate.Currency != currency || rate.Month.Time.After(month) {
+			continue
+		}
+
+		if found == nil || rate.Month.Time.After(found.Month.Time) {
+			found = &repo.ExchangeRates[i]
+		}
+	}
+
+	if found == nil {
+		return 0, fmt.Errorf("exchange rate for %s not found", currency)
+	}
+
+	return found.Rate, nil
+}
+
+// shareFractions возвращает доли стоимости подписки при стоимости listPrice за расчётный период.
+// Фиксированная сумма переводится в долю от стоимости, доли больше целого уменьшаются пропорционально,
+// остаток относится на владельца
+func (repo SubscriptionRepoMock) shareFractions(sub *model.Subscription, listPrice int) map[string]float64 {
+	fractions := make(map[string]float64)
+	total := 0.0
+
+	for _, share := range repo.Shares[sub.Id] {
+		fraction := share.Percent / 100
+
+		if share.Amount > 0 && listPrice > 0 {
+			fraction = math.Min(float64(share.Amount)/float64(listPrice), 1)
+		}
+
+		fractions[share.UserId] = fraction
+		total += fraction
+	}
+
+	if total > 1 {
+		for userId := range fractions {
+			fractions[userId] /= total
+		}
+
+		total = 1
+	}
+
+	fractions[sub.UserId] = 1 - total
+
+	return fractions
+}
+
+// priceAt возвращает стоимость подписки, действующую в месяце, с учётом пробного периода и акции
+// и по истории изменения стоимости
+func (repo SubscriptionRepoMock) priceAt(sub *model.Subscription, month time.Time) int {
+	if price, ok := IntroductoryPrice(*sub, month); ok {
+		return price
+	}
+
+	price := sub.Price
+	var effectiveFrom time.Time
+
+	for _, change := range repo.Prices[sub.Id] {
+		changeMonth := truncateMonth(change.EffectiveFrom.Time)
+
+		if !changeMonth.After(month) && !changeMonth.Before(effectiveFrom) {
+			price = change.Price
+			effectiveFrom = changeMonth
+		}
+	}
+
+	return price
+}
+
+// billedPrice рассчитывает стоимость подписки в месяце с учётом расчётного периода, отсчитываемого от BillingStart
+func billedPrice(sub *model.Subscription, price int, month time.Time, billingMode string) float64 {
+	billingStart := BillingStart(*sub)
+	start := truncateMonth(billingStart)
+	monthsSinceStart := (month.Year()-start.Year())*12 + int(month.Month()) - int(start.Month())
+
+	switch sub.BillingPeriod {
+	case model.BillingPeriodWeek:
+		if billingMode == model.BillingModeAmortize {
+			return float64(price) * 52 / 12
+		}
+
+		renewals := 0
+
+		for renewal := billingStart; renewal.Before(month.AddDate(0, 1, 0)); renewal = renewal.AddDate(0, 0, 7) {
+			if !renewal.Before(month) {
+				renewals++
+			}
+		}
+
+		return float64(price * renewals)
+	case model.BillingPeriodQuarter, model.BillingPeriodYear:
+		length := model.BillingPeriodMonths[sub.BillingPeriod]
+
+		if billingMode == model.BillingModeAmortize {
+			return float64(price) / float64(length)
+		}
+
+		if monthsSinceStart%length == 0 {
+			return float64(price)
+		}
+
+		return 0
+	default:
+		return float64(price)
+	}
+}
+
+// mockSortValues возвращает значение поля сортировки записи в том виде, в каком оно хранится в курсоре
+var mockSortValues = map[string]func(sub *model.Subscription) string{
+	model.SortById:          func(sub *model.Subscription) string { return strconv.Itoa(sub.Id) },
+	model.SortByStartDate:   func(sub *model.Subscription) string { return sub.StartDate.Time.Format("2006-01-02") },
+	model.SortByPrice:       func(sub *model.Subscription) string { return strconv.Itoa(sub.Price) },
+	model.SortByServiceName: func(sub *model.Subscription) string { return sub.ServiceName },
+}
+
+// mockCompareSortValues сравнивает значения поля сортировки с учётом их типа
+func mockCompareSortValues(sortBy string, a string, b string) int {
+	if sortBy == model.SortById || sortBy == model.SortByPrice {
+		intA, _ := strconv.Atoi(a)
+		intB, _ := strconv.Atoi(b)
+
+		return cmp.Compare(intA, intB)
+	}
+
+	return cmp.Compare(a, b)
+}
+
+// filterMatches проверяет запись о подписке на соответствие фильтру так же, как SubscriptionFilter.condition
+func (repo SubscriptionRepoMock) filterMatches(sub *model.Subscription, filter SubscriptionFilter) bool {
+	if sub.DeletedAt != nil {
+		return false
+	}
+
+	if len(filter.TenantIds) > 0 && !slices.Contains(filter.TenantIds, sub.TenantId) {
+		return false
+	}
+
+	if len(filter.UserIds) > 0 && !slices.Contains(filter.UserIds, sub.UserId) {
+		return false
+	}
+
+	if len(filter.ServiceNames) > 0 && !slices.Contains(filter.ServiceNames, sub.ServiceName) {
+		return false
+	}
+
+	if filter.ServiceNameSearch != "" && !strings.HasPrefix(strings.ToLower(sub.ServiceName), strings.ToLower(filter.ServiceNameSearch)) {
+		return false
+	}
+
+	if len(filter.Categories) > 0 && !slices.Contains(filter.Categories, repo.category(sub)) {
+		return false
+	}
+
+	if (filter.MinPrice > 0 && sub.Price < filter.MinPrice) || (filter.MaxPrice > 0 && sub.Price > filter.MaxPrice) {
+		return false
+	}
+
+	openEnded := sub.EndDate == nil || !sub.EndDate.Valid
+
+	switch {
+	case filter.MaxStartDate.Valid && filter.MinEndDate.Valid:
+		if sub.StartDate.Time.After(filter.MinEndDate.Time) || (!openEnded && sub.EndDate.Time.Before(filter.MaxStartDate.Time)) {
+			return false
+		}
+
+		if repo.pausedThroughout(sub, filter.MaxStartDate.Time, filter.MinEndDate.Time) {
+			return false
+		}
+	case filter.MaxStartDate.Valid:
+		if sub.StartDate.Time.After(filter.MaxStartDate.Time) {
+			return false
+		}
+	case filter.MinEndDate.Valid:
+		if openEnded || sub.EndDate.Time.Before(filter.MinEndDate.Time) {
+			return false
+		}
+	}
+
+	if filter.ActiveOn.Valid {
+		month := truncateMonth(filter.ActiveOn.Time)
+
+		if sub.StartDate.Time.After(month) || (!openEnded && sub.EndDate.Time.Before(month)) || Paused(repo.Pauses[sub.Id], month) {
+			return false
+		}
+	}
+
+	if filter.OpenEndedOnly && !openEnded {
+		return false
+	}
+
+	return true
+}
+
+// pausedThroughout проверяет, что подписка приостановлена во всех месяцах периода [from, to], в которых она действует
+func (repo SubscriptionRepoMock) pausedThroughout(sub *model.Subscription, from time.Time, to time.Time) bool {
+	month := truncateMonth(from)
+
+	if start := truncateMonth(sub.StartDate.Time); start.After(month) {
+		month = start
+	}
+
+	if sub.EndDate != nil && sub.EndDate.Valid && sub.EndDate.Time.Before(to) {
+		to = sub.EndDate.Time
+	}
+
+	for ; !month.After(to); month = month.AddDate(0, 1, 0) {
+		if !Paused(repo.Pauses[sub.Id], month) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (repo SubscriptionRepoMock) ListOverlaps(filter SubscriptionFilter) ([]model.SubscriptionOverlap, error) {
+	period := filter
+	filter.MaxStartDate, filter.MinEndDate = utils.Date{}, utils.Date{}
+	filter = repo.scoped(filter)
+
+	overlaps := []model.SubscriptionOverlap{}
+
+	for _, id := range repo.sortedIds() {
+		sub := repo.Subscriptions[id]
+
+		if !repo.filterMatches(sub, filter) {
+			continue
+		}
+
+		others, _ := repo.FindOverlapping(sub)
+
+		for _, other := range others {
+			if other.Id < sub.Id {
+				continue
+			}
+
+			overlap := mockOverlap(sub, &other)
+
+			if (period.MaxStartDate.Valid && overlap.EndDate != nil && overlap.EndDate.Time.Before(period.MaxStartDate.Time)) ||
+				(period.MinEndDate.Valid && overlap.StartDate.Time.After(period.MinEndDate.Time)) {
+				continue
+			}
+
+			overlaps = append(overlaps, overlap)
+		}
+	}
+
+	slices.SortStableFunc(overlaps, func(a, b model.SubscriptionOverlap) int {
+		return cmp.Or(cmp.Compare(a.UserId, b.UserId), cmp.Compare(a.ServiceName, b.ServiceName))
+	})
+
+	return overlaps, nil
+}
+
+func (repo SubscriptionRepoMock) FindOverlapping(entity *model.Subscription) ([]model.Subscription, error) {
+	tenantId := entity.TenantId
+
+	if repo.TenantId != "" || tenantId == "" {
+		tenantId = tenantOrDefault(repo.TenantId)
+	}
+
+	subs := []model.Subscription{}
+
+	for _, id := range repo.sortedIds() {
+		sub := repo.Subscriptions[id]
+
+		if sub.Id == entity.Id || sub.DeletedAt != nil || sub.TenantId != tenantId ||
+			sub.UserId != entity.UserId || sub.ServiceName != entity.ServiceName {
+			continue
+		}
+
+		overlap := mockOverlap(entity, sub)
+
+		if overlap.EndDate != nil && overlap.EndDate.Time.Before(overlap.StartDate.Time) {
+			continue
+		}
+
+		subs = append(subs, *sub)
+	}
+
+	slices.SortStableFunc(subs, func(a, b model.Subscription) int { return a.StartDate.Time.Compare(b.StartDate.Time) })
+
+	return subs, nil
+}
+
+// checkNoOverlap проверяет запись о подписке так же, как ограничение subscriptions_no_overlap, если мок создан с NoOverlap
+func (repo SubscriptionRepoMock) checkNoOverlap(entity *model.Subscription) error {
+	if !repo.NoOverlap {
+		return nil
+	}
+
+	if others, _ := repo.FindOverlapping(entity); len(others) > 0 {
+		return errSubscriptionOverlapConflict
+	}
 
 	return nil
 }
+
+// mockOverlap возвращает месяцы пересечения периодов двух записей о подписке; при пустом пересечении EndDate раньше StartDate
+func mockOverlap(sub *model.Subscription, other *model.Subscription) model.SubscriptionOverlap {
+	overlap := model.SubscriptionOverlap{
+		UserId:              sub.UserId,
+		ServiceName:         sub.ServiceName,
+		SubscriptionId:      sub.Id,
+		OtherSubscriptionId: other.Id,
+		StartDate:           sub.StartDate,
+	}
+
+	if other.StartDate.Time.After(sub.StartDate.Time) {
+		overlap.StartDate = other.StartDate
+	}
+
+	for _, endDate := range []*utils.Date{sub.EndDate, other.EndDate} {
+		if endDate != nil && endDate.Valid && (overlap.EndDate == nil || endDate.Time.Before(overlap.EndDate.Time)) {
+			overlap.EndDate = endDate
+		}
+	}
+
+	return overlap
+}
@@ -0,0 +1,424 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/db"
+	"subsaggregator/internal/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// errWebhookLeaseLost ошибка сохранения попытки доставки, срок работы с которой истёк
+var errWebhookLeaseLost = errors.New("webhook delivery lease expired")
+
+type WebhookRepository interface {
+	CreateEndpoint(entity *model.WebhookEndpoint) error
+	FindEndpointById(id int) (*model.WebhookEndpoint, error)
+	ListEndpoints() ([]model.WebhookEndpoint, error)
+	DeleteEndpoint(entity *model.WebhookEndpoint) error
+	DispatchEvents(limit int) (int, error)
+	ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]PendingWebhookDelivery, error)
+	SaveDeliveryAttempt(delivery *model.WebhookDelivery, leasedUntil time.Time) error
+	ListDeliveries(endpointId int, status string, limit int) ([]model.WebhookDelivery, error)
+}
+
+// PendingWebhookDelivery доставка события, взятая в работу, вместе с вебхуком и событием
+type PendingWebhookDelivery struct {
+	Delivery model.WebhookDelivery
+	Endpoint model.WebhookEndpoint
+	Event    model.WebhookEvent
+
+	// Время, до которого доставка взята в работу. Результат попытки сохраняется, только пока оно не изменилось
+	LeasedUntil time.Time
+}
+
+// WebhookRepo хранилище вебхуков и доставок событий. Вебхуки ограничены организацией TenantId,
+// а хранилище без организации разбирает и доставляет события всех организаций
+type WebhookRepo struct {
+	TenantId string
+}
+
+// webhookDeliveryColumns перечисляет столбцы webhook_deliveries в порядке полей model.WebhookDelivery
+const webhookDeliveryColumns = "d.id, d.endpoint_id, d.event_id, o.type, d.status, d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at"
+
+func (repo *WebhookRepo) CreateEndpoint(entity *model.WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (url, secret, event_types, tenant_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at;
+	`
+
+	err := db.Postgres.QueryRow(
+		query,
+		entity.Url,
+		entity.Secret,
+		pq.Array(entity.EventTypes),
+		tenantOrDefault(repo.TenantId),
+	).Scan(&entity.Id, &entity.CreatedAt)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("вебхук не зарегистрирован: %w", err).Error())
+
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Регистрация вебхука. ИД: %d. Адрес: %s. События: %v", entity.Id, entity.Url, entity.EventTypes))
+
+	return nil
+}
+
+func (repo *WebhookRepo) FindEndpointById(id int) (*model.WebhookEndpoint, error) {
+	query := `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhook_endpoints
+		WHERE id = $1 AND deleted_at IS NULL AND ($2 = '' OR tenant_id = $2);
+	`
+
+	rows, err := db.Postgres.Query(query, id, repo.TenantId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("вебхук не найден: %w", err).Error())
+
+		return nil, fmt.Errorf("webhook not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var endpoint model.WebhookEndpoint
+
+	err = rows.Scan(&endpoint.Id, &endpoint.Url, &endpoint.Secret, pq.Array(&endpoint.EventTypes), &endpoint.CreatedAt)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("вебхук невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return &endpoint, nil
+}
+
+func (repo *WebhookRepo) ListEndpoints() ([]model.WebhookEndpoint, error) {
+	query := `
+		SELECT id, url, event_types, created_at
+		FROM webhook_endpoints
+		WHERE deleted_at IS NULL AND ($1 = '' OR tenant_id = $1)
+		ORDER BY id;
+	`
+
+	rows, err := db.Postgres.Query(query, repo.TenantId)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("вебхуки не найдены: %w", err).Error())
+
+		return nil, fmt.Errorf("webhooks not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	endpoints := []model.WebhookEndpoint{}
+
+	for rows.Next() {
+		var endpoint model.WebhookEndpoint
+
+		err = rows.Scan(&endpoint.Id, &endpoint.Url, pq.Array(&endpoint.EventTypes), &endpoint.CreatedAt)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("вебхук невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("вебхуки невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// DeleteEndpoint помечает вебхук удалённым. Недоставленные по нему события больше не доставляются,
+// журнал доставок сохраняется
+func (repo *WebhookRepo) DeleteEndpoint(entity *model.WebhookEndpoint) error {
+	return withTransaction(func(q queryer) error {
+		_, err := q.Exec(`UPDATE webhook_endpoints SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL;`, entity.Id)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("вебхук не удалён: %w", err).Error())
+
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+
+		_, err = q.Exec(`
+			UPDATE webhook_deliveries
+			SET status = $2, last_error = 'webhook deleted'
+			WHERE endpoint_id = $1 AND status = $3;
+		`, entity.Id, model.WebhookDeliveryFailed, model.WebhookDeliveryPending)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("доставки удалённого вебхука не отменены: %w", err).Error())
+
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+
+		slog.Info(fmt.Sprintf("Удаление вебхука. ИД: %d. Адрес: %s", entity.Id, entity.Url))
+
+		return nil
+	})
+}
+
+// DispatchEvents создаёт доставки по подписанным вебхукам организации события для ещё не разобранных событий
+// из webhook_outbox и отмечает события разобранными. Возвращает количество разобранных событий
+func (repo *WebhookRepo) DispatchEvents(limit int) (int, error) {
+	query := `
+		WITH events AS (
+			UPDATE webhook_outbox
+			SET dispatched_at = now()
+			WHERE id IN (
+				SELECT id FROM webhook_outbox
+				WHERE dispatched_at IS NULL
+				ORDER BY created_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, type, tenant_id
+		), deliveries AS (
+			INSERT INTO webhook_deliveries (endpoint_id, event_id)
+			SELECT webhook_endpoints.id, events.id
+			FROM events
+			JOIN webhook_endpoints ON events.type = ANY (webhook_endpoints.event_types)
+				AND webhook_endpoints.tenant_id = events.tenant_id
+				AND webhook_endpoints.deleted_at IS NULL
+			ON CONFLICT DO NOTHING
+		)
+		SELECT count(*) FROM events;
+	`
+
+	var count int
+
+	err := db.Postgres.QueryRow(query, limit).Scan(&count)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("события для вебхуков не разобраны: %w", err).Error())
+
+		return 0, fmt.Errorf("failed to dispatch webhook events: %w", err)
+	}
+
+	if count > 0 {
+		slog.Info(fmt.Sprintf("Разбор событий для вебхуков. Событий: %d", count))
+	}
+
+	return count, nil
+}
+
+// ClaimDeliveries берёт в работу доставки, время попытки которых наступило, откладывая их следующую попытку на lease.
+// Если процесс завершится во время отправки, доставка будет повторена по истечении lease
+func (repo *WebhookRepo) ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]PendingWebhookDelivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET next_attempt_at = $2
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = $3 AND next_attempt_at <= $1
+				ORDER BY next_attempt_at
+				LIMIT $4
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING *
+		)
+		SELECT ` + webhookDeliveryColumns + `, e.id, e.url, e.secret, o.id, o.type, o.created_at, o.data
+		FROM claimed d
+		JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		JOIN webhook_outbox o ON o.id = d.event_id
+		ORDER BY d.id;
+	`
+
+	rows, err := db.Postgres.Query(query, now, now.Add(lease), model.WebhookDeliveryPending, limit)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("доставки по вебхукам не взяты в работу: %w", err).Error())
+
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	defer rows.Close()
+
+	pending := []PendingWebhookDelivery{}
+
+	for rows.Next() {
+		var item PendingWebhookDelivery
+		var data []byte
+
+		err = rows.Scan(
+			append(webhookDeliveryFields(&item.Delivery),
+				&item.Endpoint.Id,
+				&item.Endpoint.Url,
+				&item.Endpoint.Secret,
+				&item.Event.Id,
+				&item.Event.Type,
+				&item.Event.CreatedAt,
+				&data,
+			)...,
+		)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("доставку по вебхуку невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		item.Event.Data = json.RawMessage(data)
+		item.LeasedUntil = *item.Delivery.NextAttemptAt
+
+		pending = append(pending, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("доставки по вебхукам невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	return pending, nil
+}
+
+// SaveDeliveryAttempt сохраняет результат попытки доставки, если доставка всё ещё взята в работу до leasedUntil.
+// Если срок истёк и доставку взял в работу другой экземпляр, результат не сохраняется и возвращается errWebhookLeaseLost
+func (repo *WebhookRepo) SaveDeliveryAttempt(delivery *model.WebhookDelivery, leasedUntil time.Time) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = COALESCE($4, next_attempt_at), last_status_code = $5, last_error = $6, delivered_at = $7
+		WHERE id = $1 AND status = $8 AND next_attempt_at = $9;
+	`
+
+	result, err := db.Postgres.Exec(
+		query,
+		delivery.Id,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		delivery.DeliveredAt,
+		model.WebhookDeliveryPending,
+		leasedUntil,
+	)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("результат доставки по вебхуку не сохранён: %w", err).Error())
+
+		return fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+
+	if count, err := result.RowsAffected(); err == nil && count == 0 {
+		slog.Error(fmt.Sprintf("результат доставки по вебхуку не сохранён: доставка %d взята в работу другим экземпляром", delivery.Id))
+
+		return errWebhookLeaseLost
+	}
+
+	slog.Info(fmt.Sprintf("Доставка события по вебхуку. ИД доставки: %d. Событие: %s. Статус: %s. Попытка: %d. Код ответа: %d",
+		delivery.Id,
+		delivery.EventId,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.LastStatusCode,
+	))
+
+	return nil
+}
+
+// ListDeliveries возвращает журнал доставок по вебхуку от новых к старым
+func (repo *WebhookRepo) ListDeliveries(endpointId int, status string, limit int) ([]model.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries d
+		JOIN webhook_outbox o ON o.id = d.event_id
+		WHERE d.endpoint_id = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY d.id DESC
+		LIMIT $3;
+	`
+
+	rows, err := db.Postgres.Query(query, endpointId, status, limit)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("журнал доставок по вебхуку не найден: %w", err).Error())
+
+		return nil, fmt.Errorf("webhook deliveries not found: %w", err)
+	}
+
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+
+	for rows.Next() {
+		var delivery model.WebhookDelivery
+
+		err = rows.Scan(webhookDeliveryFields(&delivery)...)
+
+		if err != nil {
+			slog.Error(fmt.Errorf("доставку по вебхуку невозможно прочитать: %w", err).Error())
+
+			return nil, fmt.Errorf("failing to read data from database: %w", err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		slog.Error(fmt.Errorf("журнал доставок по вебхуку невозможно прочитать: %w", err).Error())
+
+		return nil, fmt.Errorf("failing to read data from database: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Получение журнала доставок по вебхуку. ИД: %d", endpointId))
+
+	return deliveries, nil
+}
+
+// insertWebhookEvent сохраняет событие организации tenantId в webhook_outbox в рамках транзакции изменения, породившего событие
+func insertWebhookEvent(q queryer, tenantId string, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+
+	if err != nil {
+		return fmt.Errorf("failed to record webhook event: %w", err)
+	}
+
+	_, err = q.Exec(`INSERT INTO webhook_outbox (type, data, tenant_id) VALUES ($1, $2, $3);`, eventType, string(payload), tenantOrDefault(tenantId))
+
+	if err != nil {
+		slog.Error(fmt.Errorf("событие для вебхуков не сохранено: %w", err).Error())
+
+		return fmt.Errorf("failed to record webhook event: %w", err)
+	}
+
+	return nil
+}
+
+// webhookDeliveryFields возвращает поля доставки в порядке webhookDeliveryColumns
+func webhookDeliveryFields(delivery *model.WebhookDelivery) []any {
+	return []any{
+		&delivery.Id,
+		&delivery.EndpointId,
+		&delivery.EventId,
+		&delivery.EventType,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&delivery.DeliveredAt,
+	}
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"slices"
+	"sort"
+	"subsaggregator/internal/model"
+	"time"
+)
+
+type WebhookRepoMock struct {
+	// Удалённые вебхуки хранятся как nil, чтобы их ИД не использовались повторно
+	Endpoints  map[int]*model.WebhookEndpoint
+	Outbox     map[string]*model.WebhookEvent
+	Dispatched map[string]bool
+	Deliveries map[int]*model.WebhookDelivery
+}
+
+func (repo WebhookRepoMock) CreateEndpoint(entity *model.WebhookEndpoint) error {
+	entity.Id = len(repo.Endpoints) + 1
+	entity.CreatedAt = time.Now()
+
+	endpoint := *entity
+
+	repo.Endpoints[entity.Id] = &endpoint
+
+	return nil
+}
+
+func (repo WebhookRepoMock) FindEndpointById(id int) (*model.WebhookEndpoint, error) {
+	if endpoint := repo.Endpoints[id]; endpoint != nil {
+		result := *endpoint
+
+		return &result, nil
+	}
+
+	return nil, nil
+}
+
+func (repo WebhookRepoMock) ListEndpoints() ([]model.WebhookEndpoint, error) {
+	endpoints := []model.WebhookEndpoint{}
+
+	for _, endpoint := range repo.Endpoints {
+		if endpoint == nil {
+			continue
+		}
+
+		result := *endpoint
+		result.Secret = ""
+
+		endpoints = append(endpoints, result)
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Id < endpoints[j].Id
+	})
+
+	return endpoints, nil
+}
+
+func (repo WebhookRepoMock) DeleteEndpoint(entity *model.WebhookEndpoint) error {
+	repo.Endpoints[entity.Id] = nil
+
+	for _, delivery := range repo.Deliveries {
+		if delivery.EndpointId == entity.Id && delivery.Status == model.WebhookDeliveryPending {
+			delivery.Status = model.WebhookDeliveryFailed
+			delivery.LastError = "webhook deleted"
+		}
+	}
+
+	return nil
+}
+
+func (repo WebhookRepoMock) DispatchEvents(limit int) (int, error) {
+	events := []*model.WebhookEvent{}
+
+	for id, event := range repo.Outbox {
+		if !repo.Dispatched[id] {
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id < events[j].Id
+	})
+
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	endpointIds := []int{}
+
+	for id, endpoint := range repo.Endpoints {
+		if endpoint != nil {
+			endpointIds = append(endpointIds, id)
+		}
+	}
+
+	sort.Ints(endpointIds)
+
+	for _, event := range events {
+		for _, endpointId := range endpointIds {
+			if !slices.Contains(repo.Endpoints[endpointId].EventTypes, event.Type) {
+				continue
+			}
+
+			id := len(repo.Deliveries) + 1
+			nextAttemptAt := event.CreatedAt
+
+			repo.Deliveries[id] = &model.WebhookDelivery{
+				Id:            id,
+				EndpointId:    endpointId,
+				EventId:       event.Id,
+				EventType:     event.Type,
+				Status:        model.WebhookDeliveryPending,
+				NextAttemptAt: &nextAttemptAt,
+				CreatedAt:     event.CreatedAt,
+			}
+		}
+
+		repo.Dispatched[event.Id] = true
+	}
+
+	return len(events), nil
+}
+
+func (repo WebhookRepoMock) ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]PendingWebhookDelivery, error) {
+	pending := []PendingWebhookDelivery{}
+
+	for id := 1; id <= len(repo.Deliveries) && len(pending) < limit; id++ {
+		delivery := repo.Deliveries[id]
+
+		if delivery.Status != model.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+
+		endpoint := repo.Endpoints[delivery.EndpointId]
+
+		if endpoint == nil {
+			continue
+		}
+
+		nextAttemptAt := now.Add(lease)
+		delivery.NextAttemptAt = &nextAttemptAt
+
+		pending = append(pending, PendingWebhookDelivery{
+			Delivery:    *delivery,
+			Endpoint:    *endpoint,
+			Event:       *repo.Outbox[delivery.EventId],
+			LeasedUntil: nextAttemptAt,
+		})
+	}
+
+	return pending, nil
+}
+
+func (repo WebhookRepoMock) SaveDeliveryAttempt(delivery *model.WebhookDelivery, leasedUntil time.Time) error {
+	saved := repo.Deliveries[delivery.Id]
+
+	if saved.Status != model.WebhookDeliveryPending || !saved.NextAttemptAt.Equal(leasedUntil) {
+		return errWebhookLeaseLost
+	}
+
+	saved.Status = delivery.Status
+	saved.Attempts = delivery.Attempts
+	saved.LastStatusCode = delivery.LastStatusCode
+	saved.LastError = delivery.LastError
+	saved.DeliveredAt = delivery.DeliveredAt
+
+	if delivery.NextAttemptAt != nil {
+		saved.NextAttemptAt = delivery.NextAttemptAt
+	}
+
+	return nil
+}
+
+func (repo WebhookRepoMock) ListDeliveries(endpointId int, status string, limit int) ([]model.WebhookDelivery, error) {
+	deliveries := []model.WebhookDelivery{}
+
+	for id := len(repo.Deliveries); id >= 1 && len(deliveries) < limit; id-- {
+		delivery := repo.Deliveries[id]
+
+		if delivery.EndpointId == endpointId && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+
+	return deliveries, nil
+}
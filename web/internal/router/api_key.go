@@ -0,0 +1,108 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// apiKeyResolver проверяет API-ключ из заголовка Authorization: ApiKey
+func apiKeyResolver(key string, now time.Time) (*auth.Principal, error) {
+	return service.AuthenticateApiKey(key, now, &repository.ApiKeyRepo{})
+}
+
+// listApiKeys получает список API-ключей
+// @Summary Получает список API-ключей
+// @Description Получает все API-ключи, включая отозванные. Сами ключи не возвращаются
+// @Tags ApiKeys
+// @Produce json
+// @Success 200 {array} model.ApiKey "API-ключи"
+// @Failure 400
+// @Security BearerAuth
+// @Router /api-key [get]
+func listApiKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := service.ListApiKeys(apiKeyRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, keys, http.StatusOK)
+}
+
+// createApiKey создаёт API-ключ
+// @Summary Создаёт API-ключ
+// @Description Создаёт API-ключ для сервисов и фоновых задач. Ключ передаётся в заголовке "Authorization: ApiKey <ключ>"
+// @Description и возвращается только в ответе на создание. Разрешения: subscriptions:read, subscriptions:write, reports:read, admin.
+// @Description Ключ действует в организации, в которой создан. Ключ с user_id действует только для подписок этого пользователя,
+// @Description без user_id — для всех пользователей организации
+// @Tags ApiKeys
+// @Accept json
+// @Produce json
+// @Param apiKey body service.CreateApiKeyRequest true "Параметры API-ключа"
+// @Success 201 {object} model.ApiKey "Созданный API-ключ"
+// @Failure 400
+// @Security BearerAuth
+// @Router /api-key [post]
+func createApiKey(w http.ResponseWriter, r *http.Request) {
+	var req service.CreateApiKeyRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := service.CreateApiKey(req, time.Now(), apiKeyRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, key, http.StatusCreated)
+}
+
+// revokeApiKey отзывает API-ключ
+// @Summary Отзывает API-ключ
+// @Description Отзывает API-ключ, после чего запросы с ним отклоняются. Запись о ключе сохраняется
+// @Tags ApiKeys
+// @Produce json
+// @Param apiKeyId path int true "ИД API-ключа"
+// @Success 200 {object} model.ApiKey "Отозванный API-ключ"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /api-key/{apiKeyId} [delete]
+func revokeApiKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyId, err := strconv.Atoi(chi.URLParam(r, "apiKeyId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := service.RevokeApiKey(apiKeyId, apiKeyRepo(r))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, key, http.StatusOK)
+}
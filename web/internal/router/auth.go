@@ -0,0 +1,57 @@
+package router
+
+import (
+	"net/http"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// authorizeUsers ограничивает фильтр запроса по пользователям подписками вызывающего и при отказе отвечает 403
+func authorizeUsers(w http.ResponseWriter, r *http.Request, userId *string, userIds []string) bool {
+	allowed, err := service.AuthorizeUsers(auth.FromContext(r.Context()), *userId, userIds)
+
+	if err != nil {
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+		return false
+	}
+
+	*userId = allowed
+
+	return true
+}
+
+// authorizeUser проверяет доступ к данным пользователя из пути запроса и при отказе отвечает 403
+func authorizeUser(w http.ResponseWriter, r *http.Request) bool {
+	if !auth.FromContext(r.Context()).Owns(chi.URLParam(r, "userId")) {
+		http.Error(w, "Forbidden: access to this user is denied", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// authorizeSubscription проверяет доступ к записи о подписке и для чужой записи отвечает 404
+func authorizeSubscription(w http.ResponseWriter, r *http.Request, subId int) bool {
+	err := service.AuthorizeSubscription(auth.FromContext(r.Context()), subId, subscriptionRepo(r))
+
+	if err != nil {
+		http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+		return false
+	}
+
+	return true
+}
+
+// authorizeBudget проверяет доступ к бюджету и для чужого бюджета отвечает 404
+func authorizeBudget(w http.ResponseWriter, r *http.Request, budgetId int) bool {
+	err := service.AuthorizeBudget(auth.FromContext(r.Context()), budgetId, budgetRepo(r))
+
+	if err != nil {
+		http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,213 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// listBudgets получает бюджеты пользователя
+// @Summary Получает бюджеты пользователя
+// @Description Получает лимиты расходов пользователя на подписки в месяц: общий и по сервисам
+// @Tags Budgets
+// @Produce json
+// @Param userId path string true "ИД пользователя"
+// @Success 200 {array} model.Budget "Бюджеты"
+// @Failure 400
+// @Security BearerAuth
+// @Router /user/{userId}/budget [get]
+func listBudgets(w http.ResponseWriter, r *http.Request) {
+	if !authorizeUser(w, r) {
+		return
+	}
+
+	budgets, err := service.ListBudgets(chi.URLParam(r, "userId"), budgetRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, budgets, http.StatusOK)
+}
+
+// createBudget создаёт бюджет пользователя
+// @Summary Создаёт бюджет пользователя
+// @Description Создаёт лимит расходов пользователя на подписки в месяц. Если сервис не указан, лимит ограничивает расходы на все подписки.
+// @Description Когда создание или изменение подписки доводит расходы за месяц до 80% или 100% лимита,
+// @Description по вебхукам отправляется событие budget.threshold_reached
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Param userId path string true "ИД пользователя"
+// @Param budget body service.SaveBudgetRequest true "Параметры бюджета"
+// @Success 201 {object} model.Budget "Созданный бюджет"
+// @Failure 400
+// @Security BearerAuth
+// @Router /user/{userId}/budget [post]
+func createBudget(w http.ResponseWriter, r *http.Request) {
+	if !authorizeUser(w, r) {
+		return
+	}
+
+	var req service.SaveBudgetRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	budget, err := service.CreateBudget(req, chi.URLParam(r, "userId"), budgetRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, budget, http.StatusCreated)
+}
+
+// updateBudget изменяет бюджет
+// @Summary Изменяет бюджет
+// @Description Изменяет сервис, лимит и валюту бюджета. Отметки о достигнутых порогах сбрасываются
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Param budgetId path int true "ИД бюджета"
+// @Param budget body service.SaveBudgetRequest true "Параметры бюджета"
+// @Success 200 {object} model.Budget "Изменённый бюджет"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /budget/{budgetId} [post]
+func updateBudget(w http.ResponseWriter, r *http.Request) {
+	budgetId, err := strconv.Atoi(chi.URLParam(r, "budgetId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeBudget(w, r, budgetId) {
+		return
+	}
+
+	var req service.SaveBudgetRequest
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	budget, err := service.UpdateBudget(req, budgetId, budgetRepo(r))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, budget, http.StatusOK)
+}
+
+// deleteBudget удаляет бюджет
+// @Summary Удаляет бюджет
+// @Description Удаляет бюджет вместе с отметками о достигнутых порогах
+// @Tags Budgets
+// @Param budgetId path int true "ИД бюджета"
+// @Success 204
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /budget/{budgetId} [delete]
+func deleteBudget(w http.ResponseWriter, r *http.Request) {
+	budgetId, err := strconv.Atoi(chi.URLParam(r, "budgetId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeBudget(w, r, budgetId) {
+		return
+	}
+
+	err = service.DeleteBudget(budgetId, budgetRepo(r))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, nil, http.StatusNoContent)
+}
+
+// getBudgetStatus сравнивает расходы пользователя с бюджетами
+// @Summary Сравнивает расходы пользователя с бюджетами
+// @Description Для каждого бюджета пользователя возвращает расходы по месяцам, процент от лимита и достигнутый порог.
+// @Description Расходы считаются как в /subscription/sum-price/monthly, подписки без даты окончания учитываются до конца периода
+// @Tags Budgets
+// @Produce json
+// @Param userId path string true "ИД пользователя"
+// @Param start_date query string false "Первый месяц периода в формате MM-YYYY, по умолчанию текущий"
+// @Param end_date query string false "Последний месяц периода в формате MM-YYYY, по умолчанию первый месяц, не больше 24 месяцев"
+// @Param billing_mode query string false "Режим учёта стоимости: charge или amortize"
+// @Success 200 {array} model.BudgetStatus "Расходы по бюджетам"
+// @Failure 400
+// @Security BearerAuth
+// @Router /user/{userId}/budget/status [get]
+func getBudgetStatus(w http.ResponseWriter, r *http.Request) {
+	if !authorizeUser(w, r) {
+		return
+	}
+
+	req := service.BudgetStatusRequest{BillingMode: r.URL.Query().Get("billing_mode")}
+
+	var err error
+
+	if value := r.URL.Query().Get("start_date"); value != "" {
+		req.StartDate, err = utils.ParseDate(value)
+
+		if err != nil {
+			http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if value := r.URL.Query().Get("end_date"); value != "" {
+		req.EndDate, err = utils.ParseDate(value)
+
+		if err != nil {
+			http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	statuses, err := service.GetBudgetStatus(req, chi.URLParam(r, "userId"), time.Now(), budgetRepo(r), subscriptionRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, statuses, http.StatusOK)
+}
@@ -0,0 +1,134 @@
+package router
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// calendarFeedResolver проверяет токен ленты календаря из параметра token
+func calendarFeedResolver(token string) (*auth.Principal, error) {
+	return service.AuthenticateCalendarFeed(token, &repository.CalendarFeedRepo{})
+}
+
+// getSubscriptionCalendar отдаёт календарь продлений и окончаний подписок пользователя
+// @Summary Отдаёт календарь продлений и окончаний подписок пользователя
+// @Description Отдаёт календарь iCalendar с событиями продления подписок по расчётному периоду и окончания подписок
+// @Description с сегодняшнего дня на horizon месяцев вперёд. Ссылку можно добавить в приложение календаря как подписку.
+// @Description Приложения календаря не передают заголовок Authorization, поэтому лента запрашивается не с JWT или API-ключом,
+// @Description а с токеном ленты в параметре token, выпущенным POST /user/{userId}/calendar/token.
+// @Description Токен открывает только ленту своего пользователя и отзывается DELETE /user/{userId}/calendar/token
+// @Tags Calendar
+// @Produce text/calendar
+// @Param userId path string true "ИД пользователя"
+// @Param token query string true "Токен ленты календаря"
+// @Param horizon query int false "Горизонт календаря в месяцах, по умолчанию 12, не больше 36"
+// @Success 200 {file} file "Календарь iCalendar"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Router /user/{userId}/calendar.ics [get]
+func getSubscriptionCalendar(w http.ResponseWriter, r *http.Request) {
+	if !authorizeUser(w, r) {
+		return
+	}
+
+	userId := chi.URLParam(r, "userId")
+	horizon := 0
+
+	if value := r.URL.Query().Get("horizon"); value != "" {
+		var err error
+
+		horizon, err = strconv.Atoi(value)
+
+		if err != nil {
+			http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	now := time.Now()
+
+	events, err := service.SubscriptionCalendar(userId, horizon, now, subscriptionRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="subscriptions.ics"`)
+
+	// Заголовки уже отправлены, поэтому ошибка записи календаря только логируется
+	if err := utils.WriteCalendar(w, fmt.Sprintf("Подписки %s", userId), events, now); err != nil {
+		slog.Error(fmt.Errorf("календарь подписок пользователя %s не отправлен: %w", userId, err).Error())
+	}
+}
+
+// createCalendarFeed выпускает токен ленты календаря пользователя
+// @Summary Выпускает токен ленты календаря пользователя
+// @Description Выпускает токен, с которым лента /user/{userId}/calendar.ics?token=<токен> открывается в приложении календаря
+// @Description без заголовка Authorization. Прежний токен пользователя перестаёт действовать.
+// @Description Токен возвращается только в ответе на выпуск
+// @Tags Calendar
+// @Produce json
+// @Param userId path string true "ИД пользователя"
+// @Success 201 {object} model.CalendarFeed "Токен ленты календаря"
+// @Failure 400
+// @Failure 403
+// @Security BearerAuth
+// @Router /user/{userId}/calendar/token [post]
+func createCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if !authorizeUser(w, r) {
+		return
+	}
+
+	feed, err := service.CreateCalendarFeed(chi.URLParam(r, "userId"), calendarFeedRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, feed, http.StatusCreated)
+}
+
+// revokeCalendarFeed отзывает токен ленты календаря пользователя
+// @Summary Отзывает токен ленты календаря пользователя
+// @Description Отзывает токен ленты календаря, после чего лента с ним не открывается
+// @Tags Calendar
+// @Param userId path string true "ИД пользователя"
+// @Success 204
+// @Failure 400
+// @Failure 403
+// @Failure 404
+// @Security BearerAuth
+// @Router /user/{userId}/calendar/token [delete]
+func revokeCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if !authorizeUser(w, r) {
+		return
+	}
+
+	err := service.RevokeCalendarFeed(chi.URLParam(r, "userId"), calendarFeedRepo(r))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,185 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// listCatalogServices получает сервисы каталога
+// @Summary Получает сервисы каталога
+// @Description Получает сервисы каталога с каноническими названиями, псевдонимами, категориями и стоимостью по умолчанию.
+// @Description Каталог общий для всех организаций
+// @Tags Catalog
+// @Produce json
+// @Param category query string false "Категория сервисов" Enums(video, music, cloud, software, games, news, education, other)
+// @Success 200 {array} model.CatalogService "Сервисы каталога"
+// @Failure 400
+// @Security BearerAuth
+// @Router /catalog [get]
+func listCatalogServices(w http.ResponseWriter, r *http.Request) {
+	services, err := service.ListCatalogServices(r.URL.Query().Get("category"), &repository.CatalogRepo{})
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, services, http.StatusOK)
+}
+
+// getCatalogService получает сервис каталога
+// @Summary Получает сервис каталога
+// @Description Получает сервис каталога по ИД
+// @Tags Catalog
+// @Produce json
+// @Param serviceId path int true "ИД сервиса каталога"
+// @Success 200 {object} model.CatalogService "Сервис каталога"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /catalog/{serviceId} [get]
+func getCatalogService(w http.ResponseWriter, r *http.Request) {
+	serviceId, err := strconv.Atoi(chi.URLParam(r, "serviceId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	catalogService, err := service.GetCatalogService(&repository.CatalogRepo{}, serviceId)
+
+	respondCatalogService(w, catalogService, err, http.StatusOK)
+}
+
+// createCatalogService добавляет сервис в каталог
+// @Summary Добавляет сервис в каталог
+// @Description Добавляет сервис в каталог. Записи о подписках всех организаций, название которых совпадает с названием или псевдонимом
+// @Description сервиса без учёта регистра и лишних пробелов, связываются с сервисом и получают каноническое название.
+// @Description Изменение каждой записи попадает в журнал изменений и в вебхуки. Удалённые записи не изменяются.
+// @Description Доступно администратору, не привязанному к организации
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Param service body service.CreateCatalogServiceRequest true "Параметры сервиса каталога"
+// @Param X-Actor header string false "Автор изменения"
+// @Success 201 {object} model.CatalogService "Сервис каталога"
+// @Failure 400
+// @Security BearerAuth
+// @Router /catalog [post]
+func createCatalogService(w http.ResponseWriter, r *http.Request) {
+	var req service.CreateCatalogServiceRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	catalogService, err := service.CreateCatalogService(req, &repository.CatalogRepo{}, requestActor(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, catalogService, http.StatusCreated)
+}
+
+// updateCatalogService изменяет сервис каталога
+// @Summary Изменяет сервис каталога
+// @Description Изменяет сервис каталога и заменяет его псевдонимы. Связанные записи о подписках получают новое каноническое название,
+// @Description записи с названием, совпадающим с новым псевдонимом, связываются с сервисом.
+// @Description Изменение каждой записи попадает в журнал изменений и в вебхуки. Удалённые записи не изменяются.
+// @Description Доступно администратору, не привязанному к организации
+// @Tags Catalog
+// @Accept json
+// @Produce json
+// @Param serviceId path int true "ИД сервиса каталога"
+// @Param service body service.UpdateCatalogServiceRequest true "Параметры сервиса каталога"
+// @Param X-Actor header string false "Автор изменения"
+// @Success 200 {object} model.CatalogService "Сервис каталога"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /catalog/{serviceId} [post]
+func updateCatalogService(w http.ResponseWriter, r *http.Request) {
+	serviceId, err := strconv.Atoi(chi.URLParam(r, "serviceId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req service.UpdateCatalogServiceRequest
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	catalogService, err := service.UpdateCatalogService(req, &repository.CatalogRepo{}, serviceId, requestActor(r))
+
+	respondCatalogService(w, catalogService, err, http.StatusOK)
+}
+
+// deleteCatalogService удаляет сервис из каталога
+// @Summary Удаляет сервис из каталога
+// @Description Удаляет сервис из каталога. Связанные записи о подписках сохраняют название и перестают быть связаны с каталогом,
+// @Description изменение каждой неудалённой записи попадает в журнал изменений и в вебхуки.
+// @Description Доступно администратору, не привязанному к организации
+// @Tags Catalog
+// @Param serviceId path int true "ИД сервиса каталога"
+// @Param X-Actor header string false "Автор изменения"
+// @Success 204
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /catalog/{serviceId} [delete]
+func deleteCatalogService(w http.ResponseWriter, r *http.Request) {
+	serviceId, err := strconv.Atoi(chi.URLParam(r, "serviceId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	catalogService, err := service.DeleteCatalogService(&repository.CatalogRepo{}, serviceId, requestActor(r))
+
+	respondCatalogService(w, catalogService, err, http.StatusNoContent)
+}
+
+// respondCatalogService отвечает сервисом каталога или ошибкой. Отсутствующий сервис возвращается со статусом 404
+func respondCatalogService(w http.ResponseWriter, catalogService *model.CatalogService, err error, status int) {
+	if err == nil && catalogService == nil {
+		http.Error(w, "Not found: service not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if status == http.StatusNoContent {
+		utils.RespondJSON(w, nil, status)
+		return
+	}
+
+	utils.RespondJSON(w, catalogService, status)
+}
@@ -0,0 +1,63 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+)
+
+// listExchangeRates получает список курсов валют
+// @Summary Получает список курсов валют
+// @Description Получает список курсов валют к рублю с фильтрацией по коду валюты
+// @Tags ExchangeRates
+// @Accept json
+// @Produce json
+// @Param currency query string false "Код валюты по ISO 4217"
+// @Success 200 {array} model.ExchangeRate "Курсы валют"
+// @Failure 400
+// @Security BearerAuth
+// @Router /exchange-rate [get]
+func listExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := service.ListExchangeRates(r.URL.Query().Get("currency"), &repository.ExchangeRateRepo{})
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, rates, http.StatusOK)
+}
+
+// saveExchangeRates загружает курсы валют
+// @Summary Загружает курсы валют
+// @Description Загружает курсы валют к рублю. Курс на уже загруженный месяц перезаписывается.
+// @Description Курсы общие для всех организаций, поэтому загружать их может только администратор без организации
+// @Tags ExchangeRates
+// @Accept json
+// @Produce json
+// @Param rates body service.SaveExchangeRatesRequest true "Параметры запроса для загрузки курсов валют"
+// @Success 200 {array} model.ExchangeRate "Загруженные курсы валют"
+// @Failure 400
+// @Security BearerAuth
+// @Router /exchange-rate [post]
+func saveExchangeRates(w http.ResponseWriter, r *http.Request) {
+	var req service.SaveExchangeRatesRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rates, err := service.SaveExchangeRates(req, &repository.ExchangeRateRepo{})
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, rates, http.StatusOK)
+}
@@ -0,0 +1,115 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+)
+
+// exportSubscriptions выгружает записи о подписках в файл
+// @Summary Выгружает записи о подписках в файл
+// @Description Выгружает записи о подписках, подходящие под фильтр списка, в CSV, XLSX или NDJSON.
+// @Description Формат выбирается параметром format или заголовком Accept, по умолчанию CSV. Без limit выгружаются все записи.
+// @Description В CSV перед значениями, начинающимися с =, +, - или @, добавляется апостроф, чтобы они не считались формулами
+// @Tags Export
+// @Accept json
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
+// @Param format query string false "Формат файла" Enums(csv, xlsx, ndjson)
+// @Param subscription body service.ListSubscriptionsRequest true "Параметры запроса для получения списка записей о подписках"
+// @Success 200 {file} file "Файл с записями о подписках"
+// @Failure 400
+// @Security BearerAuth
+// @Router /subscription/export [post]
+func exportSubscriptions(w http.ResponseWriter, r *http.Request) {
+	var req service.ListSubscriptionsRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeUsers(w, r, &req.UserId, req.UserIds) {
+		return
+	}
+
+	respondExport(w, r, "subscriptions", func(format string, export *exportResponseWriter) error {
+		return service.ExportSubscriptions(req, format, export, subscriptionRepo(r))
+	})
+}
+
+// exportSubscriptionPricesByMonth выгружает помесячную стоимость подписок в файл
+// @Summary Выгружает помесячную стоимость подписок в файл
+// @Description Выгружает стоимость подписок за каждый месяц в разрезе сервисов в CSV, XLSX или NDJSON.
+// @Description Формат выбирается параметром format или заголовком Accept, по умолчанию CSV
+// @Tags Export
+// @Accept json
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/x-ndjson
+// @Param format query string false "Формат файла" Enums(csv, xlsx, ndjson)
+// @Param subscription body service.SumSubscriptionsPricesRequest true "Параметры запроса для получения помесячной стоимости подписок"
+// @Success 200 {file} file "Файл с помесячной стоимостью подписок"
+// @Failure 400
+// @Security BearerAuth
+// @Router /subscription/sum-price/monthly/export [post]
+func exportSubscriptionPricesByMonth(w http.ResponseWriter, r *http.Request) {
+	var req service.SumSubscriptionsPricesRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeUsers(w, r, &req.UserId, req.UserIds) {
+		return
+	}
+
+	respondExport(w, r, "monthly-prices", func(format string, export *exportResponseWriter) error {
+		return service.ExportSubscriptionsPricesByMonth(req, format, export, subscriptionRepo(r))
+	})
+}
+
+// exportResponseWriter запоминает, началась ли запись файла в ответ
+type exportResponseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *exportResponseWriter) Write(data []byte) (int, error) {
+	w.written = true
+
+	return w.ResponseWriter.Write(data)
+}
+
+// respondExport выбирает формат выгрузки и передаёт файл в ответ.
+// Ошибка до начала записи файла возвращается статусом 400, после начала — только логируется, так как статус уже отправлен
+func respondExport(w http.ResponseWriter, r *http.Request, name string, export func(format string, w *exportResponseWriter) error) {
+	format, err := utils.ExportFormat(r.URL.Query().Get("format"), r.Header.Get("Accept"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", utils.ExportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+
+	exportWriter := &exportResponseWriter{ResponseWriter: w}
+
+	err = export(format, exportWriter)
+
+	if err != nil && !exportWriter.written {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		slog.Error(fmt.Errorf("выгрузка %s прервана: %w", name, err).Error())
+	}
+}
@@ -0,0 +1,46 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+	"time"
+)
+
+// forecastSubscriptionPrices прогнозирует расходы на подписки на ближайшие месяцы
+// @Summary Прогнозирует расходы на подписки
+// @Description Прогнозирует помесячные расходы на подписки, действующие в текущем месяце, на months месяцев начиная с текущего.
+// @Description Подписки без даты окончания считаются продолжающимися до конца прогноза, известные даты окончания и запланированные изменения цены учитываются.
+// @Description Возвращает итог за период, итоги по сервисам и расходы по месяцам с разбивкой по сервисам
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param forecast body service.ForecastSubscriptionsPricesRequest true "Параметры прогноза"
+// @Success 200 {object} model.Forecast "Прогноз расходов"
+// @Failure 400
+// @Security BearerAuth
+// @Router /subscription/forecast [post]
+func forecastSubscriptionPrices(w http.ResponseWriter, r *http.Request) {
+	var req service.ForecastSubscriptionsPricesRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeUsers(w, r, &req.UserId, req.UserIds) {
+		return
+	}
+
+	forecast, err := service.ForecastSubscriptionsPrices(req, time.Now(), subscriptionRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, forecast, http.StatusOK)
+}
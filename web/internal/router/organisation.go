@@ -0,0 +1,95 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+)
+
+// listOrganisations получает список организаций
+// @Summary Получает список организаций
+// @Description Получает все организации. Доступно администратору, не привязанному к организации
+// @Tags Organisations
+// @Produce json
+// @Success 200 {array} model.Organisation "Организации"
+// @Failure 400
+// @Security BearerAuth
+// @Router /organisation [get]
+func listOrganisations(w http.ResponseWriter, r *http.Request) {
+	organisations, err := service.ListOrganisations(&repository.OrganisationRepo{})
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, organisations, http.StatusOK)
+}
+
+// createOrganisation создаёт организацию
+// @Summary Создаёт организацию
+// @Description Создаёт организацию, которой принадлежат пользователи и их подписки. Вызывающий работает с данными организации
+// @Description из claim tenant_id токена или организации API-ключа; администратор без организации выбирает её заголовком X-Tenant-Id.
+// @Description Доступно администратору, не привязанному к организации
+// @Tags Organisations
+// @Accept json
+// @Produce json
+// @Param organisation body service.CreateOrganisationRequest true "Параметры организации"
+// @Success 201 {object} model.Organisation "Созданная организация"
+// @Failure 400
+// @Security BearerAuth
+// @Router /organisation [post]
+func createOrganisation(w http.ResponseWriter, r *http.Request) {
+	var req service.CreateOrganisationRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	organisation, err := service.CreateOrganisation(req, &repository.OrganisationRepo{})
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, organisation, http.StatusCreated)
+}
+
+// sumOrganisationPrices получает суммарную стоимость подписок по организациям
+// @Summary Получает суммарную стоимость подписок по организациям
+// @Description Суммирует стоимость подписок каждой организации за период так же, как /subscription/sum-price.
+// @Description Группировка по tenant_id выполняется всегда, group_by добавляет к ней user_id, service_name, month или category.
+// @Description Доступно администратору, не привязанному к организации
+// @Tags Organisations
+// @Accept json
+// @Produce json
+// @Param subscription body service.SumOrganisationsPricesRequest true "Параметры запроса для получения суммарной стоимости подписок по организациям"
+// @Success 200 {array} model.PriceGroup "Суммарная стоимость подписок по организациям"
+// @Failure 400
+// @Security BearerAuth
+// @Router /organisation/sum-price [post]
+func sumOrganisationPrices(w http.ResponseWriter, r *http.Request) {
+	var req service.SumOrganisationsPricesRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	groups, err := service.SumOrganisationsPrices(req, &repository.SubscriptionRepo{})
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, groups, http.StatusOK)
+}
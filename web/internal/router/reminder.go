@@ -0,0 +1,73 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// getReminderSettings получает настройки напоминаний пользователя
+// @Summary Получает настройки напоминаний пользователя
+// @Description Получает настройки напоминаний о продлении и окончании подписок. Если пользователь их не сохранял, напоминания выключены
+// @Tags Reminders
+// @Produce json
+// @Param userId path string true "ИД пользователя"
+// @Success 200 {object} model.ReminderSettings "Настройки напоминаний"
+// @Failure 400
+// @Security BearerAuth
+// @Router /user/{userId}/reminders [get]
+func getReminderSettings(w http.ResponseWriter, r *http.Request) {
+	if !authorizeUser(w, r) {
+		return
+	}
+
+	settings, err := service.GetReminderSettings(chi.URLParam(r, "userId"), reminderRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, settings, http.StatusOK)
+}
+
+// saveReminderSettings сохраняет настройки напоминаний пользователя
+// @Summary Сохраняет настройки напоминаний пользователя
+// @Description Включает или выключает напоминания о продлении и окончании подписок по почте и через вебхук.
+// @Description Напоминание по каждому каналу отправляется один раз за days_before дней до даты.
+// @Description Вебхук на localhost, адреса loopback, частных сетей и link-local отклоняется со статусом 400
+// @Tags Reminders
+// @Accept json
+// @Produce json
+// @Param userId path string true "ИД пользователя"
+// @Param settings body service.SaveReminderSettingsRequest true "Настройки напоминаний"
+// @Success 200 {object} model.ReminderSettings "Сохранённые настройки напоминаний"
+// @Failure 400
+// @Security BearerAuth
+// @Router /user/{userId}/reminders [post]
+func saveReminderSettings(w http.ResponseWriter, r *http.Request) {
+	if !authorizeUser(w, r) {
+		return
+	}
+
+	var req service.SaveReminderSettingsRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := service.SaveReminderSettings(req, chi.URLParam(r, "userId"), reminderRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, settings, http.StatusOK)
+}
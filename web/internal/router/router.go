@@ -2,11 +2,12 @@ package router
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	_ "subsaggregator/docs"
-	"subsaggregator/internal/repository"
+	"subsaggregator/internal/auth"
 	"subsaggregator/internal/service"
 	"subsaggregator/internal/utils"
 
@@ -14,7 +15,19 @@ import (
 	"github.com/swaggo/http-swagger"
 )
 
-func NewRouter() *chi.Mux {
+// maxImportSize максимальный размер импортируемого файла в байтах
+const maxImportSize = 10 << 20
+
+// actorHeader заголовок с идентификатором автора изменений для журнала изменений подписок
+const actorHeader = "X-Actor"
+
+// NewRouter собирает маршруты API. Все маршруты, кроме /ping, /swagger и ленты календаря, требуют JWT или API-ключ,
+// для API-ключа маршрут также требует разрешения. Лента календаря требует токен ленты в параметре token.
+// Если verifier не задан, аутентификация отключена
+// и запросы выполняются с правами администратора.
+// Запросы работают с данными одной организации: организации вызывающего, а для администратора без организации —
+// выбранной заголовком X-Tenant-Id
+func NewRouter(verifier *auth.Verifier) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Get("/swagger/*", httpSwagger.Handler(
@@ -23,17 +36,137 @@ func NewRouter() *chi.Mux {
 
 	r.Get("/ping", pong)
 
-	r.Post("/subscription", createSubscription)
+	r.Group(func(r chi.Router) {
+		r.Use(auth.FeedTokenMiddleware(verifier, calendarFeedResolver))
+		r.Use(auth.ResolveTenant)
+
+		r.Get("/user/{userId}/calendar.ics", getSubscriptionCalendar)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware(verifier, apiKeyResolver))
+		r.Use(auth.ResolveTenant)
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeSubscriptionsRead))
+
+			r.Post("/subscription/list", listSubscription)
+
+			r.Post("/subscription/overlaps", listSubscriptionOverlaps)
+
+			r.Get("/subscription/{subscriptionId}", getOneSubscription)
+
+			r.Get("/subscription/{subscriptionId}/history", getSubscriptionHistory)
+
+			r.Get("/subscription/{subscriptionId}/price", listSubscriptionPrices)
+
+			r.Get("/subscription/{subscriptionId}/shares", listSubscriptionShares)
+
+			r.Get("/subscription/{subscriptionId}/pauses", listSubscriptionPauses)
+
+			r.Get("/user/{userId}/reminders", getReminderSettings)
+
+			r.Get("/user/{userId}/budget", listBudgets)
+
+			r.Get("/catalog", listCatalogServices)
+
+			r.Get("/catalog/{serviceId}", getCatalogService)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeSubscriptionsWrite))
+
+			r.Post("/subscription", createSubscription)
+
+			r.Post("/subscription/batch", batchSubscriptions)
+
+			r.Post("/subscription/import", importSubscriptions)
+
+			r.Post("/subscription/{subscriptionId}", updateSubscription)
+
+			r.Delete("/subscription/{subscriptionId}", deleteSubscription)
+
+			r.Post("/subscription/{subscriptionId}/restore", restoreSubscription)
+
+			r.Post("/subscription/{subscriptionId}/price", changeSubscriptionPrice)
+
+			r.Post("/subscription/{subscriptionId}/shares", saveSubscriptionShares)
+
+			r.Post("/subscription/{subscriptionId}/pause", pauseSubscription)
+
+			r.Post("/subscription/{subscriptionId}/resume", resumeSubscription)
+
+			r.Post("/user/{userId}/calendar/token", createCalendarFeed)
+
+			r.Delete("/user/{userId}/calendar/token", revokeCalendarFeed)
+
+			r.Post("/user/{userId}/reminders", saveReminderSettings)
+
+			r.Post("/user/{userId}/budget", createBudget)
+
+			r.Post("/budget/{budgetId}", updateBudget)
+
+			r.Delete("/budget/{budgetId}", deleteBudget)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeReportsRead))
+
+			r.Post("/subscription/export", exportSubscriptions)
+
+			r.Post("/subscription/sum-price", sumSubscriptionPrices)
+
+			r.Post("/subscription/sum-price/monthly", sumSubscriptionPricesByMonth)
 
-	r.Post("/subscription/list", listSubscription)
+			r.Post("/subscription/sum-price/monthly/export", exportSubscriptionPricesByMonth)
 
-	r.Post("/subscription/sum-price", sumSubscriptionPrices)
+			r.Post("/subscription/forecast", forecastSubscriptionPrices)
 
-	r.Get("/subscription/{subscriptionId}", getOneSubscription)
+			r.Post("/subscription/settlement", settleSubscriptionShares)
 
-	r.Post("/subscription/{subscriptionId}", updateSubscription)
+			r.Get("/user/{userId}/budget/status", getBudgetStatus)
 
-	r.Delete("/subscription/{subscriptionId}", deleteSubscription)
+			r.Get("/exchange-rate", listExchangeRates)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireAdmin)
+
+			r.Get("/webhook", listWebhooks)
+
+			r.Post("/webhook", createWebhook)
+
+			r.Delete("/webhook/{webhookId}", deleteWebhook)
+
+			r.Get("/webhook/{webhookId}/deliveries", listWebhookDeliveries)
+
+			r.Get("/api-key", listApiKeys)
+
+			r.Post("/api-key", createApiKey)
+
+			r.Delete("/api-key/{apiKeyId}", revokeApiKey)
+
+			r.Post("/subscription/overlaps/merge", mergeSubscriptionOverlaps)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequirePlatformAdmin)
+
+			r.Post("/exchange-rate", saveExchangeRates)
+
+			r.Get("/organisation", listOrganisations)
+
+			r.Post("/organisation", createOrganisation)
+
+			r.Post("/organisation/sum-price", sumOrganisationPrices)
+
+			r.Post("/catalog", createCatalogService)
+
+			r.Post("/catalog/{serviceId}", updateCatalogService)
+
+			r.Delete("/catalog/{serviceId}", deleteCatalogService)
+		})
+	})
 
 	return r
 }
@@ -51,13 +184,20 @@ func pong(response http.ResponseWriter, request *http.Request) {
 
 // createSubscription создаёт запись о подписке
 // @Summary Создаёт запись о подписке
-// @Description Создаёт запись о подписке
+// @Description Создаёт запись о подписке. Название сервиса, найденное в каталоге по названию или псевдониму, заменяется каноническим,
+// @Description и запись связывается с сервисом каталога; если стоимость месячной подписки в RUB не указана, подставляется стоимость сервиса по умолчанию.
+// @Description При overlap_mode = reject запись, период которой пересекается с записью того же пользователя на тот же сервис, не создаётся,
+// @Description при overlap_mode = warn пересечения возвращаются в overlaps.
+// @Description Месяцы до trial_end_date включительно не оплачиваются, следующие promo_months месяцев оплачиваются по promo_price
 // @Tags Subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body service.CreateSubscriptionRequest true "Параметры запроса для создания записи о подписке"
+// @Param X-Actor header string false "Автор изменения"
 // @Success 200 {object} model.Subscription "Запись о подписке"
 // @Failure 400
+// @Failure 409
+// @Security BearerAuth
 // @Router /subscription [post]
 func createSubscription(w http.ResponseWriter, r *http.Request) {
 	var req service.CreateSubscriptionRequest
@@ -69,9 +209,18 @@ func createSubscription(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sub, err := service.CreateSubscription(req, &repository.SubscriptionRepo{})
+	if !authorizeUsers(w, r, &req.UserId, nil) {
+		return
+	}
+
+	sub, err := service.CreateSubscription(req, subscriptionRepo(r), budgetRepo(r), requestActor(r))
 
 	if err != nil {
+		if strings.Contains(err.Error(), "conflict") {
+			http.Error(w, "Conflict: "+err.Error(), http.StatusConflict)
+			return
+		}
+
 		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -79,16 +228,116 @@ func createSubscription(w http.ResponseWriter, r *http.Request) {
 	utils.RespondJSON(w, sub, http.StatusOK)
 }
 
+// batchSubscriptions выполняет пакет операций над записями о подписках
+// @Summary Выполняет пакет операций над записями о подписках
+// @Description Создаёт, изменяет и удаляет записи о подписках в одной транзакции и возвращает результат каждой операции.
+// @Description В режиме all_or_nothing ошибка любой операции отменяет весь пакет и возвращается статус 422,
+// @Description в режиме best_effort применяются все операции без ошибок
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param batch body service.BatchSubscriptionsRequest true "Параметры запроса для выполнения пакета операций"
+// @Param X-Actor header string false "Автор изменения"
+// @Success 200 {array} model.BatchResult "Результаты операций"
+// @Failure 400
+// @Failure 422 {array} model.BatchResult "Результаты операций отменённого пакета"
+// @Security BearerAuth
+// @Router /subscription/batch [post]
+func batchSubscriptions(w http.ResponseWriter, r *http.Request) {
+	var req service.BatchSubscriptionsRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := service.BatchSubscriptions(req, subscriptionRepo(r), budgetRepo(r), requestActor(r), auth.FromContext(r.Context()))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if service.BatchRolledBack(results) {
+		utils.RespondJSON(w, results, http.StatusUnprocessableEntity)
+		return
+	}
+
+	utils.RespondJSON(w, results, http.StatusOK)
+}
+
+// importSubscriptions импортирует записи о подписках из CSV
+// @Summary Импортирует записи о подписках из CSV
+// @Description Проверяет строки CSV со столбцами service_name, price, user_id, start_date, end_date (даты в формате MM-YYYY)
+// @Description и создаёт записи о подписках по строкам без ошибок в одной транзакции. Файл передаётся телом запроса
+// @Description или полем file формы multipart/form-data. При dry_run=true записи не создаются
+// @Tags Subscriptions
+// @Accept text/csv,multipart/form-data
+// @Produce json
+// @Param dry_run query bool false "Только проверить файл"
+// @Param file formData file false "CSV файл"
+// @Param X-Actor header string false "Автор изменения"
+// @Success 200 {object} model.ImportReport "Отчёт об импорте"
+// @Failure 400
+// @Security BearerAuth
+// @Router /subscription/import [post]
+func importSubscriptions(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	dryRun := false
+
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		var err error
+
+		dryRun, err = strconv.ParseBool(value)
+
+		if err != nil {
+			http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var file io.Reader = r.Body
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		formFile, _, err := r.FormFile("file")
+
+		if err != nil {
+			http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		defer formFile.Close()
+
+		file = formFile
+	}
+
+	report, err := service.ImportSubscriptions(file, dryRun, subscriptionRepo(r), budgetRepo(r), requestActor(r), auth.FromContext(r.Context()))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, report, http.StatusOK)
+}
+
 // listSubscription получает список записей о подписках за выбранный период с фильтрацией по ИД пользователя и названию сервиса
 // @Summary Получает список записей о подписках
-// @Description Получает список записей о подписках за выбранный период с фильтрацией по ИД пользователя и названию сервиса
+// @Description Получает список записей о подписках за выбранный период с фильтрацией по ИД пользователя и названию сервиса.
+// @Description Список сортируется по sort_by и sort_order и выдаётся постранично по курсорам next_cursor и prev_cursor.
+// @Description Курсор действителен только с теми же sort_by и sort_order, иначе запрос отклоняется со статусом 400.
+// @Description categories ограничивает список категориями сервисов каталога, подписки вне каталога относятся к категории other
 // @Tags Subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body service.ListSubscriptionsRequest true "Параметры запроса для получения списка записей о подписках"
-// @Success 200 {array} model.Subscription "Запись о подписке"
+// @Success 200 {object} model.SubscriptionPage "Страница записей о подписках"
 // @Failure 400
 // @Failure 404
+// @Security BearerAuth
 // @Router /subscription/list [post]
 func listSubscription(w http.ResponseWriter, r *http.Request) {
 	var req service.ListSubscriptionsRequest
@@ -100,7 +349,11 @@ func listSubscription(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	subs, err := service.ListSubscriptions(req, &repository.SubscriptionRepo{})
+	if !authorizeUsers(w, r, &req.UserId, req.UserIds) {
+		return
+	}
+
+	page, err := service.ListSubscriptions(req, subscriptionRepo(r))
 
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -112,18 +365,22 @@ func listSubscription(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.RespondJSON(w, subs, http.StatusOK)
+	utils.RespondJSON(w, page, http.StatusOK)
 }
 
 // sumSubscriptionPrices получает суммарную стоимость подписок за выбранный период с фильтрацией по ИД пользователя и названию сервиса
 // @Summary Получает суммарную стоимость подписок
-// @Description Получает суммарную стоимость подписок за выбранный период с фильтрацией по ИД пользователя и названию сервиса
+// @Description Получает суммарную стоимость подписок за выбранный период с фильтрацией по ИД пользователя и названию сервиса.
+// @Description При указании group_by возвращает список групп с суммарной стоимостью и количеством подписок.
+// @Description Группировка по category объединяет подписки по категориям сервисов каталога, подписки вне каталога относятся к категории other
 // @Tags Subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body service.SumSubscriptionsPricesRequest true "Параметры запроса для получения суммарной стоимости подписок"
 // @Success 200 {integer} 100
+// @Success 200 {array} model.PriceGroup "Суммарная стоимость подписок по группам"
 // @Failure 400
+// @Security BearerAuth
 // @Router /subscription/sum-price [post]
 func sumSubscriptionPrices(w http.ResponseWriter, r *http.Request) {
 	var req service.SumSubscriptionsPricesRequest
@@ -135,7 +392,23 @@ func sumSubscriptionPrices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sumPrice, err := service.SumSubscriptionsPrices(req, &repository.SubscriptionRepo{})
+	if !authorizeUsers(w, r, &req.UserId, req.UserIds) {
+		return
+	}
+
+	if len(req.GroupBy) > 0 {
+		groups, err := service.GroupSubscriptionsPrices(req, subscriptionRepo(r))
+
+		if err != nil {
+			http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		utils.RespondJSON(w, groups, http.StatusOK)
+		return
+	}
+
+	sumPrice, err := service.SumSubscriptionsPrices(req, subscriptionRepo(r))
 
 	if err != nil {
 		http.Error(w, "Bad request: "+err.Error(), http.StatusNotFound)
@@ -145,6 +418,41 @@ func sumSubscriptionPrices(w http.ResponseWriter, r *http.Request) {
 	utils.RespondJSON(w, &sumPrice, http.StatusOK)
 }
 
+// sumSubscriptionPricesByMonth получает помесячную стоимость подписок за выбранный период с фильтрацией по ИД пользователя и названию сервиса
+// @Summary Получает помесячную стоимость подписок
+// @Description Получает помесячную стоимость подписок с разбивкой по сервисам за выбранный период с фильтрацией по ИД пользователя и названию сервиса
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param subscription body service.SumSubscriptionsPricesRequest true "Параметры запроса для получения помесячной стоимости подписок"
+// @Success 200 {array} model.MonthlyPrice "Помесячная стоимость подписок"
+// @Failure 400
+// @Security BearerAuth
+// @Router /subscription/sum-price/monthly [post]
+func sumSubscriptionPricesByMonth(w http.ResponseWriter, r *http.Request) {
+	var req service.SumSubscriptionsPricesRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeUsers(w, r, &req.UserId, req.UserIds) {
+		return
+	}
+
+	monthlyPrices, err := service.SumSubscriptionsPricesByMonth(req, subscriptionRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, monthlyPrices, http.StatusOK)
+}
+
 // getOneSubscription получает запись о подписке
 // @Summary Получает запись о подписке
 // @Description Получает запись о подписке
@@ -155,6 +463,7 @@ func sumSubscriptionPrices(w http.ResponseWriter, r *http.Request) {
 // @Success 200 "Список записей о подписках"
 // @Failure 400
 // @Failure 404
+// @Security BearerAuth
 // @Router /subscription/{subscriptionId} [get]
 func getOneSubscription(w http.ResponseWriter, r *http.Request) {
 	stringSubId := chi.URLParam(r, "subscriptionId")
@@ -165,7 +474,11 @@ func getOneSubscription(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sub, err := service.GetOneSubscription(&repository.SubscriptionRepo{}, subId)
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	sub, err := service.GetOneSubscription(subscriptionRepo(r), subId)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -182,15 +495,23 @@ func getOneSubscription(w http.ResponseWriter, r *http.Request) {
 
 // updateSubscription изменяет запись о подписке
 // @Summary Изменяет запись о подписке
-// @Description Изменяет запись о подписке
+// @Description Изменяет запись о подписке. Название сервиса, найденное в каталоге по названию или псевдониму, заменяется каноническим.
+// @Description При overlap_mode = reject изменение, после которого период пересекается с записью того же пользователя на тот же сервис, отклоняется,
+// @Description при overlap_mode = warn пересечения возвращаются в overlaps.
+// @Description Месяцы до trial_end_date включительно не оплачиваются, следующие promo_months месяцев оплачиваются по promo_price.
+// @Description Новая стоимость записывается в историю стоимости с текущего месяца, прошлые месяцы оплачиваются по прежней стоимости.
+// @Description Стоимость закончившейся подписки изменяется через /subscription/{subscriptionId}/price
 // @Tags Subscriptions
 // @Accept json
 // @Produce json
 // @Param subscriptionId path int true "Идентификатор пользователя"
 // @Param subscription body service.UpdateSubscriptionRequest true "Параметры запроса для изменения записи о подписке"
+// @Param X-Actor header string false "Автор изменения"
 // @Success 200 {object} model.Subscription "Запись о подписке"
 // @Failure 400
 // @Failure 404
+// @Failure 409
+// @Security BearerAuth
 // @Router /subscription/{subscriptionId} [post]
 func updateSubscription(w http.ResponseWriter, r *http.Request) {
 	stringSubId := chi.URLParam(r, "subscriptionId")
@@ -201,6 +522,10 @@ func updateSubscription(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
 	var req service.UpdateSubscriptionRequest
 
 	err = json.NewDecoder(r.Body).Decode(&req)
@@ -210,9 +535,18 @@ func updateSubscription(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sub, err := service.UpdateSubscription(req, &repository.SubscriptionRepo{}, subId)
+	if !authorizeUsers(w, r, &req.UserId, nil) {
+		return
+	}
+
+	sub, err := service.UpdateSubscription(req, subscriptionRepo(r), budgetRepo(r), subId, requestActor(r))
 
 	if err != nil {
+		if strings.Contains(err.Error(), "conflict") {
+			http.Error(w, "Conflict: "+err.Error(), http.StatusConflict)
+			return
+		}
+
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
 			return
@@ -232,9 +566,11 @@ func updateSubscription(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param subscriptionId path int true "Идентификатор пользователя"
+// @Param X-Actor header string false "Автор изменения"
 // @Success 204
 // @Failure 400
 // @Failure 404
+// @Security BearerAuth
 // @Router /subscription/{subscriptionId} [delete]
 func deleteSubscription(w http.ResponseWriter, r *http.Request) {
 	stringSubId := chi.URLParam(r, "subscriptionId")
@@ -245,7 +581,11 @@ func deleteSubscription(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = service.DeleteSubscription(&repository.SubscriptionRepo{}, subId)
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	err = service.DeleteSubscription(subscriptionRepo(r), budgetRepo(r), subId, requestActor(r))
 
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -259,3 +599,192 @@ func deleteSubscription(w http.ResponseWriter, r *http.Request) {
 
 	utils.RespondJSON(w, nil, http.StatusNoContent)
 }
+
+// restoreSubscription восстанавливает удалённую запись о подписке
+// @Summary Восстанавливает запись о подписке
+// @Description Восстанавливает удалённую запись о подписке. Если действует ограничение subscriptions_no_overlap,
+// @Description запись, период которой пересекается с другой записью того же пользователя на тот же сервис, не восстанавливается
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param subscriptionId path int true "Идентификатор записи о подписке"
+// @Param X-Actor header string false "Автор изменения"
+// @Success 200 {object} model.Subscription "Запись о подписке"
+// @Failure 400
+// @Failure 404
+// @Failure 409
+// @Security BearerAuth
+// @Router /subscription/{subscriptionId}/restore [post]
+func restoreSubscription(w http.ResponseWriter, r *http.Request) {
+	stringSubId := chi.URLParam(r, "subscriptionId")
+	subId, err := strconv.Atoi(stringSubId)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	sub, err := service.RestoreSubscription(subscriptionRepo(r), budgetRepo(r), subId, requestActor(r))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "conflict") {
+			http.Error(w, "Conflict: "+err.Error(), http.StatusConflict)
+			return
+		}
+
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, sub, http.StatusOK)
+}
+
+// getSubscriptionHistory получает журнал изменений записи о подписке
+// @Summary Получает журнал изменений записи о подписке
+// @Description Получает журнал создания, изменения, удаления и восстановления записи о подписке с состоянием до и после изменения,
+// @Description а также изменений стоимости через /subscription/{subscriptionId}/price
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param subscriptionId path int true "Идентификатор записи о подписке"
+// @Success 200 {array} model.SubscriptionAudit "Журнал изменений"
+// @Failure 400
+// @Security BearerAuth
+// @Router /subscription/{subscriptionId}/history [get]
+func getSubscriptionHistory(w http.ResponseWriter, r *http.Request) {
+	stringSubId := chi.URLParam(r, "subscriptionId")
+	subId, err := strconv.Atoi(stringSubId)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	entries, err := service.GetSubscriptionHistory(subscriptionRepo(r), subId)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, entries, http.StatusOK)
+}
+
+// listSubscriptionPrices получает историю изменения стоимости подписки
+// @Summary Получает историю стоимости подписки
+// @Description Получает историю изменения стоимости подписки
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param subscriptionId path int true "Идентификатор записи о подписке"
+// @Success 200 {array} model.SubscriptionPrice "История стоимости подписки"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /subscription/{subscriptionId}/price [get]
+func listSubscriptionPrices(w http.ResponseWriter, r *http.Request) {
+	stringSubId := chi.URLParam(r, "subscriptionId")
+	subId, err := strconv.Atoi(stringSubId)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	prices, err := service.ListSubscriptionPrices(subscriptionRepo(r), subId)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, prices, http.StatusOK)
+}
+
+// changeSubscriptionPrice изменяет стоимость подписки с указанного месяца
+// @Summary Изменяет стоимость подписки
+// @Description Записывает новую стоимость подписки, действующую с указанного месяца. Суммарная стоимость за предыдущие месяцы не меняется
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param subscriptionId path int true "Идентификатор записи о подписке"
+// @Param price body service.ChangeSubscriptionPriceRequest true "Параметры запроса для изменения стоимости подписки"
+// @Param X-Actor header string false "Автор изменения"
+// @Success 200 {object} model.SubscriptionPrice "Изменение стоимости подписки"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /subscription/{subscriptionId}/price [post]
+func changeSubscriptionPrice(w http.ResponseWriter, r *http.Request) {
+	stringSubId := chi.URLParam(r, "subscriptionId")
+	subId, err := strconv.Atoi(stringSubId)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	var req service.ChangeSubscriptionPriceRequest
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	price, err := service.ChangeSubscriptionPrice(req, subscriptionRepo(r), budgetRepo(r), subId, requestActor(r))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, price, http.StatusOK)
+}
+
+// requestActor возвращает автора изменений: ИД аутентифицированного пользователя, API-ключ без пользователя,
+// а если аутентификация отключена — значение заголовка запроса
+func requestActor(r *http.Request) string {
+	principal := auth.FromContext(r.Context())
+
+	switch {
+	case principal.UserId != "":
+		return principal.UserId
+	case principal.ApiKeyId != 0:
+		return "api-key:" + strconv.Itoa(principal.ApiKeyId)
+	}
+
+	return r.Header.Get(actorHeader)
+}
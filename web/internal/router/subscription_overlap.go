@@ -0,0 +1,89 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+)
+
+// listSubscriptionOverlaps находит пересекающиеся записи о подписках
+// @Summary Находит пересекающиеся записи о подписках
+// @Description Находит пары записей о подписках одного пользователя на один сервис, периоды которых пересекаются, и месяцы пересечения.
+// @Description В суммарной стоимости за эти месяцы учитывается только одна запись из пары.
+// @Description start_date и end_date ограничивают месяцы пересечения
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param overlaps body service.ListSubscriptionOverlapsRequest true "Параметры поиска пересечений"
+// @Success 200 {array} model.SubscriptionOverlap "Пересечения записей о подписках"
+// @Failure 400
+// @Security BearerAuth
+// @Router /subscription/overlaps [post]
+func listSubscriptionOverlaps(w http.ResponseWriter, r *http.Request) {
+	var req service.ListSubscriptionOverlapsRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeUsers(w, r, &req.UserId, req.UserIds) {
+		return
+	}
+
+	overlaps, err := service.ListSubscriptionOverlaps(req, subscriptionRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, overlaps, http.StatusOK)
+}
+
+// mergeSubscriptionOverlaps объединяет пересекающиеся записи о подписках
+// @Summary Объединяет пересекающиеся записи о подписках
+// @Description Объединяет каждую группу записей о подписках одного пользователя на один сервис, связанных пересечениями периодов,
+// @Description в самую раннюю запись группы с периодом от начала самой ранней до окончания самой поздней записи. Остальные записи удаляются.
+// @Description Позволяет применить к существующим данным ограничение subscriptions_no_overlap. При dry_run объединение только рассчитывается.
+// @Description Если стоимость или доли удаляемой записи отличаются от самой ранней записи или у любой из них есть паузы в объединённом периоде,
+// @Description различия возвращаются в conflicts, а без dry_run объединение отклоняется со статусом 409 и ни одна группа не объединяется.
+// @Description Доступно администратору
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param merge body service.MergeSubscriptionOverlapsRequest true "Параметры объединения"
+// @Param X-Actor header string false "Автор изменения"
+// @Success 200 {array} model.SubscriptionMerge "Объединённые записи о подписках"
+// @Failure 400
+// @Failure 409
+// @Security BearerAuth
+// @Router /subscription/overlaps/merge [post]
+func mergeSubscriptionOverlaps(w http.ResponseWriter, r *http.Request) {
+	var req service.MergeSubscriptionOverlapsRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	merges, err := service.MergeSubscriptionOverlaps(req, subscriptionRepo(r), budgetRepo(r), requestActor(r))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "conflict") {
+			http.Error(w, "Conflict: "+err.Error(), http.StatusConflict)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, merges, http.StatusOK)
+}
@@ -0,0 +1,155 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// listSubscriptionPauses получает паузы подписки
+// @Summary Получает паузы подписки
+// @Description Получает паузы подписки. Месяцы паузы не учитываются в суммарной стоимости и в фильтрах по периоду
+// @Tags Subscriptions
+// @Produce json
+// @Param subscriptionId path int true "Идентификатор записи о подписке"
+// @Success 200 {array} model.SubscriptionPause "Паузы подписки"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /subscription/{subscriptionId}/pauses [get]
+func listSubscriptionPauses(w http.ResponseWriter, r *http.Request) {
+	subId, err := strconv.Atoi(chi.URLParam(r, "subscriptionId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	pauses, err := service.ListSubscriptionPauses(subscriptionRepo(r), subId)
+
+	respondSubscriptionPauses(w, pauses, err)
+}
+
+// pauseSubscription приостанавливает подписку
+// @Summary Приостанавливает подписку
+// @Description Приостанавливает подписку с месяца start_date по месяц end_date включительно, без end_date — до возобновления.
+// @Description Месяцы паузы не оплачиваются: они не учитываются в суммарной стоимости, а продления в них не попадают в календарь и напоминания.
+// @Description Пауза, пересекающаяся с другой паузой подписки, отклоняется со статусом 409.
+// @Description Пауза записывается в журнал изменений и отправляется по вебхукам событием subscription.paused
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param subscriptionId path int true "Идентификатор записи о подписке"
+// @Param pause body service.PauseSubscriptionRequest true "Параметры паузы"
+// @Success 200 {array} model.SubscriptionPause "Паузы подписки"
+// @Failure 400
+// @Failure 404
+// @Failure 409
+// @Security BearerAuth
+// @Router /subscription/{subscriptionId}/pause [post]
+func pauseSubscription(w http.ResponseWriter, r *http.Request) {
+	subId, err := strconv.Atoi(chi.URLParam(r, "subscriptionId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	var req service.PauseSubscriptionRequest
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pauses, err := service.PauseSubscription(req, subscriptionRepo(r), budgetRepo(r), subId, requestActor(r))
+
+	respondSubscriptionPauses(w, pauses, err)
+}
+
+// resumeSubscription возобновляет подписку
+// @Summary Возобновляет подписку
+// @Description Возобновляет подписку с месяца resume_date, по умолчанию с текущего месяца: пауза, на которую он приходится,
+// @Description заканчивается в предыдущем месяце. Возобновление в первый месяц паузы отменяет паузу.
+// @Description Возобновление записывается в журнал изменений и отправляется по вебхукам событием subscription.resumed
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param subscriptionId path int true "Идентификатор записи о подписке"
+// @Param resume body service.ResumeSubscriptionRequest false "Параметры возобновления"
+// @Success 200 {array} model.SubscriptionPause "Паузы подписки"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /subscription/{subscriptionId}/resume [post]
+func resumeSubscription(w http.ResponseWriter, r *http.Request) {
+	subId, err := strconv.Atoi(chi.URLParam(r, "subscriptionId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	var req service.ResumeSubscriptionRequest
+
+	// Тело запроса необязательно: без него подписка возобновляется с текущего месяца
+	err = json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pauses, err := service.ResumeSubscription(req, time.Now(), subscriptionRepo(r), budgetRepo(r), subId, requestActor(r))
+
+	respondSubscriptionPauses(w, pauses, err)
+}
+
+// respondSubscriptionPauses отвечает паузами подписки или ошибкой. Отсутствующая запись возвращается со статусом 404,
+// пересечение пауз — со статусом 409
+func respondSubscriptionPauses(w http.ResponseWriter, pauses []model.SubscriptionPause, err error) {
+	if err == nil && pauses == nil {
+		http.Error(w, "Not found: subscription not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		if strings.Contains(err.Error(), "conflict") {
+			http.Error(w, "Conflict: "+err.Error(), http.StatusConflict)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, pauses, http.StatusOK)
+}
@@ -0,0 +1,140 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// listSubscriptionShares получает доли участников подписки
+// @Summary Получает доли участников подписки
+// @Description Получает доли участников в стоимости подписки. Владелец подписки несёт стоимость, оставшуюся после долей участников
+// @Tags Subscriptions
+// @Produce json
+// @Param subscriptionId path int true "Идентификатор записи о подписке"
+// @Success 200 {array} model.SubscriptionShare "Доли участников подписки"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /subscription/{subscriptionId}/shares [get]
+func listSubscriptionShares(w http.ResponseWriter, r *http.Request) {
+	subId, err := strconv.Atoi(chi.URLParam(r, "subscriptionId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	shares, err := service.ListSubscriptionShares(subscriptionRepo(r), subId)
+
+	respondSubscriptionShares(w, shares, err)
+}
+
+// saveSubscriptionShares заменяет доли участников подписки
+// @Summary Заменяет доли участников подписки
+// @Description Заменяет доли участников в стоимости подписки. Доля задаётся процентом или фиксированной суммой за расчётный период.
+// @Description Суммарная стоимость подписок и группировка по пользователям распределяют стоимость каждого месяца по долям, остаток относится на владельца.
+// @Description Если доли превышают стоимость месяца, они уменьшаются пропорционально.
+// @Description Изменение долей записывается в журнал изменений действием change_shares и отправляется по вебхукам событием subscription.updated,
+// @Description после него проверяются пороги бюджетов владельца и участников до и после изменения
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param subscriptionId path int true "Идентификатор записи о подписке"
+// @Param shares body service.SaveSubscriptionSharesRequest true "Доли участников подписки"
+// @Success 200 {array} model.SubscriptionShare "Доли участников подписки"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /subscription/{subscriptionId}/shares [post]
+func saveSubscriptionShares(w http.ResponseWriter, r *http.Request) {
+	subId, err := strconv.Atoi(chi.URLParam(r, "subscriptionId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeSubscription(w, r, subId) {
+		return
+	}
+
+	var req service.SaveSubscriptionSharesRequest
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shares, err := service.SaveSubscriptionShares(req, subscriptionRepo(r), budgetRepo(r), subId, requestActor(r))
+
+	respondSubscriptionShares(w, shares, err)
+}
+
+// settleSubscriptionShares рассчитывает долги пользователей по долям в подписках
+// @Summary Рассчитывает долги по долям в подписках
+// @Description Рассчитывает, кто кому должен за период: участник должен владельцу подписки свою долю стоимости каждого месяца.
+// @Description Встречные долги двух пользователей взаимозачитываются. Если указаны пользователи, возвращаются только их долги и долги перед ними
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Param settlement body service.SettleSubscriptionSharesRequest true "Параметры расчёта"
+// @Success 200 {object} model.SettlementReport "Долги пользователей"
+// @Failure 400
+// @Security BearerAuth
+// @Router /subscription/settlement [post]
+func settleSubscriptionShares(w http.ResponseWriter, r *http.Request) {
+	var req service.SettleSubscriptionSharesRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeUsers(w, r, &req.UserId, req.UserIds) {
+		return
+	}
+
+	report, err := service.SettleSubscriptionShares(req, subscriptionRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, report, http.StatusOK)
+}
+
+// respondSubscriptionShares отвечает долями участников подписки или ошибкой. Отсутствующая запись возвращается со статусом 404
+func respondSubscriptionShares(w http.ResponseWriter, shares []model.SubscriptionShare, err error) {
+	if err == nil && shares == nil {
+		http.Error(w, "Not found: subscription not found", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, shares, http.StatusOK)
+}
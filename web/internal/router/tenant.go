@@ -0,0 +1,37 @@
+package router
+
+import (
+	"net/http"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/repository"
+)
+
+// subscriptionRepo возвращает хранилище записей о подписках организации запроса
+func subscriptionRepo(r *http.Request) *repository.SubscriptionRepo {
+	return &repository.SubscriptionRepo{TenantId: auth.TenantFromContext(r.Context())}
+}
+
+// budgetRepo возвращает хранилище бюджетов организации запроса
+func budgetRepo(r *http.Request) *repository.BudgetRepo {
+	return &repository.BudgetRepo{TenantId: auth.TenantFromContext(r.Context())}
+}
+
+// reminderRepo возвращает хранилище настроек напоминаний организации запроса
+func reminderRepo(r *http.Request) *repository.ReminderRepo {
+	return &repository.ReminderRepo{TenantId: auth.TenantFromContext(r.Context())}
+}
+
+// webhookRepo возвращает хранилище вебхуков организации запроса
+func webhookRepo(r *http.Request) *repository.WebhookRepo {
+	return &repository.WebhookRepo{TenantId: auth.TenantFromContext(r.Context())}
+}
+
+// calendarFeedRepo возвращает хранилище токенов лент календаря организации запроса
+func calendarFeedRepo(r *http.Request) *repository.CalendarFeedRepo {
+	return &repository.CalendarFeedRepo{TenantId: auth.TenantFromContext(r.Context())}
+}
+
+// apiKeyRepo возвращает хранилище API-ключей организации запроса
+func apiKeyRepo(r *http.Request) *repository.ApiKeyRepo {
+	return &repository.ApiKeyRepo{TenantId: auth.TenantFromContext(r.Context())}
+}
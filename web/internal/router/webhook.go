@@ -0,0 +1,147 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// createWebhook регистрирует вебхук
+// @Summary Регистрирует вебхук
+// @Description Регистрирует адрес, на который POST-запросом с JSON отправляются события создания, изменения, удаления
+// @Description и восстановления записей о подписках. Запрос подписывается заголовком X-Webhook-Signature:
+// @Description sha256= и HMAC-SHA256 строки "<X-Webhook-Timestamp>.<тело запроса>" на ключе secret.
+// @Description Ключ возвращается только в ответе на регистрацию.
+// @Description Адрес на localhost, в сетях loopback, частных и link-local отклоняется со статусом 400, при отправке адрес проверяется повторно
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body service.CreateWebhookRequest true "Параметры вебхука"
+// @Success 201 {object} model.WebhookEndpoint "Зарегистрированный вебхук"
+// @Failure 400
+// @Security BearerAuth
+// @Router /webhook [post]
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	var req service.CreateWebhookRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	endpoint, err := service.CreateWebhook(req, webhookRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, endpoint, http.StatusCreated)
+}
+
+// listWebhooks получает список вебхуков
+// @Summary Получает список вебхуков
+// @Description Получает список зарегистрированных вебхуков без ключей подписи
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} model.WebhookEndpoint "Вебхуки"
+// @Failure 400
+// @Security BearerAuth
+// @Router /webhook [get]
+func listWebhooks(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := service.ListWebhooks(webhookRepo(r))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, endpoints, http.StatusOK)
+}
+
+// deleteWebhook удаляет вебхук
+// @Summary Удаляет вебхук
+// @Description Удаляет вебхук. Недоставленные по нему события отменяются, журнал доставок сохраняется
+// @Tags Webhooks
+// @Param webhookId path int true "ИД вебхука"
+// @Success 204
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /webhook/{webhookId} [delete]
+func deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId, err := strconv.Atoi(chi.URLParam(r, "webhookId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = service.DeleteWebhook(webhookId, webhookRepo(r))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, nil, http.StatusNoContent)
+}
+
+// listWebhookDeliveries получает журнал доставок по вебхуку
+// @Summary Получает журнал доставок по вебхуку
+// @Description Получает доставки событий по вебхуку от новых к старым с числом попыток, кодом ответа и ошибкой последней попытки
+// @Tags Webhooks
+// @Produce json
+// @Param webhookId path int true "ИД вебхука"
+// @Param status query string false "Статус доставки: pending, delivered или failed"
+// @Param limit query int false "Количество доставок, по умолчанию 100, не больше 1000"
+// @Success 200 {array} model.WebhookDelivery "Доставки"
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /webhook/{webhookId}/deliveries [get]
+func listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhookId, err := strconv.Atoi(chi.URLParam(r, "webhookId"))
+
+	if err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+
+		if err != nil {
+			http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	deliveries, err := service.ListWebhookDeliveries(webhookId, r.URL.Query().Get("status"), limit, webhookRepo(r))
+
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Not found: "+err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(w, deliveries, http.StatusOK)
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/notifier"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/service"
+	"time"
+)
+
+// defaultInterval интервал между проверками подписок по умолчанию
+const defaultInterval = time.Hour
+
+// Scheduler периодически проверяет подписки и отправляет напоминания о продлении и окончании
+type Scheduler struct {
+	// Интервал между проверками. Если не задан, используется defaultInterval
+	Interval time.Duration
+
+	SubscriptionRepo repository.SubscriptionRepository
+	ReminderRepo     repository.ReminderRepository
+	Notifiers        []notifier.Notifier
+
+	// Источник текущего времени, для тестов. Если не задан, используется time.Now
+	Now func() time.Time
+}
+
+// Run выполняет проверку сразу и затем через каждый интервал, пока не будет отменён ctx
+func (s *Scheduler) Run(ctx context.Context) {
+	slog.Info("Планировщик напоминаний запущен")
+
+	every(ctx, s.Interval, s.Tick)
+
+	slog.Info("Планировщик напоминаний остановлен")
+}
+
+// Tick выполняет одну проверку подписок и отправку напоминаний
+func (s *Scheduler) Tick(ctx context.Context) {
+	now := time.Now
+
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	_, err := service.SendReminders(ctx, now(), s.SubscriptionRepo, s.ReminderRepo, s.Notifiers)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("напоминания отправлены не полностью: %w", err).Error())
+	}
+}
+
+// every вызывает tick сразу и затем через каждый interval, пока не будет отменён ctx.
+// Если interval не задан, используется defaultInterval
+func every(ctx context.Context, interval time.Duration, tick func(ctx context.Context)) {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/service"
+	"subsaggregator/internal/webhook"
+	"time"
+)
+
+// defaultWebhookInterval интервал между проходами доставки событий по вебхукам по умолчанию
+const defaultWebhookInterval = 10 * time.Second
+
+// WebhookDispatcher периодически доставляет события жизненного цикла подписок из outbox по вебхукам
+type WebhookDispatcher struct {
+	// Интервал между проходами. Если не задан, используется defaultWebhookInterval
+	Interval time.Duration
+
+	Repo   repository.WebhookRepository
+	Sender *webhook.Sender
+
+	// Источник текущего времени, для тестов. Если не задан, используется time.Now
+	Now func() time.Time
+}
+
+// Run выполняет проход сразу и затем через каждый интервал, пока не будет отменён ctx
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	interval := d.Interval
+
+	if interval <= 0 {
+		interval = defaultWebhookInterval
+	}
+
+	slog.Info("Доставка событий по вебхукам запущена")
+
+	every(ctx, interval, d.Tick)
+
+	slog.Info("Доставка событий по вебхукам остановлена")
+}
+
+// Tick выполняет один проход доставки событий
+func (d *WebhookDispatcher) Tick(ctx context.Context) {
+	now := time.Now
+
+	if d.Now != nil {
+		now = d.Now
+	}
+
+	_, err := service.DeliverWebhooks(ctx, now(), d.Repo, d.Sender)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("события доставлены по вебхукам не полностью: %w", err).Error())
+	}
+}
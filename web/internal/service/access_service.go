@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/repository"
+)
+
+// AuthorizeUsers проверяет фильтр запроса по пользователям и возвращает user_id, который нужно подставить в запрос.
+// Администратору доступны все пользователи, остальные вызывающие могут запрашивать только свои подписки,
+// поэтому для них фильтр всегда ограничивается их ИД
+func AuthorizeUsers(principal auth.Principal, userId string, userIds []string) (string, error) {
+	if principal.Admin {
+		return userId, nil
+	}
+
+	if principal.UserId == "" {
+		return "", fmt.Errorf("forbidden: authentication is required")
+	}
+
+	for _, id := range append([]string{userId}, userIds...) {
+		if id != "" && id != principal.UserId {
+			return "", fmt.Errorf("forbidden: access to subscriptions of user %s is denied", id)
+		}
+	}
+
+	return principal.UserId, nil
+}
+
+// AuthorizeSubscription проверяет, что вызывающий может работать с записью о подписке, в том числе удалённой.
+// Чужая запись не раскрывается и считается ненайденной
+func AuthorizeSubscription(principal auth.Principal, subId int, repo repository.SubscriptionRepository) error {
+	if principal.Admin {
+		return nil
+	}
+
+	sub, _ := repo.FindById(subId)
+
+	if sub == nil {
+		sub, _ = repo.FindDeletedById(subId)
+	}
+
+	if sub == nil || !principal.Owns(sub.UserId) {
+		return fmt.Errorf("subscription not found")
+	}
+
+	return nil
+}
+
+// AuthorizeBudget проверяет, что вызывающий может работать с бюджетом. Чужой бюджет считается ненайденным
+func AuthorizeBudget(principal auth.Principal, budgetId int, repo repository.BudgetRepository) error {
+	if principal.Admin {
+		return nil
+	}
+
+	budget, err := repo.FindById(budgetId)
+
+	if err != nil {
+		return err
+	}
+
+	if budget == nil || !principal.Owns(budget.UserId) {
+		return fmt.Errorf("budget not found")
+	}
+
+	return nil
+}
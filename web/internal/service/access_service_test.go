@@ -0,0 +1,110 @@
+package service
+
+import (
+	"database/sql"
+	"strings"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestAuthorizeUsers(t *testing.T) {
+	user := auth.Principal{UserId: "Тестовый UUID"}
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		userId    string
+		userIds   []string
+		want      string
+		wantErr   bool
+	}{
+		{"администратор без фильтра", auth.Principal{Admin: true}, "", nil, "", false},
+		{"администратор с чужим пользователем", auth.Principal{Admin: true}, "Другой UUID", nil, "Другой UUID", false},
+		{"пользователь без фильтра", user, "", nil, "Тестовый UUID", false},
+		{"пользователь со своим ИД", user, "", []string{"Тестовый UUID"}, "Тестовый UUID", false},
+		{"пользователь с чужим ИД", user, "Другой UUID", nil, "", true},
+		{"пользователь с чужим ИД в списке", user, "", []string{"Тестовый UUID", "Другой UUID"}, "", true},
+		{"без аутентификации", auth.Principal{}, "", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AuthorizeUsers(tt.principal, tt.userId, tt.userIds)
+
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("AuthorizeUsers() = %q, %v, want %q, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizeSubscriptionAccess(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Count:         0,
+	}
+
+	startDate := &utils.Date{NullTime: sql.NullTime{Time: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+
+	own, _ := CreateSubscription(CreateSubscriptionRequest{ServiceName: "Видео", Price: 500, UserId: "Тестовый UUID", StartDate: startDate}, subscriptionRepo, nil, "")
+	other, _ := CreateSubscription(CreateSubscriptionRequest{ServiceName: "Видео", Price: 500, UserId: "Другой UUID", StartDate: startDate}, subscriptionRepo, nil, "")
+	deleted, _ := CreateSubscription(CreateSubscriptionRequest{ServiceName: "Музыка", Price: 200, UserId: "Тестовый UUID", StartDate: startDate}, subscriptionRepo, nil, "")
+
+	DeleteSubscription(subscriptionRepo, nil, deleted.Id, "")
+
+	user := auth.Principal{UserId: "Тестовый UUID"}
+
+	if err := AuthorizeSubscription(user, own.Id, subscriptionRepo); err != nil {
+		t.Errorf("AuthorizeSubscription() своей подписки error = %v", err)
+	}
+
+	if err := AuthorizeSubscription(user, deleted.Id, subscriptionRepo); err != nil {
+		t.Errorf("AuthorizeSubscription() своей удалённой подписки error = %v", err)
+	}
+
+	if err := AuthorizeSubscription(user, other.Id, subscriptionRepo); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("AuthorizeSubscription() чужой подписки error = %v", err)
+	}
+
+	if err := AuthorizeSubscription(auth.Principal{Admin: true}, other.Id, subscriptionRepo); err != nil {
+		t.Errorf("AuthorizeSubscription() администратором error = %v", err)
+	}
+
+	results, err := BatchSubscriptions(BatchSubscriptionsRequest{
+		Mode: model.BatchModeBestEffort,
+		Operations: []BatchSubscriptionOperation{
+			{Action: model.AuditActionDelete, Id: own.Id},
+			{Action: model.AuditActionDelete, Id: other.Id},
+			{Action: model.AuditActionCreate, Subscription: &CreateSubscriptionRequest{ServiceName: "Книги", Price: 300, UserId: "Другой UUID", StartDate: startDate}},
+			{Action: model.AuditActionUpdate, Id: deleted.Id, Subscription: &CreateSubscriptionRequest{ServiceName: "Музыка", Price: 200, UserId: "Тестовый UUID", StartDate: startDate}},
+		},
+	}, subscriptionRepo, nil, "", user)
+
+	if err != nil {
+		t.Errorf("BatchSubscriptions() error = %v", err)
+		return
+	}
+
+	wantStatuses := []string{model.BatchStatusOk, model.BatchStatusFailed, model.BatchStatusFailed, model.BatchStatusFailed}
+
+	for i, result := range results {
+		if result.Status != wantStatuses[i] {
+			t.Errorf("BatchSubscriptions() result %d = %+v, want status %s", i, result, wantStatuses[i])
+		}
+	}
+
+	if subscriptionRepo.Subscriptions[other.Id].DeletedAt != nil {
+		t.Errorf("BatchSubscriptions() удалил чужую подписку")
+	}
+
+	report, err := ImportSubscriptions(strings.NewReader("Кино,400,Тестовый UUID,07-2025,12-2025\nКино,400,Другой UUID,07-2025,12-2025\n"), true, subscriptionRepo, nil, "", user)
+
+	if err != nil || report.ValidRows != 1 || len(report.Errors) != 1 || report.Errors[0].Row != 2 || report.Errors[0].Column != "user_id" {
+		t.Errorf("ImportSubscriptions() = %+v, %v", report, err)
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"time"
+)
+
+const (
+	// apiKeyPrefix начало всех API-ключей, по которому их легко найти в логах и конфигурации
+	apiKeyPrefix = "sa_"
+
+	// apiKeyDisplayLength длина начала ключа, которое сохраняется для опознания ключа в списке
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+)
+
+// CreateApiKeyRequest Модель данных для создания API-ключа
+//
+//	@modelId	create-api-key-request
+//	@required	Name Scopes
+type CreateApiKeyRequest struct {
+	Name string `json:"name" example:"Ночная выгрузка отчётов"`
+
+	// Пользователь, от имени которого действует ключ. Если не указан, ключ действует для всех пользователей организации
+	UserId string `json:"user_id,omitempty"`
+
+	Scopes    []string   `json:"scopes" example:"subscriptions:read,reports:read"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateApiKey создаёт API-ключ и возвращает его целиком. В базе хранится только хеш ключа,
+// поэтому получить ключ повторно нельзя
+func CreateApiKey(req CreateApiKeyRequest, now time.Time, repo repository.ApiKeyRepository) (*model.ApiKey, error) {
+	name := strings.TrimSpace(req.Name)
+
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("scopes are required")
+	}
+
+	scopes := []string{}
+
+	for _, scope := range req.Scopes {
+		if !slices.Contains(auth.Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope: %s", scope)
+		}
+
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.UserId != "" && slices.Contains(scopes, auth.ScopeAdmin) {
+		return nil, fmt.Errorf("scope %s is not allowed for a key of a single user", auth.ScopeAdmin)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+
+	apiKey := &model.ApiKey{
+		Name:      name,
+		Prefix:    key[:apiKeyDisplayLength],
+		UserId:    req.UserId,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	err := repo.Create(apiKey, hashApiKey(key))
+
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey.Key = key
+
+	return apiKey, nil
+}
+
+func ListApiKeys(repo repository.ApiKeyRepository) ([]model.ApiKey, error) {
+	return repo.List()
+}
+
+func RevokeApiKey(id int, repo repository.ApiKeyRepository) (*model.ApiKey, error) {
+	apiKey, err := repo.FindById(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == nil {
+		return nil, fmt.Errorf("api key not found")
+	}
+
+	err = repo.Revoke(apiKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// AuthenticateApiKey возвращает вызывающего по действующему API-ключу. Ключ привязан к организации, в которой создан;
+// ключ без пользователя действует для всех пользователей организации в пределах своих разрешений
+func AuthenticateApiKey(key string, now time.Time, repo repository.ApiKeyRepository) (*auth.Principal, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, fmt.Errorf("invalid api key")
+	}
+
+	apiKey, err := repo.Authenticate(hashApiKey(key), now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == nil {
+		return nil, fmt.Errorf("invalid api key")
+	}
+
+	scopes := apiKey.Scopes
+
+	// Scopes == nil даёт вызывающему доступ ко всем маршрутам, поэтому ключ без разрешений получает пустой список
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return &auth.Principal{
+		UserId:   apiKey.UserId,
+		Admin:    apiKey.UserId == "",
+		Scopes:   scopes,
+		ApiKeyId: apiKey.Id,
+		TenantId: apiKey.TenantId,
+	}, nil
+}
+
+// hashApiKey возвращает SHA-256 ключа. Ключи случайные и длинные, поэтому медленный хеш не нужен
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"strings"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"testing"
+	"time"
+)
+
+func TestApiKeys(t *testing.T) {
+	repo := repository.ApiKeyRepoMock{
+		Keys:   make(map[int]*model.ApiKey),
+		Hashes: make(map[int]string),
+	}
+
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+
+	invalid := []CreateApiKeyRequest{
+		{Name: " ", Scopes: []string{auth.ScopeReportsRead}},
+		{Name: "Отчёты"},
+		{Name: "Отчёты", Scopes: []string{"reports:write"}},
+		{Name: "Отчёты", UserId: "Тестовый UUID", Scopes: []string{auth.ScopeAdmin}},
+		{Name: "Отчёты", Scopes: []string{auth.ScopeReportsRead}, ExpiresAt: &past},
+	}
+
+	for _, req := range invalid {
+		if _, err := CreateApiKey(req, now, repo); err == nil {
+			t.Errorf("CreateApiKey(%+v) не вернула ошибку", req)
+		}
+	}
+
+	reports, err := CreateApiKey(CreateApiKeyRequest{
+		Name:   "Отчёты",
+		Scopes: []string{auth.ScopeReportsRead, auth.ScopeSubscriptionsRead, auth.ScopeReportsRead},
+	}, now, repo)
+
+	if err != nil {
+		t.Errorf("CreateApiKey() error = %v", err)
+		return
+	}
+
+	if !strings.HasPrefix(reports.Key, reports.Prefix) || len(reports.Scopes) != 2 {
+		t.Errorf("CreateApiKey() = %+v", reports)
+	}
+
+	if repo.Hashes[reports.Id] == reports.Key || repo.Keys[reports.Id].Key != "" {
+		t.Errorf("CreateApiKey() сохранил ключ в открытом виде")
+	}
+
+	principal, err := AuthenticateApiKey(reports.Key, now, repo)
+
+	if err != nil || !principal.Admin || principal.ApiKeyId != reports.Id || !principal.HasScope(auth.ScopeReportsRead) || principal.HasScope(auth.ScopeSubscriptionsWrite) {
+		t.Errorf("AuthenticateApiKey() = %+v, %v", principal, err)
+	}
+
+	expiresAt := now.Add(time.Hour)
+
+	user, _ := CreateApiKey(CreateApiKeyRequest{
+		Name:      "Пользовательская задача",
+		UserId:    "Тестовый UUID",
+		Scopes:    []string{auth.ScopeSubscriptionsWrite},
+		ExpiresAt: &expiresAt,
+	}, now, repo)
+
+	principal, err = AuthenticateApiKey(user.Key, now, repo)
+
+	if err != nil || principal.Admin || principal.UserId != "Тестовый UUID" {
+		t.Errorf("AuthenticateApiKey() ключа пользователя = %+v, %v", principal, err)
+	}
+
+	if _, err := AuthenticateApiKey(user.Key, expiresAt, repo); err == nil {
+		t.Errorf("AuthenticateApiKey() просроченного ключа не вернула ошибку")
+	}
+
+	if _, err := AuthenticateApiKey(reports.Key+"0", now, repo); err == nil {
+		t.Errorf("AuthenticateApiKey() неверного ключа не вернула ошибку")
+	}
+
+	if _, err := RevokeApiKey(reports.Id, repo); err != nil {
+		t.Errorf("RevokeApiKey() error = %v", err)
+	}
+
+	if _, err := RevokeApiKey(reports.Id, repo); err == nil {
+		t.Errorf("RevokeApiKey() повторно не вернула ошибку")
+	}
+
+	if _, err := AuthenticateApiKey(reports.Key, now, repo); err == nil {
+		t.Errorf("AuthenticateApiKey() отозванного ключа не вернула ошибку")
+	}
+
+	keys, _ := ListApiKeys(repo)
+
+	if len(keys) != 2 || keys[0].RevokedAt == nil || keys[0].Key != "" {
+		t.Errorf("ListApiKeys() = %+v", keys)
+	}
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"time"
+)
+
+const (
+	// maxBudgetStatusMonths наибольшее количество месяцев в сравнении расходов с бюджетом
+	maxBudgetStatusMonths = 24
+
+	// budgetAlertMonths количество месяцев начиная с текущего, для которых проверяются пороги бюджета после изменения подписки
+	budgetAlertMonths = 12
+)
+
+// SaveBudgetRequest Модель данных для создания и изменения бюджета
+//
+//	@modelId	save-budget-request
+//	@required	Amount
+type SaveBudgetRequest struct {
+	ServiceName string `json:"service_name,omitempty" example:"Yandex Plus"`
+	Amount      int    `json:"amount" example:"1500"`
+	Currency    string `json:"currency,omitempty" example:"RUB"`
+}
+
+// BudgetStatusRequest Модель данных для сравнения расходов с бюджетами
+type BudgetStatusRequest struct {
+	StartDate   utils.Date
+	EndDate     utils.Date
+	BillingMode string
+}
+
+func ListBudgets(userId string, repo repository.BudgetRepository) ([]model.Budget, error) {
+	return repo.ListByUser(userId)
+}
+
+func CreateBudget(req SaveBudgetRequest, userId string, repo repository.BudgetRepository) (*model.Budget, error) {
+	if userId == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	budget := &model.Budget{UserId: userId}
+
+	err := applyBudgetRequest(budget, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.Create(budget)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return budget, nil
+}
+
+func UpdateBudget(req SaveBudgetRequest, id int, repo repository.BudgetRepository) (*model.Budget, error) {
+	budget, err := repo.FindById(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if budget == nil {
+		return nil, fmt.Errorf("budget not found")
+	}
+
+	err = applyBudgetRequest(budget, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.Update(budget)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return budget, nil
+}
+
+func DeleteBudget(id int, repo repository.BudgetRepository) error {
+	budget, err := repo.FindById(id)
+
+	if err != nil {
+		return err
+	}
+
+	if budget == nil {
+		return fmt.Errorf("budget not found")
+	}
+
+	return repo.Delete(budget)
+}
+
+// GetBudgetStatus сравнивает расходы пользователя по месяцам с лимитами его бюджетов.
+// Расходы считаются как в SumSubscriptionsPricesByMonth, подписки без даты окончания учитываются до конца периода.
+// По умолчанию период — текущий месяц
+func GetBudgetStatus(req BudgetStatusRequest, userId string, now time.Time, budgetRepo repository.BudgetRepository, subscriptionRepo repository.SubscriptionRepository) ([]model.BudgetStatus, error) {
+	billingMode, err := normalizeBillingMode(req.BillingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	from := monthOf(now)
+
+	if req.StartDate.Valid {
+		from = monthOf(req.StartDate.Time)
+	}
+
+	to := from
+
+	if req.EndDate.Valid {
+		to = monthOf(req.EndDate.Time)
+	}
+
+	if to.Before(from) {
+		return nil, fmt.Errorf("end_date is before start_date")
+	}
+
+	if monthsBetween(from, to) >= maxBudgetStatusMonths {
+		return nil, fmt.Errorf("period must not exceed %d months", maxBudgetStatusMonths)
+	}
+
+	budgets, err := budgetRepo.ListByUser(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []model.BudgetStatus{}
+
+	for _, budget := range budgets {
+		months, err := budgetMonths(budget, from, to, billingMode, subscriptionRepo)
+
+		if err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, model.BudgetStatus{Budget: budget, Months: months})
+	}
+
+	return statuses, nil
+}
+
+// CheckBudgetAlerts проверяет бюджеты пользователя после создания или изменения подписки и для месяцев,
+// в которых расходы впервые достигли порога, сохраняет предупреждение и событие для вебхуков.
+// Проверяются месяцы действия подписки до и после изменения в пределах budgetAlertMonths от текущего месяца.
+// Если расходы опустились ниже порога, отметка о нём снимается, и при повторном превышении предупреждение отправится снова.
+// Кроме бюджетов владельца проверяются бюджеты участников participants, на которых относятся доли подписки до или после изменения
+func CheckBudgetAlerts(sub model.Subscription, before *model.Subscription, participants []string, now time.Time, budgetRepo repository.BudgetRepository, subscriptionRepo repository.SubscriptionRepository) ([]model.BudgetAlert, error) {
+	windowFrom := monthOf(now)
+	windowTo := windowFrom.AddDate(0, budgetAlertMonths-1, 0)
+
+	changed := []model.Subscription{sub}
+
+	if before != nil {
+		changed = append(changed, *before)
+	}
+
+	from, to := windowTo, windowFrom
+
+	for _, s := range changed {
+		if s.StartDate == nil || !s.StartDate.Valid {
+			continue
+		}
+
+		start, end := monthOf(s.StartDate.Time), windowTo
+
+		if s.EndDate != nil && s.EndDate.Valid {
+			end = monthOf(s.EndDate.Time)
+		}
+
+		from = minTime(from, maxTime(start, windowFrom))
+		to = maxTime(to, minTime(end, windowTo))
+	}
+
+	if to.Before(from) {
+		return nil, nil
+	}
+
+	alerts := []model.BudgetAlert{}
+
+	for _, userId := range budgetUsers(changed, participants) {
+		budgets, err := budgetRepo.ListByUser(userId)
+
+		if err != nil {
+			return alerts, err
+		}
+
+		for _, budget := range budgets {
+			if !budgetCovers(budget, changed, participants) {
+				continue
+			}
+
+			months, err := budgetMonths(budget, from, to, model.BillingModeCharge, subscriptionRepo)
+
+			if err != nil {
+				return alerts, err
+			}
+
+			for _, month := range months {
+				err = budgetRepo.ClearAlerts(budget.Id, *month.Month, month.Percent)
+
+				if err != nil {
+					return alerts, err
+				}
+
+				for _, threshold := range model.BudgetThresholds {
+					if month.Percent < threshold {
+						continue
+					}
+
+					alert := model.BudgetAlert{Budget: budget, Month: month.Month, Threshold: threshold, Spent: month.Spent}
+
+					recorded, err := budgetRepo.RecordAlert(&alert)
+
+					if err != nil {
+						return alerts, err
+					}
+
+					if recorded {
+						alerts = append(alerts, alert)
+					}
+				}
+			}
+		}
+	}
+
+	return alerts, nil
+}
+
+// budgetMonths считает расходы по бюджету за каждый месяц с from по to включительно
+func budgetMonths(budget model.Budget, from time.Time, to time.Time, billingMode string, subscriptionRepo repository.SubscriptionRepository) ([]model.BudgetMonth, error) {
+	filter := repository.SubscriptionFilter{
+		UserIds:      []string{budget.UserId},
+		MaxStartDate: utils.Date{NullTime: sql.NullTime{Time: from, Valid: true}},
+		MinEndDate:   utils.Date{NullTime: sql.NullTime{Time: to, Valid: true}},
+		ProjectUntil: utils.Date{NullTime: sql.NullTime{Time: to, Valid: true}},
+	}
+
+	if budget.ServiceName != "" {
+		filter.ServiceNames = []string{budget.ServiceName}
+	}
+
+	monthlyPrices, err := subscriptionRepo.SumPricesByMonth(filter, budget.Currency, billingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	spent := make(map[time.Time]int)
+
+	for _, monthlyPrice := range monthlyPrices {
+		spent[monthOf(monthlyPrice.Month.Time)] = monthlyPrice.Total
+	}
+
+	months := []model.BudgetMonth{}
+
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		budgetMonth := model.BudgetMonth{
+			Month:   &utils.Date{NullTime: sql.NullTime{Time: month, Valid: true}},
+			Spent:   spent[month],
+			Percent: spent[month] * 100 / budget.Amount,
+		}
+
+		for _, threshold := range model.BudgetThresholds {
+			if budgetMonth.Percent >= threshold {
+				budgetMonth.Threshold = threshold
+			}
+		}
+
+		months = append(months, budgetMonth)
+	}
+
+	return months, nil
+}
+
+// applyBudgetRequest проверяет параметры бюджета и переносит их в запись о бюджете
+func applyBudgetRequest(budget *model.Budget, req SaveBudgetRequest) error {
+	if req.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+
+	currency, err := normalizeCurrency(req.Currency)
+
+	if err != nil {
+		return err
+	}
+
+	budget.ServiceName = req.ServiceName
+	budget.Amount = req.Amount
+	budget.Currency = currency
+
+	return nil
+}
+
+// checkBudgetAlerts проверяет пороги бюджетов budgetRepo после создания, изменения, удаления или восстановления записи,
+// изменения стоимости, долей и приостановки. Проверяются бюджеты владельца и текущих участников подписки,
+// а также участников beforeShares, доли которых были до изменения. Без хранилища бюджетов пороги не проверяются.
+// Ошибка проверки не отменяет изменение подписки и только логируется
+func checkBudgetAlerts(repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, sub model.Subscription, before *model.Subscription, beforeShares []model.SubscriptionShare) {
+	if budgetRepo == nil {
+		return
+	}
+
+	shares, err := repo.ListShares(sub.Id)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("пороги бюджетов не проверены: %w", err).Error())
+
+		return
+	}
+
+	participants := []string{}
+
+	for _, share := range slices.Concat(beforeShares, shares) {
+		if !slices.Contains(participants, share.UserId) {
+			participants = append(participants, share.UserId)
+		}
+	}
+
+	_, err = CheckBudgetAlerts(sub, before, participants, time.Now(), budgetRepo, repo)
+
+	if err != nil {
+		slog.Error(fmt.Errorf("пороги бюджетов не проверены: %w", err).Error())
+	}
+}
+
+// checkBatchBudgetAlerts проверяет пороги бюджетов после выполненных операций пакета
+func checkBatchBudgetAlerts(repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, operations []repository.BatchOperation, results []model.BatchResult) {
+	before := make(map[int]*model.Subscription)
+
+	for _, operation := range operations {
+		before[operation.Index] = operation.Before
+	}
+
+	for _, result := range results {
+		if result.Status == model.BatchStatusOk && result.Subscription != nil {
+			checkBudgetAlerts(repo, budgetRepo, *result.Subscription, before[result.Index], nil)
+		}
+	}
+}
+
+// budgetUsers возвращает владельцев изменённых подписок и участников их долей без повторов
+func budgetUsers(subs []model.Subscription, participants []string) []string {
+	userIds := []string{}
+
+	for _, sub := range subs {
+		if !slices.Contains(userIds, sub.UserId) {
+			userIds = append(userIds, sub.UserId)
+		}
+	}
+
+	for _, userId := range participants {
+		if !slices.Contains(userIds, userId) {
+			userIds = append(userIds, userId)
+		}
+	}
+
+	return userIds
+}
+
+// budgetCovers сообщает, учитывает ли бюджет владельца или участника хотя бы одну из изменённых подписок
+func budgetCovers(budget model.Budget, subs []model.Subscription, participants []string) bool {
+	participant := slices.Contains(participants, budget.UserId)
+
+	for _, sub := range subs {
+		if (sub.UserId == budget.UserId || participant) && (budget.ServiceName == "" || budget.ServiceName == sub.ServiceName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// monthOf возвращает первое число месяца даты
+func monthOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthsBetween возвращает количество месяцев от from до to
+func monthsBetween(from time.Time, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
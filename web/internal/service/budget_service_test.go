@@ -0,0 +1,214 @@
+package service
+
+import (
+	"database/sql"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestBudgets(t *testing.T) {
+	outbox := make(map[string]*model.WebhookEvent)
+
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Count:         0,
+	}
+
+	budgetRepo := repository.BudgetRepoMock{
+		Budgets: make(map[int]*model.Budget),
+		Alerts:  make(map[string]bool),
+		Outbox:  outbox,
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	if _, err := CreateBudget(SaveBudgetRequest{Amount: 0}, "Тестовый UUID", budgetRepo); err == nil {
+		t.Errorf("CreateBudget() с нулевым лимитом не вернула ошибку")
+	}
+
+	total, err := CreateBudget(SaveBudgetRequest{Amount: 1000}, "Тестовый UUID", budgetRepo)
+
+	if err != nil || total.Currency != model.DefaultCurrency {
+		t.Errorf("CreateBudget() = %+v, %v", total, err)
+		return
+	}
+
+	if _, err := CreateBudget(SaveBudgetRequest{Amount: 2000}, "Тестовый UUID", budgetRepo); err == nil {
+		t.Errorf("CreateBudget() повторного общего бюджета не вернула ошибку")
+	}
+
+	music, _ := CreateBudget(SaveBudgetRequest{ServiceName: "Музыка", Amount: 300}, "Тестовый UUID", budgetRepo)
+
+	video, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Видео",
+		Price:       500,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.April),
+	}, subscriptionRepo, nil, "")
+
+	alerts, err := CheckBudgetAlerts(*video, nil, nil, now, budgetRepo, subscriptionRepo)
+
+	if err != nil || len(alerts) != 0 {
+		t.Errorf("CheckBudgetAlerts() = %+v, %v", alerts, err)
+	}
+
+	musicSub, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Музыка",
+		Price:       350,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.March),
+	}, subscriptionRepo, nil, "")
+
+	alerts, err = CheckBudgetAlerts(*musicSub, nil, nil, now, budgetRepo, subscriptionRepo)
+
+	if err != nil {
+		t.Errorf("CheckBudgetAlerts() error = %v", err)
+		return
+	}
+
+	// Общий бюджет: 850 из 1000 в марте и апреле. Бюджет на музыку: 350 из 300 в каждом из 12 месяцев
+	if len(alerts) != 2*1+12*2 {
+		t.Errorf("CheckBudgetAlerts() = %d предупреждений", len(alerts))
+	}
+
+	if alerts[0].Budget.Id != total.Id || alerts[0].Threshold != 80 || alerts[0].Spent != 850 || alerts[0].Month.String() != "03-2025" {
+		t.Errorf("CheckBudgetAlerts() первое предупреждение = %+v", alerts[0])
+	}
+
+	if len(outbox) != len(alerts) {
+		t.Errorf("outbox = %d событий, want %d", len(outbox), len(alerts))
+	}
+
+	alerts, _ = CheckBudgetAlerts(*musicSub, nil, nil, now, budgetRepo, subscriptionRepo)
+
+	if len(alerts) != 0 {
+		t.Errorf("CheckBudgetAlerts() повторно = %+v", alerts)
+	}
+
+	if _, err := ChangeSubscriptionPrice(ChangeSubscriptionPriceRequest{EffectiveFrom: date(2025, time.January), Price: 700}, subscriptionRepo, nil, video.Id, ""); err != nil {
+		t.Errorf("ChangeSubscriptionPrice() error = %v", err)
+	}
+
+	alerts, _ = CheckBudgetAlerts(*video, nil, nil, now, budgetRepo, subscriptionRepo)
+
+	if len(alerts) != 2 || alerts[0].Threshold != 100 || alerts[0].Spent != 1050 || alerts[1].Month.String() != "04-2025" {
+		t.Errorf("CheckBudgetAlerts() после изменения = %+v", alerts)
+	}
+
+	start, _ := utils.ParseDate("02-2025")
+	end, _ := utils.ParseDate("05-2025")
+
+	statuses, err := GetBudgetStatus(BudgetStatusRequest{StartDate: start, EndDate: end}, "Тестовый UUID", now, budgetRepo, subscriptionRepo)
+
+	if err != nil || len(statuses) != 2 {
+		t.Errorf("GetBudgetStatus() = %+v, %v", statuses, err)
+		return
+	}
+
+	want := []struct {
+		spent     int
+		percent   int
+		threshold int
+	}{
+		{700, 70, 0},
+		{1050, 105, 100},
+		{1050, 105, 100},
+		{350, 35, 0},
+	}
+
+	for i, month := range statuses[0].Months {
+		if month.Spent != want[i].spent || month.Percent != want[i].percent || month.Threshold != want[i].threshold {
+			t.Errorf("GetBudgetStatus() общий бюджет, месяц %s = %+v, want %+v", month.Month, month, want[i])
+		}
+	}
+
+	if statuses[1].Budget.Id != music.Id || statuses[1].Months[0].Spent != 0 || statuses[1].Months[3].Threshold != 100 {
+		t.Errorf("GetBudgetStatus() бюджет на музыку = %+v", statuses[1])
+	}
+
+	end, _ = utils.ParseDate("05-2027")
+
+	if _, err := GetBudgetStatus(BudgetStatusRequest{StartDate: start, EndDate: end}, "Тестовый UUID", now, budgetRepo, subscriptionRepo); err == nil {
+		t.Errorf("GetBudgetStatus() со слишком длинным периодом не вернула ошибку")
+	}
+
+	if _, err := UpdateBudget(SaveBudgetRequest{Amount: 2000}, total.Id, budgetRepo); err != nil {
+		t.Errorf("UpdateBudget() error = %v", err)
+	}
+
+	alerts, _ = CheckBudgetAlerts(*video, nil, nil, now, budgetRepo, subscriptionRepo)
+
+	if len(alerts) != 0 {
+		t.Errorf("CheckBudgetAlerts() после увеличения лимита = %+v", alerts)
+	}
+
+	if err := DeleteBudget(music.Id, budgetRepo); err != nil {
+		t.Errorf("DeleteBudget() error = %v", err)
+	}
+
+	if err := DeleteBudget(music.Id, budgetRepo); err == nil {
+		t.Errorf("DeleteBudget() удалённого бюджета не вернула ошибку")
+	}
+}
+
+func TestBudgetAlertsOnSubscriptionChanges(t *testing.T) {
+	outbox := make(map[string]*model.WebhookEvent)
+
+	budgetRepo := repository.BudgetRepoMock{
+		Budgets: make(map[int]*model.Budget),
+		Alerts:  make(map[string]bool),
+		Outbox:  outbox,
+	}
+
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Pauses:        make(map[int][]model.SubscriptionPause),
+	}
+
+	CreateBudget(SaveBudgetRequest{Amount: 1000}, "Тестовый UUID", budgetRepo)
+
+	month := monthDate(time.Now())
+
+	sub, err := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Видео",
+		Price:       500,
+		UserId:      "Тестовый UUID",
+		StartDate:   month,
+	}, subscriptionRepo, budgetRepo, "")
+
+	if err != nil || len(outbox) != 0 {
+		t.Errorf("CreateSubscription() = %+v, %v, outbox = %d событий", sub, err, len(outbox))
+		return
+	}
+
+	// Изменение стоимости проверяет пороги так же, как изменение записи: 900 из 1000 в каждом из 12 месяцев
+	if _, err := ChangeSubscriptionPrice(ChangeSubscriptionPriceRequest{EffectiveFrom: month, Price: 900}, subscriptionRepo, budgetRepo, sub.Id, ""); err != nil {
+		t.Errorf("ChangeSubscriptionPrice() error = %v", err)
+	}
+
+	if len(outbox) != budgetAlertMonths {
+		t.Errorf("outbox после изменения стоимости = %d событий, want %d", len(outbox), budgetAlertMonths)
+	}
+
+	// Пауза снимает отметки о порогах, и после возобновления предупреждения отправляются снова
+	PauseSubscription(PauseSubscriptionRequest{StartDate: month}, subscriptionRepo, budgetRepo, sub.Id, "")
+	ResumeSubscription(ResumeSubscriptionRequest{}, time.Now(), subscriptionRepo, budgetRepo, sub.Id, "")
+
+	if len(outbox) != 2*budgetAlertMonths {
+		t.Errorf("outbox после паузы и возобновления = %d событий, want %d", len(outbox), 2*budgetAlertMonths)
+	}
+
+	if users := budgetUsers([]model.Subscription{{UserId: "a"}, {UserId: "b"}, {UserId: "a"}}, nil); len(users) != 2 {
+		t.Errorf("budgetUsers() = %v, want [a b]", users)
+	}
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"time"
+)
+
+// Горизонт календаря продлений в месяцах
+const (
+	defaultCalendarHorizon = 12
+	maxCalendarHorizon     = 36
+)
+
+// SubscriptionCalendar собирает события календаря пользователя: продления подписок по расчётному периоду
+// и окончания подписок с сегодняшнего дня на horizonMonths месяцев вперёд
+func SubscriptionCalendar(userId string, horizonMonths int, now time.Time, subscriptionRepo repository.SubscriptionRepository) ([]utils.CalendarEvent, error) {
+	if userId == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	if horizonMonths == 0 {
+		horizonMonths = defaultCalendarHorizon
+	}
+
+	if horizonMonths < 0 || horizonMonths > maxCalendarHorizon {
+		return nil, fmt.Errorf("horizon must be between 1 and %d months", maxCalendarHorizon)
+	}
+
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, horizonMonths, 0)
+
+	filter := repository.SubscriptionFilter{
+		UserIds:      []string{userId},
+		MaxStartDate: utils.Date{NullTime: sql.NullTime{Time: from.AddDate(0, 0, 1-from.Day()), Valid: true}},
+		MinEndDate:   utils.Date{NullTime: sql.NullTime{Time: to, Valid: true}},
+	}
+
+	subs := []model.Subscription{}
+
+	err := subscriptionRepo.ListEach(filter, repository.ListOptions{SortBy: model.SortById}, func(sub *model.Subscription) error {
+		subs = append(subs, *sub)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	events := []utils.CalendarEvent{}
+
+	for _, sub := range subs {
+		prices, err := subscriptionRepo.ListPrices(sub.Id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pauses, err := subscriptionRepo.ListPauses(sub.Id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, date := range subscriptionDates(sub, prices, pauses, from, to) {
+			if date.kind == model.ReminderKindEnd {
+				events = append(events, utils.CalendarEvent{
+					UID:         fmt.Sprintf("subscription-%d-end@subsaggregator", sub.Id),
+					Date:        date.date,
+					Summary:     fmt.Sprintf("Окончание подписки %s", sub.ServiceName),
+					Description: fmt.Sprintf("Подписка %s заканчивается %s", sub.ServiceName, date.date.Format("02.01.2006")),
+				})
+
+				continue
+			}
+
+			events = append(events, utils.CalendarEvent{
+				UID:         fmt.Sprintf("subscription-%d-renewal-%s@subsaggregator", sub.Id, date.date.Format("20060102")),
+				Date:        date.date,
+				Summary:     fmt.Sprintf("Продление подписки %s: %d %s", sub.ServiceName, date.price, sub.Currency),
+				Description: fmt.Sprintf("Расчётный период: %s", sub.BillingPeriod),
+			})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date)
+	})
+
+	return events, nil
+}
+
+// subscriptionDate дата продления или окончания подписки
+type subscriptionDate struct {
+	// Вид даты, model.ReminderKindRenewal или model.ReminderKindEnd
+	kind string
+
+	date time.Time
+
+	// Стоимость продления по истории изменения стоимости
+	price int
+}
+
+// subscriptionDates возвращает даты продления и окончания подписки в промежутке [from, to), упорядоченные по дате.
+// Продление приходится на первый день месяца начала подписки или месяца после пробного периода и каждого следующего
+// расчётного периода, окончание — на последний день месяца окончания подписки. Продления в месяцы пауз пропускаются
+func subscriptionDates(sub model.Subscription, prices []model.SubscriptionPrice, pauses []model.SubscriptionPause, from time.Time, to time.Time) []subscriptionDate {
+	dates := []subscriptionDate{}
+
+	billingStart := repository.BillingStart(sub)
+	start := time.Date(billingStart.Year(), billingStart.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := to
+	hasEnd := sub.EndDate != nil && sub.EndDate.Valid
+
+	if hasEnd {
+		end = time.Date(sub.EndDate.Time.Year(), sub.EndDate.Time.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+	}
+
+	for i := 0; ; i++ {
+		renewal := renewalDate(start, sub.BillingPeriod, i)
+
+		if renewal.After(end) || !renewal.Before(to) {
+			break
+		}
+
+		if renewal.Before(from) || repository.Paused(pauses, renewal) {
+			continue
+		}
+
+		dates = append(dates, subscriptionDate{
+			kind:  model.ReminderKindRenewal,
+			date:  renewal,
+			price: priceOn(sub, prices, renewal),
+		})
+	}
+
+	if hasEnd && !end.Before(from) && end.Before(to) {
+		dates = append(dates, subscriptionDate{kind: model.ReminderKindEnd, date: end})
+	}
+
+	return dates
+}
+
+// renewalDate возвращает дату i-го продления подписки, начавшейся в start, по расчётному периоду
+func renewalDate(start time.Time, billingPeriod string, i int) time.Time {
+	if billingPeriod == model.BillingPeriodWeek {
+		return start.AddDate(0, 0, 7*i)
+	}
+
+	months, ok := model.BillingPeriodMonths[billingPeriod]
+
+	if !ok {
+		months = 1
+	}
+
+	return start.AddDate(0, months*i, 0)
+}
+
+// priceOn возвращает стоимость подписки, действующую на дату, с учётом акции и по истории изменения стоимости
+func priceOn(sub model.Subscription, prices []model.SubscriptionPrice, date time.Time) int {
+	if price, ok := repository.IntroductoryPrice(sub, date); ok {
+		return price
+	}
+
+	return listPriceOn(sub, prices, date)
+}
+
+// listPriceOn возвращает стоимость подписки, действующую на дату, по истории изменения стоимости без учёта акции
+func listPriceOn(sub model.Subscription, prices []model.SubscriptionPrice, date time.Time) int {
+	price := sub.Price
+	var effectiveFrom time.Time
+
+	for _, change := range prices {
+		if change.EffectiveFrom == nil || change.EffectiveFrom.Time.After(date) || change.EffectiveFrom.Time.Before(effectiveFrom) {
+			continue
+		}
+
+		price = change.Price
+		effectiveFrom = change.EffectiveFrom.Time
+	}
+
+	return price
+}
+
+// calendarFeedTokenPrefix начало всех токенов лент календаря
+const calendarFeedTokenPrefix = "cal_"
+
+// CreateCalendarFeed выпускает токен ленты календаря пользователя и возвращает его целиком. Прежний токен пользователя
+// перестаёт действовать. В базе хранится только хеш токена, поэтому получить токен повторно нельзя
+func CreateCalendarFeed(userId string, repo repository.CalendarFeedRepository) (*model.CalendarFeed, error) {
+	if userId == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	token := calendarFeedTokenPrefix + hex.EncodeToString(secret)
+	feed := &model.CalendarFeed{UserId: userId}
+
+	err := repo.Save(feed, hashApiKey(token))
+
+	if err != nil {
+		return nil, err
+	}
+
+	feed.Token = token
+
+	return feed, nil
+}
+
+// RevokeCalendarFeed отзывает токен ленты календаря пользователя
+func RevokeCalendarFeed(userId string, repo repository.CalendarFeedRepository) error {
+	deleted, err := repo.Delete(userId)
+
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return fmt.Errorf("calendar feed not found")
+	}
+
+	return nil
+}
+
+// AuthenticateCalendarFeed возвращает вызывающего по токену ленты календаря. Вызывающий не получает разрешений,
+// поэтому токен открывает только ленту календаря своего пользователя
+func AuthenticateCalendarFeed(token string, repo repository.CalendarFeedRepository) (*auth.Principal, error) {
+	if !strings.HasPrefix(token, calendarFeedTokenPrefix) {
+		return nil, fmt.Errorf("invalid calendar feed token")
+	}
+
+	feed, err := repo.Authenticate(hashApiKey(token))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if feed == nil {
+		return nil, fmt.Errorf("invalid calendar feed token")
+	}
+
+	return &auth.Principal{
+		UserId:   feed.UserId,
+		Scopes:   []string{},
+		TenantId: feed.TenantId,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"strconv"
+	"strings"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionCalendar(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Count:         0,
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	monthly, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Ежемесячный сервис",
+		Price:       300,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.April),
+	}, subscriptionRepo, nil, "")
+
+	yearly, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName:   "Годовой сервис",
+		Price:         1200,
+		BillingPeriod: model.BillingPeriodYear,
+		UserId:        "Тестовый UUID",
+		StartDate:     date(2024, time.June),
+	}, subscriptionRepo, nil, "")
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Чужой сервис",
+		Price:       100,
+		UserId:      "Другой UUID",
+		StartDate:   date(2025, time.January),
+	}, subscriptionRepo, nil, "")
+
+	ChangeSubscriptionPrice(ChangeSubscriptionPriceRequest{EffectiveFrom: date(2025, time.April), Price: 350}, subscriptionRepo, nil, monthly.Id, "")
+
+	now := time.Date(2025, time.February, 15, 12, 0, 0, 0, time.UTC)
+
+	events, err := SubscriptionCalendar("Тестовый UUID", 6, now, subscriptionRepo)
+
+	if err != nil {
+		t.Errorf("SubscriptionCalendar() error = %v", err)
+		return
+	}
+
+	want := []struct {
+		date    string
+		summary string
+	}{
+		{"20250301", "Продление подписки Ежемесячный сервис: 300 RUB"},
+		{"20250401", "Продление подписки Ежемесячный сервис: 350 RUB"},
+		{"20250430", "Окончание подписки Ежемесячный сервис"},
+		{"20250601", "Продление подписки Годовой сервис: 1200 RUB"},
+	}
+
+	if len(events) != len(want) {
+		t.Errorf("SubscriptionCalendar() = %+v", events)
+		return
+	}
+
+	for i, event := range events {
+		if event.Date.Format("20060102") != want[i].date || event.Summary != want[i].summary {
+			t.Errorf("SubscriptionCalendar() event %d = %+v, want %+v", i, event, want[i])
+		}
+	}
+
+	if events[3].UID != "subscription-"+strconv.Itoa(yearly.Id)+"-renewal-20250601@subsaggregator" {
+		t.Errorf("SubscriptionCalendar() UID = %s", events[3].UID)
+	}
+
+	var out bytes.Buffer
+
+	if err := utils.WriteCalendar(&out, "Подписки", events, now); err != nil {
+		t.Errorf("WriteCalendar() error = %v", err)
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("WriteCalendar() строка длиннее 75 байт: %q", line)
+		}
+	}
+
+	if strings.Count(out.String(), "BEGIN:VEVENT") != len(events) {
+		t.Errorf("WriteCalendar() = %s", out.String())
+	}
+
+	if _, err := SubscriptionCalendar("Тестовый UUID", 100, now, subscriptionRepo); err == nil {
+		t.Errorf("SubscriptionCalendar() со слишком большим горизонтом не вернула ошибку")
+	}
+}
+
+func TestCalendarFeed(t *testing.T) {
+	feedRepo := repository.CalendarFeedRepoMock{
+		Feeds:  make(map[string]*model.CalendarFeed),
+		Hashes: make(map[string]string),
+	}
+
+	feed, err := CreateCalendarFeed("Тестовый UUID", feedRepo)
+
+	if err != nil || !strings.HasPrefix(feed.Token, calendarFeedTokenPrefix) || feed.TenantId != model.DefaultTenantId {
+		t.Errorf("CreateCalendarFeed() = %+v, %v", feed, err)
+		return
+	}
+
+	principal, err := AuthenticateCalendarFeed(feed.Token, feedRepo)
+
+	if err != nil || !principal.Owns("Тестовый UUID") || principal.Owns("Другой UUID") || principal.HasScope(auth.ScopeSubscriptionsRead) {
+		t.Errorf("AuthenticateCalendarFeed() = %+v, %v", principal, err)
+	}
+
+	// Новый токен заменяет прежний
+	rotated, _ := CreateCalendarFeed("Тестовый UUID", feedRepo)
+
+	if _, err := AuthenticateCalendarFeed(feed.Token, feedRepo); err == nil {
+		t.Errorf("AuthenticateCalendarFeed() с заменённым токеном не вернула ошибку")
+	}
+
+	if err := RevokeCalendarFeed("Тестовый UUID", feedRepo); err != nil {
+		t.Errorf("RevokeCalendarFeed() error = %v", err)
+	}
+
+	if _, err := AuthenticateCalendarFeed(rotated.Token, feedRepo); err == nil {
+		t.Errorf("AuthenticateCalendarFeed() с отозванным токеном не вернула ошибку")
+	}
+
+	if err := RevokeCalendarFeed("Тестовый UUID", feedRepo); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("RevokeCalendarFeed() без токена error = %v", err)
+	}
+}
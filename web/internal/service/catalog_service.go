@@ -0,0 +1,193 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+)
+
+// CreateCatalogServiceRequest Модель данных для добавления сервиса в каталог
+//
+//	@modelId	create-catalog-service-request
+//	@required	Name
+type CreateCatalogServiceRequest struct {
+	Name         string   `json:"name" example:"Yandex Plus"`
+	Aliases      []string `json:"aliases,omitempty" example:"Яндекс Плюс,yandex plus"`
+	Category     string   `json:"category,omitempty" enums:"video,music,cloud,software,games,news,education,other" example:"video"`
+	DefaultPrice int      `json:"default_price,omitempty" example:"400"`
+	VendorUrl    string   `json:"vendor_url,omitempty" example:"https://plus.yandex.ru"`
+	LogoUrl      string   `json:"logo_url,omitempty" example:"https://yastatic.net/s3/plus/logo.svg"`
+}
+
+// UpdateCatalogServiceRequest Модель данных для изменения сервиса каталога. Псевдонимы заменяются целиком
+//
+//	@modelId	update-catalog-service-request
+//	@required	Name
+type UpdateCatalogServiceRequest struct {
+	Name         string   `json:"name" example:"Yandex Plus"`
+	Aliases      []string `json:"aliases,omitempty" example:"Яндекс Плюс,yandex plus"`
+	Category     string   `json:"category,omitempty" enums:"video,music,cloud,software,games,news,education,other" example:"video"`
+	DefaultPrice int      `json:"default_price,omitempty" example:"400"`
+	VendorUrl    string   `json:"vendor_url,omitempty" example:"https://plus.yandex.ru"`
+	LogoUrl      string   `json:"logo_url,omitempty" example:"https://yastatic.net/s3/plus/logo.svg"`
+}
+
+func ListCatalogServices(category string, repo repository.CatalogRepository) ([]model.CatalogService, error) {
+	if category != "" && !slices.Contains(model.ServiceCategories, category) {
+		return nil, fmt.Errorf("unknown service category: %s", category)
+	}
+
+	return repo.List(category)
+}
+
+func GetCatalogService(repo repository.CatalogRepository, serviceId int) (*model.CatalogService, error) {
+	return repo.FindById(serviceId)
+}
+
+func CreateCatalogService(req CreateCatalogServiceRequest, repo repository.CatalogRepository, actor string) (*model.CatalogService, error) {
+	service, err := newCatalogService(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.Create(service, actor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+func UpdateCatalogService(req UpdateCatalogServiceRequest, repo repository.CatalogRepository, serviceId int, actor string) (*model.CatalogService, error) {
+	existing, err := repo.FindById(serviceId)
+
+	if existing == nil {
+		return nil, err
+	}
+
+	service, err := newCatalogService(CreateCatalogServiceRequest(req))
+
+	if err != nil {
+		return nil, err
+	}
+
+	service.Id = existing.Id
+
+	err = repo.Update(service, actor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+func DeleteCatalogService(repo repository.CatalogRepository, serviceId int, actor string) (*model.CatalogService, error) {
+	service, err := repo.FindById(serviceId)
+
+	if service == nil {
+		return nil, err
+	}
+
+	err = repo.Delete(service, actor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+// newCatalogService проверяет параметры сервиса каталога и собирает по ним сервис.
+// Пустые и повторяющиеся псевдонимы, а также псевдонимы, совпадающие с названием, отбрасываются
+func newCatalogService(req CreateCatalogServiceRequest) (*model.CatalogService, error) {
+	name := strings.Join(strings.Fields(req.Name), " ")
+
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	category := req.Category
+
+	if category == "" {
+		category = model.ServiceCategoryOther
+	}
+
+	if !slices.Contains(model.ServiceCategories, category) {
+		return nil, fmt.Errorf("unknown service category: %s", category)
+	}
+
+	if req.DefaultPrice < 0 {
+		return nil, fmt.Errorf("default_price must not be negative")
+	}
+
+	for field, value := range map[string]string{"vendor_url": req.VendorUrl, "logo_url": req.LogoUrl} {
+		if value == "" {
+			continue
+		}
+
+		parsed, err := url.Parse(value)
+
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid %s: %s", field, value)
+		}
+	}
+
+	service := &model.CatalogService{
+		Name:         name,
+		Aliases:      []string{},
+		Category:     category,
+		DefaultPrice: req.DefaultPrice,
+		VendorUrl:    req.VendorUrl,
+		LogoUrl:      req.LogoUrl,
+	}
+
+	keys := []string{repository.ServiceAliasKey(name)}
+
+	for _, alias := range req.Aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		key := repository.ServiceAliasKey(alias)
+
+		if alias == "" || slices.Contains(keys, key) {
+			continue
+		}
+
+		keys = append(keys, key)
+		service.Aliases = append(service.Aliases, alias)
+	}
+
+	return service, nil
+}
+
+// resolveCatalogService связывает запись о подписке с сервисом каталога по названию или псевдониму
+// и заменяет название каноническим. Запись, название которой не найдено в каталоге, отвязывается от каталога.
+// Месячной подписке в валюте по умолчанию без стоимости подставляется стоимость сервиса по умолчанию
+func resolveCatalogService(repo repository.SubscriptionRepository, sub *model.Subscription) error {
+	service, err := repo.ResolveService(sub.ServiceName)
+
+	if err != nil {
+		return err
+	}
+
+	if service == nil {
+		sub.ServiceId = nil
+
+		return nil
+	}
+
+	sub.ServiceId = &service.Id
+	sub.ServiceName = service.Name
+
+	if sub.Price == 0 && service.DefaultPrice > 0 &&
+		sub.BillingPeriod == model.BillingPeriodMonth && sub.Currency == model.DefaultCurrency {
+		sub.Price = service.DefaultPrice
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"database/sql"
+	"slices"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestCreateCatalogService(t *testing.T) {
+	deletedAt := time.Now()
+	subscriptions := map[int]*model.Subscription{
+		1: {Id: 1, ServiceName: "yandex  plus", TenantId: "default"},
+		2: {Id: 2, ServiceName: " Яндекс Плюс", TenantId: "marketing"},
+		3: {Id: 3, ServiceName: "Кинопоиск", TenantId: "default"},
+		4: {Id: 4, ServiceName: "Yandex Plus", TenantId: "default", DeletedAt: &deletedAt},
+	}
+
+	catalogRepo := repository.CatalogRepoMock{
+		Services:      make(map[int]*model.CatalogService),
+		Subscriptions: subscriptions,
+		Audit:         make(map[int][]model.SubscriptionAudit),
+		Outbox:        make(map[string]*model.WebhookEvent),
+	}
+
+	yandex, err := CreateCatalogService(CreateCatalogServiceRequest{
+		Name:         " Yandex  Plus ",
+		Aliases:      []string{"Яндекс Плюс", "яндекс плюс", "YANDEX PLUS", " "},
+		Category:     model.ServiceCategoryVideo,
+		DefaultPrice: 400,
+		VendorUrl:    "https://plus.yandex.ru",
+	}, catalogRepo, "admin")
+
+	if err != nil || yandex.Name != "Yandex Plus" || !slices.Equal(yandex.Aliases, []string{"Яндекс Плюс"}) {
+		t.Errorf("CreateCatalogService() = %+v, %v", yandex, err)
+		return
+	}
+
+	for _, id := range []int{1, 2} {
+		if sub := subscriptions[id]; sub.ServiceId == nil || *sub.ServiceId != yandex.Id || sub.ServiceName != "Yandex Plus" {
+			t.Errorf("запись о подписке %d не связана с каталогом: %+v", id, sub)
+		}
+	}
+
+	for _, id := range []int{3, 4} {
+		if subscriptions[id].ServiceId != nil {
+			t.Errorf("запись о подписке %d связана с каталогом: %+v", id, subscriptions[id])
+		}
+	}
+
+	tests := []struct {
+		name string
+		req  CreateCatalogServiceRequest
+	}{
+		{"без названия", CreateCatalogServiceRequest{Name: "  "}},
+		{"неизвестная категория", CreateCatalogServiceRequest{Name: "Okko", Category: "movies"}},
+		{"отрицательная стоимость", CreateCatalogServiceRequest{Name: "Okko", DefaultPrice: -1}},
+		{"неверный адрес поставщика", CreateCatalogServiceRequest{Name: "Okko", VendorUrl: "okko.tv"}},
+		{"неверный адрес логотипа", CreateCatalogServiceRequest{Name: "Okko", LogoUrl: "ftp://okko.tv/logo.svg"}},
+		{"повторное название", CreateCatalogServiceRequest{Name: "Yandex Plus"}},
+		{"чужой псевдоним", CreateCatalogServiceRequest{Name: "Okko", Aliases: []string{"ЯНДЕКС ПЛЮС"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if service, err := CreateCatalogService(tt.req, catalogRepo, "admin"); err == nil {
+				t.Errorf("CreateCatalogService() = %+v, want error", service)
+			}
+		})
+	}
+
+	yandex, err = UpdateCatalogService(UpdateCatalogServiceRequest{
+		Name:     "Яндекс Плюс",
+		Aliases:  []string{"Yandex Plus", "Кинопоиск"},
+		Category: model.ServiceCategoryVideo,
+	}, catalogRepo, yandex.Id, "admin")
+
+	if err != nil {
+		t.Errorf("UpdateCatalogService() error = %v", err)
+		return
+	}
+
+	for _, sub := range subscriptions {
+		if sub.DeletedAt == nil && (sub.ServiceId == nil || sub.ServiceName != "Яндекс Плюс") {
+			t.Errorf("запись о подписке %d не переименована: %+v", sub.Id, sub)
+		}
+	}
+
+	if service, _ := UpdateCatalogService(UpdateCatalogServiceRequest{Name: "Okko"}, catalogRepo, 100, "admin"); service != nil {
+		t.Errorf("UpdateCatalogService() отсутствующего сервиса = %+v", service)
+	}
+
+	if _, err := DeleteCatalogService(catalogRepo, yandex.Id, "admin"); err != nil || subscriptions[1].ServiceId != nil {
+		t.Errorf("DeleteCatalogService() error = %v, запись о подписке: %+v", err, subscriptions[1])
+	}
+
+	// Каждое связывание, переименование и отвязывание неудалённой записи попадает в журнал изменений и в вебхуки
+	if audit := catalogRepo.Audit[1]; len(audit) != 3 || audit[0].Actor != "admin" || audit[0].Before.ServiceName != "yandex  plus" ||
+		audit[0].After.ServiceName != "Yandex Plus" || len(catalogRepo.Audit[3]) != 2 || len(catalogRepo.Audit[4]) != 0 {
+		t.Errorf("журнал изменений записей о подписках = %+v", catalogRepo.Audit)
+	}
+
+	if len(catalogRepo.Outbox) != 8 {
+		t.Errorf("outbox = %d событий, want 8", len(catalogRepo.Outbox))
+	}
+
+	if subscriptions[4].ServiceId != nil || subscriptions[4].ServiceName != "Yandex Plus" {
+		t.Errorf("удалённая запись о подписке изменена: %+v", subscriptions[4])
+	}
+
+	if _, err := ListCatalogServices("movies", catalogRepo); err == nil {
+		t.Errorf("ListCatalogServices() с неизвестной категорией не вернула ошибку")
+	}
+}
+
+func TestSubscriptionCatalogResolution(t *testing.T) {
+	services := map[int]*model.CatalogService{
+		1: {Id: 1, Name: "Yandex Plus", Aliases: []string{"Яндекс Плюс"}, Category: model.ServiceCategoryVideo, DefaultPrice: 400},
+		2: {Id: 2, Name: "Spotify", Category: model.ServiceCategoryMusic},
+	}
+
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Services:      services,
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	yandex, err := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "  яндекс   плюс ",
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.March),
+	}, subscriptionRepo, nil, "")
+
+	if err != nil || yandex.ServiceName != "Yandex Plus" || yandex.ServiceId == nil || *yandex.ServiceId != 1 || yandex.Price != 400 {
+		t.Errorf("CreateSubscription() = %+v, %v", yandex, err)
+		return
+	}
+
+	yearly, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName:   "Yandex Plus",
+		BillingPeriod: model.BillingPeriodYear,
+		UserId:        "Другой UUID",
+		StartDate:     date(2025, time.January),
+		EndDate:       date(2025, time.December),
+	}, subscriptionRepo, nil, "")
+
+	if yearly.Price != 0 {
+		t.Errorf("CreateSubscription() годовой подписке подставлена стоимость по умолчанию: %d", yearly.Price)
+	}
+
+	spotify, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "spotify",
+		Price:       300,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.February),
+	}, subscriptionRepo, nil, "")
+
+	other, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Кинопоиск",
+		Price:       200,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.January),
+	}, subscriptionRepo, nil, "")
+
+	if other.ServiceId != nil || other.ServiceName != "Кинопоиск" {
+		t.Errorf("CreateSubscription() связала сервис вне каталога: %+v", other)
+	}
+
+	groups, err := GroupSubscriptionsPrices(SumSubscriptionsPricesRequest{
+		StartDate: *date(2025, time.January),
+		EndDate:   *date(2025, time.December),
+		GroupBy:   []string{model.GroupByCategory},
+	}, subscriptionRepo)
+
+	want := map[string]int{model.ServiceCategoryVideo: 1200, model.ServiceCategoryMusic: 600, model.ServiceCategoryOther: 200}
+
+	if err != nil || len(groups) != len(want) {
+		t.Errorf("GroupSubscriptionsPrices() = %+v, %v", groups, err)
+	}
+
+	for _, group := range groups {
+		if group.Total != want[group.Category] {
+			t.Errorf("GroupSubscriptionsPrices() %s = %d, want %d", group.Category, group.Total, want[group.Category])
+		}
+	}
+
+	page, err := ListSubscriptions(ListSubscriptionsRequest{
+		Categories: []string{model.ServiceCategoryMusic, model.ServiceCategoryOther},
+		StartDate:  *date(2025, time.January),
+		Limit:      10,
+	}, subscriptionRepo)
+
+	if err != nil || page.Total != 2 || page.Items[0].Id != spotify.Id || page.Items[1].Id != other.Id {
+		t.Errorf("ListSubscriptions() = %+v, %v", page, err)
+	}
+
+	if _, err := ListSubscriptions(ListSubscriptionsRequest{Categories: []string{"movies"}, Limit: 10}, subscriptionRepo); err == nil {
+		t.Errorf("ListSubscriptions() с неизвестной категорией не вернула ошибку")
+	}
+
+	spotify, err = UpdateSubscription(UpdateSubscriptionRequest{
+		ServiceName: "Apple Music",
+		Price:       300,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.February),
+	}, subscriptionRepo, nil, spotify.Id, "")
+
+	if err != nil || spotify.ServiceId != nil || subscriptionRepo.Subscriptions[spotify.Id].ServiceId != nil {
+		t.Errorf("UpdateSubscription() не отвязала запись от каталога: %+v, %v", spotify, err)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"fmt"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+)
+
+// SaveExchangeRatesRequest Модель данных для загрузки курсов валют
+//
+//	@modelId	save-exchange-rates-request
+//	@required	Rates
+type SaveExchangeRatesRequest struct {
+	Rates []model.ExchangeRate `json:"rates"`
+}
+
+func ListExchangeRates(currency string, exchangeRateRepo repository.ExchangeRateRepository) ([]model.ExchangeRate, error) {
+	if currency != "" {
+		var err error
+
+		currency, err = normalizeCurrency(currency)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rates, err := exchangeRateRepo.List(currency)
+
+	if err != nil {
+		return rates, err
+	}
+
+	return rates, nil
+}
+
+func SaveExchangeRates(req SaveExchangeRatesRequest, exchangeRateRepo repository.ExchangeRateRepository) ([]model.ExchangeRate, error) {
+	if len(req.Rates) == 0 {
+		return nil, fmt.Errorf("rates are empty")
+	}
+
+	rates := make([]model.ExchangeRate, 0, len(req.Rates))
+
+	for i, rate := range req.Rates {
+		currency, err := normalizeCurrency(rate.Currency)
+
+		if err != nil || rate.Currency == "" {
+			return nil, fmt.Errorf("rate %d: invalid currency code: %s", i, rate.Currency)
+		}
+
+		if currency == model.DefaultCurrency {
+			return nil, fmt.Errorf("rate %d: rate of %s is always 1", i, model.DefaultCurrency)
+		}
+
+		if rate.Month == nil || !rate.Month.Valid {
+			return nil, fmt.Errorf("rate %d: month is required", i)
+		}
+
+		if rate.Rate <= 0 {
+			return nil, fmt.Errorf("rate %d: rate must be positive", i)
+		}
+
+		rate.Currency = currency
+		rates = append(rates, rate)
+	}
+
+	err := exchangeRateRepo.Save(rates)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
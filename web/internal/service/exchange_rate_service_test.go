@@ -0,0 +1,96 @@
+package service
+
+import (
+	"database/sql"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestSaveExchangeRates(t *testing.T) {
+	exchangeRateRepo := repository.ExchangeRateRepoMock{
+		ExchangeRates: make(map[string]*model.ExchangeRate),
+	}
+
+	month := &utils.Date{NullTime: sql.NullTime{
+		Time:  time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC),
+		Valid: true,
+	}}
+
+	type args struct {
+		req              SaveExchangeRatesRequest
+		exchangeRateRepo repository.ExchangeRateRepository
+	}
+
+	tests := []struct {
+		name      string
+		args      args
+		wantSaved int
+		wantErr   bool
+	}{
+		{
+			name: "Загрузка курсов валют",
+			args: args{
+				req: SaveExchangeRatesRequest{Rates: []model.ExchangeRate{
+					{Currency: "usd", Month: month, Rate: 80},
+					{Currency: "EUR", Month: month, Rate: 90},
+				}},
+				exchangeRateRepo: exchangeRateRepo,
+			},
+			wantSaved: 2,
+			wantErr:   false,
+		},
+		{
+			name: "Некорректный код валюты",
+			args: args{
+				req: SaveExchangeRatesRequest{Rates: []model.ExchangeRate{
+					{Currency: "DOLLAR", Month: month, Rate: 80},
+				}},
+				exchangeRateRepo: exchangeRateRepo,
+			},
+			wantSaved: 2,
+			wantErr:   true,
+		},
+		{
+			name: "Курс рубля",
+			args: args{
+				req: SaveExchangeRatesRequest{Rates: []model.ExchangeRate{
+					{Currency: "RUB", Month: month, Rate: 2},
+				}},
+				exchangeRateRepo: exchangeRateRepo,
+			},
+			wantSaved: 2,
+			wantErr:   true,
+		},
+		{
+			name: "Неположительный курс",
+			args: args{
+				req: SaveExchangeRatesRequest{Rates: []model.ExchangeRate{
+					{Currency: "CNY", Month: month, Rate: 0},
+				}},
+				exchangeRateRepo: exchangeRateRepo,
+			},
+			wantSaved: 2,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SaveExchangeRates(tt.args.req, tt.args.exchangeRateRepo)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SaveExchangeRates() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			rates, _ := ListExchangeRates("", tt.args.exchangeRateRepo)
+
+			if len(rates) != tt.wantSaved {
+				t.Errorf("SaveExchangeRates() saved = %v, want %v", len(rates), tt.wantSaved)
+			}
+		})
+	}
+}
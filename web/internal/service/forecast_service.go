@@ -0,0 +1,128 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"time"
+)
+
+const (
+	// defaultForecastMonths количество месяцев прогноза, если months не указан
+	defaultForecastMonths = 12
+
+	// maxForecastMonths наибольшее количество месяцев прогноза
+	maxForecastMonths = 36
+)
+
+// ForecastSubscriptionsPricesRequest Модель данных для прогноза расходов на подписки
+//
+//	@modelId	forecast-subs-prices-request
+type ForecastSubscriptionsPricesRequest struct {
+	ServiceName  string   `json:"service_name,omitempty"`
+	UserId       string   `json:"user_id,omitempty"`
+	ServiceNames []string `json:"service_names,omitempty" example:"Yandex Plus"`
+	UserIds      []string `json:"user_ids,omitempty"`
+
+	// Количество месяцев прогноза начиная с текущего, по умолчанию 12, не больше 36
+	Months      int    `json:"months,omitempty" example:"12"`
+	Currency    string `json:"currency,omitempty" example:"RUB"`
+	BillingMode string `json:"billing_mode,omitempty" enums:"charge,amortize" example:"charge"`
+}
+
+// ForecastSubscriptionsPrices прогнозирует помесячные расходы на подписки, действующие в текущем месяце,
+// на months месяцев вперёд. Подписки без даты окончания считаются продолжающимися до конца прогноза,
+// известные даты окончания и запланированные изменения цены учитываются
+func ForecastSubscriptionsPrices(req ForecastSubscriptionsPricesRequest, now time.Time, subscriptionRepo repository.SubscriptionRepository) (*model.Forecast, error) {
+	months := req.Months
+
+	if months == 0 {
+		months = defaultForecastMonths
+	}
+
+	if months < 0 || months > maxForecastMonths {
+		return nil, fmt.Errorf("months must be between 1 and %d", maxForecastMonths)
+	}
+
+	currency, err := normalizeCurrency(req.Currency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	billingMode, err := normalizeBillingMode(req.BillingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	from := monthOf(now)
+	to := from.AddDate(0, months-1, 0)
+
+	filter, err := subscriptionFilter(repository.SubscriptionFilter{
+		UserIds:      req.UserIds,
+		ServiceNames: req.ServiceNames,
+		MaxStartDate: utils.Date{NullTime: sql.NullTime{Time: from, Valid: true}},
+		MinEndDate:   utils.Date{NullTime: sql.NullTime{Time: to, Valid: true}},
+		ActiveOn:     utils.Date{NullTime: sql.NullTime{Time: from, Valid: true}},
+		ProjectUntil: utils.Date{NullTime: sql.NullTime{Time: to, Valid: true}},
+	}, req.UserId, req.ServiceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	monthlyPrices, err := subscriptionRepo.SumPricesByMonth(filter, currency, billingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byMonth := make(map[time.Time]model.MonthlyPrice)
+
+	for _, monthlyPrice := range monthlyPrices {
+		byMonth[monthOf(monthlyPrice.Month.Time)] = monthlyPrice
+	}
+
+	forecast := &model.Forecast{
+		From:     &utils.Date{NullTime: sql.NullTime{Time: from, Valid: true}},
+		To:       &utils.Date{NullTime: sql.NullTime{Time: to, Valid: true}},
+		Currency: currency,
+		Services: []model.ServicePrice{},
+		Months:   []model.MonthlyPrice{},
+	}
+
+	serviceTotals := make(map[string]int)
+
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		monthlyPrice, ok := byMonth[month]
+
+		if !ok {
+			monthlyPrice = model.MonthlyPrice{
+				Month:    &utils.Date{NullTime: sql.NullTime{Time: month, Valid: true}},
+				Services: []model.ServicePrice{},
+			}
+		}
+
+		for _, servicePrice := range monthlyPrice.Services {
+			serviceTotals[servicePrice.ServiceName] += servicePrice.Total
+		}
+
+		forecast.Total += monthlyPrice.Total
+		forecast.Months = append(forecast.Months, monthlyPrice)
+	}
+
+	for name, total := range serviceTotals {
+		forecast.Services = append(forecast.Services, model.ServicePrice{ServiceName: name, Total: total})
+	}
+
+	sort.Slice(forecast.Services, func(i, j int) bool {
+		return forecast.Services[i].ServiceName < forecast.Services[j].ServiceName
+	})
+
+	return forecast, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"database/sql"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestForecastSubscriptionsPrices(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Count:         0,
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	video, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Видео",
+		Price:       500,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+	}, subscriptionRepo, nil, "")
+
+	if _, err := ChangeSubscriptionPrice(ChangeSubscriptionPriceRequest{EffectiveFrom: date(2025, time.June), Price: 700}, subscriptionRepo, nil, video.Id, ""); err != nil {
+		t.Errorf("ChangeSubscriptionPrice() error = %v", err)
+		return
+	}
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Музыка",
+		Price:       200,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.February),
+		EndDate:     date(2025, time.April),
+	}, subscriptionRepo, nil, "")
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Книги",
+		Price:       300,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.June),
+	}, subscriptionRepo, nil, "")
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Видео",
+		Price:       400,
+		UserId:      "Другой UUID",
+		StartDate:   date(2025, time.January),
+	}, subscriptionRepo, nil, "")
+
+	forecast, err := ForecastSubscriptionsPrices(ForecastSubscriptionsPricesRequest{UserId: "Тестовый UUID", Months: 6}, now, subscriptionRepo)
+
+	if err != nil {
+		t.Errorf("ForecastSubscriptionsPrices() error = %v", err)
+		return
+	}
+
+	if forecast.From.String() != "03-2025" || forecast.To.String() != "08-2025" || forecast.Currency != model.DefaultCurrency {
+		t.Errorf("ForecastSubscriptionsPrices() период = %s - %s %s", forecast.From, forecast.To, forecast.Currency)
+	}
+
+	want := []int{700, 700, 500, 700, 700, 700}
+
+	if len(forecast.Months) != len(want) {
+		t.Errorf("ForecastSubscriptionsPrices() = %d месяцев, want %d", len(forecast.Months), len(want))
+		return
+	}
+
+	for i, month := range forecast.Months {
+		if month.Total != want[i] {
+			t.Errorf("ForecastSubscriptionsPrices() месяц %s = %d, want %d", month.Month, month.Total, want[i])
+		}
+	}
+
+	// Книги начинаются после текущего месяца и в прогноз не попадают
+	if forecast.Total != 4000 || len(forecast.Services) != 2 ||
+		forecast.Services[0] != (model.ServicePrice{ServiceName: "Видео", Total: 3600}) ||
+		forecast.Services[1] != (model.ServicePrice{ServiceName: "Музыка", Total: 400}) {
+		t.Errorf("ForecastSubscriptionsPrices() итог = %d, %+v", forecast.Total, forecast.Services)
+	}
+
+	forecast, err = ForecastSubscriptionsPrices(ForecastSubscriptionsPricesRequest{}, now, subscriptionRepo)
+
+	if err != nil || len(forecast.Months) != defaultForecastMonths || forecast.Months[0].Total != 1100 {
+		t.Errorf("ForecastSubscriptionsPrices() по умолчанию = %+v, %v", forecast, err)
+	}
+
+	if _, err := ForecastSubscriptionsPrices(ForecastSubscriptionsPricesRequest{Months: maxForecastMonths + 1}, now, subscriptionRepo); err == nil {
+		t.Errorf("ForecastSubscriptionsPrices() со слишком длинным периодом не вернула ошибку")
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+)
+
+var organisationIdPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// CreateOrganisationRequest Модель данных для создания организации
+//
+//	@modelId	create-organisation-request
+//	@required	Id Name
+type CreateOrganisationRequest struct {
+	// ИД организации: строчные латинские буквы, цифры, дефис и подчёркивание
+	Id   string `json:"id" example:"marketing"`
+	Name string `json:"name" example:"Отдел маркетинга"`
+}
+
+// SumOrganisationsPricesRequest Модель данных для получения суммарной стоимости подписок по организациям.
+// Стоимость всегда группируется по tenant_id, group_by добавляет к нему дополнительные поля
+//
+//	@modelId	sum-organisations-prices-request
+//	@required	StartDate EndDate
+type SumOrganisationsPricesRequest struct {
+	// ИД организаций. Если не указаны, суммируются подписки всех организаций
+	TenantIds []string `json:"tenant_ids,omitempty" example:"default,marketing"`
+
+	SumSubscriptionsPricesRequest
+}
+
+func ListOrganisations(repo repository.OrganisationRepository) ([]model.Organisation, error) {
+	return repo.List()
+}
+
+func CreateOrganisation(req CreateOrganisationRequest, repo repository.OrganisationRepository) (*model.Organisation, error) {
+	if !organisationIdPattern.MatchString(req.Id) {
+		return nil, fmt.Errorf("invalid organisation id: %s", req.Id)
+	}
+
+	name := strings.TrimSpace(req.Name)
+
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	organisation := &model.Organisation{
+		Id:   req.Id,
+		Name: name,
+	}
+
+	err := repo.Create(organisation)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return organisation, nil
+}
+
+// SumOrganisationsPrices возвращает суммарную стоимость подписок каждой организации.
+// subscriptionRepo не должен быть ограничен организацией, иначе в ответе будет только она
+func SumOrganisationsPrices(req SumOrganisationsPricesRequest, subscriptionRepo repository.SubscriptionRepository) ([]model.PriceGroup, error) {
+	req.GroupBy = append([]string{model.GroupByTenantId}, req.GroupBy...)
+
+	return groupSubscriptionsPrices(
+		req.SumSubscriptionsPricesRequest,
+		req.TenantIds,
+		append([]string{model.GroupByTenantId}, subscriptionGroupByFields...),
+		subscriptionRepo,
+	)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"database/sql"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestCreateOrganisation(t *testing.T) {
+	organisationRepo := repository.OrganisationRepoMock{
+		Organisations: map[string]*model.Organisation{
+			model.DefaultTenantId: {Id: model.DefaultTenantId, Name: "Организация по умолчанию"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		req     CreateOrganisationRequest
+		wantErr bool
+	}{
+		{"организация", CreateOrganisationRequest{Id: "marketing", Name: " Отдел маркетинга "}, false},
+		{"повторный ИД", CreateOrganisationRequest{Id: model.DefaultTenantId, Name: "Ещё одна"}, true},
+		{"ИД с заглавными буквами", CreateOrganisationRequest{Id: "Sales", Name: "Продажи"}, true},
+		{"пустой ИД", CreateOrganisationRequest{Name: "Продажи"}, true},
+		{"без названия", CreateOrganisationRequest{Id: "sales", Name: "  "}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			organisation, err := CreateOrganisation(tt.req, organisationRepo)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateOrganisation() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err == nil && (organisation.Name != "Отдел маркетинга" || organisationRepo.Organisations[tt.req.Id] == nil) {
+				t.Errorf("CreateOrganisation() = %+v", organisation)
+			}
+		})
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	subscriptions := make(map[int]*model.Subscription)
+	prices := make(map[int][]model.SubscriptionPrice)
+
+	marketingRepo := repository.SubscriptionRepoMock{Subscriptions: subscriptions, Prices: prices, TenantId: "marketing"}
+	salesRepo := repository.SubscriptionRepoMock{Subscriptions: subscriptions, Prices: prices, TenantId: "sales"}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	req := CreateSubscriptionRequest{
+		ServiceName: "Yandex Plus",
+		Price:       400,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.March),
+	}
+
+	marketingSub, _ := CreateSubscription(req, marketingRepo, nil, "")
+
+	req.Price = 500
+
+	salesSub, _ := CreateSubscription(req, salesRepo, nil, "")
+
+	if marketingSub.TenantId != "marketing" || salesSub.TenantId != "sales" {
+		t.Errorf("CreateSubscription() организации = %s, %s", marketingSub.TenantId, salesSub.TenantId)
+	}
+
+	if sub, _ := GetOneSubscription(salesRepo, marketingSub.Id); sub != nil {
+		t.Errorf("GetOneSubscription() вернул запись другой организации: %+v", sub)
+	}
+
+	_ = DeleteSubscription(salesRepo, nil, marketingSub.Id, "")
+
+	if marketingSub.DeletedAt != nil {
+		t.Errorf("DeleteSubscription() удалил запись другой организации")
+	}
+
+	page, err := ListSubscriptions(ListSubscriptionsRequest{UserId: "Тестовый UUID", StartDate: *date(2025, time.January), Limit: 10}, marketingRepo)
+
+	if err != nil || page.Total != 1 || page.Items[0].Id != marketingSub.Id {
+		t.Errorf("ListSubscriptions() = %+v, %v", page, err)
+	}
+
+	sumReq := SumSubscriptionsPricesRequest{
+		StartDate: *date(2025, time.January),
+		EndDate:   *date(2025, time.December),
+	}
+
+	sum, err := SumSubscriptionsPrices(sumReq, salesRepo)
+
+	if err != nil || *sum != 1500 {
+		t.Errorf("SumSubscriptionsPrices() = %v, %v, want 1500", sum, err)
+	}
+
+	allTenantsRepo := repository.SubscriptionRepoMock{Subscriptions: subscriptions, Prices: prices}
+
+	tests := []struct {
+		name      string
+		tenantIds []string
+		groupBy   []string
+		want      map[string]int
+		wantErr   bool
+	}{
+		{"все организации", nil, nil, map[string]int{"marketing": 1200, "sales": 1500}, false},
+		{"выбранная организация", []string{"sales"}, nil, map[string]int{"sales": 1500}, false},
+		{"по организациям и месяцам", nil, []string{model.GroupByMonth}, map[string]int{"marketing": 400, "sales": 500}, false},
+		{"повторная группировка по организации", nil, []string{model.GroupByTenantId}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := SumOrganisationsPricesRequest{TenantIds: tt.tenantIds, SumSubscriptionsPricesRequest: sumReq}
+			req.GroupBy = tt.groupBy
+
+			groups, err := SumOrganisationsPrices(req, allTenantsRepo)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SumOrganisationsPrices() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			for _, group := range groups {
+				if tt.groupBy != nil && (group.Month == nil || group.Total != tt.want[group.TenantId]) {
+					t.Errorf("SumOrganisationsPrices() группа = %+v", group)
+				}
+
+				if tt.groupBy == nil && group.Total != tt.want[group.TenantId] {
+					t.Errorf("SumOrganisationsPrices() %s = %d, want %d", group.TenantId, group.Total, tt.want[group.TenantId])
+				}
+			}
+
+			if tt.groupBy == nil && len(groups) != len(tt.want) {
+				t.Errorf("SumOrganisationsPrices() = %d групп, want %d", len(groups), len(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/notifier"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"time"
+)
+
+// Количество дней до продления или окончания подписки, за которое отправляется напоминание
+const (
+	defaultReminderDaysBefore = 3
+	maxReminderDaysBefore     = 30
+)
+
+// webhookLookupTimeout время на разрешение имени узла вебхука при сохранении настроек
+const webhookLookupTimeout = 3 * time.Second
+
+// SaveReminderSettingsRequest Модель данных для изменения настроек напоминаний пользователя
+//
+//	@modelId	save-reminder-settings-request
+//	@required	Enabled
+type SaveReminderSettingsRequest struct {
+	Enabled    bool   `json:"enabled"`
+	Email      string `json:"email,omitempty" example:"user@example.com"`
+	WebhookUrl string `json:"webhook_url,omitempty" example:"https://example.com/hooks/reminders"`
+	DaysBefore int    `json:"days_before,omitempty" example:"3"`
+}
+
+// GetReminderSettings возвращает настройки напоминаний пользователя.
+// Если пользователь их не сохранял, напоминания выключены
+func GetReminderSettings(userId string, reminderRepo repository.ReminderRepository) (*model.ReminderSettings, error) {
+	settings, err := reminderRepo.FindSettings(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		settings = &model.ReminderSettings{
+			UserId:     userId,
+			DaysBefore: defaultReminderDaysBefore,
+		}
+	}
+
+	return settings, nil
+}
+
+func SaveReminderSettings(req SaveReminderSettingsRequest, userId string, reminderRepo repository.ReminderRepository) (*model.ReminderSettings, error) {
+	if userId == "" {
+		return nil, fmt.Errorf("user_id is required")
+	}
+
+	if req.DaysBefore == 0 {
+		req.DaysBefore = defaultReminderDaysBefore
+	}
+
+	if req.DaysBefore < 0 || req.DaysBefore > maxReminderDaysBefore {
+		return nil, fmt.Errorf("days_before must be between 1 and %d", maxReminderDaysBefore)
+	}
+
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			return nil, fmt.Errorf("invalid email: %w", err)
+		}
+	}
+
+	if req.WebhookUrl != "" {
+		webhookUrl, err := url.Parse(req.WebhookUrl)
+
+		if err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") || webhookUrl.Host == "" {
+			return nil, fmt.Errorf("invalid webhook_url: %s", req.WebhookUrl)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), webhookLookupTimeout)
+		err = notifier.CheckWebhookHost(ctx, webhookUrl.Hostname())
+		cancel()
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook_url: %w", err)
+		}
+	}
+
+	if req.Enabled && req.Email == "" && req.WebhookUrl == "" {
+		return nil, fmt.Errorf("email or webhook_url is required to enable reminders")
+	}
+
+	settings := &model.ReminderSettings{
+		UserId:     userId,
+		Enabled:    req.Enabled,
+		Email:      req.Email,
+		WebhookUrl: req.WebhookUrl,
+		DaysBefore: req.DaysBefore,
+	}
+
+	err := reminderRepo.SaveSettings(settings)
+
+	if err != nil {
+		return settings, err
+	}
+
+	return settings, nil
+}
+
+// DueReminders возвращает напоминания о продлениях и окончаниях подписок пользователя
+// с сегодняшнего дня на settings.DaysBefore дней вперёд включительно
+func DueReminders(settings model.ReminderSettings, now time.Time, subscriptionRepo repository.SubscriptionRepository) ([]model.Reminder, error) {
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, settings.DaysBefore+1)
+
+	filter := repository.SubscriptionFilter{
+		TenantIds:    []string{settings.TenantId},
+		UserIds:      []string{settings.UserId},
+		MaxStartDate: utils.Date{NullTime: sql.NullTime{Time: from.AddDate(0, 0, 1-from.Day()), Valid: true}},
+		MinEndDate:   utils.Date{NullTime: sql.NullTime{Time: to, Valid: true}},
+	}
+
+	subs := []model.Subscription{}
+
+	err := subscriptionRepo.ListEach(filter, repository.ListOptions{SortBy: model.SortById}, func(sub *model.Subscription) error {
+		subs = append(subs, *sub)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	reminders := []model.Reminder{}
+
+	for _, sub := range subs {
+		prices, err := subscriptionRepo.ListPrices(sub.Id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pauses, err := subscriptionRepo.ListPauses(sub.Id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, date := range subscriptionDates(sub, prices, pauses, from, to) {
+			reminder := model.Reminder{
+				Kind:           date.kind,
+				SubscriptionId: sub.Id,
+				UserId:         sub.UserId,
+				ServiceName:    sub.ServiceName,
+				DueDate:        date.date,
+			}
+
+			if date.kind == model.ReminderKindRenewal {
+				reminder.Price = date.price
+				reminder.Currency = sub.Currency
+			}
+
+			reminders = append(reminders, reminder)
+		}
+	}
+
+	return reminders, nil
+}
+
+// SendReminders отправляет напоминания всем пользователям, включившим их, по каждому каналу с указанным адресом.
+// Напоминание отмечается отправленным до отправки и не отправляется повторно; если отправить не удалось,
+// отметка снимается и напоминание будет отправлено при следующем запуске. Возвращает количество отправленных напоминаний
+func SendReminders(ctx context.Context, now time.Time, subscriptionRepo repository.SubscriptionRepository, reminderRepo repository.ReminderRepository, notifiers []notifier.Notifier) (int, error) {
+	settingsList, err := reminderRepo.ListEnabledSettings()
+
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	errs := []error{}
+
+	for _, settings := range settingsList {
+		reminders, err := DueReminders(settings, now, subscriptionRepo)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", settings.UserId, err))
+			continue
+		}
+
+		for _, reminder := range reminders {
+			for _, n := range notifiers {
+				if ctx.Err() != nil {
+					return sent, ctx.Err()
+				}
+
+				if !n.Accepts(settings) {
+					continue
+				}
+
+				claimed, err := reminderRepo.MarkSent(reminder, n.Channel())
+
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+
+				if !claimed {
+					continue
+				}
+
+				err = n.Notify(ctx, settings, reminder)
+
+				if err != nil {
+					slog.Error(fmt.Errorf("напоминание о подписке %d не отправлено по каналу %s: %w", reminder.SubscriptionId, n.Channel(), err).Error())
+
+					errs = append(errs, err)
+
+					if err := reminderRepo.UnmarkSent(reminder, n.Channel()); err != nil {
+						errs = append(errs, err)
+					}
+
+					continue
+				}
+
+				sent++
+			}
+		}
+	}
+
+	slog.Info(fmt.Sprintf("Отправка напоминаний о подписках. Отправлено: %d", sent))
+
+	return sent, errors.Join(errs...)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/notifier"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+// fakeNotifier запоминает отправленные напоминания и может имитировать ошибку отправки
+type fakeNotifier struct {
+	channel string
+	sent    *[]model.Reminder
+	fail    bool
+}
+
+func (n fakeNotifier) Channel() string {
+	return n.channel
+}
+
+func (n fakeNotifier) Accepts(settings model.ReminderSettings) bool {
+	if n.channel == model.ReminderChannelEmail {
+		return settings.Email != ""
+	}
+
+	return settings.WebhookUrl != ""
+}
+
+func (n fakeNotifier) Notify(ctx context.Context, settings model.ReminderSettings, reminder model.Reminder) error {
+	if n.fail {
+		return errors.New("notifier is unavailable")
+	}
+
+	*n.sent = append(*n.sent, reminder)
+
+	return nil
+}
+
+func TestSendReminders(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Count:         0,
+	}
+
+	reminderRepo := repository.ReminderRepoMock{
+		Settings: make(map[string]*model.ReminderSettings),
+		Sent:     make(map[string]bool),
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Ежемесячный сервис",
+		Price:       300,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.February),
+	}, subscriptionRepo, nil, "")
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Сервис без согласия",
+		Price:       100,
+		UserId:      "Другой UUID",
+		StartDate:   date(2025, time.January),
+	}, subscriptionRepo, nil, "")
+
+	if _, err := SaveReminderSettings(SaveReminderSettingsRequest{Enabled: true}, "Тестовый UUID", reminderRepo); err == nil {
+		t.Errorf("SaveReminderSettings() без адреса не вернула ошибку")
+	}
+
+	if _, err := SaveReminderSettings(SaveReminderSettingsRequest{Enabled: true, Email: "не адрес"}, "Тестовый UUID", reminderRepo); err == nil {
+		t.Errorf("SaveReminderSettings() с неверной почтой не вернула ошибку")
+	}
+
+	if _, err := SaveReminderSettings(SaveReminderSettingsRequest{Enabled: true, WebhookUrl: "ftp://example.com"}, "Тестовый UUID", reminderRepo); err == nil {
+		t.Errorf("SaveReminderSettings() с неверным вебхуком не вернула ошибку")
+	}
+
+	for _, webhookUrl := range []string{"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://10.0.0.5/hook", "http://169.254.169.254/latest", "http://[::1]/hook"} {
+		if _, err := SaveReminderSettings(SaveReminderSettingsRequest{Enabled: true, WebhookUrl: webhookUrl}, "Тестовый UUID", reminderRepo); err == nil {
+			t.Errorf("SaveReminderSettings() с вебхуком во внутренней сети %s не вернула ошибку", webhookUrl)
+		}
+	}
+
+	settings, err := GetReminderSettings("Другой UUID", reminderRepo)
+
+	if err != nil || settings.Enabled || settings.DaysBefore != defaultReminderDaysBefore {
+		t.Errorf("GetReminderSettings() = %+v, %v", settings, err)
+	}
+
+	_, err = SaveReminderSettings(SaveReminderSettingsRequest{
+		Enabled:    true,
+		Email:      "user@example.com",
+		WebhookUrl: "https://example.com/hooks",
+	}, "Тестовый UUID", reminderRepo)
+
+	if err != nil {
+		t.Errorf("SaveReminderSettings() error = %v", err)
+		return
+	}
+
+	emails := []model.Reminder{}
+	webhooks := []model.Reminder{}
+
+	notifiers := []notifier.Notifier{
+		fakeNotifier{channel: model.ReminderChannelEmail, sent: &emails},
+		fakeNotifier{channel: model.ReminderChannelWebhook, sent: &webhooks, fail: true},
+	}
+
+	now := time.Date(2025, time.January, 30, 9, 0, 0, 0, time.UTC)
+
+	sent, err := SendReminders(context.Background(), now, subscriptionRepo, reminderRepo, notifiers)
+
+	if err == nil {
+		t.Errorf("SendReminders() не вернула ошибку вебхука")
+	}
+
+	if sent != 1 || len(emails) != 1 || emails[0].Kind != model.ReminderKindRenewal || emails[0].Price != 300 {
+		t.Errorf("SendReminders() = %d, emails = %+v", sent, emails)
+	}
+
+	if emails[0].DueDate.Format("2006-01-02") != "2025-02-01" || emails[0].UserId != "Тестовый UUID" {
+		t.Errorf("SendReminders() email = %+v", emails[0])
+	}
+
+	notifiers[1] = fakeNotifier{channel: model.ReminderChannelWebhook, sent: &webhooks}
+
+	sent, err = SendReminders(context.Background(), now, subscriptionRepo, reminderRepo, notifiers)
+
+	if err != nil || sent != 1 || len(emails) != 1 || len(webhooks) != 1 {
+		t.Errorf("SendReminders() повторно = %d, %v, emails = %d, webhooks = %d", sent, err, len(emails), len(webhooks))
+	}
+
+	now = time.Date(2025, time.February, 26, 9, 0, 0, 0, time.UTC)
+
+	sent, err = SendReminders(context.Background(), now, subscriptionRepo, reminderRepo, notifiers)
+
+	if err != nil || sent != 2 || emails[1].Kind != model.ReminderKindEnd || emails[1].DueDate.Format("2006-01-02") != "2025-02-28" {
+		t.Errorf("SendReminders() окончание = %d, %v, emails = %+v", sent, err, emails)
+	}
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"fmt"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"time"
+)
+
+// maxBatchSize максимальное количество операций в одном пакете
+const maxBatchSize = 1000
+
+// BatchSubscriptionOperation Модель данных операции пакета над записью о подписке.
+// Для create и update передаётся Subscription, для update и delete — Id
+//
+//	@modelId	batch-sub-operation
+//	@required	Action
+type BatchSubscriptionOperation struct {
+	Action       string                     `json:"action" enums:"create,update,delete" example:"create"`
+	Id           int                        `json:"id,omitempty"`
+	Subscription *CreateSubscriptionRequest `json:"subscription,omitempty"`
+}
+
+// BatchSubscriptionsRequest Модель данных для выполнения пакета операций над записями о подписках
+//
+//	@modelId	batch-subs-request
+//	@required	Operations
+type BatchSubscriptionsRequest struct {
+	Mode       string                       `json:"mode,omitempty" enums:"all_or_nothing,best_effort" example:"all_or_nothing"`
+	Operations []BatchSubscriptionOperation `json:"operations"`
+}
+
+// BatchSubscriptions выполняет пакет операций над записями о подписках в одной транзакции.
+// Операции с некорректными параметрами и операции над чужими подписками не передаются в репозиторий;
+// в режиме model.BatchModeAllOrNothing такая операция отменяет весь пакет
+func BatchSubscriptions(req BatchSubscriptionsRequest, repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, actor string, principal auth.Principal) ([]model.BatchResult, error) {
+	mode := req.Mode
+
+	switch mode {
+	case "":
+		mode = model.BatchModeAllOrNothing
+	case model.BatchModeAllOrNothing, model.BatchModeBestEffort:
+	default:
+		return nil, fmt.Errorf("unknown batch mode: %s", mode)
+	}
+
+	if len(req.Operations) == 0 {
+		return nil, fmt.Errorf("operations are empty")
+	}
+
+	if len(req.Operations) > maxBatchSize {
+		return nil, fmt.Errorf("too many operations: %d, maximum is %d", len(req.Operations), maxBatchSize)
+	}
+
+	results := make([]model.BatchResult, len(req.Operations))
+	operations := []repository.BatchOperation{}
+	usedIds := make(map[int]bool)
+	overlaps := make(map[int][]model.SubscriptionOverlap)
+	invalid := false
+
+	for i, reqOperation := range req.Operations {
+		results[i] = model.BatchResult{Index: i, Action: reqOperation.Action}
+
+		operation, operationOverlaps, err := batchOperation(reqOperation, repo, usedIds, principal)
+
+		if err != nil {
+			results[i].Status = model.BatchStatusFailed
+			results[i].Error = err.Error()
+			invalid = true
+
+			continue
+		}
+
+		overlaps[i] = operationOverlaps
+		operation.Index = i
+		operation.Actor = actor
+		operations = append(operations, *operation)
+	}
+
+	if invalid && mode == model.BatchModeAllOrNothing {
+		for i := range results {
+			if results[i].Status != model.BatchStatusFailed {
+				results[i].Status = model.BatchStatusRolledBack
+			}
+		}
+
+		return results, nil
+	}
+
+	if len(operations) == 0 {
+		return results, nil
+	}
+
+	repoResults, err := repo.Batch(operations, mode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	checkBatchBudgetAlerts(repo, budgetRepo, operations, repoResults)
+
+	for _, result := range repoResults {
+		if result.Subscription != nil {
+			result.Subscription = withOverlaps(result.Subscription, overlaps[result.Index])
+		}
+
+		results[result.Index] = result
+	}
+
+	return results, nil
+}
+
+// BatchRolledBack сообщает, отменён ли пакет операций целиком
+func BatchRolledBack(results []model.BatchResult) bool {
+	for _, result := range results {
+		if result.Status == model.BatchStatusRolledBack {
+			return true
+		}
+	}
+
+	return false
+}
+
+// batchOperation проверяет операцию пакета и права вызывающего и собирает по ней операцию репозитория.
+// Пересечения проверяются только с сохранёнными записями, но не с другими операциями пакета
+func batchOperation(
+	req BatchSubscriptionOperation,
+	repo repository.SubscriptionRepository,
+	usedIds map[int]bool,
+	principal auth.Principal,
+) (*repository.BatchOperation, []model.SubscriptionOverlap, error) {
+	switch req.Action {
+	case model.AuditActionCreate:
+		if req.Subscription == nil {
+			return nil, nil, fmt.Errorf("subscription is required")
+		}
+
+		sub, err := newSubscription(*req.Subscription)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := resolveCatalogService(repo, sub); err != nil {
+			return nil, nil, err
+		}
+
+		sub.UserId, err = AuthorizeUsers(principal, sub.UserId, nil)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		overlaps, err := checkSubscriptionOverlaps(req.Subscription.OverlapMode, repo, sub)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &repository.BatchOperation{Action: req.Action, Subscription: sub}, overlaps, nil
+	case model.AuditActionUpdate, model.AuditActionDelete:
+		if req.Action == model.AuditActionUpdate && req.Subscription == nil {
+			return nil, nil, fmt.Errorf("subscription is required")
+		}
+
+		if usedIds[req.Id] {
+			return nil, nil, fmt.Errorf("subscription %d is used by several operations", req.Id)
+		}
+
+		usedIds[req.Id] = true
+
+		sub, err := GetOneSubscription(repo, req.Id)
+
+		if sub == nil || !principal.Owns(sub.UserId) {
+			if err == nil {
+				err = fmt.Errorf("subscription not found")
+			}
+
+			return nil, nil, err
+		}
+
+		before := *sub
+		after := *sub
+
+		var overlaps []model.SubscriptionOverlap
+		var price *model.SubscriptionPrice
+
+		if req.Action == model.AuditActionUpdate {
+			err = applySubscriptionUpdate(&after, UpdateSubscriptionRequest(*req.Subscription))
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if err := resolveCatalogService(repo, &after); err != nil {
+				return nil, nil, err
+			}
+
+			after.UserId, err = AuthorizeUsers(principal, after.UserId, nil)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			overlaps, err = checkSubscriptionOverlaps(req.Subscription.OverlapMode, repo, &after)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			price, err = priceChangeOnUpdate(repo, before, &after, time.Now())
+
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return &repository.BatchOperation{Action: req.Action, Subscription: &after, Before: &before, Price: price}, overlaps, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown batch action: %s", req.Action)
+	}
+}
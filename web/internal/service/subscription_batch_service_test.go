@@ -0,0 +1,220 @@
+package service
+
+import (
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"testing"
+)
+
+func TestBatchSubscriptions(t *testing.T) {
+	newRepo := func() (repository.SubscriptionRepoMock, []model.Subscription) {
+		subscriptionRepo := repository.SubscriptionRepoMock{
+			Subscriptions: make(map[int]*model.Subscription),
+			Prices:        make(map[int][]model.SubscriptionPrice),
+			Audit:         make(map[int][]model.SubscriptionAudit),
+			Count:         0,
+		}
+
+		return subscriptionRepo, createTestSubscriptions(subscriptionRepo, 2)
+	}
+
+	operations := func(subs []model.Subscription, deleteId int) []BatchSubscriptionOperation {
+		create := CreateSubscriptionRequest{
+			ServiceName: "Пакетный сервис",
+			Price:       500,
+			UserId:      "Пакетный UUID",
+			StartDate:   subs[0].StartDate,
+			EndDate:     subs[0].EndDate,
+		}
+
+		update := create
+		update.ServiceName = "Изменённый сервис"
+
+		return []BatchSubscriptionOperation{
+			{Action: model.AuditActionCreate, Subscription: &create},
+			{Action: model.AuditActionUpdate, Id: subs[0].Id, Subscription: &update},
+			{Action: model.AuditActionDelete, Id: deleteId},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		mode         string
+		deleteExists bool
+		wantStatuses []string
+		wantCount    int
+	}{
+		{
+			name:         "Все операции выполнены",
+			mode:         model.BatchModeAllOrNothing,
+			deleteExists: true,
+			wantStatuses: []string{model.BatchStatusOk, model.BatchStatusOk, model.BatchStatusOk},
+			wantCount:    2,
+		},
+		{
+			name:         "Ошибка отменяет весь пакет",
+			mode:         "",
+			deleteExists: false,
+			wantStatuses: []string{model.BatchStatusRolledBack, model.BatchStatusRolledBack, model.BatchStatusFailed},
+			wantCount:    2,
+		},
+		{
+			name:         "Ошибка пропускает только свою операцию",
+			mode:         model.BatchModeBestEffort,
+			deleteExists: false,
+			wantStatuses: []string{model.BatchStatusOk, model.BatchStatusOk, model.BatchStatusFailed},
+			wantCount:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscriptionRepo, subs := newRepo()
+
+			deleteId := 0
+
+			if tt.deleteExists {
+				deleteId = subs[1].Id
+			}
+
+			results, err := BatchSubscriptions(BatchSubscriptionsRequest{
+				Mode:       tt.mode,
+				Operations: operations(subs, deleteId),
+			}, subscriptionRepo, nil, "тест", auth.Principal{Admin: true})
+
+			if err != nil {
+				t.Errorf("BatchSubscriptions() error = %v", err)
+				return
+			}
+
+			for i, result := range results {
+				if result.Index != i || result.Status != tt.wantStatuses[i] {
+					t.Errorf("BatchSubscriptions() result %d = %+v, want status %s", i, result, tt.wantStatuses[i])
+				}
+			}
+
+			page, _ := ListSubscriptions(ListSubscriptionsRequest{}, subscriptionRepo)
+
+			if page.Total != tt.wantCount {
+				t.Errorf("BatchSubscriptions() подписок после пакета = %d, want %d", page.Total, tt.wantCount)
+			}
+
+			if results[1].Status == model.BatchStatusOk && subscriptionRepo.Subscriptions[subs[0].Id].ServiceName != "Изменённый сервис" {
+				t.Errorf("BatchSubscriptions() подписка не изменена")
+			}
+
+			if results[0].Status == model.BatchStatusOk && len(subscriptionRepo.Audit[results[0].Subscription.Id]) != 1 {
+				t.Errorf("BatchSubscriptions() создание не записано в журнал изменений")
+			}
+		})
+	}
+}
+
+func TestBatchSubscriptionsOwnUser(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Audit:         make(map[int][]model.SubscriptionAudit),
+		Count:         0,
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 1)
+	principal := auth.Principal{UserId: subs[0].UserId}
+
+	create := CreateSubscriptionRequest{
+		ServiceName: "Пакетный сервис",
+		Price:       500,
+		StartDate:   subs[0].StartDate,
+		EndDate:     subs[0].EndDate,
+	}
+
+	update := create
+	update.ServiceName = "Изменённый сервис"
+
+	results, err := BatchSubscriptions(BatchSubscriptionsRequest{
+		Mode: model.BatchModeAllOrNothing,
+		Operations: []BatchSubscriptionOperation{
+			{Action: model.AuditActionCreate, Subscription: &create},
+			{Action: model.AuditActionUpdate, Id: subs[0].Id, Subscription: &update},
+		},
+	}, subscriptionRepo, nil, "тест", principal)
+
+	if err != nil {
+		t.Fatalf("BatchSubscriptions() error = %v", err)
+	}
+
+	for i, result := range results {
+		if result.Status != model.BatchStatusOk {
+			t.Fatalf("BatchSubscriptions() result %d = %+v, want status %s", i, result, model.BatchStatusOk)
+		}
+
+		if got := subscriptionRepo.Subscriptions[result.Subscription.Id].UserId; got != principal.UserId {
+			t.Errorf("BatchSubscriptions() операция %d: пользователь = %q, want %q", i, got, principal.UserId)
+		}
+	}
+}
+
+func TestBatchSubscriptionsValidation(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 1)
+
+	tests := []struct {
+		name    string
+		req     BatchSubscriptionsRequest
+		wantErr bool
+		want    string
+	}{
+		{
+			name:    "Пустой пакет",
+			req:     BatchSubscriptionsRequest{},
+			wantErr: true,
+		},
+		{
+			name: "Неизвестный режим",
+			req: BatchSubscriptionsRequest{
+				Mode:       "sometimes",
+				Operations: []BatchSubscriptionOperation{{Action: model.AuditActionDelete, Id: subs[0].Id}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Неизвестное действие",
+			req: BatchSubscriptionsRequest{
+				Mode:       model.BatchModeBestEffort,
+				Operations: []BatchSubscriptionOperation{{Action: model.AuditActionRestore, Id: subs[0].Id}},
+			},
+			want: model.BatchStatusFailed,
+		},
+		{
+			name: "Повтор подписки в пакете",
+			req: BatchSubscriptionsRequest{
+				Mode: model.BatchModeBestEffort,
+				Operations: []BatchSubscriptionOperation{
+					{Action: model.AuditActionDelete, Id: subs[0].Id},
+					{Action: model.AuditActionDelete, Id: subs[0].Id},
+				},
+			},
+			want: model.BatchStatusFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := BatchSubscriptions(tt.req, subscriptionRepo, nil, "", auth.Principal{Admin: true})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BatchSubscriptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !tt.wantErr && results[len(results)-1].Status != tt.want {
+				t.Errorf("BatchSubscriptions() = %+v, want status %s", results, tt.want)
+			}
+		})
+	}
+}
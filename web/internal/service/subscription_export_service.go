@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+)
+
+// subscriptionExportHeader столбцы выгрузки записей о подписках
+var subscriptionExportHeader = []string{"id", "service_name", "price", "currency", "billing_period", "user_id", "start_date", "end_date"}
+
+// monthlyPriceExportHeader столбцы выгрузки помесячной стоимости подписок
+var monthlyPriceExportHeader = []string{"month", "service_name", "total", "currency"}
+
+// ExportSubscriptions выгружает записи о подписках в формате format, передавая их в w по мере чтения из базы данных.
+// Фильтр и сортировка берутся из запроса списка; без limit выгружаются все подходящие записи.
+// Ошибки параметров возвращаются до записи в w
+func ExportSubscriptions(req ListSubscriptionsRequest, format string, w io.Writer, subscriptionRepo repository.SubscriptionRepository) error {
+	filter, err := req.filter()
+
+	if err != nil {
+		return err
+	}
+
+	options, err := listOptions(req)
+
+	if err != nil {
+		return err
+	}
+
+	if options.Cursor != nil && options.Cursor.Backward {
+		return fmt.Errorf("backward cursor is not supported for export")
+	}
+
+	if req.Limit <= 0 {
+		options.Limit = 0
+	}
+
+	writer, err := utils.NewRowWriter(format, w, subscriptionExportHeader)
+
+	if err != nil {
+		return err
+	}
+
+	err = subscriptionRepo.ListEach(filter, options, func(sub *model.Subscription) error {
+		var endDate any
+
+		if sub.EndDate != nil && sub.EndDate.Valid {
+			endDate = sub.EndDate.String()
+		}
+
+		return writer.WriteRow([]any{
+			sub.Id,
+			sub.ServiceName,
+			sub.Price,
+			sub.Currency,
+			sub.BillingPeriod,
+			sub.UserId,
+			sub.StartDate.String(),
+			endDate,
+		})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// ExportSubscriptionsPricesByMonth выгружает помесячную стоимость подписок в разрезе сервисов в формате format,
+// передавая строки в w по мере чтения из базы данных. Ошибки параметров возвращаются до записи в w
+func ExportSubscriptionsPricesByMonth(req SumSubscriptionsPricesRequest, format string, w io.Writer, subscriptionRepo repository.SubscriptionRepository) error {
+	filter, err := req.filter()
+
+	if err != nil {
+		return err
+	}
+
+	currency, err := normalizeCurrency(req.Currency)
+
+	if err != nil {
+		return err
+	}
+
+	billingMode, err := normalizeBillingMode(req.BillingMode)
+
+	if err != nil {
+		return err
+	}
+
+	writer, err := utils.NewRowWriter(format, w, monthlyPriceExportHeader)
+
+	if err != nil {
+		return err
+	}
+
+	err = subscriptionRepo.SumPricesByMonthEach(filter, currency, billingMode, func(month utils.Date, servicePrice model.ServicePrice) error {
+		return writer.WriteRow([]any{month.String(), servicePrice.ServiceName, servicePrice.Total, currency})
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
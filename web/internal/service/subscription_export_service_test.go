@@ -0,0 +1,207 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+)
+
+func TestExportSubscriptions(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 12)
+
+	req := ListSubscriptionsRequest{
+		SortBy:    model.SortByPrice,
+		SortOrder: "desc",
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		var out bytes.Buffer
+
+		err := ExportSubscriptions(req, utils.ExportFormatCSV, &out, subscriptionRepo)
+
+		if err != nil {
+			t.Errorf("ExportSubscriptions() error = %v", err)
+			return
+		}
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+		if len(lines) != len(subs)+1 || lines[0] != strings.Join(subscriptionExportHeader, ",") {
+			t.Errorf("ExportSubscriptions() = %q", out.String())
+			return
+		}
+
+		want := strings.Join([]string{
+			strconv.Itoa(subs[11].Id),
+			subs[11].ServiceName,
+			strconv.Itoa(subs[11].Price),
+			subs[11].Currency,
+			subs[11].BillingPeriod,
+			subs[11].UserId,
+			subs[11].StartDate.String(),
+			subs[11].EndDate.String(),
+		}, ",")
+
+		if lines[1] != want {
+			t.Errorf("ExportSubscriptions() first row = %q, want %q", lines[1], want)
+		}
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		var out bytes.Buffer
+
+		err := ExportSubscriptions(ListSubscriptionsRequest{UserId: subs[0].UserId}, utils.ExportFormatNDJSON, &out, subscriptionRepo)
+
+		if err != nil {
+			t.Errorf("ExportSubscriptions() error = %v", err)
+			return
+		}
+
+		var row map[string]any
+
+		if err := json.Unmarshal(bytes.TrimSpace(out.Bytes()), &row); err != nil {
+			t.Errorf("ExportSubscriptions() = %q, error = %v", out.String(), err)
+			return
+		}
+
+		if row["service_name"] != subs[0].ServiceName || row["price"] != float64(subs[0].Price) {
+			t.Errorf("ExportSubscriptions() = %v", row)
+		}
+	})
+
+	t.Run("XLSX", func(t *testing.T) {
+		var out bytes.Buffer
+
+		err := ExportSubscriptions(req, utils.ExportFormatXLSX, &out, subscriptionRepo)
+
+		if err != nil {
+			t.Errorf("ExportSubscriptions() error = %v", err)
+			return
+		}
+
+		archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+
+		if err != nil {
+			t.Errorf("ExportSubscriptions() не является zip-архивом: %v", err)
+			return
+		}
+
+		for _, file := range archive.File {
+			if file.Name != "xl/worksheets/sheet1.xml" {
+				continue
+			}
+
+			reader, _ := file.Open()
+			sheet, _ := io.ReadAll(reader)
+
+			if strings.Count(string(sheet), "<row>") != len(subs)+1 || !strings.Contains(string(sheet), subs[0].ServiceName) {
+				t.Errorf("ExportSubscriptions() sheet = %s", sheet)
+			}
+
+			return
+		}
+
+		t.Errorf("ExportSubscriptions() в книге нет листа")
+	})
+
+	t.Run("Ошибка параметров до начала выгрузки", func(t *testing.T) {
+		var out bytes.Buffer
+
+		err := ExportSubscriptions(ListSubscriptionsRequest{SortBy: "user"}, utils.ExportFormatCSV, &out, subscriptionRepo)
+
+		if err == nil || out.Len() != 0 {
+			t.Errorf("ExportSubscriptions() error = %v, written = %q", err, out.String())
+		}
+	})
+}
+
+func TestExportSubscriptionsPricesByMonth(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 1)
+
+	var out bytes.Buffer
+
+	err := ExportSubscriptionsPricesByMonth(SumSubscriptionsPricesRequest{}, utils.ExportFormatCSV, &out, subscriptionRepo)
+
+	if err != nil {
+		t.Errorf("ExportSubscriptionsPricesByMonth() error = %v", err)
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := strings.Join([]string{subs[0].StartDate.String(), subs[0].ServiceName, strconv.Itoa(subs[0].Price), model.DefaultCurrency}, ",")
+
+	if len(lines) != 8 || lines[1] != want {
+		t.Errorf("ExportSubscriptionsPricesByMonth() = %q, want first row %q", out.String(), want)
+	}
+}
+
+func TestExportSubscriptionsFormulas(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 1)
+
+	subscriptionRepo.Subscriptions[subs[0].Id].ServiceName = "=HYPERLINK(\"http://example.com\")"
+	subscriptionRepo.Subscriptions[subs[0].Id].UserId = "@SUM(A1)"
+
+	var out bytes.Buffer
+
+	if err := ExportSubscriptions(ListSubscriptionsRequest{}, utils.ExportFormatCSV, &out, subscriptionRepo); err != nil {
+		t.Errorf("ExportSubscriptions() error = %v", err)
+		return
+	}
+
+	if !strings.Contains(out.String(), `"'=HYPERLINK(""http://example.com"")"`) || !strings.Contains(out.String(), ",'@SUM(A1),") {
+		t.Errorf("ExportSubscriptions() CSV без экранирования формул = %q", out.String())
+	}
+
+	out.Reset()
+
+	if err := ExportSubscriptions(ListSubscriptionsRequest{}, utils.ExportFormatXLSX, &out, subscriptionRepo); err != nil {
+		t.Errorf("ExportSubscriptions() error = %v", err)
+		return
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+
+	if err != nil {
+		t.Errorf("ExportSubscriptions() не является zip-архивом: %v", err)
+		return
+	}
+
+	for _, file := range archive.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+
+		reader, _ := file.Open()
+		sheet, _ := io.ReadAll(reader)
+
+		if strings.Contains(string(sheet), "<f>") || !strings.Contains(string(sheet), `<c t="inlineStr"><is><t xml:space="preserve">@SUM(A1)</t></is></c>`) {
+			t.Errorf("ExportSubscriptions() XLSX sheet = %s", sheet)
+		}
+
+		return
+	}
+
+	t.Errorf("ExportSubscriptions() в книге нет листа")
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+)
+
+// maxImportRows максимальное количество строк с данными в импортируемом файле
+const maxImportRows = 10000
+
+// importColumns столбцы импортируемого файла в порядке по умолчанию, если в файле нет заголовка
+var importColumns = []string{"service_name", "price", "user_id", "start_date", "end_date"}
+
+// importOptionalColumns столбцы, которые можно указать только в заголовке файла
+var importOptionalColumns = []string{"currency", "billing_period"}
+
+// ImportSubscriptions проверяет строки CSV с записями о подписках и создаёт записи по строкам без ошибок в одной транзакции.
+// Первая строка считается заголовком, если содержит service_name; разделитель ";" определяется по первой строке.
+// Пустые строки пропускаются, ошибки указываются с номером строки в файле.
+// Строки с подписками пользователей, к которым у вызывающего нет доступа, считаются ошибочными.
+// Названия сервисов, найденные в каталоге, заменяются каноническими.
+// При dryRun записи не создаются, а возвращается только отчёт о проверке
+func ImportSubscriptions(reader io.Reader, dryRun bool, repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, actor string, principal auth.Principal) (*model.ImportReport, error) {
+	records, err := readImportRecords(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ImportReport{
+		DryRun:     dryRun,
+		CreatedIds: []int{},
+		Errors:     []model.ImportRowError{},
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+
+	columns := importColumns
+
+	if header := normalizeImportHeader(records[0].fields); slices.Contains(header, "service_name") {
+		columns = header
+		records = records[1:]
+
+		for _, column := range columns {
+			if !slices.Contains(importColumns, column) && !slices.Contains(importOptionalColumns, column) {
+				return nil, fmt.Errorf("unknown column: %s", column)
+			}
+		}
+
+		for _, column := range importColumns[:4] {
+			if !slices.Contains(columns, column) {
+				return nil, fmt.Errorf("required column is missing: %s", column)
+			}
+		}
+	}
+
+	if len(records) > maxImportRows {
+		return nil, fmt.Errorf("too many rows: %d, maximum is %d", len(records), maxImportRows)
+	}
+
+	operations := []repository.BatchOperation{}
+
+	for _, record := range records {
+		if strings.TrimSpace(strings.Join(record.fields, "")) == "" {
+			continue
+		}
+
+		report.TotalRows++
+
+		row := record.line
+
+		sub, rowErrors := parseImportRecord(record.fields, columns, row)
+
+		if len(rowErrors) > 0 {
+			report.Errors = append(report.Errors, rowErrors...)
+			continue
+		}
+
+		if _, err := AuthorizeUsers(principal, sub.UserId, nil); err != nil {
+			report.Errors = append(report.Errors, model.ImportRowError{Row: row, Column: "user_id", Error: err.Error()})
+			continue
+		}
+
+		if err := resolveCatalogService(repo, sub); err != nil {
+			report.Errors = append(report.Errors, model.ImportRowError{Row: row, Column: "service_name", Error: err.Error()})
+			continue
+		}
+
+		operations = append(operations, repository.BatchOperation{
+			Index:        row,
+			Action:       model.AuditActionCreate,
+			Subscription: sub,
+			Actor:        actor,
+		})
+	}
+
+	report.ValidRows = len(operations)
+
+	if dryRun || len(operations) == 0 {
+		return report, nil
+	}
+
+	results, err := repo.Batch(operations, model.BatchModeAllOrNothing)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if BatchRolledBack(results) {
+		for _, result := range results {
+			if result.Status == model.BatchStatusFailed {
+				report.Errors = append(report.Errors, model.ImportRowError{Row: result.Index, Error: result.Error})
+			}
+		}
+
+		return report, nil
+	}
+
+	checkBatchBudgetAlerts(repo, budgetRepo, operations, results)
+
+	for _, result := range results {
+		report.CreatedIds = append(report.CreatedIds, result.Subscription.Id)
+	}
+
+	report.Imported = len(report.CreatedIds)
+
+	return report, nil
+}
+
+// readImportRecords читает все строки CSV. Строки с неверным количеством столбцов проверяются при разборе строки
+func readImportRecords(reader io.Reader) ([]importRecord, error) {
+	buffered := bufio.NewReader(reader)
+
+	firstLine, err := buffered.Peek(buffered.Size())
+
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if end := strings.IndexByte(string(firstLine), '\n'); end >= 0 {
+		firstLine = firstLine[:end]
+	}
+
+	csvReader := csv.NewReader(buffered)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	if strings.Count(string(firstLine), ";") > strings.Count(string(firstLine), ",") {
+		csvReader.Comma = ';'
+	}
+
+	records := []importRecord{}
+
+	for {
+		fields, err := csvReader.Read()
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+
+		line, _ := csvReader.FieldPos(0)
+
+		records = append(records, importRecord{line: line, fields: fields})
+	}
+
+	if len(records) > 0 {
+		records[0].fields[0] = strings.TrimPrefix(records[0].fields[0], "\ufeff")
+	}
+
+	return records, nil
+}
+
+// importRecord строка импортируемого файла с номером строки в файле
+type importRecord struct {
+	line   int
+	fields []string
+}
+
+// normalizeImportHeader приводит названия столбцов заголовка к нижнему регистру без пробелов по краям
+func normalizeImportHeader(record []string) []string {
+	header := make([]string, len(record))
+
+	for i, column := range record {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+	}
+
+	return header
+}
+
+// parseImportRecord проверяет строку файла и собирает по ней запись о подписке
+func parseImportRecord(record []string, columns []string, row int) (*model.Subscription, []model.ImportRowError) {
+	rowErrors := []model.ImportRowError{}
+
+	addError := func(column string, format string, args ...any) {
+		rowErrors = append(rowErrors, model.ImportRowError{Row: row, Column: column, Error: fmt.Sprintf(format, args...)})
+	}
+
+	if len(record) > len(columns) {
+		addError("", "expected at most %d columns, got %d", len(columns), len(record))
+
+		return nil, rowErrors
+	}
+
+	values := make(map[string]string)
+
+	for i, column := range columns {
+		if i < len(record) {
+			values[column] = strings.TrimSpace(record[i])
+		}
+	}
+
+	req := CreateSubscriptionRequest{
+		ServiceName:   values["service_name"],
+		UserId:        values["user_id"],
+		Currency:      values["currency"],
+		BillingPeriod: values["billing_period"],
+	}
+
+	if req.ServiceName == "" {
+		addError("service_name", "service_name is required")
+	}
+
+	if req.UserId == "" {
+		addError("user_id", "user_id is required")
+	}
+
+	price, err := strconv.Atoi(values["price"])
+
+	if err != nil || price <= 0 {
+		addError("price", "price must be a positive integer: %q", values["price"])
+	}
+
+	req.Price = price
+
+	startDate, err := utils.ParseDate(values["start_date"])
+
+	if err != nil {
+		addError("start_date", "start_date must be in MM-YYYY format: %q", values["start_date"])
+	}
+
+	req.StartDate = &startDate
+
+	if values["end_date"] != "" {
+		endDate, err := utils.ParseDate(values["end_date"])
+
+		if err != nil {
+			addError("end_date", "end_date must be in MM-YYYY format: %q", values["end_date"])
+		} else if startDate.Valid && endDate.Time.Before(startDate.Time) {
+			addError("end_date", "end_date is before start_date")
+		}
+
+		req.EndDate = &endDate
+	}
+
+	if _, err := normalizeCurrency(req.Currency); err != nil {
+		addError("currency", "%s", err.Error())
+	}
+
+	if _, err := normalizeBillingPeriod(req.BillingPeriod); err != nil {
+		addError("billing_period", "%s", err.Error())
+	}
+
+	if len(rowErrors) > 0 {
+		return nil, rowErrors
+	}
+
+	sub, err := newSubscription(req)
+
+	if err != nil {
+		addError("", "%s", err.Error())
+
+		return nil, rowErrors
+	}
+
+	return sub, nil
+}
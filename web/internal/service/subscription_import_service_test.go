@@ -0,0 +1,94 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"testing"
+)
+
+func TestImportSubscriptions(t *testing.T) {
+	csv := "\ufeffservice_name;price;user_id;start_date;end_date;currency\n" +
+		"Yandex Plus;400;Тестовый UUID;07-2025;12-2025;rub\n" +
+		"\n" +
+		"Kinopoisk;-1;Тестовый UUID;2025-07;06-2025;\n" +
+		"Netflix;10;Тестовый UUID;01-2025;;USD\n"
+
+	tests := []struct {
+		name         string
+		dryRun       bool
+		wantImported int
+		wantRows     int
+	}{
+		{
+			name:         "Проверка без создания записей",
+			dryRun:       true,
+			wantImported: 0,
+			wantRows:     0,
+		},
+		{
+			name:         "Импорт строк без ошибок",
+			dryRun:       false,
+			wantImported: 2,
+			wantRows:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscriptionRepo := repository.SubscriptionRepoMock{
+				Subscriptions: make(map[int]*model.Subscription),
+				Count:         0,
+			}
+
+			report, err := ImportSubscriptions(strings.NewReader(csv), tt.dryRun, subscriptionRepo, nil, "", auth.Principal{Admin: true})
+
+			if err != nil {
+				t.Errorf("ImportSubscriptions() error = %v", err)
+				return
+			}
+
+			if report.TotalRows != 3 || report.ValidRows != 2 || report.Imported != tt.wantImported {
+				t.Errorf("ImportSubscriptions() = %+v", report)
+			}
+
+			wantErrors := []model.ImportRowError{
+				{Row: 4, Column: "price", Error: `price must be a positive integer: "-1"`},
+				{Row: 4, Column: "start_date", Error: `start_date must be in MM-YYYY format: "2025-07"`},
+			}
+
+			if !reflect.DeepEqual(report.Errors, wantErrors) {
+				t.Errorf("ImportSubscriptions() errors = %+v, want %+v", report.Errors, wantErrors)
+			}
+
+			if len(subscriptionRepo.Subscriptions) != tt.wantRows {
+				t.Errorf("ImportSubscriptions() создано записей = %d, want %d", len(subscriptionRepo.Subscriptions), tt.wantRows)
+			}
+
+			if !tt.dryRun && subscriptionRepo.Subscriptions[report.CreatedIds[1]].Currency != "USD" {
+				t.Errorf("ImportSubscriptions() валюта = %s, want USD", subscriptionRepo.Subscriptions[report.CreatedIds[1]].Currency)
+			}
+		})
+	}
+}
+
+func TestImportSubscriptionsWithoutHeader(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	report, err := ImportSubscriptions(strings.NewReader("Yandex Plus,400,Тестовый UUID,07-2025,12-2025\n"), false, subscriptionRepo, nil, "", auth.Principal{Admin: true})
+
+	if err != nil || report.Imported != 1 {
+		t.Errorf("ImportSubscriptions() = %+v, error = %v", report, err)
+	}
+
+	_, err = ImportSubscriptions(strings.NewReader("service_name,price,user_id,start_date,comment\n"), false, subscriptionRepo, nil, "", auth.Principal{Admin: true})
+
+	if err == nil {
+		t.Errorf("ImportSubscriptions() с неизвестным столбцом не вернула ошибку")
+	}
+}
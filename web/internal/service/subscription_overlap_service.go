@@ -0,0 +1,437 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"time"
+)
+
+// ListSubscriptionOverlapsRequest Модель данных для поиска пересекающихся записей о подписках.
+// Период ограничивает месяцы пересечения
+//
+//	@modelId	list-sub-overlaps-request
+type ListSubscriptionOverlapsRequest struct {
+	ServiceName  string     `json:"service_name,omitempty"`
+	UserId       string     `json:"user_id,omitempty"`
+	ServiceNames []string   `json:"service_names,omitempty" example:"Yandex Plus"`
+	UserIds      []string   `json:"user_ids,omitempty"`
+	StartDate    utils.Date `json:"start_date,omitempty" swaggertype:"string" example:"01-2025"`
+	EndDate      utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"12-2025"`
+}
+
+// MergeSubscriptionOverlapsRequest Модель данных для объединения пересекающихся записей о подписках.
+// При DryRun объединение только рассчитывается
+//
+//	@modelId	merge-sub-overlaps-request
+type MergeSubscriptionOverlapsRequest struct {
+	ServiceName  string   `json:"service_name,omitempty"`
+	UserId       string   `json:"user_id,omitempty"`
+	ServiceNames []string `json:"service_names,omitempty" example:"Yandex Plus"`
+	UserIds      []string `json:"user_ids,omitempty"`
+	DryRun       bool     `json:"dry_run,omitempty"`
+}
+
+func ListSubscriptionOverlaps(req ListSubscriptionOverlapsRequest, repo repository.SubscriptionRepository) ([]model.SubscriptionOverlap, error) {
+	if req.StartDate.Valid && req.EndDate.Valid && req.EndDate.Time.Before(req.StartDate.Time) {
+		return nil, fmt.Errorf("end_date is before start_date")
+	}
+
+	filter, err := subscriptionFilter(repository.SubscriptionFilter{
+		UserIds:      req.UserIds,
+		ServiceNames: req.ServiceNames,
+		MaxStartDate: req.StartDate,
+		MinEndDate:   req.EndDate,
+	}, req.UserId, req.ServiceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.ListOverlaps(filter)
+}
+
+// checkSubscriptionOverlaps проверяет пересечения записи о подписке с сохранёнными записями того же пользователя
+// на тот же сервис в режиме mode. В режиме model.OverlapModeReject пересечение возвращается ошибкой,
+// в режиме model.OverlapModeWarn возвращаются найденные пересечения
+func checkSubscriptionOverlaps(mode string, repo repository.SubscriptionRepository, sub *model.Subscription) ([]model.SubscriptionOverlap, error) {
+	switch mode {
+	case "", model.OverlapModeAllow:
+		return nil, nil
+	case model.OverlapModeWarn, model.OverlapModeReject:
+	default:
+		return nil, fmt.Errorf("unknown overlap mode: %s", mode)
+	}
+
+	others, err := repo.FindOverlapping(sub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(others) == 0 {
+		return nil, nil
+	}
+
+	overlaps := make([]model.SubscriptionOverlap, len(others))
+	ids := make([]string, len(others))
+
+	for i := range others {
+		overlaps[i] = subscriptionOverlap(sub, &others[i])
+		ids[i] = strconv.Itoa(others[i].Id)
+	}
+
+	if mode == model.OverlapModeReject {
+		return nil, fmt.Errorf("subscription conflict: period overlaps with subscriptions %s", strings.Join(ids, ", "))
+	}
+
+	return overlaps, nil
+}
+
+// withOverlaps возвращает копию записи о подписке с пересечениями для ответа, не меняя сохранённую запись
+func withOverlaps(sub *model.Subscription, overlaps []model.SubscriptionOverlap) *model.Subscription {
+	if len(overlaps) == 0 {
+		return sub
+	}
+
+	result := *sub
+	result.Overlaps = overlaps
+
+	for i := range result.Overlaps {
+		result.Overlaps[i].SubscriptionId = sub.Id
+	}
+
+	return &result
+}
+
+// subscriptionOverlap возвращает месяцы пересечения периодов двух записей о подписке
+func subscriptionOverlap(sub *model.Subscription, other *model.Subscription) model.SubscriptionOverlap {
+	overlap := model.SubscriptionOverlap{
+		UserId:              sub.UserId,
+		ServiceName:         sub.ServiceName,
+		SubscriptionId:      sub.Id,
+		OtherSubscriptionId: other.Id,
+		StartDate:           sub.StartDate,
+	}
+
+	if other.StartDate.Time.After(sub.StartDate.Time) {
+		overlap.StartDate = other.StartDate
+	}
+
+	for _, endDate := range []*utils.Date{sub.EndDate, other.EndDate} {
+		if endDate != nil && endDate.Valid && (overlap.EndDate == nil || endDate.Time.Before(overlap.EndDate.Time)) {
+			overlap.EndDate = endDate
+		}
+	}
+
+	return overlap
+}
+
+// MergeSubscriptionOverlaps объединяет каждую группу записей о подписках одного пользователя на один сервис,
+// связанных пересечениями периодов, в самую раннюю запись группы. Объединённая запись получает период от начала
+// самой ранней до окончания самой поздней записи, её стоимость и история стоимости сохраняются, остальные записи удаляются.
+// Если стоимость, доли или паузы удаляемой записи отличаются от объединённой записи, объединение изменило бы расходы,
+// поэтому различия возвращаются в conflicts, а без DryRun ни одна группа не объединяется.
+// Каждая группа объединяется в отдельной транзакции
+func MergeSubscriptionOverlaps(req MergeSubscriptionOverlapsRequest, repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, actor string) ([]model.SubscriptionMerge, error) {
+	filter, err := subscriptionFilter(repository.SubscriptionFilter{
+		UserIds:      req.UserIds,
+		ServiceNames: req.ServiceNames,
+	}, req.UserId, req.ServiceName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	overlaps, err := repo.ListOverlaps(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	merges := []model.SubscriptionMerge{}
+	batches := [][]repository.BatchOperation{}
+
+	for _, group := range overlapGroups(overlaps) {
+		subs := make([]model.Subscription, 0, len(group))
+
+		for _, id := range group {
+			sub, err := GetOneSubscription(repo, id)
+
+			if sub == nil {
+				if err == nil {
+					err = fmt.Errorf("subscription %d not found", id)
+				}
+
+				return merges, err
+			}
+
+			subs = append(subs, *sub)
+		}
+
+		slices.SortFunc(subs, func(a, b model.Subscription) int {
+			return cmp.Or(a.StartDate.Time.Compare(b.StartDate.Time), cmp.Compare(a.Id, b.Id))
+		})
+
+		before := subs[0]
+		merged := subs[0]
+		operations := []repository.BatchOperation{}
+		mergedIds := []int{}
+
+		conflicts := []string{}
+
+		for i := range subs[1:] {
+			other := subs[i+1]
+
+			otherConflicts, err := mergeConflicts(repo, before, other)
+
+			if err != nil {
+				return merges, err
+			}
+
+			for _, conflict := range otherConflicts {
+				if !slices.Contains(conflicts, conflict) {
+					conflicts = append(conflicts, conflict)
+				}
+			}
+
+			if merged.EndDate != nil && merged.EndDate.Valid &&
+				(other.EndDate == nil || !other.EndDate.Valid || other.EndDate.Time.After(merged.EndDate.Time)) {
+				merged.EndDate = other.EndDate
+			}
+
+			deleted := other
+
+			operations = append(operations, repository.BatchOperation{
+				Index:        len(operations),
+				Action:       model.AuditActionDelete,
+				Subscription: &deleted,
+				Before:       &other,
+				Actor:        actor,
+			})
+			mergedIds = append(mergedIds, other.Id)
+		}
+
+		// Записи удаляются до изменения объединённой записи, чтобы изменение не нарушило ограничение subscriptions_no_overlap
+		operations = append(operations, repository.BatchOperation{
+			Index:        len(operations),
+			Action:       model.AuditActionUpdate,
+			Subscription: &merged,
+			Before:       &before,
+			Actor:        actor,
+		})
+
+		merges = append(merges, model.SubscriptionMerge{Subscription: &merged, MergedIds: mergedIds})
+		batches = append(batches, operations)
+
+		if len(conflicts) > 0 {
+			merges[len(merges)-1].Conflicts = conflicts
+		}
+	}
+
+	if req.DryRun {
+		return merges, nil
+	}
+
+	for _, merge := range merges {
+		if len(merge.Conflicts) > 0 {
+			return nil, fmt.Errorf("subscription conflict: cannot merge into subscription %d: %s", merge.Subscription.Id, strings.Join(merge.Conflicts, "; "))
+		}
+	}
+
+	for i, operations := range batches {
+		results, err := repo.Batch(operations, model.BatchModeAllOrNothing)
+
+		if err != nil {
+			return merges[:i], err
+		}
+
+		for _, result := range results {
+			if result.Status == model.BatchStatusFailed {
+				return merges[:i], fmt.Errorf("failed to merge subscription %d: %s", merges[i].Subscription.Id, result.Error)
+			}
+		}
+
+		checkBatchBudgetAlerts(repo, budgetRepo, operations, results)
+	}
+
+	return merges, nil
+}
+
+// mergeConflicts возвращает различия записи other с записью merged, в которую она объединяется. После объединения месяцы other
+// оплачиваются по стоимости и истории стоимости merged, доли и паузы other удаляются вместе с ней, а паузы merged
+// распространяются на месяцы other. Поэтому объединение возможно, только если стоимость в каждом месяце other и доли совпадают,
+// а пауз ни у одной из записей в объединённом периоде нет
+func mergeConflicts(repo repository.SubscriptionRepository, merged model.Subscription, other model.Subscription) ([]string, error) {
+	conflicts := []string{}
+
+	mergedPrices, err := repo.ListPrices(merged.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	otherPrices, err := repo.ListPrices(other.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if merged.Currency != other.Currency || merged.BillingPeriod != other.BillingPeriod {
+		conflicts = append(conflicts, fmt.Sprintf("subscription %d is billed in %s per %s instead of %s per %s",
+			other.Id, other.Currency, other.BillingPeriod, merged.Currency, merged.BillingPeriod))
+	} else if month, ok := priceDifference(merged, mergedPrices, other, otherPrices); ok {
+		conflicts = append(conflicts, fmt.Sprintf("price of subscription %d differs in %s: %d instead of %d",
+			other.Id, month.Format("01-2006"), priceOn(other, otherPrices, month), priceOn(merged, mergedPrices, month)))
+	}
+
+	mergedShares, err := repo.ListShares(merged.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	otherShares, err := repo.ListShares(other.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameShares(mergedShares, otherShares) {
+		conflicts = append(conflicts, fmt.Sprintf("shares of subscription %d differ", other.Id))
+	}
+
+	from, to := minTime(merged.StartDate.Time, other.StartDate.Time), merged.EndDate
+
+	if to == nil || !to.Valid || other.EndDate == nil || !other.EndDate.Valid {
+		to = nil
+	} else if other.EndDate.Time.After(to.Time) {
+		to = other.EndDate
+	}
+
+	for _, sub := range []model.Subscription{merged, other} {
+		pauses, err := repo.ListPauses(sub.Id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if pausedWithin(pauses, from, to) {
+			conflicts = append(conflicts, fmt.Sprintf("subscription %d has pauses", sub.Id))
+		}
+	}
+
+	return conflicts, nil
+}
+
+// pausedWithin сообщает, пересекается ли хотя бы одна из пауз с периодом с месяца from по месяц to включительно.
+// Период без даты окончания не ограничен
+func pausedWithin(pauses []model.SubscriptionPause, from time.Time, to *utils.Date) bool {
+	for _, pause := range pauses {
+		startsBeforeEnd := to == nil || !to.Valid || !pause.StartDate.Time.After(monthOf(to.Time))
+		endsAfterStart := pause.EndDate == nil || !pause.EndDate.Valid || !pause.EndDate.Time.Before(monthOf(from))
+
+		if startsBeforeEnd && endsAfterStart {
+			return true
+		}
+	}
+
+	return false
+}
+
+// priceDifference возвращает первый месяц периода other, стоимость в котором по условиям other отличается от стоимости
+// по условиям merged. Для записи без даты окончания месяцы проверяются до последнего изменения стоимости, после которого она постоянна
+func priceDifference(merged model.Subscription, mergedPrices []model.SubscriptionPrice, other model.Subscription, otherPrices []model.SubscriptionPrice) (time.Time, bool) {
+	last := monthOf(other.StartDate.Time)
+
+	if other.EndDate != nil && other.EndDate.Valid {
+		last = monthOf(other.EndDate.Time)
+	} else {
+		for _, sub := range []model.Subscription{merged, other} {
+			last = maxTime(last, monthOf(repository.BillingStart(sub)).AddDate(0, sub.PromoMonths, 0))
+		}
+
+		for _, price := range append(slices.Clone(mergedPrices), otherPrices...) {
+			last = maxTime(last, monthOf(price.EffectiveFrom.Time))
+		}
+	}
+
+	for month := monthOf(other.StartDate.Time); !month.After(last); month = month.AddDate(0, 1, 0) {
+		if priceOn(merged, mergedPrices, month) != priceOn(other, otherPrices, month) {
+			return month, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// sameShares проверяет, что доли участников двух записей о подписке совпадают
+func sameShares(a []model.SubscriptionShare, b []model.SubscriptionShare) bool {
+	key := func(share model.SubscriptionShare) string {
+		return fmt.Sprintf("%s:%g:%d", share.UserId, share.Percent, share.Amount)
+	}
+
+	keys := func(shares []model.SubscriptionShare) []string {
+		result := make([]string, len(shares))
+
+		for i, share := range shares {
+			result[i] = key(share)
+		}
+
+		slices.Sort(result)
+
+		return result
+	}
+
+	return slices.Equal(keys(a), keys(b))
+}
+
+// overlapGroups собирает ИД записей о подписках, связанных цепочками пересечений, в группы.
+// Группы упорядочены по наименьшему ИД, ИД в группе — по возрастанию
+func overlapGroups(overlaps []model.SubscriptionOverlap) [][]int {
+	parents := make(map[int]int)
+
+	var root func(id int) int
+
+	root = func(id int) int {
+		parent, ok := parents[id]
+
+		if !ok || parent == id {
+			parents[id] = id
+
+			return id
+		}
+
+		parents[id] = root(parent)
+
+		return parents[id]
+	}
+
+	for _, overlap := range overlaps {
+		first, second := root(overlap.SubscriptionId), root(overlap.OtherSubscriptionId)
+		parents[max(first, second)] = min(first, second)
+	}
+
+	groups := make(map[int][]int)
+
+	for id := range parents {
+		groups[root(id)] = append(groups[root(id)], id)
+	}
+
+	result := make([][]int, 0, len(groups))
+
+	for _, group := range groups {
+		slices.Sort(group)
+		result = append(result, group)
+	}
+
+	slices.SortFunc(result, func(a, b []int) int { return cmp.Compare(a[0], b[0]) })
+
+	return result
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"subsaggregator/internal/auth"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestListSubscriptionOverlaps(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	create := func(serviceName string, userId string, startDate *utils.Date, endDate *utils.Date) *model.Subscription {
+		sub, _ := CreateSubscription(CreateSubscriptionRequest{
+			ServiceName: serviceName,
+			Price:       400,
+			UserId:      userId,
+			StartDate:   startDate,
+			EndDate:     endDate,
+		}, subscriptionRepo, nil, "")
+
+		return sub
+	}
+
+	first := create("Yandex Plus", "Тестовый UUID", date(2025, time.January), date(2025, time.June))
+	second := create("Yandex Plus", "Тестовый UUID", date(2025, time.April), nil)
+	third := create("Yandex Plus", "Тестовый UUID", date(2025, time.September), nil)
+	create("Yandex Plus", "Тестовый UUID", date(2025, time.July), date(2025, time.August))
+	create("Yandex Plus", "Другой UUID", date(2025, time.January), date(2025, time.June))
+	create("Spotify", "Тестовый UUID", date(2025, time.January), date(2025, time.June))
+
+	overlaps, err := ListSubscriptionOverlaps(ListSubscriptionOverlapsRequest{UserId: "Тестовый UUID"}, subscriptionRepo)
+
+	if err != nil || len(overlaps) != 3 {
+		t.Errorf("ListSubscriptionOverlaps() = %+v, %v", overlaps, err)
+		return
+	}
+
+	if overlaps[0].SubscriptionId != first.Id || overlaps[0].OtherSubscriptionId != second.Id ||
+		!overlaps[0].StartDate.Time.Equal(date(2025, time.April).Time) || !overlaps[0].EndDate.Time.Equal(date(2025, time.June).Time) {
+		t.Errorf("ListSubscriptionOverlaps() первое пересечение = %+v", overlaps[0])
+	}
+
+	if overlaps[2].SubscriptionId != second.Id || overlaps[2].OtherSubscriptionId != third.Id || overlaps[2].EndDate != nil {
+		t.Errorf("ListSubscriptionOverlaps() пересечение записей без даты окончания = %+v", overlaps[2])
+	}
+
+	overlaps, _ = ListSubscriptionOverlaps(ListSubscriptionOverlapsRequest{
+		UserId:    "Тестовый UUID",
+		StartDate: *date(2025, time.July),
+		EndDate:   *date(2025, time.August),
+	}, subscriptionRepo)
+
+	if len(overlaps) != 1 || overlaps[0].OtherSubscriptionId != 4 {
+		t.Errorf("ListSubscriptionOverlaps() за июль — август = %+v", overlaps)
+	}
+
+	if _, err := ListSubscriptionOverlaps(ListSubscriptionOverlapsRequest{
+		StartDate: *date(2025, time.July),
+		EndDate:   *date(2025, time.January),
+	}, subscriptionRepo); err == nil {
+		t.Errorf("ListSubscriptionOverlaps() с концом периода раньше начала не вернула ошибку")
+	}
+}
+
+func TestSubscriptionOverlapMode(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	request := func(startDate *utils.Date, endDate *utils.Date, overlapMode string) CreateSubscriptionRequest {
+		return CreateSubscriptionRequest{
+			ServiceName: "Yandex Plus",
+			Price:       400,
+			UserId:      "Тестовый UUID",
+			StartDate:   startDate,
+			EndDate:     endDate,
+			OverlapMode: overlapMode,
+		}
+	}
+
+	existing, _ := CreateSubscription(request(date(2025, time.January), date(2025, time.June), ""), subscriptionRepo, nil, "")
+
+	if sub, err := CreateSubscription(request(date(2025, time.May), nil, model.OverlapModeReject), subscriptionRepo, nil, ""); err == nil {
+		t.Errorf("CreateSubscription() в режиме reject = %+v, want error", sub)
+	}
+
+	if len(subscriptionRepo.Subscriptions) != 1 {
+		t.Errorf("CreateSubscription() в режиме reject сохранила запись")
+	}
+
+	if _, err := CreateSubscription(request(date(2025, time.May), nil, "strict"), subscriptionRepo, nil, ""); err == nil {
+		t.Errorf("CreateSubscription() с неизвестным режимом не вернула ошибку")
+	}
+
+	warned, err := CreateSubscription(request(date(2025, time.May), nil, model.OverlapModeWarn), subscriptionRepo, nil, "")
+
+	if err != nil || len(warned.Overlaps) != 1 || warned.Overlaps[0].SubscriptionId != warned.Id ||
+		warned.Overlaps[0].OtherSubscriptionId != existing.Id || !warned.Overlaps[0].EndDate.Time.Equal(date(2025, time.June).Time) {
+		t.Errorf("CreateSubscription() в режиме warn = %+v, %v", warned, err)
+		return
+	}
+
+	if subscriptionRepo.Subscriptions[warned.Id].Overlaps != nil {
+		t.Errorf("CreateSubscription() сохранила пересечения в записи")
+	}
+
+	updated, err := UpdateSubscription(UpdateSubscriptionRequest(request(date(2025, time.July), nil, model.OverlapModeReject)), subscriptionRepo, nil, warned.Id, "")
+
+	if err != nil || updated.Overlaps != nil {
+		t.Errorf("UpdateSubscription() без пересечений = %+v, %v", updated, err)
+	}
+
+	if sub, err := UpdateSubscription(UpdateSubscriptionRequest(request(date(2025, time.March), nil, model.OverlapModeReject)), subscriptionRepo, nil, warned.Id, ""); err == nil {
+		t.Errorf("UpdateSubscription() в режиме reject = %+v, want error", sub)
+	}
+
+	results, err := BatchSubscriptions(BatchSubscriptionsRequest{
+		Mode: model.BatchModeBestEffort,
+		Operations: []BatchSubscriptionOperation{
+			{Action: model.AuditActionCreate, Subscription: &CreateSubscriptionRequest{
+				ServiceName: "Yandex Plus", Price: 400, UserId: "Тестовый UUID", StartDate: date(2025, time.February), OverlapMode: model.OverlapModeReject,
+			}},
+			{Action: model.AuditActionCreate, Subscription: &CreateSubscriptionRequest{
+				ServiceName: "Yandex Plus", Price: 400, UserId: "Тестовый UUID", StartDate: date(2024, time.December), EndDate: date(2025, time.January), OverlapMode: model.OverlapModeWarn,
+			}},
+		},
+	}, subscriptionRepo, nil, "", auth.Principal{Admin: true})
+
+	if err != nil || results[0].Status != model.BatchStatusFailed || results[1].Status != model.BatchStatusOk ||
+		len(results[1].Subscription.Overlaps) != 1 || results[1].Subscription.Overlaps[0].OtherSubscriptionId != existing.Id {
+		t.Errorf("BatchSubscriptions() = %+v, %v", results, err)
+	}
+}
+
+func TestMergeSubscriptionOverlaps(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Audit:         make(map[int][]model.SubscriptionAudit),
+		Pauses:        make(map[int][]model.SubscriptionPause),
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	create := func(serviceName string, startDate *utils.Date, endDate *utils.Date) *model.Subscription {
+		sub, _ := CreateSubscription(CreateSubscriptionRequest{
+			ServiceName: serviceName,
+			Price:       400,
+			UserId:      "Тестовый UUID",
+			StartDate:   startDate,
+			EndDate:     endDate,
+		}, subscriptionRepo, nil, "")
+
+		return sub
+	}
+
+	middle := create("Yandex Plus", date(2025, time.March), date(2025, time.August))
+	earliest := create("Yandex Plus", date(2025, time.January), date(2025, time.April))
+	latest := create("Yandex Plus", date(2025, time.July), date(2025, time.October))
+	separate := create("Yandex Plus", date(2025, time.December), nil)
+	spotifyFirst := create("Spotify", date(2025, time.January), nil)
+	spotifySecond := create("Spotify", date(2025, time.June), date(2025, time.July))
+
+	merges, err := MergeSubscriptionOverlaps(MergeSubscriptionOverlapsRequest{DryRun: true}, subscriptionRepo, nil, "admin")
+
+	if err != nil || len(merges) != 2 || subscriptionRepo.Subscriptions[middle.Id].DeletedAt != nil {
+		t.Errorf("MergeSubscriptionOverlaps() с dry_run = %+v, %v", merges, err)
+		return
+	}
+
+	merges, err = MergeSubscriptionOverlaps(MergeSubscriptionOverlapsRequest{}, subscriptionRepo, nil, "admin")
+
+	if err != nil || len(merges) != 2 {
+		t.Errorf("MergeSubscriptionOverlaps() = %+v, %v", merges, err)
+		return
+	}
+
+	yandex := merges[0]
+
+	if yandex.Subscription.Id != earliest.Id || !slices.Equal(yandex.MergedIds, []int{middle.Id, latest.Id}) ||
+		!yandex.Subscription.EndDate.Time.Equal(date(2025, time.October).Time) {
+		t.Errorf("MergeSubscriptionOverlaps() Yandex Plus = %+v", yandex)
+	}
+
+	if stored := subscriptionRepo.Subscriptions[earliest.Id]; !stored.EndDate.Time.Equal(date(2025, time.October).Time) {
+		t.Errorf("MergeSubscriptionOverlaps() не изменила запись: %+v", stored)
+	}
+
+	if subscriptionRepo.Subscriptions[middle.Id].DeletedAt == nil || subscriptionRepo.Subscriptions[latest.Id].DeletedAt == nil ||
+		subscriptionRepo.Subscriptions[separate.Id].DeletedAt != nil {
+		t.Errorf("MergeSubscriptionOverlaps() удалила не те записи")
+	}
+
+	if spotify := merges[1]; spotify.Subscription.Id != spotifyFirst.Id || spotify.Subscription.EndDate != nil ||
+		!slices.Equal(spotify.MergedIds, []int{spotifySecond.Id}) {
+		t.Errorf("MergeSubscriptionOverlaps() Spotify = %+v", spotify)
+	}
+
+	if audit := subscriptionRepo.Audit[earliest.Id]; len(audit) != 2 || audit[1].Actor != "admin" || audit[1].Action != model.AuditActionUpdate {
+		t.Errorf("MergeSubscriptionOverlaps() журнал изменений = %+v", audit)
+	}
+
+	if overlaps, _ := ListSubscriptionOverlaps(ListSubscriptionOverlapsRequest{}, subscriptionRepo); len(overlaps) != 0 {
+		t.Errorf("ListSubscriptionOverlaps() после объединения = %+v", overlaps)
+	}
+
+	// Записи с разной стоимостью не объединяются: при dry_run различие возвращается в conflicts
+	okko := create("Okko", date(2026, time.January), date(2026, time.April))
+	okkoRaised, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Okko",
+		Price:       500,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2026, time.March),
+		EndDate:     date(2026, time.June),
+	}, subscriptionRepo, nil, "")
+
+	merges, err = MergeSubscriptionOverlaps(MergeSubscriptionOverlapsRequest{ServiceName: "Okko", DryRun: true}, subscriptionRepo, nil, "admin")
+
+	if err != nil || len(merges) != 1 || merges[0].Subscription.Id != okko.Id || len(merges[0].Conflicts) != 1 ||
+		!strings.Contains(merges[0].Conflicts[0], "price") {
+		t.Errorf("MergeSubscriptionOverlaps() с разной стоимостью и dry_run = %+v, %v", merges, err)
+	}
+
+	if _, err := MergeSubscriptionOverlaps(MergeSubscriptionOverlapsRequest{ServiceName: "Okko"}, subscriptionRepo, nil, "admin"); err == nil ||
+		!strings.Contains(err.Error(), "conflict") || subscriptionRepo.Subscriptions[okkoRaised.Id].DeletedAt != nil {
+		t.Errorf("MergeSubscriptionOverlaps() с разной стоимостью error = %v, want conflict", err)
+	}
+
+	// Пауза записи, в которую объединяются другие, распространилась бы на их месяцы, поэтому записи не объединяются
+	ivi := create("Ivi", date(2026, time.January), date(2026, time.June))
+	iviLater := create("Ivi", date(2026, time.May), date(2026, time.September))
+
+	if _, err := PauseSubscription(PauseSubscriptionRequest{StartDate: date(2026, time.May), EndDate: date(2026, time.June)}, subscriptionRepo, nil, ivi.Id, "admin"); err != nil {
+		t.Errorf("PauseSubscription() error = %v", err)
+		return
+	}
+
+	merges, err = MergeSubscriptionOverlaps(MergeSubscriptionOverlapsRequest{ServiceName: "Ivi", DryRun: true}, subscriptionRepo, nil, "admin")
+
+	if err != nil || len(merges) != 1 || merges[0].Subscription.Id != ivi.Id || !slices.Equal(merges[0].MergedIds, []int{iviLater.Id}) ||
+		len(merges[0].Conflicts) != 1 || !strings.Contains(merges[0].Conflicts[0], fmt.Sprintf("subscription %d has pauses", ivi.Id)) {
+		t.Errorf("MergeSubscriptionOverlaps() с паузой объединённой записи и dry_run = %+v, %v", merges, err)
+	}
+
+	subscriptionRepo.NoOverlap = true
+
+	if _, err := RestoreSubscription(subscriptionRepo, nil, middle.Id, "admin"); err == nil || !strings.Contains(err.Error(), "conflict") {
+		t.Errorf("RestoreSubscription() пересекающейся записи error = %v, want conflict", err)
+	}
+
+	if _, err := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Spotify",
+		Price:       300,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2026, time.January),
+	}, subscriptionRepo, nil, ""); err == nil || !strings.Contains(err.Error(), "conflict") {
+		t.Errorf("CreateSubscription() пересекающейся записи error = %v, want conflict", err)
+	}
+}
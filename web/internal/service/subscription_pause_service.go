@@ -0,0 +1,149 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"time"
+)
+
+// PauseSubscriptionRequest Модель данных для приостановки подписки. Без end_date подписка приостановлена до возобновления
+//
+//	@modelId	pause-subscription-request
+//	@required	StartDate
+type PauseSubscriptionRequest struct {
+	StartDate *utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
+	EndDate   *utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"09-2025"`
+}
+
+// ResumeSubscriptionRequest Модель данных для возобновления подписки. Без resume_date подписка возобновляется с текущего месяца
+//
+//	@modelId	resume-subscription-request
+type ResumeSubscriptionRequest struct {
+	ResumeDate *utils.Date `json:"resume_date,omitempty" swaggertype:"string" example:"09-2025"`
+}
+
+func ListSubscriptionPauses(repo repository.SubscriptionRepository, subId int) ([]model.SubscriptionPause, error) {
+	sub, err := GetOneSubscription(repo, subId)
+
+	if sub == nil {
+		return nil, err
+	}
+
+	return repo.ListPauses(sub.Id)
+}
+
+// PauseSubscription приостанавливает подписку с месяца start_date по месяц end_date включительно.
+// Пауза должна приходиться на период подписки и не пересекаться с другими паузами. Возвращает паузы подписки
+func PauseSubscription(req PauseSubscriptionRequest, repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, subId int, actor string) ([]model.SubscriptionPause, error) {
+	sub, err := GetOneSubscription(repo, subId)
+
+	if sub == nil {
+		return nil, err
+	}
+
+	if req.StartDate == nil || !req.StartDate.Valid {
+		return nil, fmt.Errorf("start_date is required")
+	}
+
+	pause := &model.SubscriptionPause{
+		SubscriptionId: sub.Id,
+		StartDate:      monthDate(req.StartDate.Time),
+	}
+
+	if req.EndDate != nil && req.EndDate.Valid {
+		pause.EndDate = monthDate(req.EndDate.Time)
+
+		if pause.EndDate.Time.Before(pause.StartDate.Time) {
+			return nil, fmt.Errorf("end_date is before start_date")
+		}
+	}
+
+	hasEnd := sub.EndDate != nil && sub.EndDate.Valid
+
+	if pause.StartDate.Time.Before(monthOf(sub.StartDate.Time)) || (hasEnd && pause.StartDate.Time.After(sub.EndDate.Time)) ||
+		(hasEnd && pause.EndDate != nil && pause.EndDate.Time.After(sub.EndDate.Time)) {
+		return nil, fmt.Errorf("pause is outside of subscription period")
+	}
+
+	pauses, err := repo.ListPauses(sub.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, other := range pauses {
+		if pausesOverlap(*pause, other) {
+			return nil, fmt.Errorf("subscription conflict: pause overlaps with pause from %s", other.StartDate.Time.Format("01-2006"))
+		}
+	}
+
+	err = repo.SavePause(pause, model.AuditActionPause, actor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	checkBudgetAlerts(repo, budgetRepo, *sub, nil, nil)
+
+	return repo.ListPauses(sub.Id)
+}
+
+// ResumeSubscription возобновляет подписку с месяца resume_date: пауза, на которую он приходится, заканчивается
+// в предыдущем месяце. Если подписка возобновляется в первый месяц паузы, пауза удаляется. Возвращает паузы подписки
+func ResumeSubscription(req ResumeSubscriptionRequest, now time.Time, repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, subId int, actor string) ([]model.SubscriptionPause, error) {
+	sub, err := GetOneSubscription(repo, subId)
+
+	if sub == nil {
+		return nil, err
+	}
+
+	resumeDate := monthDate(now)
+
+	if req.ResumeDate != nil && req.ResumeDate.Valid {
+		resumeDate = monthDate(req.ResumeDate.Time)
+	}
+
+	pauses, err := repo.ListPauses(sub.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pause := range pauses {
+		if !repository.Paused([]model.SubscriptionPause{pause}, resumeDate.Time) {
+			continue
+		}
+
+		if resumeDate.Time.Equal(pause.StartDate.Time) {
+			err = repo.DeletePause(&pause, actor)
+		} else {
+			pause.EndDate = monthDate(resumeDate.Time.AddDate(0, -1, 0))
+			err = repo.SavePause(&pause, model.AuditActionResume, actor)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		checkBudgetAlerts(repo, budgetRepo, *sub, nil, nil)
+
+		return repo.ListPauses(sub.Id)
+	}
+
+	return nil, fmt.Errorf("subscription is not paused in %s", resumeDate.Time.Format("01-2006"))
+}
+
+// pausesOverlap проверяет, что у пауз есть общие месяцы. Пауза без даты окончания длится до возобновления
+func pausesOverlap(a model.SubscriptionPause, b model.SubscriptionPause) bool {
+	return (b.EndDate == nil || !b.EndDate.Valid || !a.StartDate.Time.After(b.EndDate.Time)) &&
+		(a.EndDate == nil || !a.EndDate.Valid || !b.StartDate.Time.After(a.EndDate.Time))
+}
+
+// monthDate возвращает первый день месяца даты t
+func monthDate(t time.Time) *utils.Date {
+	return &utils.Date{NullTime: sql.NullTime{Time: monthOf(t), Valid: true}}
+}
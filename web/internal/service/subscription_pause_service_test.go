@@ -0,0 +1,158 @@
+package service
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestPauseSubscription(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Pauses:        make(map[int][]model.SubscriptionPause),
+		Audit:         make(map[int][]model.SubscriptionAudit),
+		Outbox:        make(map[string]*model.WebhookEvent),
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	sub, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Yandex Plus",
+		Price:       400,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.December),
+	}, subscriptionRepo, nil, "")
+
+	pauses, err := PauseSubscription(PauseSubscriptionRequest{StartDate: date(2025, time.March), EndDate: date(2025, time.May)}, subscriptionRepo, nil, sub.Id, "Тестовый пользователь")
+
+	if err != nil || len(pauses) != 1 || !pauses[0].EndDate.Time.Equal(date(2025, time.May).Time) {
+		t.Errorf("PauseSubscription() = %+v, %v", pauses, err)
+		return
+	}
+
+	tests := []struct {
+		name string
+		req  PauseSubscriptionRequest
+	}{
+		{"без начала паузы", PauseSubscriptionRequest{EndDate: date(2025, time.July)}},
+		{"конец паузы раньше начала", PauseSubscriptionRequest{StartDate: date(2025, time.July), EndDate: date(2025, time.June)}},
+		{"пауза до начала подписки", PauseSubscriptionRequest{StartDate: date(2024, time.December)}},
+		{"пауза после окончания подписки", PauseSubscriptionRequest{StartDate: date(2025, time.November), EndDate: date(2026, time.January)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if pauses, err := PauseSubscription(tt.req, subscriptionRepo, nil, sub.Id, "Тестовый пользователь"); err == nil {
+				t.Errorf("PauseSubscription() = %+v, want error", pauses)
+			}
+		})
+	}
+
+	if _, err := PauseSubscription(PauseSubscriptionRequest{StartDate: date(2025, time.April)}, subscriptionRepo, nil, sub.Id, "Тестовый пользователь"); err == nil || !strings.Contains(err.Error(), "conflict") {
+		t.Errorf("PauseSubscription() пересекающейся паузы error = %v, want conflict", err)
+	}
+
+	sumPrices := func() int {
+		sum, _ := SumSubscriptionsPrices(SumSubscriptionsPricesRequest{
+			StartDate: *date(2025, time.January),
+			EndDate:   *date(2025, time.December),
+		}, subscriptionRepo)
+
+		return *sum
+	}
+
+	if sum := sumPrices(); sum != 400*9 {
+		t.Errorf("SumSubscriptionsPrices() с паузой = %d, want %d", sum, 400*9)
+	}
+
+	listTotal := func(req ListSubscriptionsRequest) int {
+		page, err := ListSubscriptions(req, subscriptionRepo)
+
+		if err != nil {
+			t.Errorf("ListSubscriptions() error = %v", err)
+			return 0
+		}
+
+		return page.Total
+	}
+
+	if total := listTotal(ListSubscriptionsRequest{StartDate: *date(2025, time.March), EndDate: *date(2025, time.May)}); total != 0 {
+		t.Errorf("ListSubscriptions() за период паузы = %d записей, want 0", total)
+	}
+
+	if total := listTotal(ListSubscriptionsRequest{StartDate: *date(2025, time.April), EndDate: *date(2025, time.June)}); total != 1 {
+		t.Errorf("ListSubscriptions() за период, частично попадающий на паузу = %d записей, want 1", total)
+	}
+
+	if total := listTotal(ListSubscriptionsRequest{ActiveOn: *date(2025, time.April)}); total != 0 {
+		t.Errorf("ListSubscriptions() в месяце паузы = %d записей, want 0", total)
+	}
+
+	// Пауза до возобновления заканчивается в месяце перед возобновлением
+	PauseSubscription(PauseSubscriptionRequest{StartDate: date(2025, time.September)}, subscriptionRepo, nil, sub.Id, "Тестовый пользователь")
+
+	pauses, err = ResumeSubscription(ResumeSubscriptionRequest{}, time.Date(2025, time.November, 10, 0, 0, 0, 0, time.UTC), subscriptionRepo, nil, sub.Id, "Тестовый пользователь")
+
+	if err != nil || len(pauses) != 2 || !pauses[1].EndDate.Time.Equal(date(2025, time.October).Time) {
+		t.Errorf("ResumeSubscription() = %+v, %v", pauses, err)
+		return
+	}
+
+	if sum := sumPrices(); sum != 400*7 {
+		t.Errorf("SumSubscriptionsPrices() после возобновления = %d, want %d", sum, 400*7)
+	}
+
+	if _, err := ResumeSubscription(ResumeSubscriptionRequest{ResumeDate: date(2025, time.July)}, time.Now(), subscriptionRepo, nil, sub.Id, "Тестовый пользователь"); err == nil {
+		t.Errorf("ResumeSubscription() без паузы не вернула ошибку")
+	}
+
+	pauses, err = ResumeSubscription(ResumeSubscriptionRequest{ResumeDate: date(2025, time.March)}, time.Now(), subscriptionRepo, nil, sub.Id, "Тестовый пользователь")
+
+	if err != nil || len(pauses) != 1 || !pauses[0].StartDate.Time.Equal(date(2025, time.September).Time) {
+		t.Errorf("ResumeSubscription() в первый месяц паузы = %+v, %v", pauses, err)
+	}
+
+	// Пауза, уже удалённая параллельным возобновлением, не записывает повторное возобновление
+	if err := subscriptionRepo.DeletePause(&model.SubscriptionPause{SubscriptionId: sub.Id, StartDate: date(2025, time.March)}, "Тестовый пользователь"); err == nil {
+		t.Errorf("DeletePause() удалённой паузы не вернула ошибку")
+	}
+
+	// Пауза и оба возобновления записаны в журнал изменений и в события для вебхуков
+	history, _ := GetSubscriptionHistory(subscriptionRepo, sub.Id)
+	actions := []string{}
+
+	for _, entry := range history {
+		if entry.Pause != nil && entry.Actor == "Тестовый пользователь" {
+			actions = append(actions, entry.Action)
+		}
+	}
+
+	wantActions := []string{model.AuditActionPause, model.AuditActionPause, model.AuditActionResume, model.AuditActionResume}
+
+	if !slices.Equal(actions, wantActions) {
+		t.Errorf("GetSubscriptionHistory() действия с паузами = %v, want %v", actions, wantActions)
+	}
+
+	events := map[string]int{}
+
+	for _, event := range subscriptionRepo.Outbox {
+		events[event.Type]++
+	}
+
+	if events[model.WebhookEventSubscriptionPaused] != 2 || events[model.WebhookEventSubscriptionResumed] != 2 {
+		t.Errorf("события для вебхуков = %v, want 2 %s и 2 %s", events, model.WebhookEventSubscriptionPaused, model.WebhookEventSubscriptionResumed)
+	}
+
+	if pauses, err := ListSubscriptionPauses(subscriptionRepo, 100); pauses != nil || err != nil {
+		t.Errorf("ListSubscriptionPauses() отсутствующей записи = %+v, %v", pauses, err)
+	}
+}
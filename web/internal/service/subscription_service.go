@@ -1,58 +1,155 @@
 package service
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	_ "subsaggregator/docs"
 	"subsaggregator/internal/model"
 	"subsaggregator/internal/repository"
 	"subsaggregator/internal/utils"
+	"time"
 )
 
-// CreateSubscriptionRequest Модель данных для создания записи о подписке
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// defaultListLimit размер страницы списка записей о подписках, если limit не указан
+const defaultListLimit = 10
+
+// CreateSubscriptionRequest Модель данных для создания записи о подписке.
+// Название сервиса, найденное в каталоге, заменяется каноническим; если стоимость месячной подписки в RUB не указана,
+// подставляется стоимость сервиса по умолчанию.
+// TrialEndDate — последний месяц бесплатного пробного периода, PromoPrice действует PromoMonths месяцев после него.
+// OverlapMode задаёт проверку пересечений с записями того же пользователя на тот же сервис: allow, warn или reject
 //
 //	@modelId	create-sub-request
 //	@required	ServiceName Price UserId StartDate EndDate
 type CreateSubscriptionRequest struct {
-	ServiceName string      `json:"service_name"`
-	Price       int         `json:"price"`
-	UserId      string      `json:"user_id"`
-	StartDate   *utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
-	EndDate     *utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"07-2025"`
+	ServiceName   string      `json:"service_name"`
+	Price         int         `json:"price"`
+	Currency      string      `json:"currency,omitempty" example:"RUB"`
+	BillingPeriod string      `json:"billing_period,omitempty" enums:"week,month,quarter,year" example:"month"`
+	UserId        string      `json:"user_id"`
+	StartDate     *utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
+	EndDate       *utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"07-2025"`
+	TrialEndDate  *utils.Date `json:"trial_end_date,omitempty" swaggertype:"string" example:"07-2025"`
+	PromoPrice    int         `json:"promo_price,omitempty" example:"199"`
+	PromoMonths   int         `json:"promo_months,omitempty" example:"3"`
+	OverlapMode   string      `json:"overlap_mode,omitempty" enums:"allow,warn,reject" example:"warn"`
 }
 
-// UpdateSubscriptionRequest Модель данных для изменения записи о подписке
+// UpdateSubscriptionRequest Модель данных для изменения записи о подписке.
+// OverlapMode задаёт проверку пересечений с записями того же пользователя на тот же сервис: allow, warn или reject
 //
 //	@modelId	update-sub-request
 //	@required	ServiceName Price UserId StartDate EndDate
 type UpdateSubscriptionRequest struct {
-	ServiceName string      `json:"service_name"`
-	Price       int         `json:"price"`
-	UserId      string      `json:"user_id"`
-	StartDate   *utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
-	EndDate     *utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"07-2025"`
+	ServiceName   string      `json:"service_name"`
+	Price         int         `json:"price"`
+	Currency      string      `json:"currency,omitempty" example:"RUB"`
+	BillingPeriod string      `json:"billing_period,omitempty" enums:"week,month,quarter,year" example:"month"`
+	UserId        string      `json:"user_id"`
+	StartDate     *utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
+	EndDate       *utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"07-2025"`
+	TrialEndDate  *utils.Date `json:"trial_end_date,omitempty" swaggertype:"string" example:"07-2025"`
+	PromoPrice    int         `json:"promo_price,omitempty" example:"199"`
+	PromoMonths   int         `json:"promo_months,omitempty" example:"3"`
+	OverlapMode   string      `json:"overlap_mode,omitempty" enums:"allow,warn,reject" example:"warn"`
+}
+
+// ChangeSubscriptionPriceRequest Модель данных для изменения стоимости подписки с указанного месяца
+//
+//	@modelId	change-sub-price-request
+//	@required	EffectiveFrom Price
+type ChangeSubscriptionPriceRequest struct {
+	EffectiveFrom *utils.Date `json:"effective_from" swaggertype:"string" example:"07-2025"`
+	Price         int         `json:"price"`
 }
 
-// ListSubscriptionsRequest Модель данных для получения списка записей о подписках
+// ListSubscriptionsRequest Модель данных для получения списка записей о подписках.
+// Cursor берётся из next_cursor или prev_cursor предыдущего ответа; при его наличии Offset не учитывается.
+// Только с end_date выбираются подписки, закончившиеся не раньше end_date, как и в суммарной стоимости.
+// min_price и max_price сравниваются с базовой стоимостью подписки без учёта истории изменения стоимости
 //
 //	@modelId	list-subs-request
-//	@required	ServiceName UserId StartDate EndDate Offset Limit
+//	@required	ServiceName UserId StartDate EndDate Limit
 type ListSubscriptionsRequest struct {
-	ServiceName string     `json:"service_name,omitempty"`
-	UserId      string     `json:"user_id,omitempty"`
-	StartDate   utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
-	EndDate     utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"07-2025"`
-	Offset      int        `json:"offset"`
-	Limit       int        `json:"limit" example:"10"`
+	ServiceName       string     `json:"service_name,omitempty"`
+	UserId            string     `json:"user_id,omitempty"`
+	ServiceNames      []string   `json:"service_names,omitempty" example:"Yandex Plus"`
+	UserIds           []string   `json:"user_ids,omitempty"`
+	ServiceNameSearch string     `json:"service_name_search,omitempty" example:"yandex"`
+	Categories        []string   `json:"categories,omitempty" example:"video"`
+	MinPrice          int        `json:"min_price,omitempty"`
+	MaxPrice          int        `json:"max_price,omitempty"`
+	StartDate         utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
+	EndDate           utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"07-2025"`
+	ActiveOn          utils.Date `json:"active_on,omitempty" swaggertype:"string" example:"07-2025"`
+	OpenEndedOnly     bool       `json:"open_ended_only,omitempty"`
+	SortBy            string     `json:"sort_by,omitempty" enums:"id,start_date,price,service_name" example:"start_date"`
+	SortOrder         string     `json:"sort_order,omitempty" enums:"asc,desc" example:"asc"`
+	Cursor            string     `json:"cursor,omitempty"`
+	Offset            int        `json:"offset"`
+	Limit             int        `json:"limit" example:"10"`
 }
 
-// SumSubscriptionsPricesRequest Модель данных для получения суммарной стоимости подписок
+// SumSubscriptionsPricesRequest Модель данных для получения суммарной стоимости подписок.
+// min_price и max_price сравниваются с базовой стоимостью подписки без учёта истории изменения стоимости
 //
 //	@modelId	sum-subs-prices-request
 //	@required	ServiceName UserId StartDate EndDate
 type SumSubscriptionsPricesRequest struct {
-	ServiceName string     `json:"service_name,omitempty"`
-	UserId      string     `json:"user_id,omitempty"`
-	StartDate   utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
-	EndDate     utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"07-2025"`
+	ServiceName       string     `json:"service_name,omitempty"`
+	UserId            string     `json:"user_id,omitempty"`
+	ServiceNames      []string   `json:"service_names,omitempty" example:"Yandex Plus"`
+	UserIds           []string   `json:"user_ids,omitempty"`
+	ServiceNameSearch string     `json:"service_name_search,omitempty" example:"yandex"`
+	Categories        []string   `json:"categories,omitempty" example:"video"`
+	MinPrice          int        `json:"min_price,omitempty"`
+	MaxPrice          int        `json:"max_price,omitempty"`
+	StartDate         utils.Date `json:"start_date" swaggertype:"string" example:"07-2025"`
+	EndDate           utils.Date `json:"end_date,omitempty" swaggertype:"string" example:"07-2025"`
+	ActiveOn          utils.Date `json:"active_on,omitempty" swaggertype:"string" example:"07-2025"`
+	OpenEndedOnly     bool       `json:"open_ended_only,omitempty"`
+	Currency          string     `json:"currency,omitempty" example:"RUB"`
+	BillingMode       string     `json:"billing_mode,omitempty" enums:"charge,amortize" example:"charge"`
+	GroupBy           []string   `json:"group_by,omitempty" enums:"service_name,user_id,month,category" example:"service_name"`
+}
+
+// filter собирает фильтр записей о подписках из параметров запроса
+func (req ListSubscriptionsRequest) filter() (repository.SubscriptionFilter, error) {
+	return subscriptionFilter(repository.SubscriptionFilter{
+		UserIds:           req.UserIds,
+		ServiceNames:      req.ServiceNames,
+		ServiceNameSearch: req.ServiceNameSearch,
+		Categories:        req.Categories,
+		MinPrice:          req.MinPrice,
+		MaxPrice:          req.MaxPrice,
+		MaxStartDate:      req.StartDate,
+		MinEndDate:        req.EndDate,
+		ActiveOn:          req.ActiveOn,
+		OpenEndedOnly:     req.OpenEndedOnly,
+	}, req.UserId, req.ServiceName)
+}
+
+// filter собирает фильтр записей о подписках из параметров запроса
+func (req SumSubscriptionsPricesRequest) filter() (repository.SubscriptionFilter, error) {
+	return subscriptionFilter(repository.SubscriptionFilter{
+		UserIds:           req.UserIds,
+		ServiceNames:      req.ServiceNames,
+		ServiceNameSearch: req.ServiceNameSearch,
+		Categories:        req.Categories,
+		MinPrice:          req.MinPrice,
+		MaxPrice:          req.MaxPrice,
+		MaxStartDate:      req.StartDate,
+		MinEndDate:        req.EndDate,
+		ActiveOn:          req.ActiveOn,
+		OpenEndedOnly:     req.OpenEndedOnly,
+	}, req.UserId, req.ServiceName)
 }
 
 func GetOneSubscription(subscriptionRepo repository.SubscriptionRepository, subsId int) (*model.Subscription, error) {
@@ -67,29 +164,86 @@ func GetOneSubscription(subscriptionRepo repository.SubscriptionRepository, subs
 	return sub, nil
 }
 
-func ListSubscriptions(req ListSubscriptionsRequest, subscriptionRepo repository.SubscriptionRepository) ([]model.Subscription, error) {
-	subs, err := subscriptionRepo.List(
-		req.UserId,
-		req.ServiceName,
-		req.StartDate,
-		req.EndDate,
-		req.Offset,
-		req.Limit,
-	)
+func ListSubscriptions(req ListSubscriptionsRequest, subscriptionRepo repository.SubscriptionRepository) (*model.SubscriptionPage, error) {
+	filter, err := req.filter()
+
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := listOptions(req)
 
 	if err != nil {
-		return subs, err
+		return nil, err
+	}
+
+	limit := options.Limit
+	options.Limit++
+
+	subs, err := subscriptionRepo.List(filter, options)
+
+	if err != nil {
+		return nil, err
 	}
 
-	return subs, nil
+	backward := options.Cursor != nil && options.Cursor.Backward
+	hasMore := len(subs) > limit
+
+	if hasMore && backward {
+		subs = subs[1:]
+	} else if hasMore {
+		subs = subs[:limit]
+	}
+
+	total, err := subscriptionRepo.Total(filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.SubscriptionPage{
+		Items: subs,
+		Total: total,
+	}
+
+	if len(subs) == 0 {
+		return page, nil
+	}
+
+	if (hasMore && !backward) || backward {
+		page.NextCursor = encodeCursor(subs[len(subs)-1], options, false)
+	}
+
+	if (hasMore && backward) || (!backward && (options.Cursor != nil || options.Offset > 0)) {
+		page.PrevCursor = encodeCursor(subs[0], options, true)
+	}
+
+	return page, nil
 }
 
 func SumSubscriptionsPrices(req SumSubscriptionsPricesRequest, subscriptionRepo repository.SubscriptionRepository) (*int, error) {
+	filter, err := req.filter()
+
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := normalizeCurrency(req.Currency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	billingMode, err := normalizeBillingMode(req.BillingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
 	sum, err := subscriptionRepo.SumPrices(
-		req.UserId,
-		req.ServiceName,
-		req.StartDate,
-		req.EndDate,
+		filter,
+		currency,
+		billingMode,
 	)
 
 	if err != nil {
@@ -99,28 +253,331 @@ func SumSubscriptionsPrices(req SumSubscriptionsPricesRequest, subscriptionRepo
 	return sum, nil
 }
 
-func CreateSubscription(req CreateSubscriptionRequest, repo repository.SubscriptionRepository) (*model.Subscription, error) {
+// subscriptionGroupByFields поля, по которым группируется суммарная стоимость подписок организации
+var subscriptionGroupByFields = []string{model.GroupByUserId, model.GroupByServiceName, model.GroupByMonth, model.GroupByCategory}
+
+func GroupSubscriptionsPrices(req SumSubscriptionsPricesRequest, subscriptionRepo repository.SubscriptionRepository) ([]model.PriceGroup, error) {
+	return groupSubscriptionsPrices(req, nil, subscriptionGroupByFields, subscriptionRepo)
+}
+
+// groupSubscriptionsPrices группирует суммарную стоимость подписок организаций tenantIds по полям req.GroupBy из groupByFields.
+// Пустой tenantIds не ограничивает организации сверх ограничения хранилища
+func groupSubscriptionsPrices(req SumSubscriptionsPricesRequest, tenantIds []string, groupByFields []string, subscriptionRepo repository.SubscriptionRepository) ([]model.PriceGroup, error) {
+	fields := make(map[string]bool)
+
+	for _, field := range req.GroupBy {
+		if !slices.Contains(groupByFields, field) {
+			return nil, fmt.Errorf("unknown group_by field: %s", field)
+		}
+
+		if fields[field] {
+			return nil, fmt.Errorf("duplicated group_by field: %s", field)
+		}
+
+		fields[field] = true
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("group_by is empty")
+	}
+
+	filter, err := req.filter()
+
+	if err != nil {
+		return nil, err
+	}
+
+	filter.TenantIds = tenantIds
+
+	currency, err := normalizeCurrency(req.Currency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	billingMode, err := normalizeBillingMode(req.BillingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := subscriptionRepo.SumPricesGrouped(
+		filter,
+		currency,
+		billingMode,
+		req.GroupBy,
+	)
+
+	if err != nil {
+		return groups, err
+	}
+
+	return groups, nil
+}
+
+func SumSubscriptionsPricesByMonth(req SumSubscriptionsPricesRequest, subscriptionRepo repository.SubscriptionRepository) ([]model.MonthlyPrice, error) {
+	filter, err := req.filter()
+
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := normalizeCurrency(req.Currency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	billingMode, err := normalizeBillingMode(req.BillingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	monthlyPrices, err := subscriptionRepo.SumPricesByMonth(
+		filter,
+		currency,
+		billingMode,
+	)
+
+	if err != nil {
+		return monthlyPrices, err
+	}
+
+	return monthlyPrices, nil
+}
+
+func CreateSubscription(req CreateSubscriptionRequest, repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, actor string) (*model.Subscription, error) {
+	sub, err := newSubscription(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = resolveCatalogService(repo, sub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	overlaps, err := checkSubscriptionOverlaps(req.OverlapMode, repo, sub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.Create(sub, actor)
+
+	if err != nil {
+		return sub, err
+	}
+
+	checkBudgetAlerts(repo, budgetRepo, *sub, nil, nil)
+
+	return withOverlaps(sub, overlaps), nil
+}
+
+func UpdateSubscription(req UpdateSubscriptionRequest, repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, subsId int, actor string) (*model.Subscription, error) {
+	sub, err := GetOneSubscription(repo, subsId)
+
+	if sub == nil {
+		return nil, err
+	}
+
+	before := *sub
+
+	err = applySubscriptionUpdate(sub, req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = resolveCatalogService(repo, sub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	overlaps, err := checkSubscriptionOverlaps(req.OverlapMode, repo, sub)
+
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := priceChangeOnUpdate(repo, before, sub, time.Now())
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.Update(sub, &before, price, actor)
+
+	if err != nil {
+		return sub, err
+	}
+
+	checkBudgetAlerts(repo, budgetRepo, *sub, &before, nil)
+
+	return withOverlaps(sub, overlaps), nil
+}
+
+func ListSubscriptionPrices(repo repository.SubscriptionRepository, subId int) ([]model.SubscriptionPrice, error) {
+	sub, err := GetOneSubscription(repo, subId)
+
+	if sub == nil {
+		return nil, err
+	}
+
+	prices, err := repo.ListPrices(sub.Id)
+
+	if err != nil {
+		return prices, err
+	}
+
+	return prices, nil
+}
+
+func ChangeSubscriptionPrice(req ChangeSubscriptionPriceRequest, repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, subId int, actor string) (*model.SubscriptionPrice, error) {
+	sub, err := GetOneSubscription(repo, subId)
+
+	if sub == nil {
+		return nil, err
+	}
+
+	if req.Price <= 0 {
+		return nil, fmt.Errorf("price must be positive")
+	}
+
+	if req.EffectiveFrom == nil || !req.EffectiveFrom.Valid {
+		return nil, fmt.Errorf("effective_from is required")
+	}
+
+	effectiveFrom := req.EffectiveFrom.Time
+
+	if effectiveFrom.Before(time.Date(sub.StartDate.Time.Year(), sub.StartDate.Time.Month(), 1, 0, 0, 0, 0, time.UTC)) ||
+		(sub.EndDate != nil && sub.EndDate.Valid && effectiveFrom.After(sub.EndDate.Time)) {
+		return nil, fmt.Errorf("effective_from is outside of subscription period")
+	}
+
+	price := &model.SubscriptionPrice{
+		SubscriptionId: sub.Id,
+		EffectiveFrom:  req.EffectiveFrom,
+		Price:          req.Price,
+	}
+
+	err = repo.ChangePrice(price, actor)
+
+	if err != nil {
+		return price, err
+	}
+
+	checkBudgetAlerts(repo, budgetRepo, *sub, nil, nil)
+
+	return price, nil
+}
+
+func DeleteSubscription(repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, subId int, actor string) error {
+	sub, err := GetOneSubscription(repo, subId)
+
+	if sub == nil {
+		return err
+	}
+
+	err = repo.Delete(sub, actor)
+
+	if err != nil {
+		return err
+	}
+
+	checkBudgetAlerts(repo, budgetRepo, *sub, nil, nil)
+
+	return nil
+}
+
+func RestoreSubscription(repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, subId int, actor string) (*model.Subscription, error) {
+	sub, err := repo.FindDeletedById(subId)
+
+	if sub == nil {
+		return nil, err
+	}
+
+	err = repo.Restore(sub, actor)
+
+	if err != nil {
+		return sub, err
+	}
+
+	checkBudgetAlerts(repo, budgetRepo, *sub, nil, nil)
+
+	return sub, nil
+}
+
+func GetSubscriptionHistory(repo repository.SubscriptionRepository, subId int) ([]model.SubscriptionAudit, error) {
+	entries, err := repo.ListAudit(subId)
+
+	if err != nil {
+		return entries, err
+	}
+
+	return entries, nil
+}
+
+// newSubscription собирает новую запись о подписке из параметров запроса
+func newSubscription(req CreateSubscriptionRequest) (*model.Subscription, error) {
+	currency, err := normalizeCurrency(req.Currency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	billingPeriod, err := normalizeBillingPeriod(req.BillingPeriod)
+
+	if err != nil {
+		return nil, err
+	}
+
 	sub := &model.Subscription{}
 	sub.ServiceName = req.ServiceName
 	sub.Price = req.Price
+	sub.Currency = currency
+	sub.BillingPeriod = billingPeriod
 	sub.UserId = req.UserId
 	sub.StartDate = req.StartDate
 	sub.EndDate = req.EndDate
+	sub.TrialEndDate = req.TrialEndDate
+	sub.PromoPrice = req.PromoPrice
+	sub.PromoMonths = req.PromoMonths
 
-	err := repo.Create(sub)
+	err = checkIntroductoryTerms(sub)
 
 	if err != nil {
-		return sub, err
+		return nil, err
 	}
 
 	return sub, nil
 }
 
-func UpdateSubscription(req UpdateSubscriptionRequest, repo repository.SubscriptionRepository, subsId int) (*model.Subscription, error) {
-	sub, err := GetOneSubscription(repo, subsId)
+// applySubscriptionUpdate переносит параметры запроса в запись о подписке.
+// Пустые валюта и расчётный период не меняются
+func applySubscriptionUpdate(sub *model.Subscription, req UpdateSubscriptionRequest) error {
+	if req.Currency != "" {
+		currency, err := normalizeCurrency(req.Currency)
 
-	if sub == nil {
-		return nil, err
+		if err != nil {
+			return err
+		}
+
+		sub.Currency = currency
+	}
+
+	if req.BillingPeriod != "" {
+		billingPeriod, err := normalizeBillingPeriod(req.BillingPeriod)
+
+		if err != nil {
+			return err
+		}
+
+		sub.BillingPeriod = billingPeriod
 	}
 
 	sub.ServiceName = req.ServiceName
@@ -128,27 +585,251 @@ func UpdateSubscription(req UpdateSubscriptionRequest, repo repository.Subscript
 	sub.UserId = req.UserId
 	sub.StartDate = req.StartDate
 	sub.EndDate = req.EndDate
+	sub.TrialEndDate = req.TrialEndDate
+	sub.PromoPrice = req.PromoPrice
+	sub.PromoMonths = req.PromoMonths
+
+	return checkIntroductoryTerms(sub)
+}
 
-	err = repo.Update(sub)
+// priceChangeOnUpdate переносит изменение стоимости при изменении записи о подписке в историю стоимости:
+// новая стоимость действует с месяца now, прошлые месяцы по-прежнему оплачиваются по прежней стоимости,
+// а начальная стоимость записи не меняется. Если подписка начинается не раньше месяца now, оплаченных месяцев нет
+// и меняется начальная стоимость. Стоимость закончившейся подписки меняется только через историю стоимости
+func priceChangeOnUpdate(repo repository.SubscriptionRepository, before model.Subscription, sub *model.Subscription, now time.Time) (*model.SubscriptionPrice, error) {
+	month := monthOf(now)
+
+	if sub.StartDate == nil || !sub.StartDate.Valid || !month.After(monthOf(sub.StartDate.Time)) {
+		return nil, nil
+	}
+
+	prices, err := repo.ListPrices(sub.Id)
 
 	if err != nil {
-		return sub, err
+		return nil, err
 	}
 
-	return sub, nil
+	price := sub.Price
+	sub.Price = before.Price
+
+	if price == listPriceOn(before, prices, month) {
+		return nil, nil
+	}
+
+	if sub.EndDate != nil && sub.EndDate.Valid && sub.EndDate.Time.Before(month) {
+		return nil, fmt.Errorf("price of ended subscription can only be changed with /subscription/%d/price", sub.Id)
+	}
+
+	return &model.SubscriptionPrice{
+		SubscriptionId: sub.Id,
+		EffectiveFrom:  monthDate(month),
+		Price:          price,
+	}, nil
 }
 
-func DeleteSubscription(repo repository.SubscriptionRepository, subId int) error {
-	sub, err := GetOneSubscription(repo, subId)
+// checkIntroductoryTerms проверяет пробный период и акцию записи о подписке.
+// Пробный период должен закончиться в пределах периода подписки, стоимость по акции требует длительности акции
+func checkIntroductoryTerms(sub *model.Subscription) error {
+	if sub.TrialEndDate != nil && !sub.TrialEndDate.Valid {
+		sub.TrialEndDate = nil
+	}
 
-	if sub == nil {
-		return err
+	if sub.TrialEndDate != nil {
+		if sub.StartDate == nil || !sub.StartDate.Valid ||
+			sub.TrialEndDate.Time.Before(time.Date(sub.StartDate.Time.Year(), sub.StartDate.Time.Month(), 1, 0, 0, 0, 0, time.UTC)) {
+			return fmt.Errorf("trial_end_date is before start_date")
+		}
+
+		if sub.EndDate != nil && sub.EndDate.Valid && sub.TrialEndDate.Time.After(sub.EndDate.Time) {
+			return fmt.Errorf("trial_end_date is after end_date")
+		}
 	}
 
-	err = repo.Delete(sub)
+	if sub.PromoPrice < 0 || sub.PromoMonths < 0 {
+		return fmt.Errorf("promo_price and promo_months must not be negative")
+	}
+
+	if sub.PromoPrice > 0 && sub.PromoMonths == 0 {
+		return fmt.Errorf("promo_months is required with promo_price")
+	}
+
+	return nil
+}
+
+// subscriptionFilter добавляет к фильтру одиночные ИД пользователя и название сервиса и проверяет границы фильтра
+func subscriptionFilter(filter repository.SubscriptionFilter, userId string, serviceName string) (repository.SubscriptionFilter, error) {
+	if userId != "" {
+		filter.UserIds = append(slices.Clone(filter.UserIds), userId)
+	}
+
+	if serviceName != "" {
+		filter.ServiceNames = append(slices.Clone(filter.ServiceNames), serviceName)
+	}
+
+	for _, category := range filter.Categories {
+		if !slices.Contains(model.ServiceCategories, category) {
+			return filter, fmt.Errorf("unknown service category: %s", category)
+		}
+	}
+
+	if filter.MinPrice < 0 || filter.MaxPrice < 0 {
+		return filter, fmt.Errorf("price bounds must not be negative")
+	}
+
+	if filter.MaxPrice > 0 && filter.MinPrice > filter.MaxPrice {
+		return filter, fmt.Errorf("min_price is greater than max_price")
+	}
+
+	return filter, nil
+}
+
+// normalizeCurrency приводит код валюты к верхнему регистру и подставляет валюту по умолчанию
+func normalizeCurrency(currency string) (string, error) {
+	if currency == "" {
+		return model.DefaultCurrency, nil
+	}
+
+	currency = strings.ToUpper(currency)
+
+	if !currencyPattern.MatchString(currency) {
+		return "", fmt.Errorf("invalid currency code: %s", currency)
+	}
+
+	return currency, nil
+}
+
+// normalizeBillingPeriod проверяет расчётный период подписки и подставляет месячный период по умолчанию
+func normalizeBillingPeriod(billingPeriod string) (string, error) {
+	switch billingPeriod {
+	case "":
+		return model.BillingPeriodMonth, nil
+	case model.BillingPeriodWeek, model.BillingPeriodMonth, model.BillingPeriodQuarter, model.BillingPeriodYear:
+		return billingPeriod, nil
+	default:
+		return "", fmt.Errorf("unknown billing period: %s", billingPeriod)
+	}
+}
+
+// normalizeBillingMode проверяет режим учёта стоимости и подставляет учёт в месяце продления по умолчанию
+func normalizeBillingMode(billingMode string) (string, error) {
+	switch billingMode {
+	case "":
+		return model.BillingModeCharge, nil
+	case model.BillingModeCharge, model.BillingModeAmortize:
+		return billingMode, nil
+	default:
+		return "", fmt.Errorf("unknown billing mode: %s", billingMode)
+	}
+}
+
+// listOptions проверяет параметры сортировки и постраничного получения списка записей о подписках
+func listOptions(req ListSubscriptionsRequest) (repository.ListOptions, error) {
+	options := repository.ListOptions{
+		SortBy: req.SortBy,
+		Offset: req.Offset,
+		Limit:  req.Limit,
+	}
+
+	switch req.SortBy {
+	case "":
+		options.SortBy = model.SortById
+	case model.SortById, model.SortByStartDate, model.SortByPrice, model.SortByServiceName:
+	default:
+		return options, fmt.Errorf("unknown sort field: %s", req.SortBy)
+	}
+
+	switch req.SortOrder {
+	case "", "asc":
+	case "desc":
+		options.Descending = true
+	default:
+		return options, fmt.Errorf("unknown sort order: %s", req.SortOrder)
+	}
+
+	if options.Limit <= 0 {
+		options.Limit = defaultListLimit
+	}
+
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor)
+
+		if err != nil {
+			return options, err
+		}
+
+		if cursor.SortBy != options.SortBy || cursor.Descending != options.Descending {
+			return options, fmt.Errorf("invalid cursor: cursor was issued for another sort_by or sort_order")
+		}
+
+		if err = checkCursorValue(cursor); err != nil {
+			return options, err
+		}
+
+		options.Cursor = cursor
+		options.Offset = 0
+	}
+
+	return options, nil
+}
+
+// encodeCursor кодирует позицию записи в списке, отсортированном по options, в непрозрачную строку
+func encodeCursor(sub model.Subscription, options repository.ListOptions, backward bool) string {
+	cursor := repository.Cursor{
+		Id:         sub.Id,
+		SortBy:     options.SortBy,
+		Descending: options.Descending,
+		Backward:   backward,
+	}
+
+	switch options.SortBy {
+	case model.SortByStartDate:
+		cursor.Value = sub.StartDate.Time.Format("2006-01-02")
+	case model.SortByPrice:
+		cursor.Value = strconv.Itoa(sub.Price)
+	case model.SortByServiceName:
+		cursor.Value = sub.ServiceName
+	default:
+		cursor.Value = strconv.Itoa(sub.Id)
+	}
+
+	jsonBytes, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(jsonBytes)
+}
+
+// decodeCursor раскодирует курсор, полученный из encodeCursor
+func decodeCursor(value string) (*repository.Cursor, error) {
+	jsonBytes, err := base64.RawURLEncoding.DecodeString(value)
 
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	var cursor repository.Cursor
+
+	err = json.Unmarshal(jsonBytes, &cursor)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	return &cursor, nil
+}
+
+// checkCursorValue проверяет, что значение поля сортировки в курсоре имеет тип поля, чтобы изменённый курсор
+// отклонялся проверкой, а не ошибкой запроса
+func checkCursorValue(cursor *repository.Cursor) error {
+	var err error
+
+	switch cursor.SortBy {
+	case model.SortByStartDate:
+		_, err = time.Parse("2006-01-02", cursor.Value)
+	case model.SortByPrice, model.SortById:
+		_, err = strconv.Atoi(cursor.Value)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid cursor: %w", err)
 	}
 
 	return nil
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"reflect"
 	"strconv"
+	"strings"
 	"subsaggregator/internal/model"
 	"subsaggregator/internal/repository"
 	"subsaggregator/internal/utils"
@@ -143,13 +144,220 @@ func TestListSubscriptions(t *testing.T) {
 				return
 			}
 
-			if !reflect.DeepEqual(got, tt.want) {
+			if !reflect.DeepEqual(got.Items, tt.want) || got.Total != len(tt.want) {
 				t.Errorf("ListSubscriptions() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestListSubscriptionsPagination(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 5)
+
+	req := ListSubscriptionsRequest{
+		StartDate: *subs[0].StartDate,
+		EndDate:   *subs[0].EndDate,
+		SortBy:    model.SortByPrice,
+		SortOrder: "desc",
+		Limit:     2,
+	}
+
+	pageIds := func(page *model.SubscriptionPage) []int {
+		ids := []int{}
+
+		for _, sub := range page.Items {
+			ids = append(ids, sub.Id)
+		}
+
+		return ids
+	}
+
+	first, err := ListSubscriptions(req, subscriptionRepo)
+
+	if err != nil || !reflect.DeepEqual(pageIds(first), []int{subs[4].Id, subs[3].Id}) || first.Total != 5 || first.PrevCursor != "" {
+		t.Errorf("ListSubscriptions() первая страница = %v, error = %v", first, err)
+		return
+	}
+
+	req.Cursor = first.NextCursor
+	second, err := ListSubscriptions(req, subscriptionRepo)
+
+	if err != nil || !reflect.DeepEqual(pageIds(second), []int{subs[2].Id, subs[1].Id}) || second.PrevCursor == "" {
+		t.Errorf("ListSubscriptions() вторая страница = %v, error = %v", second, err)
+		return
+	}
+
+	req.Cursor = second.NextCursor
+	third, err := ListSubscriptions(req, subscriptionRepo)
+
+	if err != nil || !reflect.DeepEqual(pageIds(third), []int{subs[0].Id}) || third.NextCursor != "" {
+		t.Errorf("ListSubscriptions() третья страница = %v, error = %v", third, err)
+		return
+	}
+
+	req.Cursor = second.PrevCursor
+	previous, err := ListSubscriptions(req, subscriptionRepo)
+
+	if err != nil || !reflect.DeepEqual(pageIds(previous), pageIds(first)) || previous.PrevCursor != "" || previous.NextCursor == "" {
+		t.Errorf("ListSubscriptions() предыдущая страница = %v, error = %v", previous, err)
+		return
+	}
+
+	req.Cursor = "not a cursor"
+
+	if _, err := ListSubscriptions(req, subscriptionRepo); err == nil {
+		t.Errorf("ListSubscriptions() с некорректным курсором не вернула ошибку")
+	}
+
+	// Курсор действителен только для сортировки, с которой он получен
+	for _, other := range []ListSubscriptionsRequest{
+		{SortBy: model.SortByStartDate, SortOrder: "desc"},
+		{SortBy: model.SortByPrice, SortOrder: "asc"},
+	} {
+		req.SortBy, req.SortOrder, req.Cursor = other.SortBy, other.SortOrder, first.NextCursor
+
+		if _, err := ListSubscriptions(req, subscriptionRepo); err == nil || !strings.Contains(err.Error(), "invalid cursor") {
+			t.Errorf("ListSubscriptions() с курсором другой сортировки %s %s error = %v, want invalid cursor", other.SortBy, other.SortOrder, err)
+		}
+	}
+}
+
+func TestListSubscriptionsFilters(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 4)
+
+	openEnded, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Бессрочный сервис",
+		Price:       1000,
+		UserId:      "Тестовый UUID 1",
+		StartDate:   subs[0].StartDate,
+	}, subscriptionRepo, nil, "")
+
+	activeOn := utils.Date{NullTime: sql.NullTime{Time: time.Now().AddDate(1, 0, 0), Valid: true}}
+
+	tests := []struct {
+		name    string
+		req     ListSubscriptionsRequest
+		want    []int
+		wantErr bool
+	}{
+		{
+			name: "Несколько пользователей и сервисов",
+			req: ListSubscriptionsRequest{
+				UserIds:      []string{subs[0].UserId, subs[1].UserId},
+				ServiceNames: []string{subs[1].ServiceName, subs[2].ServiceName},
+				UserId:       subs[2].UserId,
+			},
+			want:    []int{subs[1].Id, subs[2].Id},
+			wantErr: false,
+		},
+		{
+			name: "Поиск по началу названия сервиса без учёта регистра",
+			req: ListSubscriptionsRequest{
+				ServiceNameSearch: "бессрочный",
+			},
+			want:    []int{openEnded.Id},
+			wantErr: false,
+		},
+		{
+			name: "Диапазон стоимости",
+			req: ListSubscriptionsRequest{
+				MinPrice: 200,
+				MaxPrice: 300,
+			},
+			want:    []int{subs[1].Id, subs[2].Id},
+			wantErr: false,
+		},
+		{
+			// Только с end_date выбираются подписки, закончившиеся не раньше end_date, без бессрочных
+			name: "Закончившиеся не раньше даты окончания",
+			req: ListSubscriptionsRequest{
+				UserId:  subs[0].UserId,
+				EndDate: utils.Date{NullTime: sql.NullTime{Time: time.Now().AddDate(0, -1, 0), Valid: true}},
+			},
+			want:    []int{subs[0].Id},
+			wantErr: false,
+		},
+		{
+			name: "Закончившиеся раньше даты окончания не выбираются",
+			req: ListSubscriptionsRequest{
+				EndDate: utils.Date{NullTime: sql.NullTime{Time: time.Now().AddDate(0, 1, 0), Valid: true}},
+			},
+			want:    []int{},
+			wantErr: false,
+		},
+		{
+			name: "Действующие в месяце",
+			req: ListSubscriptionsRequest{
+				ActiveOn: activeOn,
+			},
+			want:    []int{openEnded.Id},
+			wantErr: false,
+		},
+		{
+			name: "Только бессрочные",
+			req: ListSubscriptionsRequest{
+				UserId:        subs[0].UserId,
+				OpenEndedOnly: true,
+			},
+			want:    []int{openEnded.Id},
+			wantErr: false,
+		},
+		{
+			name: "Минимальная стоимость больше максимальной",
+			req: ListSubscriptionsRequest{
+				MinPrice: 300,
+				MaxPrice: 200,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ListSubscriptions(tt.req, subscriptionRepo)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ListSubscriptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			ids := []int{}
+
+			for _, sub := range got.Items {
+				ids = append(ids, sub.Id)
+			}
+
+			if !reflect.DeepEqual(ids, tt.want) || got.Total != len(tt.want) {
+				t.Errorf("ListSubscriptions() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	sum, err := SumSubscriptionsPrices(SumSubscriptionsPricesRequest{
+		ServiceNames: []string{subs[0].ServiceName, subs[3].ServiceName},
+		MaxPrice:     100,
+	}, subscriptionRepo)
+
+	if err != nil || *sum != subs[0].Price*7 {
+		t.Errorf("SumSubscriptionsPrices() = %v, error = %v, want %v", sum, err, subs[0].Price*7)
+	}
+}
+
 func TestSumSubscriptionsPrices(t *testing.T) {
 	subscriptionRepo := repository.SubscriptionRepoMock{
 		Subscriptions: make(map[int]*model.Subscription),
@@ -234,6 +442,477 @@ func TestSumSubscriptionsPrices(t *testing.T) {
 	}
 }
 
+func TestSumSubscriptionsPricesWithBillingPeriod(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	startDate := &utils.Date{NullTime: sql.NullTime{Time: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	endDate := &utils.Date{NullTime: sql.NullTime{Time: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName:   "Годовой сервис",
+		Price:         1200,
+		BillingPeriod: model.BillingPeriodYear,
+		UserId:        "Тестовый UUID",
+		StartDate:     startDate,
+		EndDate:       endDate,
+	}, subscriptionRepo, nil, "")
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName:   "Еженедельный сервис",
+		Price:         100,
+		BillingPeriod: model.BillingPeriodWeek,
+		UserId:        "Тестовый UUID",
+		StartDate:     startDate,
+		EndDate:       startDate,
+	}, subscriptionRepo, nil, "")
+
+	tests := []struct {
+		name    string
+		req     SumSubscriptionsPricesRequest
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "Годовая подписка учитывается в месяце продления",
+			req: SumSubscriptionsPricesRequest{
+				ServiceName: "Годовой сервис",
+				StartDate:   *startDate,
+				EndDate:     *endDate,
+			},
+			want:    1200,
+			wantErr: false,
+		},
+		{
+			name: "Годовая подписка распределяется по месяцам",
+			req: SumSubscriptionsPricesRequest{
+				ServiceName: "Годовой сервис",
+				StartDate:   *startDate,
+				EndDate:     *endDate,
+				BillingMode: model.BillingModeAmortize,
+			},
+			want:    300,
+			wantErr: false,
+		},
+		{
+			name: "Еженедельная подписка учитывается по числу продлений в месяце",
+			req: SumSubscriptionsPricesRequest{
+				ServiceName: "Еженедельный сервис",
+				StartDate:   *startDate,
+				EndDate:     *startDate,
+			},
+			want:    500,
+			wantErr: false,
+		},
+		{
+			name: "Еженедельная подписка распределяется по месяцам",
+			req: SumSubscriptionsPricesRequest{
+				ServiceName: "Еженедельный сервис",
+				StartDate:   *startDate,
+				EndDate:     *startDate,
+				BillingMode: model.BillingModeAmortize,
+			},
+			want:    433,
+			wantErr: false,
+		},
+		{
+			name: "Неизвестный режим учёта стоимости",
+			req: SumSubscriptionsPricesRequest{
+				StartDate:   *startDate,
+				EndDate:     *endDate,
+				BillingMode: "daily",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SumSubscriptionsPrices(tt.req, subscriptionRepo)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SumSubscriptionsPrices() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != nil && *got != tt.want {
+				t.Errorf("SumSubscriptionsPrices() = %v, want %v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumSubscriptionsPricesWithCurrency(t *testing.T) {
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		ExchangeRates: []model.ExchangeRate{
+			{Currency: "USD", Month: date(2025, time.January), Rate: 100},
+			{Currency: "USD", Month: date(2025, time.March), Rate: 80},
+		},
+	}
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Рублёвый сервис",
+		Price:       400,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.April),
+	}, subscriptionRepo, nil, "")
+
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Долларовый сервис",
+		Price:       10,
+		Currency:    "USD",
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.April),
+	}, subscriptionRepo, nil, "")
+
+	tests := []struct {
+		name      string
+		currency  string
+		startDate *utils.Date
+		endDate   *utils.Date
+		want      int
+	}{
+		// 400 × 4 и 10 USD по курсу 100 в январе и феврале и по курсу 80 в марте и апреле
+		{"сумма в рублях", "", date(2025, time.January), date(2025, time.April), 1600 + 2000 + 1600},
+		// 400 RUB по курсу 100 в январе и феврале и по курсу 80 в марте и апреле и 10 USD × 4
+		{"сумма в долларах", "usd", date(2025, time.January), date(2025, time.April), 8 + 10 + 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum, err := SumSubscriptionsPrices(SumSubscriptionsPricesRequest{
+				StartDate: *tt.startDate,
+				EndDate:   *tt.endDate,
+				Currency:  tt.currency,
+			}, subscriptionRepo)
+
+			if err != nil {
+				t.Errorf("SumSubscriptionsPrices() error = %v", err)
+			} else if *sum != tt.want {
+				t.Errorf("SumSubscriptionsPrices() = %d, want %d", *sum, tt.want)
+			}
+		})
+	}
+
+	// Курс, начавший действовать позже месяца, не подставляется
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Ранний долларовый сервис",
+		Price:       10,
+		Currency:    "USD",
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2024, time.December),
+		EndDate:     date(2024, time.December),
+	}, subscriptionRepo, nil, "")
+
+	if sum, err := SumSubscriptionsPrices(SumSubscriptionsPricesRequest{
+		StartDate: *date(2024, time.December),
+		EndDate:   *date(2024, time.December),
+	}, subscriptionRepo); err == nil {
+		t.Errorf("SumSubscriptionsPrices() без курса на месяц = %d, want error", *sum)
+	}
+}
+
+func TestGroupSubscriptionsPrices(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 2)
+
+	startYear, startMonth, _ := subs[0].StartDate.NullTime.Time.Date()
+	endYear, endMonth, _ := subs[0].EndDate.NullTime.Time.Date()
+
+	period := (endYear-startYear)*12 + (int(endMonth) + 1 - int(startMonth))
+
+	type args struct {
+		req              SumSubscriptionsPricesRequest
+		subscriptionRepo repository.SubscriptionRepository
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []model.PriceGroup
+		wantErr bool
+	}{
+		{
+			name: "Группировка по названию сервиса",
+			args: args{
+				req: SumSubscriptionsPricesRequest{
+					StartDate: *subs[0].StartDate,
+					EndDate:   *subs[0].EndDate,
+					GroupBy:   []string{model.GroupByServiceName},
+				},
+				subscriptionRepo: subscriptionRepo,
+			},
+			want: []model.PriceGroup{
+				{ServiceName: subs[0].ServiceName, Total: subs[0].Price * period, Count: 1},
+				{ServiceName: subs[1].ServiceName, Total: subs[1].Price * period, Count: 1},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Группировка по ИД пользователя и названию сервиса с фильтрацией",
+			args: args{
+				req: SumSubscriptionsPricesRequest{
+					UserId:    subs[1].UserId,
+					StartDate: *subs[0].StartDate,
+					EndDate:   *subs[0].EndDate,
+					GroupBy:   []string{model.GroupByUserId, model.GroupByServiceName},
+				},
+				subscriptionRepo: subscriptionRepo,
+			},
+			want: []model.PriceGroup{
+				{UserId: subs[1].UserId, ServiceName: subs[1].ServiceName, Total: subs[1].Price * period, Count: 1},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Неизвестное поле группировки",
+			args: args{
+				req: SumSubscriptionsPricesRequest{
+					StartDate: *subs[0].StartDate,
+					EndDate:   *subs[0].EndDate,
+					GroupBy:   []string{"price"},
+				},
+				subscriptionRepo: subscriptionRepo,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Повторяющееся поле группировки",
+			args: args{
+				req: SumSubscriptionsPricesRequest{
+					StartDate: *subs[0].StartDate,
+					EndDate:   *subs[0].EndDate,
+					GroupBy:   []string{model.GroupByMonth, model.GroupByMonth},
+				},
+				subscriptionRepo: subscriptionRepo,
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GroupSubscriptionsPrices(tt.args.req, tt.args.subscriptionRepo)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GroupSubscriptionsPrices() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupSubscriptionsPrices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumSubscriptionsPricesByMonth(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Count:         0,
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 2)
+
+	startYear, startMonth, _ := subs[0].StartDate.NullTime.Time.Date()
+	endYear, endMonth, _ := subs[0].EndDate.NullTime.Time.Date()
+
+	period := (endYear-startYear)*12 + (int(endMonth) + 1 - int(startMonth))
+
+	type args struct {
+		req              SumSubscriptionsPricesRequest
+		subscriptionRepo repository.SubscriptionRepository
+	}
+
+	tests := []struct {
+		name         string
+		args         args
+		wantMonths   int
+		wantTotal    int
+		wantServices int
+		wantErr      bool
+	}{
+		{
+			name: "Получение помесячной стоимости всех подписок",
+			args: args{
+				req: SumSubscriptionsPricesRequest{
+					StartDate: *subs[0].StartDate,
+					EndDate:   *subs[0].EndDate,
+				},
+				subscriptionRepo: subscriptionRepo,
+			},
+			wantMonths:   period,
+			wantTotal:    subs[0].Price + subs[1].Price,
+			wantServices: 2,
+			wantErr:      false,
+		},
+		{
+			name: "Получение помесячной стоимости отфильтрованных подписок",
+			args: args{
+				req: SumSubscriptionsPricesRequest{
+					ServiceName: subs[1].ServiceName,
+					UserId:      subs[1].UserId,
+					StartDate:   *subs[0].StartDate,
+					EndDate:     *subs[0].EndDate,
+				},
+				subscriptionRepo: subscriptionRepo,
+			},
+			wantMonths:   period,
+			wantTotal:    subs[1].Price,
+			wantServices: 1,
+			wantErr:      false,
+		},
+		{
+			name: "Отфильтрован пустой список записей о подписках",
+			args: args{
+				req: SumSubscriptionsPricesRequest{
+					ServiceName: "Тестовый сервис 3",
+					UserId:      "Тестовый UUID 3",
+					StartDate:   *subs[0].StartDate,
+					EndDate:     *subs[0].EndDate,
+				},
+				subscriptionRepo: subscriptionRepo,
+			},
+			wantMonths: 0,
+			wantErr:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SumSubscriptionsPricesByMonth(tt.args.req, tt.args.subscriptionRepo)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SumSubscriptionsPricesByMonth() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if len(got) != tt.wantMonths {
+				t.Errorf("SumSubscriptionsPricesByMonth() months = %v, want %v", len(got), tt.wantMonths)
+				return
+			}
+
+			for _, monthlyPrice := range got {
+				if monthlyPrice.Total != tt.wantTotal || len(monthlyPrice.Services) != tt.wantServices {
+					t.Errorf("SumSubscriptionsPricesByMonth() = %v, want total %v", monthlyPrice, tt.wantTotal)
+				}
+			}
+		})
+	}
+}
+
+func TestSumSubscriptionsPricesWithIntroductoryTerms(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	_, err := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName:  "Ежемесячный сервис",
+		Price:        400,
+		UserId:       "Тестовый UUID",
+		StartDate:    date(2025, time.January),
+		EndDate:      date(2025, time.December),
+		TrialEndDate: date(2025, time.February),
+		PromoPrice:   199,
+		PromoMonths:  2,
+	}, subscriptionRepo, nil, "")
+
+	if err != nil {
+		t.Errorf("CreateSubscription() error = %v", err)
+		return
+	}
+
+	_, err = CreateSubscription(CreateSubscriptionRequest{
+		ServiceName:   "Годовой сервис",
+		Price:         1200,
+		BillingPeriod: model.BillingPeriodYear,
+		UserId:        "Тестовый UUID",
+		StartDate:     date(2025, time.January),
+		EndDate:       date(2026, time.February),
+		TrialEndDate:  date(2025, time.January),
+	}, subscriptionRepo, nil, "")
+
+	if err != nil {
+		t.Errorf("CreateSubscription() error = %v", err)
+		return
+	}
+
+	months, err := SumSubscriptionsPricesByMonth(SumSubscriptionsPricesRequest{
+		StartDate: *date(2025, time.January),
+		EndDate:   *date(2025, time.June),
+	}, subscriptionRepo)
+
+	if err != nil {
+		t.Errorf("SumSubscriptionsPricesByMonth() error = %v", err)
+		return
+	}
+
+	// Январь и февраль — пробный период, март и апрель — акция, годовой сервис оплачивается в феврале после пробного месяца
+	want := map[time.Month]int{time.January: 0, time.February: 1200, time.March: 199, time.April: 199, time.May: 400, time.June: 400}
+
+	for _, monthlyPrice := range months {
+		if monthlyPrice.Total != want[monthlyPrice.Month.Time.Month()] {
+			t.Errorf("SumSubscriptionsPricesByMonth() %s = %d, want %d", monthlyPrice.Month.Time.Month(), monthlyPrice.Total, want[monthlyPrice.Month.Time.Month()])
+		}
+	}
+
+	sum, err := SumSubscriptionsPrices(SumSubscriptionsPricesRequest{
+		StartDate: *date(2025, time.January),
+		EndDate:   *date(2026, time.February),
+	}, subscriptionRepo)
+
+	// 199 × 2 + 400 × 8 за ежемесячный сервис и 1200 × 2 за годовой с продлением в феврале 2026
+	if err != nil {
+		t.Errorf("SumSubscriptionsPrices() error = %v", err)
+	} else if *sum != 5998 {
+		t.Errorf("SumSubscriptionsPrices() = %d, want 5998", *sum)
+	}
+
+	tests := []struct {
+		name string
+		req  CreateSubscriptionRequest
+	}{
+		{"пробный период до начала подписки", CreateSubscriptionRequest{TrialEndDate: date(2024, time.December)}},
+		{"пробный период после окончания подписки", CreateSubscriptionRequest{EndDate: date(2025, time.March), TrialEndDate: date(2025, time.April)}},
+		{"акция без длительности", CreateSubscriptionRequest{PromoPrice: 199}},
+		{"отрицательная длительность акции", CreateSubscriptionRequest{PromoMonths: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.ServiceName = "Ежемесячный сервис"
+			tt.req.Price = 400
+			tt.req.UserId = "Тестовый UUID"
+			tt.req.StartDate = date(2025, time.January)
+
+			if sub, err := CreateSubscription(tt.req, subscriptionRepo, nil, ""); err == nil {
+				t.Errorf("CreateSubscription() = %+v, want error", sub)
+			}
+		})
+	}
+}
+
 func TestCreateSubscription(t *testing.T) {
 	subscriptionRepo := repository.SubscriptionRepoMock{
 		Subscriptions: make(map[int]*model.Subscription),
@@ -283,7 +962,7 @@ func TestCreateSubscription(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := CreateSubscription(tt.args.req, tt.args.repo)
+			got, err := CreateSubscription(tt.args.req, tt.args.repo, nil, "")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateSubscription() error = %v, wantErr %v", err, tt.wantErr)
@@ -301,6 +980,7 @@ func TestCreateSubscription(t *testing.T) {
 func TestUpdateSubscription(t *testing.T) {
 	subscriptionRepo := repository.SubscriptionRepoMock{
 		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
 	}
 
 	subs := createTestSubscriptions(subscriptionRepo, 1)
@@ -373,7 +1053,7 @@ func TestUpdateSubscription(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := UpdateSubscription(tt.args.req, tt.args.repo, tt.args.subsId)
+			got, err := UpdateSubscription(tt.args.req, tt.args.repo, nil, tt.args.subsId, "")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("UpdateSubscription() error = %v, wantErr %v", err, tt.wantErr)
@@ -390,6 +1070,179 @@ func TestUpdateSubscription(t *testing.T) {
 	}
 }
 
+func TestUpdateSubscriptionPrice(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	now := time.Now()
+	endDate := date(now.Year()+2, time.December)
+
+	sub, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Тестовый сервис",
+		Price:       100,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     endDate,
+	}, subscriptionRepo, nil, "")
+
+	// Суммарная стоимость за январь — март 2025
+	sumPrices := func() int {
+		months, err := SumSubscriptionsPricesByMonth(SumSubscriptionsPricesRequest{
+			StartDate: *date(2025, time.January),
+			EndDate:   *date(2025, time.March),
+		}, subscriptionRepo)
+
+		if err != nil {
+			t.Errorf("SumSubscriptionsPricesByMonth() error = %v", err)
+			return 0
+		}
+
+		sum := 0
+
+		for _, month := range months {
+			sum += month.Total
+		}
+
+		return sum
+	}
+
+	before := sumPrices()
+
+	updated, err := UpdateSubscription(UpdateSubscriptionRequest{
+		ServiceName: "Тестовый сервис",
+		Price:       250,
+		UserId:      "Тестовый UUID",
+		StartDate:   date(2025, time.January),
+		EndDate:     endDate,
+	}, subscriptionRepo, nil, sub.Id, "")
+
+	if err != nil || updated.Price != 100 {
+		t.Errorf("UpdateSubscription() = %+v, %v, want base price 100", updated, err)
+		return
+	}
+
+	if sum := sumPrices(); before != 300 || sum != before {
+		t.Errorf("SumSubscriptionsPricesByMonth() за прошлые месяцы после изменения стоимости = %d, want %d", sum, before)
+	}
+
+	prices, _ := subscriptionRepo.ListPrices(sub.Id)
+
+	if len(prices) != 1 || prices[0].Price != 250 || !prices[0].EffectiveFrom.Time.Equal(monthOf(now)) {
+		t.Errorf("ListPrices() после изменения стоимости = %+v, want 250 с текущего месяца", prices)
+	}
+}
+
+func TestChangeSubscriptionPrice(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Audit:         make(map[int][]model.SubscriptionAudit),
+	}
+
+	startDate := &utils.Date{NullTime: sql.NullTime{Time: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	endDate := &utils.Date{NullTime: sql.NullTime{Time: time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+
+	sub, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Тестовый сервис",
+		Price:       100,
+		UserId:      "Тестовый UUID",
+		StartDate:   startDate,
+		EndDate:     endDate,
+	}, subscriptionRepo, nil, "")
+
+	tests := []struct {
+		name      string
+		req       ChangeSubscriptionPriceRequest
+		subId     int
+		wantSum   int
+		wantErr   bool
+		wantFound bool
+	}{
+		{
+			name: "Изменение стоимости с июля",
+			req: ChangeSubscriptionPriceRequest{
+				EffectiveFrom: &utils.Date{NullTime: sql.NullTime{Time: time.Date(2025, time.July, 1, 0, 0, 0, 0, time.UTC), Valid: true}},
+				Price:         200,
+			},
+			subId:     sub.Id,
+			wantSum:   100*6 + 200*6,
+			wantErr:   false,
+			wantFound: true,
+		},
+		{
+			name: "Изменение стоимости до начала подписки",
+			req: ChangeSubscriptionPriceRequest{
+				EffectiveFrom: &utils.Date{NullTime: sql.NullTime{Time: time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC), Valid: true}},
+				Price:         200,
+			},
+			subId:     sub.Id,
+			wantSum:   100*6 + 200*6,
+			wantErr:   true,
+			wantFound: false,
+		},
+		{
+			name: "Неположительная стоимость",
+			req: ChangeSubscriptionPriceRequest{
+				EffectiveFrom: &utils.Date{NullTime: sql.NullTime{Time: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), Valid: true}},
+				Price:         0,
+			},
+			subId:     sub.Id,
+			wantSum:   100*6 + 200*6,
+			wantErr:   true,
+			wantFound: false,
+		},
+		{
+			name: "Несуществующая подписка",
+			req: ChangeSubscriptionPriceRequest{
+				EffectiveFrom: &utils.Date{NullTime: sql.NullTime{Time: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), Valid: true}},
+				Price:         300,
+			},
+			subId:     0,
+			wantSum:   100*6 + 200*6,
+			wantErr:   false,
+			wantFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChangeSubscriptionPrice(tt.req, subscriptionRepo, nil, tt.subId, "Тестовый автор")
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ChangeSubscriptionPrice() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if (got != nil) != tt.wantFound {
+				t.Errorf("ChangeSubscriptionPrice() = %v, wantFound %v", got, tt.wantFound)
+				return
+			}
+
+			sum, _ := SumSubscriptionsPrices(SumSubscriptionsPricesRequest{
+				StartDate: *startDate,
+				EndDate:   *endDate,
+			}, subscriptionRepo)
+
+			if *sum != tt.wantSum {
+				t.Errorf("SumSubscriptionsPrices() = %v, want %v", *sum, tt.wantSum)
+			}
+		})
+	}
+
+	history, _ := GetSubscriptionHistory(subscriptionRepo, sub.Id)
+
+	if len(history) != 2 || history[1].Action != model.AuditActionChangePrice || history[1].Actor != "Тестовый автор" ||
+		history[1].Price == nil || history[1].Price.Price != 200 {
+		t.Errorf("GetSubscriptionHistory() после изменения стоимости = %+v", history)
+	}
+}
+
 func TestDeleteSubscription(t *testing.T) {
 	subscriptionRepo := repository.SubscriptionRepoMock{
 		Subscriptions: make(map[int]*model.Subscription),
@@ -427,7 +1280,7 @@ func TestDeleteSubscription(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := DeleteSubscription(tt.args.repo, tt.args.subId)
+			err := DeleteSubscription(tt.args.repo, nil, tt.args.subId, "")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DeleteSubscription() error = %v, wantErr %v", err, tt.wantErr)
@@ -437,6 +1290,108 @@ func TestDeleteSubscription(t *testing.T) {
 	}
 }
 
+func TestRestoreSubscription(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Audit:         make(map[int][]model.SubscriptionAudit),
+	}
+
+	subs := createTestSubscriptions(subscriptionRepo, 2)
+
+	DeleteSubscription(subscriptionRepo, nil, subs[0].Id, "Тестовый автор")
+
+	sum, _ := SumSubscriptionsPrices(SumSubscriptionsPricesRequest{
+		StartDate: *subs[0].StartDate,
+		EndDate:   *subs[0].EndDate,
+	}, subscriptionRepo)
+
+	remainingSum, _ := SumSubscriptionsPrices(SumSubscriptionsPricesRequest{
+		ServiceName: subs[1].ServiceName,
+		StartDate:   *subs[0].StartDate,
+		EndDate:     *subs[0].EndDate,
+	}, subscriptionRepo)
+
+	list, _ := ListSubscriptions(ListSubscriptionsRequest{
+		StartDate: *subs[0].StartDate,
+		EndDate:   *subs[0].EndDate,
+		Limit:     10,
+	}, subscriptionRepo)
+
+	if deleted, _ := GetOneSubscription(subscriptionRepo, subs[0].Id); deleted != nil || len(list.Items) != 1 || *sum != *remainingSum {
+		t.Errorf("DeleteSubscription() не исключила подписку: %v, %v, %v", deleted, list, *sum)
+		return
+	}
+
+	type args struct {
+		repo  repository.SubscriptionRepository
+		subId int
+	}
+
+	tests := []struct {
+		name        string
+		args        args
+		wantFound   bool
+		wantHistory []string
+		wantErr     bool
+	}{
+		{
+			name: "Восстановление удалённой подписки",
+			args: args{
+				repo:  subscriptionRepo,
+				subId: subs[0].Id,
+			},
+			wantFound:   true,
+			wantHistory: []string{model.AuditActionCreate, model.AuditActionDelete, model.AuditActionRestore},
+			wantErr:     false,
+		},
+		{
+			name: "Восстановление неудалённой подписки",
+			args: args{
+				repo:  subscriptionRepo,
+				subId: subs[1].Id,
+			},
+			wantFound:   false,
+			wantHistory: []string{model.AuditActionCreate},
+			wantErr:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RestoreSubscription(tt.args.repo, nil, tt.args.subId, "Тестовый автор")
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RestoreSubscription() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if (got != nil) != tt.wantFound {
+				t.Errorf("RestoreSubscription() = %v, wantFound %v", got, tt.wantFound)
+				return
+			}
+
+			history, _ := GetSubscriptionHistory(tt.args.repo, tt.args.subId)
+			actions := []string{}
+
+			for _, entry := range history {
+				actions = append(actions, entry.Action)
+			}
+
+			if !reflect.DeepEqual(actions, tt.wantHistory) {
+				t.Errorf("GetSubscriptionHistory() = %v, want %v", actions, tt.wantHistory)
+			}
+
+			if tt.wantFound {
+				restored, _ := GetOneSubscription(tt.args.repo, tt.args.subId)
+
+				if restored == nil || history[1].Before == nil || history[1].After != nil {
+					t.Errorf("RestoreSubscription() = %v, history %v", restored, history)
+				}
+			}
+		})
+	}
+}
+
 func createTestSubscriptions(repo repository.SubscriptionRepoMock, count int) []model.Subscription {
 	subs := []model.Subscription{}
 
@@ -457,7 +1412,8 @@ func createTestSubscriptions(repo repository.SubscriptionRepoMock, count int) []
 
 		sub, _ := CreateSubscription(
 			createReq,
-			repo,
+			repo, nil,
+			"",
 		)
 
 		repo.Count++
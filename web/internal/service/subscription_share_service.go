@@ -0,0 +1,198 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+)
+
+// SaveSubscriptionSharesRequest Модель данных для замены долей участников подписки. Пустой список удаляет доли
+//
+//	@modelId	save-subscription-shares-request
+type SaveSubscriptionSharesRequest struct {
+	Shares []SubscriptionShareRequest `json:"shares"`
+}
+
+// SubscriptionShareRequest Модель данных доли участника подписки. Указывается либо процент, либо фиксированная сумма
+//
+//	@modelId	subscription-share-request
+//	@required	UserId
+type SubscriptionShareRequest struct {
+	UserId  string  `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Percent float64 `json:"percent,omitempty" example:"25"`
+	Amount  int     `json:"amount,omitempty" example:"100"`
+}
+
+// SettleSubscriptionSharesRequest Модель данных для расчёта долгов по долям в подписках за период
+//
+//	@modelId	settle-subscription-shares-request
+//	@required	StartDate
+//	@required	EndDate
+type SettleSubscriptionSharesRequest struct {
+	UserId      string     `json:"user_id,omitempty"`
+	UserIds     []string   `json:"user_ids,omitempty"`
+	StartDate   utils.Date `json:"start_date" swaggertype:"string" example:"01-2025"`
+	EndDate     utils.Date `json:"end_date" swaggertype:"string" example:"12-2025"`
+	Currency    string     `json:"currency,omitempty" example:"RUB"`
+	BillingMode string     `json:"billing_mode,omitempty" enums:"charge,amortize" example:"charge"`
+}
+
+func ListSubscriptionShares(repo repository.SubscriptionRepository, subId int) ([]model.SubscriptionShare, error) {
+	sub, err := GetOneSubscription(repo, subId)
+
+	if sub == nil {
+		return nil, err
+	}
+
+	return repo.ListShares(sub.Id)
+}
+
+// SaveSubscriptionShares заменяет доли участников подписки. Владелец не может быть участником,
+// а сумма долей по текущей стоимости подписки не может превышать её стоимость.
+// Пороги бюджетов проверяются для владельца и участников до и после изменения
+func SaveSubscriptionShares(req SaveSubscriptionSharesRequest, repo repository.SubscriptionRepository, budgetRepo repository.BudgetRepository, subId int, actor string) ([]model.SubscriptionShare, error) {
+	sub, err := GetOneSubscription(repo, subId)
+
+	if sub == nil {
+		return nil, err
+	}
+
+	shares := []model.SubscriptionShare{}
+	total := 0.0
+
+	for _, item := range req.Shares {
+		userId := strings.TrimSpace(item.UserId)
+
+		if userId == "" {
+			return nil, fmt.Errorf("user_id is required")
+		}
+
+		if userId == sub.UserId {
+			return nil, fmt.Errorf("subscription owner can not be a share participant: %s", userId)
+		}
+
+		if slices.ContainsFunc(shares, func(share model.SubscriptionShare) bool { return share.UserId == userId }) {
+			return nil, fmt.Errorf("duplicate share participant: %s", userId)
+		}
+
+		if (item.Percent == 0) == (item.Amount == 0) {
+			return nil, fmt.Errorf("either percent or amount is required for share of %s", userId)
+		}
+
+		if item.Percent < 0 || item.Percent > 100 || item.Amount < 0 {
+			return nil, fmt.Errorf("invalid share of %s", userId)
+		}
+
+		if item.Percent > 0 {
+			total += item.Percent / 100
+		} else if sub.Price > 0 {
+			total += float64(item.Amount) / float64(sub.Price)
+		}
+
+		shares = append(shares, model.SubscriptionShare{
+			SubscriptionId: sub.Id,
+			UserId:         userId,
+			Percent:        item.Percent,
+			Amount:         item.Amount,
+		})
+	}
+
+	if total > 1+1e-9 {
+		return nil, fmt.Errorf("shares exceed subscription price")
+	}
+
+	before, err := repo.ListShares(sub.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.SaveShares(sub, shares, actor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	checkBudgetAlerts(repo, budgetRepo, *sub, nil, before)
+
+	slices.SortFunc(shares, func(a, b model.SubscriptionShare) int { return cmp.Compare(a.UserId, b.UserId) })
+
+	return shares, nil
+}
+
+// SettleSubscriptionShares рассчитывает, кто кому должен за период по долям в подписках. Встречные долги двух пользователей
+// взаимозачитываются. Если указаны пользователи, возвращаются только долги, в которых они участвуют
+func SettleSubscriptionShares(req SettleSubscriptionSharesRequest, repo repository.SubscriptionRepository) (*model.SettlementReport, error) {
+	if !req.StartDate.Valid || !req.EndDate.Valid {
+		return nil, fmt.Errorf("start_date and end_date are required")
+	}
+
+	if req.EndDate.Time.Before(req.StartDate.Time) {
+		return nil, fmt.Errorf("end_date is before start_date")
+	}
+
+	currency, err := normalizeCurrency(req.Currency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	billingMode, err := normalizeBillingMode(req.BillingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	users := slices.Clone(req.UserIds)
+
+	if req.UserId != "" {
+		users = append(users, req.UserId)
+	}
+
+	debts, err := repo.SumSharedPrices(repository.SubscriptionFilter{
+		MaxStartDate: req.StartDate,
+		MinEndDate:   req.EndDate,
+	}, currency, billingMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	owed := make(map[[2]string]int)
+
+	for _, debt := range debts {
+		owed[[2]string{debt.FromUserId, debt.ToUserId}] += debt.Amount
+	}
+
+	settlements := []model.Settlement{}
+
+	for pair, amount := range owed {
+		net := amount - owed[[2]string{pair[1], pair[0]}]
+
+		if net <= 0 {
+			continue
+		}
+
+		if len(users) > 0 && !slices.Contains(users, pair[0]) && !slices.Contains(users, pair[1]) {
+			continue
+		}
+
+		settlements = append(settlements, model.Settlement{FromUserId: pair[0], ToUserId: pair[1], Amount: net})
+	}
+
+	slices.SortFunc(settlements, func(a, b model.Settlement) int {
+		return cmp.Or(cmp.Compare(a.FromUserId, b.FromUserId), cmp.Compare(a.ToUserId, b.ToUserId))
+	})
+
+	return &model.SettlementReport{
+		StartDate:   &req.StartDate,
+		EndDate:     &req.EndDate,
+		Currency:    currency,
+		Settlements: settlements,
+	}, nil
+}
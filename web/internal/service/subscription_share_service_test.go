@@ -0,0 +1,272 @@
+package service
+
+import (
+	"database/sql"
+	"slices"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"testing"
+	"time"
+)
+
+func TestSaveSubscriptionShares(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Shares:        make(map[int][]model.SubscriptionShare),
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	family, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Yandex Plus",
+		Price:       400,
+		UserId:      "owner",
+		StartDate:   date(2025, time.January),
+	}, subscriptionRepo, nil, "")
+
+	shares, err := SaveSubscriptionShares(SaveSubscriptionSharesRequest{Shares: []SubscriptionShareRequest{
+		{UserId: " bob ", Amount: 100},
+		{UserId: "alice", Percent: 25},
+	}}, subscriptionRepo, nil, family.Id, "")
+
+	if err != nil || len(shares) != 2 || shares[0].UserId != "alice" || shares[1].UserId != "bob" {
+		t.Errorf("SaveSubscriptionShares() = %+v, %v", shares, err)
+		return
+	}
+
+	tests := []struct {
+		name   string
+		shares []SubscriptionShareRequest
+	}{
+		{"без участника", []SubscriptionShareRequest{{Percent: 10}}},
+		{"владелец", []SubscriptionShareRequest{{UserId: "owner", Percent: 10}}},
+		{"повторный участник", []SubscriptionShareRequest{{UserId: "alice", Percent: 10}, {UserId: "alice", Amount: 10}}},
+		{"без доли", []SubscriptionShareRequest{{UserId: "alice"}}},
+		{"процент и сумма", []SubscriptionShareRequest{{UserId: "alice", Percent: 10, Amount: 10}}},
+		{"процент больше 100", []SubscriptionShareRequest{{UserId: "alice", Percent: 120}}},
+		{"отрицательная сумма", []SubscriptionShareRequest{{UserId: "alice", Amount: -10}}},
+		{"доли больше стоимости", []SubscriptionShareRequest{{UserId: "alice", Percent: 60}, {UserId: "bob", Amount: 200}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if shares, err := SaveSubscriptionShares(SaveSubscriptionSharesRequest{Shares: tt.shares}, subscriptionRepo, nil, family.Id, ""); err == nil {
+				t.Errorf("SaveSubscriptionShares() = %+v, want error", shares)
+			}
+		})
+	}
+
+	if shares, _ := ListSubscriptionShares(subscriptionRepo, family.Id); len(shares) != 2 {
+		t.Errorf("ListSubscriptionShares() после отклонённых изменений = %+v", shares)
+	}
+
+	if shares, err := SaveSubscriptionShares(SaveSubscriptionSharesRequest{}, subscriptionRepo, nil, 100, ""); shares != nil || err != nil {
+		t.Errorf("SaveSubscriptionShares() отсутствующей записи = %+v, %v", shares, err)
+	}
+}
+
+func TestSaveSubscriptionSharesHistory(t *testing.T) {
+	outbox := make(map[string]*model.WebhookEvent)
+
+	budgetRepo := repository.BudgetRepoMock{
+		Budgets: make(map[int]*model.Budget),
+		Alerts:  make(map[string]bool),
+	}
+
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Audit:         make(map[int][]model.SubscriptionAudit),
+		Outbox:        outbox,
+		Shares:        make(map[int][]model.SubscriptionShare),
+	}
+
+	if _, err := CreateBudget(SaveBudgetRequest{Amount: 100}, "alice", budgetRepo); err != nil {
+		t.Errorf("CreateBudget() error = %v", err)
+		return
+	}
+
+	family, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Yandex Plus",
+		Price:       400,
+		UserId:      "owner",
+		StartDate:   &utils.Date{NullTime: sql.NullTime{Time: monthOf(time.Now()), Valid: true}},
+	}, subscriptionRepo, budgetRepo, "")
+
+	_, err := SaveSubscriptionShares(SaveSubscriptionSharesRequest{Shares: []SubscriptionShareRequest{
+		{UserId: "alice", Percent: 50},
+	}}, subscriptionRepo, budgetRepo, family.Id, "тест")
+
+	if err != nil {
+		t.Errorf("SaveSubscriptionShares() error = %v", err)
+		return
+	}
+
+	audit := subscriptionRepo.Audit[family.Id]
+
+	if len(audit) != 2 || audit[1].Action != model.AuditActionChangeShares || audit[1].Actor != "тест" || len(audit[1].Shares) != 1 {
+		t.Errorf("SaveSubscriptionShares() журнал изменений = %+v", audit)
+	}
+
+	updated := 0
+
+	for _, event := range outbox {
+		if event.Type == model.WebhookEventSubscriptionUpdated {
+			updated++
+		}
+	}
+
+	if updated != 1 {
+		t.Errorf("SaveSubscriptionShares() событий %s = %d, want 1", model.WebhookEventSubscriptionUpdated, updated)
+	}
+
+	if len(budgetRepo.Alerts) == 0 {
+		t.Errorf("SaveSubscriptionShares() пороги бюджета участника не проверены")
+	}
+
+	// Бюджет участника, доля которого удалена, проверяется по прежним долям, и отметки о порогах снимаются
+	_, err = SaveSubscriptionShares(SaveSubscriptionSharesRequest{}, subscriptionRepo, budgetRepo, family.Id, "тест")
+
+	if err != nil {
+		t.Errorf("SaveSubscriptionShares() error = %v", err)
+		return
+	}
+
+	if len(budgetRepo.Alerts) != 0 {
+		t.Errorf("SaveSubscriptionShares() после удаления долей отметки о порогах = %v", budgetRepo.Alerts)
+	}
+
+	if audit := subscriptionRepo.Audit[family.Id]; len(audit) != 3 || audit[2].Shares == nil || len(audit[2].Shares) != 0 {
+		t.Errorf("SaveSubscriptionShares() журнал изменений после удаления долей = %+v", audit)
+	}
+}
+
+func TestSubscriptionSharesAttribution(t *testing.T) {
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Shares:        make(map[int][]model.SubscriptionShare),
+	}
+
+	date := func(year int, month time.Month) *utils.Date {
+		return &utils.Date{NullTime: sql.NullTime{Time: time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	}
+
+	family, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Yandex Plus",
+		Price:       400,
+		UserId:      "owner",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.March),
+	}, subscriptionRepo, nil, "")
+
+	music, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Spotify",
+		Price:       300,
+		UserId:      "alice",
+		StartDate:   date(2025, time.January),
+		EndDate:     date(2025, time.February),
+	}, subscriptionRepo, nil, "")
+
+	_, err := SaveSubscriptionShares(SaveSubscriptionSharesRequest{Shares: []SubscriptionShareRequest{
+		{UserId: "alice", Percent: 25},
+		{UserId: "bob", Amount: 100},
+	}}, subscriptionRepo, nil, family.Id, "")
+
+	if err != nil {
+		t.Errorf("SaveSubscriptionShares() error = %v", err)
+		return
+	}
+
+	_, err = SaveSubscriptionShares(SaveSubscriptionSharesRequest{Shares: []SubscriptionShareRequest{
+		{UserId: "owner", Percent: 50},
+	}}, subscriptionRepo, nil, music.Id, "")
+
+	if err != nil {
+		t.Errorf("SaveSubscriptionShares() error = %v", err)
+		return
+	}
+
+	// Повышение стоимости не меняет фиксированную долю bob, остаток относится на владельца
+	_, err = ChangeSubscriptionPrice(ChangeSubscriptionPriceRequest{Price: 500, EffectiveFrom: date(2025, time.March)}, subscriptionRepo, nil, family.Id, "")
+
+	if err != nil {
+		t.Errorf("ChangeSubscriptionPrice() error = %v", err)
+		return
+	}
+
+	sums := map[string]int{}
+
+	for _, userId := range []string{"owner", "alice", "bob"} {
+		sum, err := SumSubscriptionsPrices(SumSubscriptionsPricesRequest{
+			UserId:    userId,
+			StartDate: *date(2025, time.January),
+			EndDate:   *date(2025, time.December),
+		}, subscriptionRepo)
+
+		if err != nil {
+			t.Errorf("SumSubscriptionsPrices(%s) error = %v", userId, err)
+			return
+		}
+
+		sums[userId] = *sum
+	}
+
+	// owner: 200 × 2 + 275 за Yandex Plus и 150 × 2 за Spotify; alice: 100 × 2 + 125 и 150 × 2; bob: 100 × 3
+	want := map[string]int{"owner": 975, "alice": 625, "bob": 300}
+
+	for userId, total := range want {
+		if sums[userId] != total {
+			t.Errorf("SumSubscriptionsPrices(%s) = %d, want %d", userId, sums[userId], total)
+		}
+	}
+
+	groups, err := GroupSubscriptionsPrices(SumSubscriptionsPricesRequest{
+		StartDate: *date(2025, time.January),
+		EndDate:   *date(2025, time.December),
+		GroupBy:   []string{model.GroupByUserId},
+	}, subscriptionRepo)
+
+	if err != nil || len(groups) != len(want) {
+		t.Errorf("GroupSubscriptionsPrices() = %+v, %v", groups, err)
+	}
+
+	for _, group := range groups {
+		if group.Total != want[group.UserId] {
+			t.Errorf("GroupSubscriptionsPrices() %s = %d, want %d", group.UserId, group.Total, want[group.UserId])
+		}
+	}
+
+	report, err := SettleSubscriptionShares(SettleSubscriptionSharesRequest{
+		StartDate: *date(2025, time.January),
+		EndDate:   *date(2025, time.December),
+	}, subscriptionRepo)
+
+	// alice должна owner 325 и получает от него 300, bob должен owner 300
+	wantSettlements := []model.Settlement{
+		{FromUserId: "alice", ToUserId: "owner", Amount: 25},
+		{FromUserId: "bob", ToUserId: "owner", Amount: 300},
+	}
+
+	if err != nil || report.Currency != model.DefaultCurrency || !slices.Equal(report.Settlements, wantSettlements) {
+		t.Errorf("SettleSubscriptionShares() = %+v, %v", report, err)
+	}
+
+	report, err = SettleSubscriptionShares(SettleSubscriptionSharesRequest{
+		UserId:    "bob",
+		StartDate: *date(2025, time.March),
+		EndDate:   *date(2025, time.March),
+	}, subscriptionRepo)
+
+	if err != nil || !slices.Equal(report.Settlements, []model.Settlement{{FromUserId: "bob", ToUserId: "owner", Amount: 100}}) {
+		t.Errorf("SettleSubscriptionShares() за март для bob = %+v, %v", report, err)
+	}
+
+	if _, err := SettleSubscriptionShares(SettleSubscriptionSharesRequest{StartDate: *date(2025, time.January)}, subscriptionRepo); err == nil {
+		t.Errorf("SettleSubscriptionShares() без даты окончания не вернула ошибку")
+	}
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	_ "subsaggregator/docs"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/notifier"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/webhook"
+	"time"
+)
+
+const (
+	// maxWebhookAttempts количество попыток доставки события, после которого доставка считается неудачной
+	maxWebhookAttempts = 10
+
+	// webhookRetryDelay задержка перед второй попыткой доставки, каждая следующая задержка вдвое больше
+	webhookRetryDelay = 30 * time.Second
+
+	// maxWebhookRetryDelay наибольшая задержка между попытками доставки
+	maxWebhookRetryDelay = 6 * time.Hour
+
+	// webhookLease время, на которое доставка берётся в работу. Доставки берутся по одной,
+	// поэтому время должно быть больше таймаута клиента отправителя
+	webhookLease = time.Minute
+
+	// webhookBatchSize количество событий и доставок, обрабатываемых за один проход
+	webhookBatchSize = 100
+
+	// minWebhookSecretLength наименьшая длина ключа подписи, заданного при регистрации
+	minWebhookSecretLength = 16
+
+	defaultWebhookDeliveriesLimit = 100
+	maxWebhookDeliveriesLimit     = 1000
+)
+
+// CreateWebhookRequest Модель данных для регистрации вебхука
+//
+//	@modelId	create-webhook-request
+//	@required	Url
+type CreateWebhookRequest struct {
+	Url string `json:"url" example:"https://example.com/hooks/subscriptions"`
+
+	// Ключ подписи. Если не указан, генерируется случайный
+	Secret string `json:"secret,omitempty"`
+
+	// Типы событий. Если не указаны, вебхук подписывается на все события
+	EventTypes []string `json:"event_types,omitempty" example:"subscription.created,subscription.deleted"`
+}
+
+func CreateWebhook(req CreateWebhookRequest, repo repository.WebhookRepository) (*model.WebhookEndpoint, error) {
+	endpointUrl, err := url.Parse(req.Url)
+
+	if err != nil || (endpointUrl.Scheme != "http" && endpointUrl.Scheme != "https") || endpointUrl.Host == "" {
+		return nil, fmt.Errorf("invalid url: %s", req.Url)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookLookupTimeout)
+	err = notifier.CheckWebhookHost(ctx, endpointUrl.Hostname())
+	cancel()
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+
+	eventTypes := []string{}
+
+	for _, eventType := range req.EventTypes {
+		if !slices.Contains(webhookEventTypes(), eventType) {
+			return nil, fmt.Errorf("unknown event type: %s", eventType)
+		}
+
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	if len(eventTypes) == 0 {
+		eventTypes = webhookEventTypes()
+	}
+
+	secret := req.Secret
+
+	if secret == "" {
+		key := make([]byte, 32)
+		rand.Read(key)
+
+		secret = hex.EncodeToString(key)
+	}
+
+	if len(secret) < minWebhookSecretLength {
+		return nil, fmt.Errorf("secret must be at least %d characters long", minWebhookSecretLength)
+	}
+
+	endpoint := &model.WebhookEndpoint{
+		Url:        req.Url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+	}
+
+	err = repo.CreateEndpoint(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+func ListWebhooks(repo repository.WebhookRepository) ([]model.WebhookEndpoint, error) {
+	return repo.ListEndpoints()
+}
+
+func DeleteWebhook(id int, repo repository.WebhookRepository) error {
+	endpoint, err := repo.FindEndpointById(id)
+
+	if err != nil {
+		return err
+	}
+
+	if endpoint == nil {
+		return fmt.Errorf("webhook not found")
+	}
+
+	return repo.DeleteEndpoint(endpoint)
+}
+
+// ListWebhookDeliveries возвращает журнал доставок по вебхуку от новых к старым с фильтрацией по статусу
+func ListWebhookDeliveries(id int, status string, limit int, repo repository.WebhookRepository) ([]model.WebhookDelivery, error) {
+	if status != "" && status != model.WebhookDeliveryPending && status != model.WebhookDeliveryDelivered && status != model.WebhookDeliveryFailed {
+		return nil, fmt.Errorf("unknown delivery status: %s", status)
+	}
+
+	if limit == 0 {
+		limit = defaultWebhookDeliveriesLimit
+	}
+
+	if limit < 0 || limit > maxWebhookDeliveriesLimit {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxWebhookDeliveriesLimit)
+	}
+
+	endpoint, err := repo.FindEndpointById(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint == nil {
+		return nil, fmt.Errorf("webhook not found")
+	}
+
+	return repo.ListDeliveries(id, status, limit)
+}
+
+// DeliverWebhooks разбирает новые события из outbox по подписанным вебхукам и выполняет доставки,
+// время попытки которых наступило. Неудачная доставка повторяется с экспоненциально растущей задержкой,
+// после maxWebhookAttempts попыток доставка считается неудачной. Возвращает количество доставленных событий
+func DeliverWebhooks(ctx context.Context, now time.Time, repo repository.WebhookRepository, sender *webhook.Sender) (int, error) {
+	for {
+		count, err := repo.DispatchEvents(webhookBatchSize)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if count < webhookBatchSize {
+			break
+		}
+	}
+
+	delivered := 0
+	errs := []error{}
+
+	// Доставка берётся в работу непосредственно перед отправкой, чтобы срок работы с ней не истёк,
+	// пока отправляются предыдущие доставки, и её не взял в работу другой экземпляр
+	for range webhookBatchSize {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+
+		pending, err := repo.ClaimDeliveries(now, webhookLease, 1)
+
+		if err != nil {
+			return delivered, errors.Join(append(errs, err)...)
+		}
+
+		if len(pending) == 0 {
+			break
+		}
+
+		item := pending[0]
+
+		delivery := item.Delivery
+		delivery.Attempts++
+
+		statusCode, err := sender.Send(ctx, item.Endpoint, item.Event, now)
+
+		delivery.LastStatusCode = statusCode
+		delivery.LastError = ""
+
+		switch {
+		case err == nil:
+			delivery.Status = model.WebhookDeliveryDelivered
+			delivery.DeliveredAt = &now
+			delivered++
+		case delivery.Attempts >= maxWebhookAttempts:
+			delivery.Status = model.WebhookDeliveryFailed
+			delivery.LastError = err.Error()
+		default:
+			nextAttemptAt := now.Add(webhookRetryBackoff(delivery.Attempts))
+
+			delivery.NextAttemptAt = &nextAttemptAt
+			delivery.LastError = err.Error()
+		}
+
+		if err != nil {
+			slog.Error(fmt.Errorf("событие %s не доставлено по вебхуку %d, попытка %d: %w", item.Event.Id, item.Endpoint.Id, delivery.Attempts, err).Error())
+		}
+
+		if err := repo.SaveDeliveryAttempt(&delivery, item.LeasedUntil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return delivered, errors.Join(errs...)
+}
+
+// webhookRetryBackoff возвращает задержку перед следующей попыткой доставки после attempts неудачных попыток
+func webhookRetryBackoff(attempts int) time.Duration {
+	delay := webhookRetryDelay
+
+	for i := 1; i < attempts && delay < maxWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxWebhookRetryDelay)
+}
+
+// webhookEventTypes возвращает все типы событий вебхуков
+func webhookEventTypes() []string {
+	return []string{
+		model.WebhookEventSubscriptionCreated,
+		model.WebhookEventSubscriptionUpdated,
+		model.WebhookEventSubscriptionDeleted,
+		model.WebhookEventSubscriptionRestored,
+		model.WebhookEventSubscriptionPaused,
+		model.WebhookEventSubscriptionResumed,
+		model.WebhookEventBudgetThreshold,
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"subsaggregator/internal/model"
+	"subsaggregator/internal/repository"
+	"subsaggregator/internal/utils"
+	"subsaggregator/internal/webhook"
+	"testing"
+	"time"
+)
+
+func TestDeliverWebhooks(t *testing.T) {
+	outbox := make(map[string]*model.WebhookEvent)
+
+	subscriptionRepo := repository.SubscriptionRepoMock{
+		Subscriptions: make(map[int]*model.Subscription),
+		Prices:        make(map[int][]model.SubscriptionPrice),
+		Outbox:        outbox,
+		Count:         0,
+	}
+
+	webhookRepo := repository.WebhookRepoMock{
+		Endpoints:  make(map[int]*model.WebhookEndpoint),
+		Outbox:     outbox,
+		Dispatched: make(map[string]bool),
+		Deliveries: make(map[int]*model.WebhookDelivery),
+	}
+
+	received := []model.WebhookEvent{}
+	failing := true
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+
+		if r.Header.Get(webhook.HeaderSignature) != webhook.Sign("секретный ключ вебхука", timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var event model.WebhookEvent
+
+		json.Unmarshal(body, &event)
+
+		if r.Header.Get(webhook.HeaderEventId) != event.Id {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		received = append(received, event)
+	}))
+
+	defer server.Close()
+
+	// Вебхуки регистрируются на внешний адрес, а клиент отправителя направляет все подключения на тестовый сервер
+	endpointUrl := "http://203.0.113.10/hooks"
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+
+	if _, err := CreateWebhook(CreateWebhookRequest{Url: "ftp://example.com"}, webhookRepo); err == nil {
+		t.Errorf("CreateWebhook() с неверным адресом не вернула ошибку")
+	}
+
+	for _, internalUrl := range []string{server.URL, "http://localhost:5432", "http://192.168.1.10/hooks", "http://169.254.169.254/latest/meta-data"} {
+		if _, err := CreateWebhook(CreateWebhookRequest{Url: internalUrl}, webhookRepo); err == nil {
+			t.Errorf("CreateWebhook() с адресом во внутренней сети %s не вернула ошибку", internalUrl)
+		}
+	}
+
+	if _, err := CreateWebhook(CreateWebhookRequest{Url: endpointUrl, EventTypes: []string{"subscription.paid"}}, webhookRepo); err == nil {
+		t.Errorf("CreateWebhook() с неизвестным событием не вернула ошибку")
+	}
+
+	generated, err := CreateWebhook(CreateWebhookRequest{Url: endpointUrl + "/all"}, webhookRepo)
+
+	if err != nil || len(generated.Secret) != 64 || len(generated.EventTypes) != len(webhookEventTypes()) {
+		t.Errorf("CreateWebhook() = %+v, %v", generated, err)
+		return
+	}
+
+	DeleteWebhook(generated.Id, webhookRepo)
+
+	endpoint, err := CreateWebhook(CreateWebhookRequest{
+		Url:        endpointUrl,
+		Secret:     "секретный ключ вебхука",
+		EventTypes: []string{model.WebhookEventSubscriptionCreated, model.WebhookEventSubscriptionDeleted},
+	}, webhookRepo)
+
+	if err != nil {
+		t.Errorf("CreateWebhook() error = %v", err)
+		return
+	}
+
+	startDate := &utils.Date{NullTime: sql.NullTime{Time: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+
+	sub, _ := CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Тестовый сервис",
+		Price:       300,
+		UserId:      "Тестовый UUID",
+		StartDate:   startDate,
+	}, subscriptionRepo, nil, "")
+
+	UpdateSubscription(UpdateSubscriptionRequest{Price: 400}, subscriptionRepo, nil, sub.Id, "")
+	DeleteSubscription(subscriptionRepo, nil, sub.Id, "")
+
+	if len(outbox) != 3 {
+		t.Errorf("outbox = %d событий, want 3", len(outbox))
+	}
+
+	sender := &webhook.Sender{Client: client}
+	now := time.Now().Add(time.Minute)
+
+	delivered, err := DeliverWebhooks(context.Background(), now, webhookRepo, sender)
+
+	if err != nil || delivered != 0 {
+		t.Errorf("DeliverWebhooks() = %d, %v", delivered, err)
+	}
+
+	deliveries, _ := ListWebhookDeliveries(endpoint.Id, model.WebhookDeliveryPending, 0, webhookRepo)
+
+	if len(deliveries) != 2 || deliveries[0].Attempts != 1 || deliveries[0].LastStatusCode != http.StatusServiceUnavailable {
+		t.Errorf("ListWebhookDeliveries() = %+v", deliveries)
+		return
+	}
+
+	if !deliveries[0].NextAttemptAt.Equal(now.Add(webhookRetryDelay)) {
+		t.Errorf("NextAttemptAt = %s, want %s", deliveries[0].NextAttemptAt, now.Add(webhookRetryDelay))
+	}
+
+	failing = false
+
+	if delivered, _ := DeliverWebhooks(context.Background(), now.Add(time.Second), webhookRepo, sender); delivered != 0 {
+		t.Errorf("DeliverWebhooks() до истечения задержки = %d", delivered)
+	}
+
+	delivered, err = DeliverWebhooks(context.Background(), now.Add(webhookRetryDelay), webhookRepo, sender)
+
+	if err != nil || delivered != 2 || len(received) != 2 {
+		t.Errorf("DeliverWebhooks() = %d, %v, received = %+v", delivered, err, received)
+		return
+	}
+
+	if received[0].Type != model.WebhookEventSubscriptionCreated || received[1].Type != model.WebhookEventSubscriptionDeleted {
+		t.Errorf("received = %+v", received)
+	}
+
+	var data model.Subscription
+
+	if err := json.Unmarshal(received[1].Data, &data); err != nil || data.Id != sub.Id || data.DeletedAt == nil {
+		t.Errorf("received data = %s, %v", received[1].Data, err)
+	}
+
+	deliveries, _ = ListWebhookDeliveries(endpoint.Id, model.WebhookDeliveryDelivered, 0, webhookRepo)
+
+	if len(deliveries) != 2 || deliveries[0].Attempts != 2 || deliveries[0].DeliveredAt == nil {
+		t.Errorf("ListWebhookDeliveries() = %+v", deliveries)
+	}
+
+	// Экземпляр, у которого истёк срок работы с доставкой, не перезаписывает результат другого экземпляра
+	CreateSubscription(CreateSubscriptionRequest{
+		ServiceName: "Другой сервис",
+		Price:       500,
+		UserId:      "Тестовый UUID",
+		StartDate:   startDate,
+	}, subscriptionRepo, nil, "")
+
+	later := now.Add(time.Hour)
+
+	webhookRepo.DispatchEvents(webhookBatchSize)
+	stalled, _ := webhookRepo.ClaimDeliveries(later, webhookLease, 1)
+
+	if len(stalled) != 1 {
+		t.Errorf("ClaimDeliveries() = %+v", stalled)
+		return
+	}
+
+	if delivered, err := DeliverWebhooks(context.Background(), later.Add(webhookLease), webhookRepo, sender); err != nil || delivered != 1 {
+		t.Errorf("DeliverWebhooks() после истечения срока = %d, %v", delivered, err)
+	}
+
+	attempt := stalled[0].Delivery
+	attempt.Attempts++
+	attempt.Status = model.WebhookDeliveryFailed
+
+	if err := webhookRepo.SaveDeliveryAttempt(&attempt, stalled[0].LeasedUntil); err == nil {
+		t.Errorf("SaveDeliveryAttempt() с истёкшим сроком не вернула ошибку")
+	}
+
+	if saved := webhookRepo.Deliveries[attempt.Id]; saved.Status != model.WebhookDeliveryDelivered || saved.Attempts != 1 {
+		t.Errorf("доставка после сохранения с истёкшим сроком = %+v", saved)
+	}
+
+	if _, err := ListWebhookDeliveries(generated.Id, "", 0, webhookRepo); err == nil {
+		t.Errorf("ListWebhookDeliveries() удалённого вебхука не вернула ошибку")
+	}
+}
+
+func TestWebhookRetryBackoff(t *testing.T) {
+	want := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		9:  2*time.Hour + 8*time.Minute,
+		10: 4*time.Hour + 16*time.Minute,
+		11: maxWebhookRetryDelay,
+		50: maxWebhookRetryDelay,
+	}
+
+	for attempts, delay := range want {
+		if got := webhookRetryBackoff(attempts); got != delay {
+			t.Errorf("webhookRetryBackoff(%d) = %s, want %s", attempts, got, delay)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CalendarEvent событие календаря на весь день
+type CalendarEvent struct {
+	// Уникальный и постоянный для события идентификатор, по которому календарь обновляет событие
+	UID string
+
+	// День события
+	Date time.Time
+
+	Summary     string
+	Description string
+}
+
+// calendarLineLength максимальная длина строки iCalendar в байтах без переноса строки
+const calendarLineLength = 75
+
+// WriteCalendar записывает события в формате iCalendar (RFC 5545)
+func WriteCalendar(w io.Writer, name string, events []CalendarEvent, now time.Time) error {
+	writer := bufio.NewWriter(w)
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	writeCalendarLine(writer, "BEGIN:VCALENDAR")
+	writeCalendarLine(writer, "VERSION:2.0")
+	writeCalendarLine(writer, "PRODID:-//subsaggregator//subscriptions//RU")
+	writeCalendarLine(writer, "CALSCALE:GREGORIAN")
+	writeCalendarLine(writer, "METHOD:PUBLISH")
+	writeCalendarLine(writer, "X-WR-CALNAME:"+escapeCalendarText(name))
+
+	for _, event := range events {
+		writeCalendarLine(writer, "BEGIN:VEVENT")
+		writeCalendarLine(writer, "UID:"+event.UID)
+		writeCalendarLine(writer, "DTSTAMP:"+stamp)
+		writeCalendarLine(writer, "DTSTART;VALUE=DATE:"+event.Date.Format("20060102"))
+		writeCalendarLine(writer, "DTEND;VALUE=DATE:"+event.Date.AddDate(0, 0, 1).Format("20060102"))
+		writeCalendarLine(writer, "SUMMARY:"+escapeCalendarText(event.Summary))
+
+		if event.Description != "" {
+			writeCalendarLine(writer, "DESCRIPTION:"+escapeCalendarText(event.Description))
+		}
+
+		writeCalendarLine(writer, "TRANSP:TRANSPARENT")
+		writeCalendarLine(writer, "END:VEVENT")
+	}
+
+	writeCalendarLine(writer, "END:VCALENDAR")
+
+	return writer.Flush()
+}
+
+// writeCalendarLine записывает строку iCalendar, перенося её по calendarLineLength байт без разрыва символов UTF-8
+func writeCalendarLine(w *bufio.Writer, line string) {
+	limit := calendarLineLength
+
+	for len(line) > limit {
+		cut := limit
+
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		fmt.Fprintf(w, "%s\r\n ", line[:cut])
+
+		line = line[cut:]
+		limit = calendarLineLength - 1
+	}
+
+	fmt.Fprintf(w, "%s\r\n", line)
+}
+
+// escapeCalendarText экранирует спецсимволы текстового значения iCalendar
+func escapeCalendarText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
@@ -11,6 +11,14 @@ type Date struct {
 	sql.NullTime
 }
 
+func (d *Date) String() string {
+	if d == nil || d.Time.IsZero() {
+		return ""
+	}
+
+	return d.Time.Format("01-2006")
+}
+
 func (d *Date) MarshalJSON() ([]byte, error) {
 	if d.Time.IsZero() {
 		return []byte(`null`), nil
@@ -32,16 +40,27 @@ func (d *Date) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	parsedTime, err := time.Parse("01-2006", s)
+	parsed, err := ParseDate(s)
 
 	if err != nil {
 		return err
 	}
 
-	*d = Date{NullTime: sql.NullTime{
-		Time:  parsedTime,
-		Valid: true,
-	}}
+	*d = parsed
 
 	return nil
 }
+
+// ParseDate разбирает дату в формате MM-YYYY
+func ParseDate(s string) (Date, error) {
+	parsedTime, err := time.Parse("01-2006", s)
+
+	if err != nil {
+		return Date{}, err
+	}
+
+	return Date{NullTime: sql.NullTime{
+		Time:  parsedTime,
+		Valid: true,
+	}}, nil
+}
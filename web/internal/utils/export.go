@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Форматы выгрузки файлов
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatXLSX   = "xlsx"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// exportContentTypes MIME-типы форматов выгрузки
+var exportContentTypes = map[string]string{
+	ExportFormatCSV:    "text/csv; charset=utf-8",
+	ExportFormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	ExportFormatNDJSON: "application/x-ndjson",
+}
+
+// RowWriter построчно записывает таблицу в файл выгрузки.
+// Заголовок записывается вместе с первой строкой, поэтому до первого вызова WriteRow в w ничего не пишется
+type RowWriter interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+// ExportFormat выбирает формат выгрузки по параметру format, а если он не указан — по заголовку Accept.
+// По умолчанию используется CSV
+func ExportFormat(format string, accept string) (string, error) {
+	if format != "" {
+		format = strings.ToLower(format)
+
+		if _, ok := exportContentTypes[format]; !ok {
+			return "", fmt.Errorf("unknown export format: %s", format)
+		}
+
+		return format, nil
+	}
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType := strings.TrimSpace(strings.Split(mediaRange, ";")[0])
+
+		for format, contentType := range exportContentTypes {
+			if mediaType == strings.Split(contentType, ";")[0] {
+				return format, nil
+			}
+		}
+	}
+
+	return ExportFormatCSV, nil
+}
+
+// ExportContentType возвращает MIME-тип формата выгрузки
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// NewRowWriter создаёт RowWriter формата выгрузки с заголовком header
+func NewRowWriter(format string, w io.Writer, header []string) (RowWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return &csvRowWriter{writer: csv.NewWriter(w), header: header}, nil
+	case ExportFormatXLSX:
+		return &xlsxRowWriter{archive: zip.NewWriter(w), header: header}, nil
+	case ExportFormatNDJSON:
+		return &ndjsonRowWriter{writer: bufio.NewWriter(w), header: header}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+type csvRowWriter struct {
+	writer  *csv.Writer
+	header  []string
+	started bool
+}
+
+func (w *csvRowWriter) WriteRow(values []any) error {
+	if !w.started {
+		w.started = true
+
+		if err := w.writer.Write(w.header); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(values))
+
+	for i, value := range values {
+		record[i] = csvCell(value)
+	}
+
+	return w.writer.Write(record)
+}
+
+func (w *csvRowWriter) Close() error {
+	if !w.started {
+		w.started = true
+
+		if err := w.writer.Write(w.header); err != nil {
+			return err
+		}
+	}
+
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
+
+type ndjsonRowWriter struct {
+	writer *bufio.Writer
+	header []string
+}
+
+func (w *ndjsonRowWriter) WriteRow(values []any) error {
+	object := make(map[string]any, len(values))
+
+	for i, value := range values {
+		object[w.header[i]] = value
+	}
+
+	line, err := json.Marshal(object)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.writer.Write(line); err != nil {
+		return err
+	}
+
+	return w.writer.WriteByte('\n')
+}
+
+func (w *ndjsonRowWriter) Close() error {
+	return w.writer.Flush()
+}
+
+// xlsxRowWriter пишет книгу XLSX с одним листом. Служебные части книги записываются в начало архива,
+// а строки листа — потоком в последнюю часть, поэтому книга не хранится в памяти целиком
+type xlsxRowWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	header  []string
+}
+
+// xlsxParts служебные части книги XLSX
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{
+		name: "[Content_Types].xml",
+		content: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`,
+	},
+	{
+		name: "_rels/.rels",
+		content: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`,
+	},
+	{
+		name: "xl/workbook.xml",
+		content: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+			`</workbook>`,
+	},
+	{
+		name: "xl/_rels/workbook.xml.rels",
+		content: `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`,
+	},
+}
+
+// start записывает служебные части книги, начало листа и заголовок
+func (w *xlsxRowWriter) start() error {
+	for _, part := range xlsxParts {
+		file, err := w.archive.Create(part.name)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.WriteString(file, part.content); err != nil {
+			return err
+		}
+	}
+
+	file, err := w.archive.Create("xl/worksheets/sheet1.xml")
+
+	if err != nil {
+		return err
+	}
+
+	w.sheet = bufio.NewWriter(file)
+
+	_, err = w.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	if err != nil {
+		return err
+	}
+
+	header := make([]any, len(w.header))
+
+	for i, column := range w.header {
+		header[i] = column
+	}
+
+	return w.writeRow(header)
+}
+
+func (w *xlsxRowWriter) WriteRow(values []any) error {
+	if w.sheet == nil {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	return w.writeRow(values)
+}
+
+func (w *xlsxRowWriter) writeRow(values []any) error {
+	w.sheet.WriteString("<row>")
+
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			w.sheet.WriteString("<c/>")
+		case int, int64, float64:
+			fmt.Fprintf(w.sheet, "<c><v>%v</v></c>", v)
+		default:
+			// Строка пишется ячейкой inlineStr, поэтому значение, начинающееся с =, не становится формулой
+			w.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+
+			if err := xml.EscapeText(w.sheet, []byte(exportString(v))); err != nil {
+				return err
+			}
+
+			w.sheet.WriteString("</t></is></c>")
+		}
+	}
+
+	_, err := w.sheet.WriteString("</row>")
+
+	return err
+}
+
+func (w *xlsxRowWriter) Close() error {
+	if w.sheet == nil {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.sheet.WriteString("</sheetData></worksheet>"); err != nil {
+		return err
+	}
+
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+
+	return w.archive.Close()
+}
+
+// csvFormulaPrefixes символы, с которых электронные таблицы начинают формулу
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvCell приводит значение ячейки CSV к строке. Перед строкой, которую электронная таблица прочитает как формулу,
+// добавляется апостроф. Числа выводятся как есть
+func csvCell(value any) string {
+	cell := exportString(value)
+
+	switch value.(type) {
+	case int, int64, float64:
+		return cell
+	}
+
+	if cell != "" && strings.ContainsRune(csvFormulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
+
+// exportString приводит значение ячейки к строке
+func exportString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"subsaggregator/internal/model"
+	"time"
+)
+
+// Заголовки запроса с событием
+const (
+	HeaderEventId   = "X-Webhook-Id"
+	HeaderEventType = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign возвращает подпись тела запроса: sha256= и HMAC-SHA256 строки "<timestamp>.<body>" в шестнадцатеричном виде.
+// Получатель проверяет подпись тем же ключом и отклоняет запросы со старой временной меткой
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sender отправляет события жизненного цикла подписок на вебхуки
+type Sender struct {
+	Client *http.Client
+}
+
+// Send отправляет событие POST-запросом с JSON и подписью. Возвращает код ответа;
+// ответ с кодом не из 2xx считается ошибкой
+func (s *Sender) Send(ctx context.Context, endpoint model.WebhookEndpoint, event model.WebhookEvent, now time.Time) (int, error) {
+	body, err := json.Marshal(event)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	timestamp := now.Unix()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook url: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEventId, event.Id)
+	request.Header.Set(HeaderEventType, event.Type)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	client := s.Client
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}